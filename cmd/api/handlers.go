@@ -8,6 +8,21 @@ type Handlers struct {
 	fileUpload        *handlers.UploadHandler
 	auth              *handlers.AuthHandler
 	wishlist          *handlers.WishlistHandler
+	cart              *handlers.CartHandler
+	order             *handlers.OrderHandler
+	payment           *handlers.PaymentHandler
+	inventory         *handlers.InventoryHandler
+	promotion         *handlers.PromotionHandler
+	tax               *handlers.TaxHandler
+	shipping          *handlers.ShippingHandler
+	region            *handlers.RegionHandler
+	priceList         *handlers.PriceListHandler
+	exchangeRate      *handlers.ExchangeRateHandler
+	customerGroup     *handlers.CustomerGroupHandler
+	giftCard          *handlers.GiftCardHandler
+	storeCredit       *handlers.StoreCreditHandler
+	orderReturn       *handlers.ReturnHandler
+	search            *handlers.SearchHandler
 }
 
 func (app *application) createHandlers() *Handlers {
@@ -17,5 +32,20 @@ func (app *application) createHandlers() *Handlers {
 		fileUpload:        handlers.NewUploadHandler(app.logger, app.services.Upload),
 		auth:              handlers.NewAuthHandler(app.logger, app.services.Auth),
 		wishlist:          handlers.NewWishlistHandler(app.logger, app.services.Wishlist),
+		cart:              handlers.NewCartHandler(app.logger, app.services.Cart),
+		order:             handlers.NewOrderHandler(app.logger, app.services.Order),
+		payment:           handlers.NewPaymentHandler(app.logger, app.services.Payment),
+		inventory:         handlers.NewInventoryHandler(app.logger, app.services.Inventory),
+		promotion:         handlers.NewPromotionHandler(app.logger, app.services.Promotion),
+		tax:               handlers.NewTaxHandler(app.logger, app.services.Tax),
+		shipping:          handlers.NewShippingHandler(app.logger, app.services.Shipping),
+		region:            handlers.NewRegionHandler(app.logger, app.services.Region),
+		priceList:         handlers.NewPriceListHandler(app.logger, app.services.PriceList),
+		exchangeRate:      handlers.NewExchangeRateHandler(app.logger, app.services.ExchangeRate),
+		customerGroup:     handlers.NewCustomerGroupHandler(app.logger, app.services.CustomerGroup),
+		giftCard:          handlers.NewGiftCardHandler(app.logger, app.services.GiftCard),
+		storeCredit:       handlers.NewStoreCreditHandler(app.logger, app.services.StoreCredit),
+		orderReturn:       handlers.NewReturnHandler(app.logger, app.services.Return),
+		search:            handlers.NewSearchHandler(app.logger, app.services.Search),
 	}
 }
@@ -1,15 +1,20 @@
 package main
 
 import (
+	"context"
 	"database/sql"
 	"ecom-backend/internal/handlers"
 	"ecom-backend/internal/jsonlog"
 	"ecom-backend/internal/model"
+	"ecom-backend/internal/money"
 	"ecom-backend/internal/service"
 	"ecom-backend/pkg/sqldb"
+	"errors"
 	"flag"
 	"fmt"
 	"os"
+	"strings"
+	"time"
 
 	_ "github.com/lib/pq"
 )
@@ -25,6 +30,18 @@ type config struct {
 	cors struct {
 		trustedOrigins []string
 	}
+	payment struct {
+		fakeProvider      bool
+		fakeWebhookSecret string
+	}
+	inventory struct {
+		reservationTTL time.Duration
+		sweepInterval  time.Duration
+		allocation     string
+	}
+	pricing struct {
+		baseCurrency string
+	}
 }
 
 type application struct {
@@ -51,8 +68,34 @@ func main() {
 	flag.StringVar(&cfg.db.maxIdleTime, "db-max-idle-time", "15m",
 		"PostgreSQL max connection idle time")
 
+	flag.BoolVar(&cfg.payment.fakeProvider, "payment-fake-provider", false,
+		"Register the fake payment provider, which authorizes any payment method. For development only")
+	flag.StringVar(&cfg.payment.fakeWebhookSecret, "payment-fake-webhook-secret", "",
+		"Secret used to sign the webhooks of the fake payment provider")
+
+	flag.DurationVar(&cfg.inventory.reservationTTL, "inventory-reservation-ttl", 15*time.Minute,
+		"How long the stock of a cart stays reserved during checkout")
+	flag.DurationVar(&cfg.inventory.sweepInterval, "inventory-sweep-interval", time.Minute,
+		"How often the expired inventory reservations are swept")
+	flag.StringVar(&cfg.inventory.allocation, "inventory-allocation-strategy", service.AllocationStrategyPriority,
+		"Strategy used to pick the stock location of an order line item (priority|most_stock)")
+
+	flag.StringVar(&cfg.pricing.baseCurrency, "pricing-base-currency", "usd",
+		"Currency the prices missing in other currencies are converted from")
+
 	flag.Parse()
 
+	cfg.pricing.baseCurrency = strings.ToLower(cfg.pricing.baseCurrency)
+
+	if cfg.inventory.allocation != service.AllocationStrategyPriority && cfg.inventory.allocation != service.AllocationStrategyMostStock {
+		logger.PrintFatal(fmt.Errorf("invalid inventory allocation strategy %q", cfg.inventory.allocation), nil)
+	}
+
+	// anyone knowing the secret can forge the webhooks, the one that used to be the default is public
+	if cfg.payment.fakeProvider && (cfg.payment.fakeWebhookSecret == "" || cfg.payment.fakeWebhookSecret == "fake-webhook-secret") {
+		logger.PrintFatal(errors.New("the fake payment provider needs a -payment-fake-webhook-secret of its own"), nil)
+	}
+
 	db, err := sqldb.OpenDB(sqldb.DbConfig{Dsn: cfg.db.dsn, MaxOpenConns: cfg.db.maxOpenConns, MaxIdleConns: cfg.db.maxIdleConns, MaxIdleTime: cfg.db.maxIdleTime})
 
 	if err != nil {
@@ -60,8 +103,16 @@ func main() {
 	}
 
 	app := application{cfg: cfg, logger: logger, db: db}
+
+	err = app.loadCurrencyExponents()
+
+	if err != nil {
+		logger.PrintFatal(err, nil)
+	}
+
 	app.initServices()
 	app.middleware = handlers.NewMiddleware(logger, app.services)
+	app.startReservationSweeper()
 
 	err = app.serve()
 
@@ -74,5 +125,40 @@ func (app *application) initServices() {
 	db := app.db
 	models := model.NewModels(db)
 
-	app.services = service.NewServices(db, models)
+	paymentProviders := []service.PaymentProvider{}
+
+	if app.cfg.payment.fakeProvider {
+		paymentProviders = append(paymentProviders, service.NewFakePaymentProvider(app.cfg.payment.fakeWebhookSecret))
+	}
+
+	app.services = service.NewServices(db, models, service.Config{
+		PaymentProviders:   paymentProviders,
+		ReservationTTL:     app.cfg.inventory.reservationTTL,
+		AllocationStrategy: app.cfg.inventory.allocation,
+		TaxCalculator:      service.NewTableTaxCalculator(db, models),
+		BaseCurrency:       app.cfg.pricing.baseCurrency,
+	})
+}
+
+// loadCurrencyExponents registers the number of decimals of every currency, the amounts of money are handled
+// in the minor units of their currency
+func (app *application) loadCurrencyExponents() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	currencies, err := model.NewCurrencyModel().FindAll(ctx, app.db)
+
+	if err != nil {
+		return err
+	}
+
+	exponents := map[string]int{}
+
+	for _, currency := range currencies {
+		exponents[currency.Code] = currency.Exponent
+	}
+
+	money.SetExponents(exponents)
+
+	return nil
 }
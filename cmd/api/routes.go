@@ -23,18 +23,109 @@ func (app *application) routes() http.Handler {
 	router.POST("/api/v1/products", m.AdminOnly(h.product.CreateProduct))
 	router.PATCH("/api/v1/products/:productId", m.AdminOnly(h.product.UpdateProductGeneralInfo))
 	router.PATCH("/api/v1/variants/:variantId", m.AdminOnly(h.product.UpdateVariantDetails))
+	router.POST("/api/v1/variants/:variantId/stock-movements", m.AdminOnly(h.inventory.RecordStockMovement))
+	router.GET("/api/v1/variants/:variantId/stock-movements", m.AdminOnly(h.inventory.ListStockMovements))
 	router.DELETE("/api/v1/products/:productId", m.AdminOnly(h.product.DeleteProduct))
+	router.GET("/api/v1/stock-locations", m.AdminOnly(h.inventory.ListStockLocations))
+	router.POST("/api/v1/stock-locations", m.AdminOnly(h.inventory.CreateStockLocation))
+	router.GET("/api/v1/stock-locations/:id", m.AdminOnly(h.inventory.GetStockLocation))
+	router.PATCH("/api/v1/stock-locations/:id", m.AdminOnly(h.inventory.UpdateStockLocation))
+	router.DELETE("/api/v1/stock-locations/:id", m.AdminOnly(h.inventory.DeleteStockLocation))
+	router.GET("/api/v1/reports/low-stock", m.AdminOnly(h.inventory.LowStockReport))
+	router.GET("/api/v1/promotions", m.AdminOnly(h.promotion.List))
+	router.POST("/api/v1/promotions", m.AdminOnly(h.promotion.Create))
+	router.POST("/api/v1/promotions/dry-run", m.AdminOnly(h.promotion.DryRun))
+	router.GET("/api/v1/promotions/:id", m.AdminOnly(h.promotion.Get))
+	router.PATCH("/api/v1/promotions/:id", m.AdminOnly(h.promotion.Update))
+	router.DELETE("/api/v1/promotions/:id", m.AdminOnly(h.promotion.Delete))
+	router.GET("/api/v1/tax-regions", m.AdminOnly(h.tax.ListTaxRegions))
+	router.POST("/api/v1/tax-regions", m.AdminOnly(h.tax.CreateTaxRegion))
+	router.GET("/api/v1/tax-regions/:id", m.AdminOnly(h.tax.GetTaxRegion))
+	router.PATCH("/api/v1/tax-regions/:id", m.AdminOnly(h.tax.UpdateTaxRegion))
+	router.DELETE("/api/v1/tax-regions/:id", m.AdminOnly(h.tax.DeleteTaxRegion))
+	router.GET("/api/v1/shipping-zones", m.AdminOnly(h.shipping.ListShippingZones))
+	router.POST("/api/v1/shipping-zones", m.AdminOnly(h.shipping.CreateShippingZone))
+	router.GET("/api/v1/shipping-zones/:id", m.AdminOnly(h.shipping.GetShippingZone))
+	router.PATCH("/api/v1/shipping-zones/:id", m.AdminOnly(h.shipping.UpdateShippingZone))
+	router.DELETE("/api/v1/shipping-zones/:id", m.AdminOnly(h.shipping.DeleteShippingZone))
+	router.POST("/api/v1/shipping-zones/:id/methods", m.AdminOnly(h.shipping.CreateShippingMethod))
+	router.PATCH("/api/v1/shipping-methods/:id", m.AdminOnly(h.shipping.UpdateShippingMethod))
+	router.DELETE("/api/v1/shipping-methods/:id", m.AdminOnly(h.shipping.DeleteShippingMethod))
+	router.POST("/api/v1/regions", m.AdminOnly(h.region.CreateRegion))
+	router.PATCH("/api/v1/regions/:id", m.AdminOnly(h.region.UpdateRegion))
+	router.DELETE("/api/v1/regions/:id", m.AdminOnly(h.region.DeleteRegion))
+	router.GET("/api/v1/price-lists", m.AdminOnly(h.priceList.ListPriceLists))
+	router.POST("/api/v1/price-lists", m.AdminOnly(h.priceList.CreatePriceList))
+	router.GET("/api/v1/price-lists/:id", m.AdminOnly(h.priceList.GetPriceList))
+	router.PATCH("/api/v1/price-lists/:id", m.AdminOnly(h.priceList.UpdatePriceList))
+	router.DELETE("/api/v1/price-lists/:id", m.AdminOnly(h.priceList.DeletePriceList))
+	router.GET("/api/v1/exchange-rates", m.AdminOnly(h.exchangeRate.ListExchangeRates))
+	router.POST("/api/v1/exchange-rates/import", m.AdminOnly(h.exchangeRate.ImportExchangeRates))
+	router.PUT("/api/v1/exchange-rates/:base/:quote", m.AdminOnly(h.exchangeRate.SetExchangeRate))
+	router.DELETE("/api/v1/exchange-rates/:base/:quote", m.AdminOnly(h.exchangeRate.DeleteExchangeRate))
+	router.GET("/api/v1/customer-groups", m.AdminOnly(h.customerGroup.ListCustomerGroups))
+	router.POST("/api/v1/customer-groups", m.AdminOnly(h.customerGroup.CreateCustomerGroup))
+	router.GET("/api/v1/customer-groups/:id", m.AdminOnly(h.customerGroup.GetCustomerGroup))
+	router.PATCH("/api/v1/customer-groups/:id", m.AdminOnly(h.customerGroup.UpdateCustomerGroup))
+	router.DELETE("/api/v1/customer-groups/:id", m.AdminOnly(h.customerGroup.DeleteCustomerGroup))
+	router.GET("/api/v1/customer-groups/:id/members", m.AdminOnly(h.customerGroup.ListMembers))
+	router.PUT("/api/v1/users/:id/customer-groups", m.AdminOnly(h.customerGroup.SetUserCustomerGroups))
+	router.GET("/api/v1/gift-cards", m.AdminOnly(h.giftCard.ListGiftCards))
+	router.POST("/api/v1/gift-cards", m.AdminOnly(h.giftCard.IssueGiftCard))
+	router.GET("/api/v1/gift-cards/:id", m.AdminOnly(h.giftCard.GetGiftCard))
+	router.PATCH("/api/v1/gift-cards/:id", m.AdminOnly(h.giftCard.UpdateGiftCard))
+	router.GET("/api/v1/users/:id/store-credit", m.AdminOnly(h.storeCredit.GetUserStoreCredit))
+	router.POST("/api/v1/users/:id/store-credit", m.AdminOnly(h.storeCredit.AdjustUserStoreCredit))
 	router.POST("/api/v1/product-categories", m.AdminOnly(h.productCategories.Create))
 	router.DELETE("/api/v1/product-categories/:categoryId", m.AdminOnly(h.productCategories.DeleteById))
 	router.PATCH("/api/v1/product-categories/:categoryId", m.AdminOnly(h.productCategories.UpdateById))
+	router.GET("/api/v1/orders", m.AdminOnly(h.order.ListOrders))
+	router.POST("/api/v1/orders/:id/transitions", m.AdminOnly(h.order.TransitionOrder))
+	router.GET("/api/v1/orders/:id/events", m.AdminOnly(h.order.ListEvents))
+	router.GET("/api/v1/orders/:id/payments", m.AdminOnly(h.payment.ListOrderPayments))
+	router.POST("/api/v1/orders/:id/payments/capture", m.AdminOnly(h.payment.Capture))
+	router.POST("/api/v1/orders/:id/payments/refund", m.AdminOnly(h.payment.Refund))
+	router.POST("/api/v1/orders/:id/payments/void", m.AdminOnly(h.payment.Void))
+	router.GET("/api/v1/returns", m.AdminOnly(h.orderReturn.ListReturns))
+	router.GET("/api/v1/returns/:id", m.AdminOnly(h.orderReturn.GetReturn))
+	router.POST("/api/v1/returns/:id/approve", m.AdminOnly(h.orderReturn.ApproveReturn))
+	router.POST("/api/v1/returns/:id/reject", m.AdminOnly(h.orderReturn.RejectReturn))
+	router.POST("/api/v1/returns/:id/receive", m.AdminOnly(h.orderReturn.ReceiveReturn))
+	router.POST("/api/v1/returns/:id/refund", m.AdminOnly(h.orderReturn.RefundReturn))
 
 	// Public routes
 	router.GET("/api/v1/products", h.product.GetProducts)
 	router.GET("/api/v1/products/:productId", h.product.GetProduct)
+	router.GET("/api/v1/search/suggest", h.search.Suggest)
 	router.GET("/api/v1/product-categories", h.productCategories.GetAll)
+	router.GET("/api/v1/regions", h.region.ListRegions)
+	router.GET("/api/v1/regions/:id", h.region.GetRegion)
 	router.POST("/api/v1/wishlist/add", m.RequireSessionOrUser(h.wishlist.Create))
 	router.GET("/api/v1/wishlist", m.RequireSessionOrUser(h.wishlist.GetAll))
 	router.DELETE("/api/v1/wishlist/remove/:id", m.RequireSessionOrUser(h.wishlist.DeleteItem))
+	router.GET("/api/v1/back-in-stock-subscriptions", m.RequireSessionOrUser(h.inventory.ListBackInStockSubscriptions))
+	router.POST("/api/v1/variants/:variantId/back-in-stock-subscription", m.RequireSessionOrUser(h.inventory.SubscribeBackInStock))
+	router.DELETE("/api/v1/variants/:variantId/back-in-stock-subscription", m.RequireSessionOrUser(h.inventory.UnsubscribeBackInStock))
+	router.GET("/api/v1/cart", m.RequireSessionOrUser(h.cart.Get))
+	router.DELETE("/api/v1/cart", m.RequireSessionOrUser(h.cart.Clear))
+	router.POST("/api/v1/cart/items", m.RequireSessionOrUser(h.cart.AddItem))
+	router.PATCH("/api/v1/cart/items/:id", m.RequireSessionOrUser(h.cart.UpdateItem))
+	router.DELETE("/api/v1/cart/items/:id", m.RequireSessionOrUser(h.cart.RemoveItem))
+	router.POST("/api/v1/cart/promotion", m.RequireSessionOrUser(h.cart.ApplyPromotion))
+	router.DELETE("/api/v1/cart/promotion", m.RequireSessionOrUser(h.cart.RemovePromotion))
+	router.GET("/api/v1/cart/shipping-options", m.RequireSessionOrUser(h.cart.ShippingOptions))
+	router.POST("/api/v1/cart/reservation", m.RequireSessionOrUser(h.inventory.ReserveCart))
+	router.DELETE("/api/v1/cart/reservation", m.RequireSessionOrUser(h.inventory.ReleaseCart))
+	router.POST("/api/v1/checkout", m.RequireSessionOrUser(h.order.Checkout))
+	router.GET("/api/v1/orders/:id", m.RequireSessionOrUser(h.order.GetOrder))
+	router.POST("/api/v1/orders/:id/payment-sessions", m.RequireSessionOrUser(h.payment.CreateSession))
+	router.GET("/api/v1/orders/:id/returns", m.RequireSessionOrUser(h.orderReturn.ListOrderReturns))
+	router.POST("/api/v1/orders/:id/returns", m.RequireSessionOrUser(h.orderReturn.RequestReturn))
+	router.POST("/api/v1/orders/:id/returns/:returnId/cancel", m.RequireSessionOrUser(h.orderReturn.CancelReturn))
+	router.GET("/api/v1/payment-providers", h.payment.ListProviders)
+	router.POST("/api/v1/gift-cards/balance", h.giftCard.CheckGiftCardBalance)
+	router.GET("/api/v1/store-credit", m.RequireActivation(h.storeCredit.GetStoreCredit))
+	router.POST("/api/v1/payments/webhooks/:providerId", h.payment.Webhook)
 
 	// File upload
 	router.POST("/api/v1/upload", m.RequireActivation(h.fileUpload.UploadFile))
@@ -45,5 +136,5 @@ func (app *application) routes() http.Handler {
 	router.HandlerFunc(http.MethodPost, "/api/v1/tokens/authentication", h.auth.Login)
 	router.GET("/api/v1/session", h.auth.GetSession)
 
-	return m.RecoverPanic(m.EnableCORS(m.Authenticate(router)))
+	return m.RecoverPanic(m.EnableCORS(m.Authenticate(m.SelectRegion(router))))
 }
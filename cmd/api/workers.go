@@ -0,0 +1,38 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// startReservationSweeper periodically expires the inventory reservations that outlived their TTL
+func (app *application) startReservationSweeper() {
+	go func() {
+		defer func() {
+			if err := recover(); err != nil {
+				app.logger.PrintError(fmt.Errorf("%s", err), nil)
+			}
+		}()
+
+		ticker := time.NewTicker(app.cfg.inventory.sweepInterval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+
+			count, err := app.services.Inventory.ExpireReservations(ctx)
+
+			cancel()
+
+			if err != nil {
+				app.logger.PrintError(err, nil)
+				continue
+			}
+
+			if count > 0 {
+				app.logger.PrintInfo("expired inventory reservations", map[string]string{"count": fmt.Sprint(count)})
+			}
+		}
+	}()
+}
@@ -5,3 +5,109 @@ const (
 	StatusPublished = "published"
 	StatusDeleted   = "deleted"
 )
+
+// DefaultCurrencyCode is used for pricing when the client doesn't request a specific currency
+const DefaultCurrencyCode = "usd"
+
+const (
+	OrderStatusPending   = "pending"
+	OrderStatusPaid      = "paid"
+	OrderStatusFulfilled = "fulfilled"
+	OrderStatusShipped   = "shipped"
+	OrderStatusDelivered = "delivered"
+	OrderStatusCancelled = "cancelled"
+	OrderStatusRefunded  = "refunded"
+)
+
+const (
+	PaymentSessionStatusPending        = "pending"
+	PaymentSessionStatusAuthorized     = "authorized"
+	PaymentSessionStatusRequiresAction = "requires_action" // ex: 3D Secure confirmation
+	PaymentSessionStatusDeclined       = "declined"
+	PaymentSessionStatusVoided         = "voided"
+	PaymentSessionStatusError          = "error"
+)
+
+const (
+	PaymentStatusAuthorized        = "authorized"
+	PaymentStatusCapturing         = "capturing" // the provider is collecting the payment
+	PaymentStatusCaptured          = "captured"
+	PaymentStatusPartiallyRefunded = "partially_refunded"
+	PaymentStatusRefunded          = "refunded"
+	PaymentStatusVoided            = "voided"
+)
+
+const (
+	PaymentRefundStatusPending   = "pending" // asked to the provider, counted as refunded until it's settled
+	PaymentRefundStatusSucceeded = "succeeded"
+)
+
+const (
+	ReservationStatusActive   = "active"
+	ReservationStatusConsumed = "consumed" // the reserved stock was sold through checkout
+	ReservationStatusReleased = "released"
+	ReservationStatusExpired  = "expired"
+)
+
+const (
+	StockMovementReceipt    = "receipt"
+	StockMovementSale       = "sale"
+	StockMovementReturn     = "return"
+	StockMovementAdjustment = "adjustment"
+	StockMovementCorrection = "correction"
+)
+
+const (
+	ReturnStatusRequested = "requested"
+	ReturnStatusApproved  = "approved"
+	ReturnStatusRejected  = "rejected"
+	ReturnStatusReceived  = "received"
+	ReturnStatusRefunding = "refunding" // waiting for the payment provider to refund the payment
+	ReturnStatusRefunded  = "refunded"
+	ReturnStatusCancelled = "cancelled" // withdrawn by the customer before it was approved
+)
+
+const (
+	ReturnReasonDamaged        = "damaged"
+	ReturnReasonDefective      = "defective"
+	ReturnReasonWrongItem      = "wrong_item"
+	ReturnReasonNotAsDescribed = "not_as_described"
+	ReturnReasonNoLongerNeeded = "no_longer_needed"
+	ReturnReasonOther          = "other"
+)
+
+// types of the changes of a gift card balance or of the store credit of a user
+const (
+	BalanceTransactionIssue      = "issue"
+	BalanceTransactionAdjustment = "adjustment"
+	BalanceTransactionRedeem     = "redeem"  // paid part of an order
+	BalanceTransactionRestore    = "restore" // given back when the order it paid was cancelled
+)
+
+const (
+	NotificationTypeBackInStock = "back_in_stock"
+	NotificationTypeGiftCard    = "gift_card"
+)
+
+const (
+	PromotionTypePercentage = "percentage"
+	PromotionTypeFixed      = "fixed"
+	PromotionTypeBuyXGetY   = "buy_x_get_y"
+	PromotionTypeTiered     = "tiered"
+)
+
+const (
+	PromotionTargetProduct  = "product"
+	PromotionTargetCategory = "category"
+)
+
+const (
+	ShippingRateTypeFlat        = "flat"
+	ShippingRateTypeWeightBased = "weight_based"
+)
+
+const (
+	PriceListTypeSale          = "sale"
+	PriceListTypeWholesale     = "wholesale"
+	PriceListTypeCustomerGroup = "customer_group"
+)
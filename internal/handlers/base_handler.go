@@ -22,6 +22,16 @@ type PaginationMetadata struct {
 	RowsTotal int `json:"rowsTotal"`
 }
 
+// CursorPaginationMetadata is the metadata of the listings that can be paged with cursors as well as by page number.
+// The page number and the total are only given when paging by page number.
+type CursorPaginationMetadata struct {
+	Page       int     `json:"page,omitempty"`
+	PageSize   int     `json:"pageSize"`
+	RowsTotal  *int    `json:"rowsTotal,omitempty"`
+	NextCursor *string `json:"next_cursor"` // passed as the `cursor` query parameter to get the next page
+	PrevCursor *string `json:"prev_cursor"`
+}
+
 type Envelope map[string]interface{}
 type BaseHandler struct {
 	logger *jsonlog.Logger
@@ -0,0 +1,241 @@
+package handlers
+
+import (
+	"ecom-backend/internal/jsonlog"
+	"ecom-backend/internal/model"
+	"ecom-backend/internal/service"
+	"ecom-backend/internal/validator"
+	"errors"
+	"net/http"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+type CartHandler struct {
+	BaseHandler
+	cartSvc *service.CartService
+}
+
+func NewCartHandler(logger *jsonlog.Logger, cartSvc *service.CartService) *CartHandler {
+	return &CartHandler{BaseHandler: BaseHandler{logger: logger}, cartSvc: cartSvc}
+}
+
+func (h *CartHandler) Get(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	h.writeCart(w, r, http.StatusOK)
+}
+
+func (h *CartHandler) AddItem(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	clientIdentifier := contextGetClientIdentifier(r)
+
+	var input service.AddCartItemInput
+
+	err := h.ReadJSON(w, r, &input)
+
+	if err != nil {
+		h.BadRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+
+	if input.Validate(v); !v.Valid() {
+		h.FailedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	_, err = h.cartSvc.AddItem(r.Context(), clientIdentifier, &input)
+
+	if err != nil {
+		switch {
+		case errors.Is(err, model.ErrVariantNotFound):
+			h.BadRequestResponse(w, r, err)
+		case errors.Is(err, model.ErrInsufficientInventory):
+			h.ErrorResponse(w, r, http.StatusConflict, err.Error())
+		default:
+			h.ServerErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	h.writeCart(w, r, http.StatusCreated)
+}
+
+func (h *CartHandler) UpdateItem(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	itemId := ps.ByName("id")
+	clientIdentifier := contextGetClientIdentifier(r)
+
+	if !validator.IsValidUUID(itemId) {
+		h.NotFoundResponse(w, r)
+		return
+	}
+
+	var input service.UpdateCartItemInput
+
+	err := h.ReadJSON(w, r, &input)
+
+	if err != nil {
+		h.BadRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+
+	if input.Validate(v); !v.Valid() {
+		h.FailedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	err = h.cartSvc.UpdateItemQuantity(r.Context(), clientIdentifier, itemId, &input)
+
+	if err != nil {
+		h.cartItemErrorResponse(w, r, err)
+		return
+	}
+
+	h.writeCart(w, r, http.StatusOK)
+}
+
+func (h *CartHandler) RemoveItem(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	itemId := ps.ByName("id")
+	clientIdentifier := contextGetClientIdentifier(r)
+
+	if !validator.IsValidUUID(itemId) {
+		h.NotFoundResponse(w, r)
+		return
+	}
+
+	err := h.cartSvc.RemoveItem(r.Context(), clientIdentifier, itemId)
+
+	if err != nil {
+		h.cartItemErrorResponse(w, r, err)
+		return
+	}
+
+	h.writeCart(w, r, http.StatusOK)
+}
+
+func (h *CartHandler) Clear(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	clientIdentifier := contextGetClientIdentifier(r)
+
+	err := h.cartSvc.Clear(r.Context(), clientIdentifier)
+
+	if err != nil {
+		h.ServerErrorResponse(w, r, err)
+		return
+	}
+
+	h.writeCart(w, r, http.StatusOK)
+}
+
+func (h *CartHandler) ApplyPromotion(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var input struct {
+		Code string `json:"code"`
+	}
+
+	err := h.ReadJSON(w, r, &input)
+
+	if err != nil {
+		h.BadRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+
+	if v.Check(input.Code != "", "code", "must be provided"); !v.Valid() {
+		h.FailedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	cart, err := h.cartSvc.ApplyPromotionCode(r.Context(), contextGetClientIdentifier(r), getCustomerGroupIds(r), input.Code, getCurrencyCode(r), getTaxLocation(r))
+
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrPromotionNotFound):
+			h.FailedValidationResponse(w, r, map[string]string{"code": "invalid promotion code"})
+		case errors.Is(err, service.ErrEmptyCart),
+			errors.Is(err, service.ErrPromotionNotApplicable),
+			errors.Is(err, service.ErrPromotionUsageLimitReached):
+			h.ErrorResponse(w, r, http.StatusUnprocessableEntity, err.Error())
+		default:
+			h.ServerErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = h.WriteJson(w, http.StatusOK, ResponseBody{Payload: Envelope{"cart": cart}}, nil)
+
+	if err != nil {
+		h.ServerErrorResponse(w, r, err)
+	}
+}
+
+func (h *CartHandler) RemovePromotion(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	err := h.cartSvc.RemovePromotionCode(r.Context(), contextGetClientIdentifier(r))
+
+	if err != nil {
+		h.ServerErrorResponse(w, r, err)
+		return
+	}
+
+	h.writeCart(w, r, http.StatusOK)
+}
+
+// ShippingOptions lists the shipping methods that can ship the cart to the country given through the `country` query parameter
+func (h *CartHandler) ShippingOptions(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	countryCode := r.URL.Query().Get("country")
+
+	v := validator.New()
+
+	if v.Check(len(countryCode) == 2, "country", "must be a 2 letter ISO code"); !v.Valid() {
+		h.FailedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	options, err := h.cartSvc.ListShippingOptions(r.Context(), contextGetClientIdentifier(r), getCustomerGroupIds(r), getCurrencyCode(r), countryCode)
+
+	if err != nil {
+		if errors.Is(err, service.ErrEmptyCart) {
+			h.BadRequestResponse(w, r, err)
+			return
+		}
+		h.ServerErrorResponse(w, r, err)
+		return
+	}
+
+	err = h.WriteJson(w, http.StatusOK, ResponseBody{Payload: Envelope{"shipping_options": options}}, nil)
+
+	if err != nil {
+		h.ServerErrorResponse(w, r, err)
+	}
+}
+
+// writeCart responds with the current state of the client's cart priced in the requested currency
+func (h *CartHandler) writeCart(w http.ResponseWriter, r *http.Request, status int) {
+	clientIdentifier := contextGetClientIdentifier(r)
+
+	cart, err := h.cartSvc.GetCart(r.Context(), clientIdentifier, getCustomerGroupIds(r), getCurrencyCode(r), getTaxLocation(r))
+
+	if err != nil {
+		h.ServerErrorResponse(w, r, err)
+		return
+	}
+
+	err = h.WriteJson(w, status, ResponseBody{Payload: Envelope{"cart": cart}}, nil)
+
+	if err != nil {
+		h.ServerErrorResponse(w, r, err)
+	}
+}
+
+func (h *CartHandler) cartItemErrorResponse(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, model.ErrRecordNotFound):
+		h.NotFoundResponse(w, r)
+	case errors.Is(err, service.ErrUnauthorizedRequest):
+		h.UnauthorizedResponse(w, r)
+	case errors.Is(err, model.ErrInsufficientInventory):
+		h.ErrorResponse(w, r, http.StatusConflict, err.Error())
+	default:
+		h.ServerErrorResponse(w, r, err)
+	}
+}
@@ -3,6 +3,7 @@ package handlers
 import (
 	"context"
 	"ecom-backend/internal/model"
+	"ecom-backend/internal/service"
 	"net/http"
 )
 
@@ -10,6 +11,7 @@ type contextKey string
 
 const userContextKey = contextKey("user")
 const clientIdentifierContextKey = contextKey("client-identifier") // user id for registerd users OR session id for guests
+const regionContextKey = contextKey("region")
 
 func contextSetClientIdentifier(r *http.Request, clientIdentifier string) *http.Request {
 	ctx := context.WithValue(r.Context(), clientIdentifierContextKey, clientIdentifier)
@@ -42,3 +44,16 @@ func contextGetUser(r *http.Request) *model.UserRecord {
 
 	return user
 }
+
+func contextSetRegion(r *http.Request, region *service.RegionDTO) *http.Request {
+	ctx := context.WithValue(r.Context(), regionContextKey, region)
+
+	return r.WithContext(ctx)
+}
+
+// contextGetRegion returns the region selected by the client, nil when none was selected
+func contextGetRegion(r *http.Request) *service.RegionDTO {
+	region, _ := r.Context().Value(regionContextKey).(*service.RegionDTO)
+
+	return region
+}
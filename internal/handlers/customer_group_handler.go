@@ -0,0 +1,223 @@
+package handlers
+
+import (
+	"ecom-backend/internal/jsonlog"
+	"ecom-backend/internal/model"
+	"ecom-backend/internal/service"
+	"ecom-backend/internal/validator"
+	"errors"
+	"net/http"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+type CustomerGroupHandler struct {
+	BaseHandler
+	customerGroupSvc *service.CustomerGroupService
+}
+
+func NewCustomerGroupHandler(logger *jsonlog.Logger, customerGroupSvc *service.CustomerGroupService) *CustomerGroupHandler {
+	return &CustomerGroupHandler{BaseHandler: BaseHandler{logger: logger}, customerGroupSvc: customerGroupSvc}
+}
+
+func (h *CustomerGroupHandler) CreateCustomerGroup(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var input service.CreateCustomerGroupInput
+
+	err := h.ReadJSON(w, r, &input)
+
+	if err != nil {
+		h.BadRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+
+	if input.Validate(v); !v.Valid() {
+		h.FailedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	group, err := h.customerGroupSvc.CreateCustomerGroup(r.Context(), &input)
+
+	if err != nil {
+		h.customerGroupErrorResponse(w, r, err)
+		return
+	}
+
+	err = h.WriteJson(w, http.StatusCreated, ResponseBody{Payload: Envelope{"customer_group": group}}, nil)
+
+	if err != nil {
+		h.ServerErrorResponse(w, r, err)
+	}
+}
+
+func (h *CustomerGroupHandler) ListCustomerGroups(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	groups, err := h.customerGroupSvc.ListCustomerGroups(r.Context())
+
+	if err != nil {
+		h.ServerErrorResponse(w, r, err)
+		return
+	}
+
+	err = h.WriteJson(w, http.StatusOK, ResponseBody{Payload: Envelope{"customer_groups": groups}}, nil)
+
+	if err != nil {
+		h.ServerErrorResponse(w, r, err)
+	}
+}
+
+func (h *CustomerGroupHandler) GetCustomerGroup(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	groupId := ps.ByName("id")
+
+	if !validator.IsValidUUID(groupId) {
+		h.NotFoundResponse(w, r)
+		return
+	}
+
+	group, err := h.customerGroupSvc.GetCustomerGroup(r.Context(), groupId)
+
+	if err != nil {
+		h.customerGroupErrorResponse(w, r, err)
+		return
+	}
+
+	err = h.WriteJson(w, http.StatusOK, ResponseBody{Payload: Envelope{"customer_group": group}}, nil)
+
+	if err != nil {
+		h.ServerErrorResponse(w, r, err)
+	}
+}
+
+func (h *CustomerGroupHandler) UpdateCustomerGroup(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	groupId := ps.ByName("id")
+
+	if !validator.IsValidUUID(groupId) {
+		h.NotFoundResponse(w, r)
+		return
+	}
+
+	var input service.UpdateCustomerGroupInput
+
+	err := h.ReadJSON(w, r, &input)
+
+	if err != nil {
+		h.BadRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+
+	if input.Validate(v); !v.Valid() {
+		h.FailedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	group, err := h.customerGroupSvc.UpdateCustomerGroup(r.Context(), groupId, &input)
+
+	if err != nil {
+		h.customerGroupErrorResponse(w, r, err)
+		return
+	}
+
+	err = h.WriteJson(w, http.StatusOK, ResponseBody{Payload: Envelope{"customer_group": group}}, nil)
+
+	if err != nil {
+		h.ServerErrorResponse(w, r, err)
+	}
+}
+
+func (h *CustomerGroupHandler) DeleteCustomerGroup(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	groupId := ps.ByName("id")
+
+	if !validator.IsValidUUID(groupId) {
+		h.NotFoundResponse(w, r)
+		return
+	}
+
+	err := h.customerGroupSvc.DeleteCustomerGroup(r.Context(), groupId)
+
+	if err != nil {
+		h.customerGroupErrorResponse(w, r, err)
+		return
+	}
+
+	err = h.WriteJson(w, http.StatusOK, ResponseBody{Payload: Envelope{"success": true}}, nil)
+
+	if err != nil {
+		h.ServerErrorResponse(w, r, err)
+	}
+}
+
+func (h *CustomerGroupHandler) ListMembers(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	groupId := ps.ByName("id")
+
+	if !validator.IsValidUUID(groupId) {
+		h.NotFoundResponse(w, r)
+		return
+	}
+
+	members, err := h.customerGroupSvc.ListMembers(r.Context(), groupId)
+
+	if err != nil {
+		h.customerGroupErrorResponse(w, r, err)
+		return
+	}
+
+	err = h.WriteJson(w, http.StatusOK, ResponseBody{Payload: Envelope{"members": members}}, nil)
+
+	if err != nil {
+		h.ServerErrorResponse(w, r, err)
+	}
+}
+
+// SetUserCustomerGroups replaces the groups the user belongs to, they apply from the next request of the user
+func (h *CustomerGroupHandler) SetUserCustomerGroups(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	userId := ps.ByName("id")
+
+	if !validator.IsValidUUID(userId) {
+		h.NotFoundResponse(w, r)
+		return
+	}
+
+	var input service.SetUserCustomerGroupsInput
+
+	err := h.ReadJSON(w, r, &input)
+
+	if err != nil {
+		h.BadRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+
+	if input.Validate(v); !v.Valid() {
+		h.FailedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	groupIds, err := h.customerGroupSvc.SetUserCustomerGroups(r.Context(), userId, &input)
+
+	if err != nil {
+		h.customerGroupErrorResponse(w, r, err)
+		return
+	}
+
+	err = h.WriteJson(w, http.StatusOK, ResponseBody{Payload: Envelope{"customer_group_ids": groupIds}}, nil)
+
+	if err != nil {
+		h.ServerErrorResponse(w, r, err)
+	}
+}
+
+func (h *CustomerGroupHandler) customerGroupErrorResponse(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, model.ErrRecordNotFound):
+		h.NotFoundResponse(w, r)
+	case errors.Is(err, model.ErrDuplicatedCustomerGroup):
+		h.FailedValidationResponse(w, r, map[string]string{"name": "a customer group with this name already exists"})
+	case errors.Is(err, model.ErrCustomerGroupNotFound):
+		h.FailedValidationResponse(w, r, map[string]string{"customer_group_ids": err.Error()})
+	default:
+		h.ServerErrorResponse(w, r, err)
+	}
+}
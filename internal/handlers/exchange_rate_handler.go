@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"ecom-backend/internal/jsonlog"
+	"ecom-backend/internal/model"
+	"ecom-backend/internal/service"
+	"ecom-backend/internal/validator"
+	"errors"
+	"net/http"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+type ExchangeRateHandler struct {
+	BaseHandler
+	exchangeRateSvc *service.ExchangeRateService
+}
+
+func NewExchangeRateHandler(logger *jsonlog.Logger, exchangeRateSvc *service.ExchangeRateService) *ExchangeRateHandler {
+	return &ExchangeRateHandler{BaseHandler: BaseHandler{logger: logger}, exchangeRateSvc: exchangeRateSvc}
+}
+
+func (h *ExchangeRateHandler) ListExchangeRates(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	rates, err := h.exchangeRateSvc.ListExchangeRates(r.Context())
+
+	if err != nil {
+		h.ServerErrorResponse(w, r, err)
+		return
+	}
+
+	err = h.WriteJson(w, http.StatusOK, ResponseBody{Payload: Envelope{"exchange_rates": rates}}, nil)
+
+	if err != nil {
+		h.ServerErrorResponse(w, r, err)
+	}
+}
+
+func (h *ExchangeRateHandler) SetExchangeRate(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var input service.SetExchangeRateInput
+
+	err := h.ReadJSON(w, r, &input)
+
+	if err != nil {
+		h.BadRequestResponse(w, r, err)
+		return
+	}
+
+	input.BaseCurrencyCode = ps.ByName("base")
+	input.QuoteCurrencyCode = ps.ByName("quote")
+
+	v := validator.New()
+
+	if input.Validate(v); !v.Valid() {
+		h.FailedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	rate, err := h.exchangeRateSvc.SetExchangeRate(r.Context(), &input)
+
+	if err != nil {
+		h.exchangeRateErrorResponse(w, r, err)
+		return
+	}
+
+	err = h.WriteJson(w, http.StatusOK, ResponseBody{Payload: Envelope{"exchange_rate": rate}}, nil)
+
+	if err != nil {
+		h.ServerErrorResponse(w, r, err)
+	}
+}
+
+func (h *ExchangeRateHandler) DeleteExchangeRate(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	err := h.exchangeRateSvc.DeleteExchangeRate(r.Context(), ps.ByName("base"), ps.ByName("quote"))
+
+	if err != nil {
+		h.exchangeRateErrorResponse(w, r, err)
+		return
+	}
+
+	err = h.WriteJson(w, http.StatusOK, ResponseBody{Payload: Envelope{"success": true}}, nil)
+
+	if err != nil {
+		h.ServerErrorResponse(w, r, err)
+	}
+}
+
+// ImportExchangeRates sets the rates of the CSV file sent in the "file" field of a multipart form
+func (h *ExchangeRateHandler) ImportExchangeRates(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	err := r.ParseMultipartForm(10 << 20)
+
+	if err != nil {
+		h.BadRequestResponse(w, r, err)
+		return
+	}
+
+	file, _, err := r.FormFile("file")
+
+	if err != nil {
+		h.FailedValidationResponse(w, r, map[string]string{"file": "must be provided"})
+		return
+	}
+
+	defer file.Close()
+
+	rates, err := h.exchangeRateSvc.ImportExchangeRates(r.Context(), file)
+
+	if err != nil {
+		h.exchangeRateErrorResponse(w, r, err)
+		return
+	}
+
+	err = h.WriteJson(w, http.StatusOK, ResponseBody{Payload: Envelope{"exchange_rates": rates}}, nil)
+
+	if err != nil {
+		h.ServerErrorResponse(w, r, err)
+	}
+}
+
+func (h *ExchangeRateHandler) exchangeRateErrorResponse(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, service.ErrInvalidExchangeRateFile):
+		h.FailedValidationResponse(w, r, map[string]string{"file": err.Error()})
+	case errors.Is(err, model.ErrRecordNotFound):
+		h.NotFoundResponse(w, r)
+	case errors.Is(err, model.ErrCurrencyNotFound):
+		h.FailedValidationResponse(w, r, map[string]string{"currency_code": "currency not found"})
+	case errors.Is(err, model.ErrInvalidValue):
+		h.FailedValidationResponse(w, r, map[string]string{"rate": "invalid exchange rate"})
+	default:
+		h.ServerErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,184 @@
+package handlers
+
+import (
+	"ecom-backend/internal/jsonlog"
+	"ecom-backend/internal/model"
+	"ecom-backend/internal/service"
+	"ecom-backend/internal/validator"
+	"errors"
+	"net/http"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+type GiftCardHandler struct {
+	BaseHandler
+	giftCardSvc *service.GiftCardService
+}
+
+func NewGiftCardHandler(logger *jsonlog.Logger, giftCardSvc *service.GiftCardService) *GiftCardHandler {
+	return &GiftCardHandler{BaseHandler: BaseHandler{logger: logger}, giftCardSvc: giftCardSvc}
+}
+
+func (h *GiftCardHandler) IssueGiftCard(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var input service.IssueGiftCardInput
+
+	err := h.ReadJSON(w, r, &input)
+
+	if err != nil {
+		h.BadRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+
+	if input.Validate(v); !v.Valid() {
+		h.FailedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	card, err := h.giftCardSvc.IssueGiftCard(r.Context(), &input)
+
+	if err != nil {
+		h.giftCardErrorResponse(w, r, err)
+		return
+	}
+
+	err = h.WriteJson(w, http.StatusCreated, ResponseBody{Payload: Envelope{"gift_card": card}}, nil)
+
+	if err != nil {
+		h.ServerErrorResponse(w, r, err)
+	}
+}
+
+func (h *GiftCardHandler) ListGiftCards(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	page, pageSize, err := readPaginationParams(r)
+
+	if err != nil {
+		h.BadRequestResponse(w, r, err)
+		return
+	}
+
+	cards, rowCount, err := h.giftCardSvc.ListGiftCards(r.Context(), service.GiftCardListingOptions{Page: page, PageSize: pageSize})
+
+	if err != nil {
+		h.ServerErrorResponse(w, r, err)
+		return
+	}
+
+	err = h.WriteJson(w, http.StatusOK, ResponseBody{Payload: cards, Metadata: PaginationMetadata{Page: int(page), PageSize: int(pageSize), RowsTotal: rowCount}}, nil)
+
+	if err != nil {
+		h.ServerErrorResponse(w, r, err)
+	}
+}
+
+func (h *GiftCardHandler) GetGiftCard(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	cardId := ps.ByName("id")
+
+	if !validator.IsValidUUID(cardId) {
+		h.NotFoundResponse(w, r)
+		return
+	}
+
+	card, transactions, err := h.giftCardSvc.GetGiftCard(r.Context(), cardId)
+
+	if err != nil {
+		h.giftCardErrorResponse(w, r, err)
+		return
+	}
+
+	err = h.WriteJson(w, http.StatusOK, ResponseBody{Payload: Envelope{"gift_card": card, "transactions": transactions}}, nil)
+
+	if err != nil {
+		h.ServerErrorResponse(w, r, err)
+	}
+}
+
+func (h *GiftCardHandler) UpdateGiftCard(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	cardId := ps.ByName("id")
+
+	if !validator.IsValidUUID(cardId) {
+		h.NotFoundResponse(w, r)
+		return
+	}
+
+	var input service.UpdateGiftCardInput
+
+	err := h.ReadJSON(w, r, &input)
+
+	if err != nil {
+		h.BadRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+
+	if input.Validate(v); !v.Valid() {
+		h.FailedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	card, err := h.giftCardSvc.UpdateGiftCard(r.Context(), cardId, &input)
+
+	if err != nil {
+		h.giftCardErrorResponse(w, r, err)
+		return
+	}
+
+	err = h.WriteJson(w, http.StatusOK, ResponseBody{Payload: Envelope{"gift_card": card}}, nil)
+
+	if err != nil {
+		h.ServerErrorResponse(w, r, err)
+	}
+}
+
+// CheckGiftCardBalance returns the balance of the card with the code sent in the body
+func (h *GiftCardHandler) CheckGiftCardBalance(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var input struct {
+		Code string `json:"code"`
+	}
+
+	err := h.ReadJSON(w, r, &input)
+
+	if err != nil {
+		h.BadRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+
+	if v.Check(input.Code != "", "code", "must be provided"); !v.Valid() {
+		h.FailedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	balance, err := h.giftCardSvc.CheckGiftCardBalance(r.Context(), input.Code)
+
+	if err != nil {
+		h.giftCardErrorResponse(w, r, err)
+		return
+	}
+
+	err = h.WriteJson(w, http.StatusOK, ResponseBody{Payload: Envelope{"gift_card": balance}}, nil)
+
+	if err != nil {
+		h.ServerErrorResponse(w, r, err)
+	}
+}
+
+func (h *GiftCardHandler) giftCardErrorResponse(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, model.ErrRecordNotFound),
+		errors.Is(err, service.ErrGiftCardNotFound):
+		h.NotFoundResponse(w, r)
+	case errors.Is(err, model.ErrDuplicatedGiftCardCode):
+		h.FailedValidationResponse(w, r, map[string]string{"code": "a gift card with this code already exists"})
+	case errors.Is(err, model.ErrCurrencyNotFound):
+		h.FailedValidationResponse(w, r, map[string]string{"currency_code": "currency not found"})
+	case errors.Is(err, model.ErrInvalidValue):
+		h.FailedValidationResponse(w, r, map[string]string{"balance": "must not exceed the initial balance or have more decimals than the currency"})
+	default:
+		h.ServerErrorResponse(w, r, err)
+	}
+}
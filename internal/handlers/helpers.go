@@ -1,7 +1,140 @@
 package handlers
 
-import "net/http"
+import (
+	"ecom-backend/internal/consts"
+	"ecom-backend/internal/service"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+)
 
 func getSessionId(r *http.Request) string {
 	return r.Header.Get("Session-ID")
 }
+
+// getRegionId returns the region selected through the `Region-ID` header or the `region_id` query parameter
+func getRegionId(r *http.Request) string {
+	if regionId := r.Header.Get("Region-ID"); regionId != "" {
+		return regionId
+	}
+
+	return r.URL.Query().Get("region_id")
+}
+
+// getCurrencyCode returns the currency of the selected region, the one requested through the `currency` query parameter
+// when no region is selected, or the default one
+func getCurrencyCode(r *http.Request) string {
+	if region := contextGetRegion(r); region != nil {
+		return region.CurrencyCode
+	}
+
+	currencyCode := strings.ToLower(r.URL.Query().Get("currency"))
+
+	if currencyCode == "" {
+		return consts.DefaultCurrencyCode
+	}
+
+	return currencyCode
+}
+
+// getCustomerGroupIds returns the groups of the authenticated user, the price lists and promotions of these groups
+// apply to the request. Guests belong to no group.
+func getCustomerGroupIds(r *http.Request) []string {
+	return contextGetUser(r).CustomerGroupIds
+}
+
+// getTaxLocation returns the location given through the `country` and `province` query parameters,
+// nil when no country was given since the taxes can't be estimated then. The country of a region with a single
+// one doesn't need to be given, and nothing is estimated in the regions without automatic taxes.
+func getTaxLocation(r *http.Request) *service.TaxLocation {
+	region := contextGetRegion(r)
+
+	if region != nil && !region.AutomaticTaxes {
+		return nil
+	}
+
+	countryCode := strings.ToLower(r.URL.Query().Get("country"))
+
+	if countryCode == "" && region != nil && len(region.Countries) == 1 {
+		countryCode = region.Countries[0]
+	}
+
+	if countryCode == "" {
+		return nil
+	}
+
+	location := &service.TaxLocation{CountryCode: countryCode}
+
+	if province := r.URL.Query().Get("province"); province != "" {
+		location.Province = &province
+	}
+
+	return location
+}
+
+// readPaginationParams parses the `page` and `pageSize` query parameters
+func readPaginationParams(r *http.Request) (uint, uint, error) {
+	page, err := strconv.ParseInt(r.URL.Query().Get("page"), 10, 64)
+
+	if err != nil {
+		return 0, 0, errors.New("invalid query parameter `page`")
+	}
+
+	if page <= 0 {
+		return 0, 0, errors.New("page must be > 0")
+	}
+
+	pageSize, err := readPageSizeParam(r)
+
+	if err != nil {
+		return 0, 0, err
+	}
+
+	return uint(page), pageSize, nil
+}
+
+// readCursorPaginationParams parses the `cursor` and `pageSize` query parameters, the page number is read instead of
+// the cursor when none is given
+func readCursorPaginationParams(r *http.Request) (uint, uint, *service.Cursor, error) {
+	token := r.URL.Query().Get("cursor")
+
+	if token == "" {
+		page, pageSize, err := readPaginationParams(r)
+
+		return page, pageSize, nil, err
+	}
+
+	cursor, err := service.DecodeCursor(token)
+
+	if err != nil {
+		return 0, 0, nil, errors.New("invalid query parameter `cursor`")
+	}
+
+	pageSize, err := readPageSizeParam(r)
+
+	if err != nil {
+		return 0, 0, nil, err
+	}
+
+	return 0, pageSize, cursor, nil
+}
+
+func readPageSizeParam(r *http.Request) (uint, error) {
+	pageSize, err := strconv.ParseInt(r.URL.Query().Get("pageSize"), 10, 64)
+
+	if err != nil {
+		return 0, errors.New("invalid query parameter `pageSize`")
+	}
+
+	if pageSize <= 0 {
+		return 0, errors.New("pageSize must be > 0")
+	}
+
+	return uint(pageSize), nil
+}
+
+// newCursorPaginationMetadata returns the metadata of a page of a listing paged with cursors or by page number
+func newCursorPaginationMetadata(page uint, pageSize uint, info *service.PageInfo) CursorPaginationMetadata {
+	return CursorPaginationMetadata{Page: int(page), PageSize: int(pageSize), RowsTotal: info.TotalCount, NextCursor: info.NextCursor, PrevCursor: info.PrevCursor}
+}
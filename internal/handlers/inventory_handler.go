@@ -0,0 +1,397 @@
+package handlers
+
+import (
+	"ecom-backend/internal/jsonlog"
+	"ecom-backend/internal/model"
+	"ecom-backend/internal/service"
+	"ecom-backend/internal/validator"
+	"errors"
+	"net/http"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+type InventoryHandler struct {
+	BaseHandler
+	inventorySvc *service.InventoryService
+}
+
+func NewInventoryHandler(logger *jsonlog.Logger, inventorySvc *service.InventoryService) *InventoryHandler {
+	return &InventoryHandler{BaseHandler: BaseHandler{logger: logger}, inventorySvc: inventorySvc}
+}
+
+func (h *InventoryHandler) ReserveCart(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	reservation, err := h.inventorySvc.ReserveCart(r.Context(), contextGetClientIdentifier(r))
+
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrEmptyCart),
+			errors.Is(err, model.ErrVariantNotFound):
+			h.BadRequestResponse(w, r, err)
+		case errors.Is(err, model.ErrInsufficientInventory):
+			h.ErrorResponse(w, r, http.StatusConflict, err.Error())
+		default:
+			h.ServerErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = h.WriteJson(w, http.StatusCreated, ResponseBody{Payload: Envelope{"reservation": reservation}}, nil)
+
+	if err != nil {
+		h.ServerErrorResponse(w, r, err)
+	}
+}
+
+func (h *InventoryHandler) ReleaseCart(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	err := h.inventorySvc.ReleaseCart(r.Context(), contextGetClientIdentifier(r))
+
+	if err != nil {
+		h.ServerErrorResponse(w, r, err)
+		return
+	}
+
+	err = h.WriteJson(w, http.StatusOK, ResponseBody{Payload: Envelope{"success": true}}, nil)
+
+	if err != nil {
+		h.ServerErrorResponse(w, r, err)
+	}
+}
+
+func (h *InventoryHandler) RecordStockMovement(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	variantId := ps.ByName("variantId")
+	user := contextGetUser(r)
+
+	if !validator.IsValidUUID(variantId) {
+		h.NotFoundResponse(w, r)
+		return
+	}
+
+	var input service.StockMovementInput
+
+	err := h.ReadJSON(w, r, &input)
+
+	if err != nil {
+		h.BadRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+
+	if input.Validate(v); !v.Valid() {
+		h.FailedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	movement, err := h.inventorySvc.RecordStockMovement(r.Context(), variantId, &user.Id, &input)
+
+	if err != nil {
+		switch {
+		case errors.Is(err, model.ErrRecordNotFound):
+			h.NotFoundResponse(w, r)
+		case errors.Is(err, model.ErrStockLocationNotFound):
+			h.FailedValidationResponse(w, r, map[string]string{"location_id": "stock location not found"})
+		case errors.Is(err, model.ErrInsufficientInventory):
+			h.ErrorResponse(w, r, http.StatusConflict, "the movement would take the stock below zero")
+		default:
+			h.ServerErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = h.WriteJson(w, http.StatusCreated, ResponseBody{Payload: Envelope{"stock_movement": movement}}, nil)
+
+	if err != nil {
+		h.ServerErrorResponse(w, r, err)
+	}
+}
+
+func (h *InventoryHandler) ListStockMovements(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	variantId := ps.ByName("variantId")
+
+	if !validator.IsValidUUID(variantId) {
+		h.NotFoundResponse(w, r)
+		return
+	}
+
+	page, pageSize, err := readPaginationParams(r)
+
+	if err != nil {
+		h.BadRequestResponse(w, r, err)
+		return
+	}
+
+	movements, rowCount, err := h.inventorySvc.ListStockMovements(r.Context(), variantId, service.StockMovementListingOptions{Page: page, PageSize: pageSize})
+
+	if err != nil {
+		if errors.Is(err, model.ErrRecordNotFound) {
+			h.NotFoundResponse(w, r)
+			return
+		}
+		h.ServerErrorResponse(w, r, err)
+		return
+	}
+
+	err = h.WriteJson(w, http.StatusOK, ResponseBody{Payload: movements, Metadata: PaginationMetadata{Page: int(page), PageSize: int(pageSize), RowsTotal: rowCount}}, nil)
+
+	if err != nil {
+		h.ServerErrorResponse(w, r, err)
+	}
+}
+
+func (h *InventoryHandler) CreateStockLocation(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var input service.CreateStockLocationInput
+
+	err := h.ReadJSON(w, r, &input)
+
+	if err != nil {
+		h.BadRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+
+	if input.Validate(v); !v.Valid() {
+		h.FailedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	location, err := h.inventorySvc.CreateStockLocation(r.Context(), &input)
+
+	if err != nil {
+		h.ServerErrorResponse(w, r, err)
+		return
+	}
+
+	err = h.WriteJson(w, http.StatusCreated, ResponseBody{Payload: Envelope{"stock_location": location}}, nil)
+
+	if err != nil {
+		h.ServerErrorResponse(w, r, err)
+	}
+}
+
+func (h *InventoryHandler) ListStockLocations(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	locations, err := h.inventorySvc.ListStockLocations(r.Context())
+
+	if err != nil {
+		h.ServerErrorResponse(w, r, err)
+		return
+	}
+
+	err = h.WriteJson(w, http.StatusOK, ResponseBody{Payload: Envelope{"stock_locations": locations}}, nil)
+
+	if err != nil {
+		h.ServerErrorResponse(w, r, err)
+	}
+}
+
+func (h *InventoryHandler) GetStockLocation(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	locationId := ps.ByName("id")
+
+	if !validator.IsValidUUID(locationId) {
+		h.NotFoundResponse(w, r)
+		return
+	}
+
+	location, err := h.inventorySvc.GetStockLocation(r.Context(), locationId)
+
+	if err != nil {
+		if errors.Is(err, model.ErrRecordNotFound) {
+			h.NotFoundResponse(w, r)
+			return
+		}
+		h.ServerErrorResponse(w, r, err)
+		return
+	}
+
+	err = h.WriteJson(w, http.StatusOK, ResponseBody{Payload: Envelope{"stock_location": location}}, nil)
+
+	if err != nil {
+		h.ServerErrorResponse(w, r, err)
+	}
+}
+
+func (h *InventoryHandler) UpdateStockLocation(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	locationId := ps.ByName("id")
+
+	if !validator.IsValidUUID(locationId) {
+		h.NotFoundResponse(w, r)
+		return
+	}
+
+	var input service.UpdateStockLocationInput
+
+	err := h.ReadJSON(w, r, &input)
+
+	if err != nil {
+		h.BadRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+
+	if input.Validate(v); !v.Valid() {
+		h.FailedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	location, err := h.inventorySvc.UpdateStockLocation(r.Context(), locationId, &input)
+
+	if err != nil {
+		if errors.Is(err, model.ErrRecordNotFound) {
+			h.NotFoundResponse(w, r)
+			return
+		}
+		h.ServerErrorResponse(w, r, err)
+		return
+	}
+
+	err = h.WriteJson(w, http.StatusOK, ResponseBody{Payload: Envelope{"stock_location": location}}, nil)
+
+	if err != nil {
+		h.ServerErrorResponse(w, r, err)
+	}
+}
+
+func (h *InventoryHandler) DeleteStockLocation(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	locationId := ps.ByName("id")
+
+	if !validator.IsValidUUID(locationId) {
+		h.NotFoundResponse(w, r)
+		return
+	}
+
+	err := h.inventorySvc.DeleteStockLocation(r.Context(), locationId)
+
+	if err != nil {
+		switch {
+		case errors.Is(err, model.ErrRecordNotFound):
+			h.NotFoundResponse(w, r)
+		case errors.Is(err, service.ErrStockLocationNotEmpty):
+			h.ErrorResponse(w, r, http.StatusConflict, err.Error())
+		default:
+			h.ServerErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = h.WriteJson(w, http.StatusOK, ResponseBody{Payload: Envelope{"success": true}}, nil)
+
+	if err != nil {
+		h.ServerErrorResponse(w, r, err)
+	}
+}
+
+func (h *InventoryHandler) SubscribeBackInStock(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	variantId := ps.ByName("variantId")
+	user := contextGetUser(r)
+
+	if !validator.IsValidUUID(variantId) {
+		h.NotFoundResponse(w, r)
+		return
+	}
+
+	var input service.BackInStockSubscriptionInput
+
+	err := h.ReadJSON(w, r, &input)
+
+	if err != nil {
+		h.BadRequestResponse(w, r, err)
+		return
+	}
+
+	// registered users are notified on their account email unless they ask otherwise
+	if input.Email == "" && !isAnonymousUser(user) {
+		input.Email = user.Email
+	}
+
+	v := validator.New()
+
+	if input.Validate(v); !v.Valid() {
+		h.FailedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	subscription, err := h.inventorySvc.SubscribeBackInStock(r.Context(), contextGetClientIdentifier(r), variantId, &input)
+
+	if err != nil {
+		switch {
+		case errors.Is(err, model.ErrVariantNotFound):
+			h.NotFoundResponse(w, r)
+		case errors.Is(err, service.ErrVariantInStock):
+			h.ErrorResponse(w, r, http.StatusConflict, err.Error())
+		default:
+			h.ServerErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = h.WriteJson(w, http.StatusCreated, ResponseBody{Payload: Envelope{"subscription": subscription}}, nil)
+
+	if err != nil {
+		h.ServerErrorResponse(w, r, err)
+	}
+}
+
+func (h *InventoryHandler) UnsubscribeBackInStock(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	variantId := ps.ByName("variantId")
+
+	if !validator.IsValidUUID(variantId) {
+		h.NotFoundResponse(w, r)
+		return
+	}
+
+	err := h.inventorySvc.UnsubscribeBackInStock(r.Context(), contextGetClientIdentifier(r), variantId)
+
+	if err != nil {
+		if errors.Is(err, model.ErrRecordNotFound) {
+			h.NotFoundResponse(w, r)
+			return
+		}
+		h.ServerErrorResponse(w, r, err)
+		return
+	}
+
+	err = h.WriteJson(w, http.StatusOK, ResponseBody{Payload: Envelope{"success": true}}, nil)
+
+	if err != nil {
+		h.ServerErrorResponse(w, r, err)
+	}
+}
+
+func (h *InventoryHandler) ListBackInStockSubscriptions(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	subscriptions, err := h.inventorySvc.ListBackInStockSubscriptions(r.Context(), contextGetClientIdentifier(r))
+
+	if err != nil {
+		h.ServerErrorResponse(w, r, err)
+		return
+	}
+
+	err = h.WriteJson(w, http.StatusOK, ResponseBody{Payload: Envelope{"subscriptions": subscriptions}}, nil)
+
+	if err != nil {
+		h.ServerErrorResponse(w, r, err)
+	}
+}
+
+func (h *InventoryHandler) LowStockReport(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	page, pageSize, err := readPaginationParams(r)
+
+	if err != nil {
+		h.BadRequestResponse(w, r, err)
+		return
+	}
+
+	variants, rowCount, err := h.inventorySvc.LowStockReport(r.Context(), service.LowStockReportOptions{Page: page, PageSize: pageSize})
+
+	if err != nil {
+		h.ServerErrorResponse(w, r, err)
+		return
+	}
+
+	err = h.WriteJson(w, http.StatusOK, ResponseBody{Payload: variants, Metadata: PaginationMetadata{Page: int(page), PageSize: int(pageSize), RowsTotal: rowCount}}, nil)
+
+	if err != nil {
+		h.ServerErrorResponse(w, r, err)
+	}
+}
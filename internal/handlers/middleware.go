@@ -4,6 +4,7 @@ import (
 	"ecom-backend/internal/jsonlog"
 	"ecom-backend/internal/model"
 	"ecom-backend/internal/service"
+	"ecom-backend/internal/validator"
 	"errors"
 	"fmt"
 	"net/http"
@@ -92,6 +93,38 @@ func (mid *Middleware) Authenticate(next http.Handler) http.Handler {
 	})
 }
 
+// SelectRegion loads the region selected through the `Region-ID` header or the `region_id` query parameter, if any
+func (mid *Middleware) SelectRegion(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		regionId := getRegionId(r)
+
+		if regionId == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if !validator.IsValidUUID(regionId) {
+			mid.BadRequestResponse(w, r, errors.New("invalid region id"))
+			return
+		}
+
+		region, err := mid.services.Region.GetRegion(r.Context(), regionId)
+
+		if err != nil {
+			if errors.Is(err, model.ErrRecordNotFound) {
+				mid.BadRequestResponse(w, r, errors.New("region not found"))
+				return
+			}
+			mid.ServerErrorResponse(w, r, err)
+			return
+		}
+
+		r = contextSetRegion(r, region)
+
+		next.ServeHTTP(w, r)
+	})
+}
+
 func (mid *Middleware) RequireActivation(next httprouter.Handle) httprouter.Handle {
 	return func(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 		user := contextGetUser(r)
@@ -158,7 +191,7 @@ func (mid *Middleware) EnableCORS(next http.Handler) http.Handler {
 				// Set the necessary preflight response headers, as discussed
 				// previously.
 				w.Header().Set("Access-Control-Allow-Methods", "OPTIONS, PUT, PATCH, DELETE")
-				w.Header().Set("Access-Control-Allow-Headers", "Authorization, Content-Type, Session-ID")
+				w.Header().Set("Access-Control-Allow-Headers", "Authorization, Content-Type, Session-ID, Region-ID")
 				// Write the headers along with a 200 OK status and return from
 				// the middleware with no further action.
 				w.WriteHeader(http.StatusOK)
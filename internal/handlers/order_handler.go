@@ -0,0 +1,230 @@
+package handlers
+
+import (
+	"ecom-backend/internal/jsonlog"
+	"ecom-backend/internal/model"
+	"ecom-backend/internal/service"
+	"ecom-backend/internal/validator"
+	"errors"
+	"net/http"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+type OrderHandler struct {
+	BaseHandler
+	orderSvc *service.OrderService
+}
+
+func NewOrderHandler(logger *jsonlog.Logger, orderSvc *service.OrderService) *OrderHandler {
+	return &OrderHandler{BaseHandler: BaseHandler{logger: logger}, orderSvc: orderSvc}
+}
+
+func (h *OrderHandler) Checkout(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	clientIdentifier := contextGetClientIdentifier(r)
+	user := contextGetUser(r)
+	region := contextGetRegion(r)
+
+	var input service.CheckoutInput
+
+	err := h.ReadJSON(w, r, &input)
+
+	if err != nil {
+		h.BadRequestResponse(w, r, err)
+		return
+	}
+
+	// the order is placed in the currency of the region
+	if region != nil {
+		input.CurrencyCode = region.CurrencyCode
+	}
+
+	input.CustomerGroupIds = getCustomerGroupIds(r)
+
+	v := validator.New()
+
+	if input.Validate(v); !v.Valid() {
+		h.FailedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	var userId *string
+
+	if !isAnonymousUser(user) {
+		userId = &user.Id
+	}
+
+	order, err := h.orderSvc.Checkout(r.Context(), clientIdentifier, userId, region, &input)
+
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrEmptyCart),
+			errors.Is(err, service.ErrVariantPriceNotFound),
+			errors.Is(err, model.ErrVariantNotFound):
+			h.BadRequestResponse(w, r, err)
+		case errors.Is(err, service.ErrPromotionNotFound):
+			h.FailedValidationResponse(w, r, map[string]string{"promotion_code": "invalid promotion code"})
+		case errors.Is(err, service.ErrCountryNotInRegion):
+			h.FailedValidationResponse(w, r, map[string]string{"shipping_address.country_code": err.Error()})
+		case errors.Is(err, service.ErrShippingMethodNotAvailable),
+			errors.Is(err, service.ErrShippingMethodNeeded):
+			h.FailedValidationResponse(w, r, map[string]string{"shipping_method_id": err.Error()})
+		case errors.Is(err, service.ErrGiftCardNotFound),
+			errors.Is(err, service.ErrGiftCardNotUsable):
+			h.FailedValidationResponse(w, r, map[string]string{"gift_card_codes": err.Error()})
+		case errors.Is(err, service.ErrStoreCreditUnavailable):
+			h.FailedValidationResponse(w, r, map[string]string{"use_store_credit": err.Error()})
+		case errors.Is(err, service.ErrPromotionNotApplicable),
+			errors.Is(err, service.ErrPromotionUsageLimitReached):
+			h.ErrorResponse(w, r, http.StatusUnprocessableEntity, err.Error())
+		case errors.Is(err, model.ErrInsufficientInventory):
+			h.ErrorResponse(w, r, http.StatusConflict, err.Error())
+		default:
+			h.ServerErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = h.WriteJson(w, http.StatusCreated, ResponseBody{Payload: Envelope{"order": order}}, nil)
+
+	if err != nil {
+		h.ServerErrorResponse(w, r, err)
+	}
+}
+
+func (h *OrderHandler) GetOrder(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	orderId := ps.ByName("id")
+	user := contextGetUser(r)
+
+	if !validator.IsValidUUID(orderId) {
+		h.NotFoundResponse(w, r)
+		return
+	}
+
+	var order *service.OrderDTO
+	var err error
+
+	// admins can see every order, customers only their own
+	if !isAnonymousUser(user) && user.IsAdmin {
+		order, err = h.orderSvc.GetOrder(r.Context(), orderId)
+	} else {
+		order, err = h.orderSvc.GetClientOrder(r.Context(), contextGetClientIdentifier(r), orderId)
+	}
+
+	if err != nil {
+		switch {
+		case errors.Is(err, model.ErrRecordNotFound):
+			h.NotFoundResponse(w, r)
+		case errors.Is(err, service.ErrUnauthorizedRequest):
+			h.UnauthorizedResponse(w, r)
+		default:
+			h.ServerErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = h.WriteJson(w, http.StatusOK, ResponseBody{Payload: Envelope{"order": order}}, nil)
+
+	if err != nil {
+		h.ServerErrorResponse(w, r, err)
+	}
+}
+
+func (h *OrderHandler) ListOrders(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	page, pageSize, cursor, err := readCursorPaginationParams(r)
+
+	if err != nil {
+		h.BadRequestResponse(w, r, err)
+		return
+	}
+
+	orders, info, err := h.orderSvc.ListOrders(r.Context(), service.OrderListingOptions{Page: page, PageSize: pageSize, Cursor: cursor})
+
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidCursor) {
+			h.BadRequestResponse(w, r, err)
+			return
+		}
+
+		h.ServerErrorResponse(w, r, err)
+		return
+	}
+
+	err = h.WriteJson(w, http.StatusOK, ResponseBody{Payload: orders, Metadata: newCursorPaginationMetadata(page, pageSize, info)}, nil)
+
+	if err != nil {
+		h.ServerErrorResponse(w, r, err)
+	}
+}
+
+func (h *OrderHandler) TransitionOrder(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	orderId := ps.ByName("id")
+	user := contextGetUser(r)
+
+	if !validator.IsValidUUID(orderId) {
+		h.NotFoundResponse(w, r)
+		return
+	}
+
+	var input service.TransitionOrderInput
+
+	err := h.ReadJSON(w, r, &input)
+
+	if err != nil {
+		h.BadRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+
+	if input.Validate(v); !v.Valid() {
+		h.FailedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	order, err := h.orderSvc.TransitionOrder(r.Context(), orderId, &user.Id, &input)
+
+	if err != nil {
+		switch {
+		case errors.Is(err, model.ErrRecordNotFound):
+			h.NotFoundResponse(w, r)
+		case errors.Is(err, service.ErrInvalidOrderTransition):
+			h.ErrorResponse(w, r, http.StatusConflict, err.Error())
+		default:
+			h.ServerErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = h.WriteJson(w, http.StatusOK, ResponseBody{Payload: Envelope{"order": order}}, nil)
+
+	if err != nil {
+		h.ServerErrorResponse(w, r, err)
+	}
+}
+
+func (h *OrderHandler) ListEvents(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	orderId := ps.ByName("id")
+
+	if !validator.IsValidUUID(orderId) {
+		h.NotFoundResponse(w, r)
+		return
+	}
+
+	events, err := h.orderSvc.ListOrderEvents(r.Context(), orderId)
+
+	if err != nil {
+		if errors.Is(err, model.ErrRecordNotFound) {
+			h.NotFoundResponse(w, r)
+			return
+		}
+		h.ServerErrorResponse(w, r, err)
+		return
+	}
+
+	err = h.WriteJson(w, http.StatusOK, ResponseBody{Payload: Envelope{"events": events}}, nil)
+
+	if err != nil {
+		h.ServerErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,233 @@
+package handlers
+
+import (
+	"ecom-backend/internal/jsonlog"
+	"ecom-backend/internal/model"
+	"ecom-backend/internal/service"
+	"ecom-backend/internal/validator"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+type PaymentHandler struct {
+	BaseHandler
+	paymentSvc *service.PaymentService
+}
+
+func NewPaymentHandler(logger *jsonlog.Logger, paymentSvc *service.PaymentService) *PaymentHandler {
+	return &PaymentHandler{BaseHandler: BaseHandler{logger: logger}, paymentSvc: paymentSvc}
+}
+
+func (h *PaymentHandler) ListProviders(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	err := h.WriteJson(w, http.StatusOK, ResponseBody{Payload: Envelope{"providers": h.paymentSvc.ListProviderIds(contextGetRegion(r))}}, nil)
+
+	if err != nil {
+		h.ServerErrorResponse(w, r, err)
+	}
+}
+
+func (h *PaymentHandler) CreateSession(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	orderId := ps.ByName("id")
+	clientIdentifier := contextGetClientIdentifier(r)
+
+	if !validator.IsValidUUID(orderId) {
+		h.NotFoundResponse(w, r)
+		return
+	}
+
+	var input service.CreatePaymentSessionInput
+
+	err := h.ReadJSON(w, r, &input)
+
+	if err != nil {
+		h.BadRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+
+	if input.Validate(v); !v.Valid() {
+		h.FailedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	session, err := h.paymentSvc.CreatePaymentSession(r.Context(), clientIdentifier, orderId, &input)
+
+	if err != nil {
+		h.paymentErrorResponse(w, r, err)
+		return
+	}
+
+	err = h.WriteJson(w, http.StatusCreated, ResponseBody{Payload: Envelope{"payment_session": session}}, nil)
+
+	if err != nil {
+		h.ServerErrorResponse(w, r, err)
+	}
+}
+
+func (h *PaymentHandler) ListOrderPayments(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	orderId := ps.ByName("id")
+
+	if !validator.IsValidUUID(orderId) {
+		h.NotFoundResponse(w, r)
+		return
+	}
+
+	payments, err := h.paymentSvc.ListOrderPayments(r.Context(), orderId)
+
+	if err != nil {
+		h.paymentErrorResponse(w, r, err)
+		return
+	}
+
+	err = h.WriteJson(w, http.StatusOK, ResponseBody{Payload: payments}, nil)
+
+	if err != nil {
+		h.ServerErrorResponse(w, r, err)
+	}
+}
+
+func (h *PaymentHandler) Capture(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	orderId := ps.ByName("id")
+	user := contextGetUser(r)
+
+	if !validator.IsValidUUID(orderId) {
+		h.NotFoundResponse(w, r)
+		return
+	}
+
+	payment, err := h.paymentSvc.CapturePayment(r.Context(), orderId, &user.Id)
+
+	if err != nil {
+		h.paymentErrorResponse(w, r, err)
+		return
+	}
+
+	err = h.WriteJson(w, http.StatusOK, ResponseBody{Payload: Envelope{"payment": payment}}, nil)
+
+	if err != nil {
+		h.ServerErrorResponse(w, r, err)
+	}
+}
+
+func (h *PaymentHandler) Refund(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	orderId := ps.ByName("id")
+	user := contextGetUser(r)
+
+	if !validator.IsValidUUID(orderId) {
+		h.NotFoundResponse(w, r)
+		return
+	}
+
+	var input service.RefundPaymentInput
+
+	err := h.ReadJSON(w, r, &input)
+
+	if err != nil {
+		h.BadRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+
+	if input.Validate(v); !v.Valid() {
+		h.FailedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	payment, err := h.paymentSvc.RefundPayment(r.Context(), orderId, &user.Id, &input)
+
+	if err != nil {
+		h.paymentErrorResponse(w, r, err)
+		return
+	}
+
+	err = h.WriteJson(w, http.StatusOK, ResponseBody{Payload: Envelope{"payment": payment}}, nil)
+
+	if err != nil {
+		h.ServerErrorResponse(w, r, err)
+	}
+}
+
+func (h *PaymentHandler) Void(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	orderId := ps.ByName("id")
+
+	if !validator.IsValidUUID(orderId) {
+		h.NotFoundResponse(w, r)
+		return
+	}
+
+	payment, err := h.paymentSvc.VoidPayment(r.Context(), orderId)
+
+	if err != nil {
+		h.paymentErrorResponse(w, r, err)
+		return
+	}
+
+	err = h.WriteJson(w, http.StatusOK, ResponseBody{Payload: Envelope{"payment": payment}}, nil)
+
+	if err != nil {
+		h.ServerErrorResponse(w, r, err)
+	}
+}
+
+func (h *PaymentHandler) Webhook(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	providerId := ps.ByName("providerId")
+
+	r.Body = http.MaxBytesReader(w, r.Body, 1_048_576)
+
+	// the payload is read as is since the signature is computed over the raw body
+	payload, err := io.ReadAll(r.Body)
+
+	if err != nil {
+		h.BadRequestResponse(w, r, err)
+		return
+	}
+
+	err = h.paymentSvc.HandleWebhook(r.Context(), providerId, payload, r.Header)
+
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidWebhookSignature):
+			h.UnauthorizedResponse(w, r)
+		case errors.Is(err, service.ErrInvalidWebhookPayload):
+			h.BadRequestResponse(w, r, err)
+		default:
+			h.paymentErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = h.WriteJson(w, http.StatusOK, Envelope{"success": true}, nil)
+
+	if err != nil {
+		h.ServerErrorResponse(w, r, err)
+	}
+}
+
+func (h *PaymentHandler) paymentErrorResponse(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, model.ErrRecordNotFound),
+		errors.Is(err, service.ErrPaymentProviderNotFound):
+		h.NotFoundResponse(w, r)
+	case errors.Is(err, service.ErrUnauthorizedRequest):
+		h.UnauthorizedResponse(w, r)
+	case errors.Is(err, service.ErrOrderNotPayable),
+		errors.Is(err, service.ErrPaymentSessionInProgress),
+		errors.Is(err, service.ErrPaymentRefundInProgress),
+		errors.Is(err, service.ErrInvalidPaymentOperation),
+		errors.Is(err, service.ErrInvalidOrderTransition):
+		h.ErrorResponse(w, r, http.StatusConflict, err.Error())
+	case errors.Is(err, service.ErrPaymentProviderNotInRegion):
+		h.FailedValidationResponse(w, r, map[string]string{"provider_id": err.Error()})
+	case errors.Is(err, service.ErrInvalidRefundAmount):
+		h.BadRequestResponse(w, r, err)
+	case errors.Is(err, service.ErrRefundAmountDecimals):
+		h.FailedValidationResponse(w, r, map[string]string{"amount": err.Error()})
+	default:
+		h.ServerErrorResponse(w, r, err)
+	}
+}
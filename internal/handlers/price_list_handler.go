@@ -0,0 +1,167 @@
+package handlers
+
+import (
+	"ecom-backend/internal/jsonlog"
+	"ecom-backend/internal/model"
+	"ecom-backend/internal/service"
+	"ecom-backend/internal/validator"
+	"errors"
+	"net/http"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+type PriceListHandler struct {
+	BaseHandler
+	priceListSvc *service.PriceListService
+}
+
+func NewPriceListHandler(logger *jsonlog.Logger, priceListSvc *service.PriceListService) *PriceListHandler {
+	return &PriceListHandler{BaseHandler: BaseHandler{logger: logger}, priceListSvc: priceListSvc}
+}
+
+func (h *PriceListHandler) CreatePriceList(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var input service.CreatePriceListInput
+
+	err := h.ReadJSON(w, r, &input)
+
+	if err != nil {
+		h.BadRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+
+	if input.Validate(v); !v.Valid() {
+		h.FailedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	priceList, err := h.priceListSvc.CreatePriceList(r.Context(), &input)
+
+	if err != nil {
+		h.priceListErrorResponse(w, r, err)
+		return
+	}
+
+	err = h.WriteJson(w, http.StatusCreated, ResponseBody{Payload: Envelope{"price_list": priceList}}, nil)
+
+	if err != nil {
+		h.ServerErrorResponse(w, r, err)
+	}
+}
+
+func (h *PriceListHandler) ListPriceLists(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	priceLists, err := h.priceListSvc.ListPriceLists(r.Context())
+
+	if err != nil {
+		h.ServerErrorResponse(w, r, err)
+		return
+	}
+
+	err = h.WriteJson(w, http.StatusOK, ResponseBody{Payload: Envelope{"price_lists": priceLists}}, nil)
+
+	if err != nil {
+		h.ServerErrorResponse(w, r, err)
+	}
+}
+
+func (h *PriceListHandler) GetPriceList(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	priceListId := ps.ByName("id")
+
+	if !validator.IsValidUUID(priceListId) {
+		h.NotFoundResponse(w, r)
+		return
+	}
+
+	priceList, err := h.priceListSvc.GetPriceList(r.Context(), priceListId)
+
+	if err != nil {
+		h.priceListErrorResponse(w, r, err)
+		return
+	}
+
+	err = h.WriteJson(w, http.StatusOK, ResponseBody{Payload: Envelope{"price_list": priceList}}, nil)
+
+	if err != nil {
+		h.ServerErrorResponse(w, r, err)
+	}
+}
+
+func (h *PriceListHandler) UpdatePriceList(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	priceListId := ps.ByName("id")
+
+	if !validator.IsValidUUID(priceListId) {
+		h.NotFoundResponse(w, r)
+		return
+	}
+
+	var input service.UpdatePriceListInput
+
+	err := h.ReadJSON(w, r, &input)
+
+	if err != nil {
+		h.BadRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+
+	if input.Validate(v); !v.Valid() {
+		h.FailedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	priceList, err := h.priceListSvc.UpdatePriceList(r.Context(), priceListId, &input)
+
+	if err != nil {
+		h.priceListErrorResponse(w, r, err)
+		return
+	}
+
+	err = h.WriteJson(w, http.StatusOK, ResponseBody{Payload: Envelope{"price_list": priceList}}, nil)
+
+	if err != nil {
+		h.ServerErrorResponse(w, r, err)
+	}
+}
+
+func (h *PriceListHandler) DeletePriceList(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	priceListId := ps.ByName("id")
+
+	if !validator.IsValidUUID(priceListId) {
+		h.NotFoundResponse(w, r)
+		return
+	}
+
+	err := h.priceListSvc.DeletePriceList(r.Context(), priceListId)
+
+	if err != nil {
+		h.priceListErrorResponse(w, r, err)
+		return
+	}
+
+	err = h.WriteJson(w, http.StatusOK, ResponseBody{Payload: Envelope{"success": true}}, nil)
+
+	if err != nil {
+		h.ServerErrorResponse(w, r, err)
+	}
+}
+
+func (h *PriceListHandler) priceListErrorResponse(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, model.ErrRecordNotFound):
+		h.NotFoundResponse(w, r)
+	case errors.Is(err, model.ErrVariantNotFound):
+		h.FailedValidationResponse(w, r, map[string]string{"prices.variant_id": err.Error()})
+	case errors.Is(err, model.ErrCurrencyNotFound):
+		h.FailedValidationResponse(w, r, map[string]string{"prices.currency_code": err.Error()})
+	case errors.Is(err, model.ErrCustomerGroupNotFound),
+		errors.Is(err, service.ErrPriceListWithoutCustomerGroups):
+		h.FailedValidationResponse(w, r, map[string]string{"customer_group_ids": err.Error()})
+	case errors.Is(err, model.ErrInvalidValue):
+		h.FailedValidationResponse(w, r, map[string]string{"ends_at": "must be after starts_at"})
+	default:
+		h.ServerErrorResponse(w, r, err)
+	}
+}
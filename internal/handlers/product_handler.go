@@ -1,13 +1,16 @@
 package handlers
 
 import (
+	"ecom-backend/internal/consts"
 	"ecom-backend/internal/jsonlog"
 	"ecom-backend/internal/model"
+	"ecom-backend/internal/money"
 	"ecom-backend/internal/service"
 	"ecom-backend/internal/validator"
 	"errors"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/julienschmidt/httprouter"
 )
@@ -133,7 +136,9 @@ func (h *ProductHandler) UpdateVariantDetails(w http.ResponseWriter, r *http.Req
 		return
 	}
 
-	_, err = h.productSvc.UpdateVariantDetails(r.Context(), variantId, &input)
+	user := contextGetUser(r)
+
+	_, err = h.productSvc.UpdateVariantDetails(r.Context(), variantId, &user.Id, &input)
 
 	if err != nil {
 		if errors.Is(err, model.ErrRecordNotFound) {
@@ -147,41 +152,143 @@ func (h *ProductHandler) UpdateVariantDetails(w http.ResponseWriter, r *http.Req
 	h.WriteJson(w, http.StatusOK, Envelope{"success": true}, nil)
 }
 
-func (h *ProductHandler) GetProducts(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+type ProductListingMetadata struct {
+	CursorPaginationMetadata
+	Hits   map[string]*service.ProductSearchHit `json:"hits,omitempty"` // keyed by product id, only when searching
+	Facets *service.ProductFacets               `json:"facets"`
+}
 
-	page, err := strconv.ParseInt(r.URL.Query().Get("page"), 10, 64)
+// GetProducts lists the products. They're searched with `q`, filtered with `category_id`, `option` given as
+// title:value and repeated for several values, `min_price` and `max_price` in the currency of the request, `in_stock`
+// and `status`, and sorted with `sort`.
+func (h *ProductHandler) GetProducts(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	page, pageSize, cursor, err := readCursorPaginationParams(r)
 
 	if err != nil {
-		h.BadRequestResponse(w, r, errors.New("invalid query parameter `page`"))
+		h.BadRequestResponse(w, r, err)
 		return
 	}
 
-	if page <= 0 {
-		h.BadRequestResponse(w, r, errors.New("page must be > 0"))
+	opt := service.ProductListingOptions{Page: page, PageSize: pageSize, Cursor: cursor, CustomerGroupIds: getCustomerGroupIds(r), PriceCurrencyCode: getCurrencyCode(r)}
+
+	v := validator.New()
+
+	if readProductListingParams(r, &opt, v); !v.Valid() {
+		h.FailedValidationResponse(w, r, v.Errors)
 		return
 	}
 
-	pageSize, err := strconv.ParseInt(r.URL.Query().Get("pageSize"), 10, 64)
+	// the storefront of a region only shows its prices
+	if region := contextGetRegion(r); region != nil {
+		opt.CurrencyCode = &region.CurrencyCode
+	}
+
+	list, hits, info, err := h.productSvc.ListAggregateProducts(r.Context(), opt)
 
 	if err != nil {
-		h.BadRequestResponse(w, r, errors.New("invalid query parameter `pageSize`"))
-		return
-	}
+		// the cursor was made for another sort
+		if errors.Is(err, service.ErrInvalidCursor) {
+			h.BadRequestResponse(w, r, err)
+			return
+		}
 
-	if pageSize <= 0 {
-		h.BadRequestResponse(w, r, errors.New("pageSize must be > 0"))
+		h.ServerErrorResponse(w, r, err)
 		return
 	}
 
-	list, rowCount, err := h.productSvc.ListAggregateProducts(r.Context(), service.ProductListingOptions{Page: uint(page), PageSize: uint(pageSize)})
+	facets, err := h.productSvc.ListProductFacets(r.Context(), opt)
 
 	if err != nil {
 		h.ServerErrorResponse(w, r, err)
 		return
 	}
 
-	h.WriteJson(w, http.StatusOK, ResponseBody{Payload: list, Metadata: PaginationMetadata{Page: int(page), PageSize: int(pageSize), RowsTotal: rowCount}}, nil)
+	metadata := ProductListingMetadata{
+		CursorPaginationMetadata: newCursorPaginationMetadata(page, pageSize, info),
+		Hits:                     hits,
+		Facets:                   facets,
+	}
+
+	h.WriteJson(w, http.StatusOK, ResponseBody{Payload: list, Metadata: metadata}, nil)
 }
+
+// readProductListingParams reads the search, the filters and the sort of the product listing from the query string
+func readProductListingParams(r *http.Request, opt *service.ProductListingOptions, v *validator.Validator) {
+	qs := r.URL.Query()
+
+	if query := strings.TrimSpace(qs.Get("q")); query != "" {
+		v.Check(len(query) <= 256, "q", "must not be more than 256 bytes long")
+		opt.Query = &query
+	}
+
+	if categoryId := qs.Get("category_id"); categoryId != "" {
+		v.Check(validator.IsValidUUID(categoryId), "category_id", "must be a valid UUID")
+		opt.CategoryId = &categoryId
+	}
+
+	for _, option := range qs["option"] {
+		title, value, ok := strings.Cut(option, ":")
+		title = strings.ToLower(strings.TrimSpace(title))
+		value = strings.ToLower(strings.TrimSpace(value))
+
+		if !ok || title == "" || value == "" {
+			v.AddError("option", "must be given as title:value")
+			continue
+		}
+
+		if opt.OptionValues == nil {
+			opt.OptionValues = map[string][]string{}
+		}
+
+		opt.OptionValues[title] = append(opt.OptionValues[title], value)
+	}
+
+	opt.MinPrice = readPriceParam(qs.Get("min_price"), opt.PriceCurrencyCode, "min_price", v)
+	opt.MaxPrice = readPriceParam(qs.Get("max_price"), opt.PriceCurrencyCode, "max_price", v)
+
+	if opt.MinPrice != nil && opt.MaxPrice != nil {
+		v.Check(!opt.MaxPrice.LessThan(*opt.MinPrice), "max_price", "must not be less than the min price")
+	}
+
+	if inStock := qs.Get("in_stock"); inStock != "" {
+		value, err := strconv.ParseBool(inStock)
+		v.Check(err == nil, "in_stock", "must be true or false")
+		opt.InStock = value
+	}
+
+	if status := qs.Get("status"); status != "" {
+		v.Check(validator.In(status, consts.StatusDraft, consts.StatusPublished), "status", "invalid status")
+		opt.Status = &status
+	}
+
+	if sort := qs.Get("sort"); sort != "" {
+		v.Check(validator.In(sort, service.ProductSortSafelist...), "sort", "invalid sort value")
+		v.Check(sort != service.ProductSortRelevance || opt.Query != nil, "sort", "relevance only applies to a search")
+		opt.Sort = sort
+	}
+}
+
+// readPriceParam parses a price of the query string in the currency, nil when it's missing or invalid
+func readPriceParam(value string, currencyCode string, key string, v *validator.Validator) *money.Money {
+	if value == "" {
+		return nil
+	}
+
+	amount, err := money.ParseDecimal(value)
+
+	if err != nil {
+		v.AddError(key, "must be a decimal number")
+		return nil
+	}
+
+	v.Check(amount.Sign() >= 0, key, "should not be negative")
+	v.Check(amount.FitsCurrency(currencyCode), key, "has more decimals than the currency")
+
+	price := amount.Money(currencyCode)
+
+	return &price
+}
+
 func (h *ProductHandler) GetProduct(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
 	productId := ps.ByName("productId")
 
@@ -190,7 +297,13 @@ func (h *ProductHandler) GetProduct(w http.ResponseWriter, r *http.Request, ps h
 		return
 	}
 
-	product, err := h.productSvc.GetAggregateProductById(r.Context(), productId)
+	var currencyCode *string
+
+	if region := contextGetRegion(r); region != nil {
+		currencyCode = &region.CurrencyCode
+	}
+
+	product, err := h.productSvc.GetAggregateProductById(r.Context(), productId, currencyCode, getCustomerGroupIds(r))
 
 	if err != nil {
 		if errors.Is(err, model.ErrRecordNotFound) {
@@ -0,0 +1,211 @@
+package handlers
+
+import (
+	"ecom-backend/internal/jsonlog"
+	"ecom-backend/internal/model"
+	"ecom-backend/internal/service"
+	"ecom-backend/internal/validator"
+	"errors"
+	"net/http"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+type PromotionHandler struct {
+	BaseHandler
+	promotionSvc *service.PromotionService
+}
+
+func NewPromotionHandler(logger *jsonlog.Logger, promotionSvc *service.PromotionService) *PromotionHandler {
+	return &PromotionHandler{BaseHandler: BaseHandler{logger: logger}, promotionSvc: promotionSvc}
+}
+
+func (h *PromotionHandler) Create(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var input service.CreatePromotionInput
+
+	err := h.ReadJSON(w, r, &input)
+
+	if err != nil {
+		h.BadRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+
+	if input.Validate(v); !v.Valid() {
+		h.FailedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	promotion, err := h.promotionSvc.CreatePromotion(r.Context(), &input)
+
+	if err != nil {
+		h.promotionErrorResponse(w, r, err)
+		return
+	}
+
+	err = h.WriteJson(w, http.StatusCreated, ResponseBody{Payload: Envelope{"promotion": promotion}}, nil)
+
+	if err != nil {
+		h.ServerErrorResponse(w, r, err)
+	}
+}
+
+func (h *PromotionHandler) List(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	page, pageSize, err := readPaginationParams(r)
+
+	if err != nil {
+		h.BadRequestResponse(w, r, err)
+		return
+	}
+
+	promotions, rowCount, err := h.promotionSvc.ListPromotions(r.Context(), service.PromotionListingOptions{Page: page, PageSize: pageSize})
+
+	if err != nil {
+		h.ServerErrorResponse(w, r, err)
+		return
+	}
+
+	err = h.WriteJson(w, http.StatusOK, ResponseBody{Payload: promotions, Metadata: PaginationMetadata{Page: int(page), PageSize: int(pageSize), RowsTotal: rowCount}}, nil)
+
+	if err != nil {
+		h.ServerErrorResponse(w, r, err)
+	}
+}
+
+func (h *PromotionHandler) Get(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	promotionId := ps.ByName("id")
+
+	if !validator.IsValidUUID(promotionId) {
+		h.NotFoundResponse(w, r)
+		return
+	}
+
+	promotion, err := h.promotionSvc.GetPromotion(r.Context(), promotionId)
+
+	if err != nil {
+		h.promotionErrorResponse(w, r, err)
+		return
+	}
+
+	err = h.WriteJson(w, http.StatusOK, ResponseBody{Payload: Envelope{"promotion": promotion}}, nil)
+
+	if err != nil {
+		h.ServerErrorResponse(w, r, err)
+	}
+}
+
+func (h *PromotionHandler) Update(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	promotionId := ps.ByName("id")
+
+	if !validator.IsValidUUID(promotionId) {
+		h.NotFoundResponse(w, r)
+		return
+	}
+
+	var input service.UpdatePromotionInput
+
+	err := h.ReadJSON(w, r, &input)
+
+	if err != nil {
+		h.BadRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+
+	if input.Validate(v); !v.Valid() {
+		h.FailedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	promotion, err := h.promotionSvc.UpdatePromotion(r.Context(), promotionId, &input)
+
+	if err != nil {
+		h.promotionErrorResponse(w, r, err)
+		return
+	}
+
+	err = h.WriteJson(w, http.StatusOK, ResponseBody{Payload: Envelope{"promotion": promotion}}, nil)
+
+	if err != nil {
+		h.ServerErrorResponse(w, r, err)
+	}
+}
+
+func (h *PromotionHandler) Delete(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	promotionId := ps.ByName("id")
+
+	if !validator.IsValidUUID(promotionId) {
+		h.NotFoundResponse(w, r)
+		return
+	}
+
+	err := h.promotionSvc.DeletePromotion(r.Context(), promotionId)
+
+	if err != nil {
+		h.promotionErrorResponse(w, r, err)
+		return
+	}
+
+	err = h.WriteJson(w, http.StatusOK, ResponseBody{Payload: Envelope{"success": true}}, nil)
+
+	if err != nil {
+		h.ServerErrorResponse(w, r, err)
+	}
+}
+
+// DryRun explains which promotions would apply to the given items and why the others wouldn't
+func (h *PromotionHandler) DryRun(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var input service.PromotionDryRunInput
+
+	err := h.ReadJSON(w, r, &input)
+
+	if err != nil {
+		h.BadRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+
+	if input.Validate(v); !v.Valid() {
+		h.FailedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	evaluation, err := h.promotionSvc.DryRun(r.Context(), &input)
+
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrPromotionNotFound):
+			h.FailedValidationResponse(w, r, map[string]string{"promotion_code": "invalid promotion code"})
+		case errors.Is(err, model.ErrVariantNotFound),
+			errors.Is(err, service.ErrVariantPriceNotFound):
+			h.BadRequestResponse(w, r, err)
+		default:
+			h.ServerErrorResponse(w, r, err)
+		}
+		return
+	}
+
+	err = h.WriteJson(w, http.StatusOK, ResponseBody{Payload: Envelope{"evaluation": evaluation}}, nil)
+
+	if err != nil {
+		h.ServerErrorResponse(w, r, err)
+	}
+}
+
+func (h *PromotionHandler) promotionErrorResponse(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, model.ErrRecordNotFound):
+		h.NotFoundResponse(w, r)
+	case errors.Is(err, model.ErrDuplicatedPromotionCode):
+		h.FailedValidationResponse(w, r, map[string]string{"code": "a promotion with this code already exists"})
+	case errors.Is(err, model.ErrInvalidValue):
+		h.FailedValidationResponse(w, r, map[string]string{"currency_rules": "invalid currency"})
+	case errors.Is(err, model.ErrCustomerGroupNotFound):
+		h.FailedValidationResponse(w, r, map[string]string{"customer_group_ids": err.Error()})
+	default:
+		h.ServerErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,164 @@
+package handlers
+
+import (
+	"ecom-backend/internal/jsonlog"
+	"ecom-backend/internal/model"
+	"ecom-backend/internal/service"
+	"ecom-backend/internal/validator"
+	"errors"
+	"net/http"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+type RegionHandler struct {
+	BaseHandler
+	regionSvc *service.RegionService
+}
+
+func NewRegionHandler(logger *jsonlog.Logger, regionSvc *service.RegionService) *RegionHandler {
+	return &RegionHandler{BaseHandler: BaseHandler{logger: logger}, regionSvc: regionSvc}
+}
+
+func (h *RegionHandler) CreateRegion(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var input service.CreateRegionInput
+
+	err := h.ReadJSON(w, r, &input)
+
+	if err != nil {
+		h.BadRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+
+	if input.Validate(v); !v.Valid() {
+		h.FailedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	region, err := h.regionSvc.CreateRegion(r.Context(), &input)
+
+	if err != nil {
+		h.regionErrorResponse(w, r, err)
+		return
+	}
+
+	err = h.WriteJson(w, http.StatusCreated, ResponseBody{Payload: Envelope{"region": region}}, nil)
+
+	if err != nil {
+		h.ServerErrorResponse(w, r, err)
+	}
+}
+
+func (h *RegionHandler) ListRegions(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	regions, err := h.regionSvc.ListRegions(r.Context())
+
+	if err != nil {
+		h.ServerErrorResponse(w, r, err)
+		return
+	}
+
+	err = h.WriteJson(w, http.StatusOK, ResponseBody{Payload: Envelope{"regions": regions}}, nil)
+
+	if err != nil {
+		h.ServerErrorResponse(w, r, err)
+	}
+}
+
+func (h *RegionHandler) GetRegion(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	regionId := ps.ByName("id")
+
+	if !validator.IsValidUUID(regionId) {
+		h.NotFoundResponse(w, r)
+		return
+	}
+
+	region, err := h.regionSvc.GetRegion(r.Context(), regionId)
+
+	if err != nil {
+		h.regionErrorResponse(w, r, err)
+		return
+	}
+
+	err = h.WriteJson(w, http.StatusOK, ResponseBody{Payload: Envelope{"region": region}}, nil)
+
+	if err != nil {
+		h.ServerErrorResponse(w, r, err)
+	}
+}
+
+func (h *RegionHandler) UpdateRegion(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	regionId := ps.ByName("id")
+
+	if !validator.IsValidUUID(regionId) {
+		h.NotFoundResponse(w, r)
+		return
+	}
+
+	var input service.UpdateRegionInput
+
+	err := h.ReadJSON(w, r, &input)
+
+	if err != nil {
+		h.BadRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+
+	if input.Validate(v); !v.Valid() {
+		h.FailedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	region, err := h.regionSvc.UpdateRegion(r.Context(), regionId, &input)
+
+	if err != nil {
+		h.regionErrorResponse(w, r, err)
+		return
+	}
+
+	err = h.WriteJson(w, http.StatusOK, ResponseBody{Payload: Envelope{"region": region}}, nil)
+
+	if err != nil {
+		h.ServerErrorResponse(w, r, err)
+	}
+}
+
+func (h *RegionHandler) DeleteRegion(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	regionId := ps.ByName("id")
+
+	if !validator.IsValidUUID(regionId) {
+		h.NotFoundResponse(w, r)
+		return
+	}
+
+	err := h.regionSvc.DeleteRegion(r.Context(), regionId)
+
+	if err != nil {
+		h.regionErrorResponse(w, r, err)
+		return
+	}
+
+	err = h.WriteJson(w, http.StatusOK, ResponseBody{Payload: Envelope{"success": true}}, nil)
+
+	if err != nil {
+		h.ServerErrorResponse(w, r, err)
+	}
+}
+
+func (h *RegionHandler) regionErrorResponse(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, model.ErrRecordNotFound):
+		h.NotFoundResponse(w, r)
+	case errors.Is(err, model.ErrCurrencyNotFound):
+		h.FailedValidationResponse(w, r, map[string]string{"currency_code": err.Error()})
+	case errors.Is(err, model.ErrCountryInOtherRegion):
+		h.FailedValidationResponse(w, r, map[string]string{"countries": err.Error()})
+	case errors.Is(err, service.ErrPaymentProviderNotFound):
+		h.FailedValidationResponse(w, r, map[string]string{"payment_providers": err.Error()})
+	default:
+		h.ServerErrorResponse(w, r, err)
+	}
+}
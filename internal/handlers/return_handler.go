@@ -0,0 +1,343 @@
+package handlers
+
+import (
+	"context"
+	"ecom-backend/internal/consts"
+	"ecom-backend/internal/jsonlog"
+	"ecom-backend/internal/model"
+	"ecom-backend/internal/service"
+	"ecom-backend/internal/validator"
+	"errors"
+	"net/http"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+type ReturnHandler struct {
+	BaseHandler
+	returnSvc *service.ReturnService
+}
+
+func NewReturnHandler(logger *jsonlog.Logger, returnSvc *service.ReturnService) *ReturnHandler {
+	return &ReturnHandler{BaseHandler: BaseHandler{logger: logger}, returnSvc: returnSvc}
+}
+
+// RequestReturn lets the client return lines of one of their delivered orders
+func (h *ReturnHandler) RequestReturn(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	orderId := ps.ByName("id")
+	user := contextGetUser(r)
+
+	if !validator.IsValidUUID(orderId) {
+		h.NotFoundResponse(w, r)
+		return
+	}
+
+	var input service.RequestReturnInput
+
+	err := h.ReadJSON(w, r, &input)
+
+	if err != nil {
+		h.BadRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+
+	if input.Validate(v); !v.Valid() {
+		h.FailedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	var userId *string
+
+	if !isAnonymousUser(user) {
+		userId = &user.Id
+	}
+
+	orderReturn, err := h.returnSvc.RequestReturn(r.Context(), contextGetClientIdentifier(r), userId, orderId, &input)
+
+	if err != nil {
+		h.returnErrorResponse(w, r, err)
+		return
+	}
+
+	err = h.WriteJson(w, http.StatusCreated, ResponseBody{Payload: Envelope{"return": orderReturn}}, nil)
+
+	if err != nil {
+		h.ServerErrorResponse(w, r, err)
+	}
+}
+
+func (h *ReturnHandler) ListOrderReturns(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	orderId := ps.ByName("id")
+
+	if !validator.IsValidUUID(orderId) {
+		h.NotFoundResponse(w, r)
+		return
+	}
+
+	returns, err := h.returnSvc.ListClientOrderReturns(r.Context(), contextGetClientIdentifier(r), orderId)
+
+	if err != nil {
+		h.returnErrorResponse(w, r, err)
+		return
+	}
+
+	err = h.WriteJson(w, http.StatusOK, ResponseBody{Payload: Envelope{"returns": returns}}, nil)
+
+	if err != nil {
+		h.ServerErrorResponse(w, r, err)
+	}
+}
+
+// CancelReturn lets the client withdraw a return that wasn't reviewed yet
+func (h *ReturnHandler) CancelReturn(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	orderId := ps.ByName("id")
+	returnId := ps.ByName("returnId")
+	user := contextGetUser(r)
+
+	if !validator.IsValidUUID(orderId) || !validator.IsValidUUID(returnId) {
+		h.NotFoundResponse(w, r)
+		return
+	}
+
+	var input service.ReturnTransitionInput
+
+	err := h.ReadJSON(w, r, &input)
+
+	if err != nil {
+		h.BadRequestResponse(w, r, err)
+		return
+	}
+
+	var userId *string
+
+	if !isAnonymousUser(user) {
+		userId = &user.Id
+	}
+
+	orderReturn, err := h.returnSvc.CancelClientReturn(r.Context(), contextGetClientIdentifier(r), userId, orderId, returnId, &input)
+
+	if err != nil {
+		h.returnErrorResponse(w, r, err)
+		return
+	}
+
+	err = h.WriteJson(w, http.StatusOK, ResponseBody{Payload: Envelope{"return": orderReturn}}, nil)
+
+	if err != nil {
+		h.ServerErrorResponse(w, r, err)
+	}
+}
+
+func (h *ReturnHandler) ListReturns(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	page, pageSize, err := readPaginationParams(r)
+
+	if err != nil {
+		h.BadRequestResponse(w, r, err)
+		return
+	}
+
+	opt := service.ReturnListingOptions{Page: page, PageSize: pageSize}
+
+	if status := r.URL.Query().Get("status"); status != "" {
+		v := validator.New()
+
+		statuses := []string{
+			consts.ReturnStatusRequested,
+			consts.ReturnStatusApproved,
+			consts.ReturnStatusRejected,
+			consts.ReturnStatusReceived,
+			consts.ReturnStatusRefunding,
+			consts.ReturnStatusRefunded,
+			consts.ReturnStatusCancelled,
+		}
+
+		if v.Check(validator.In(status, statuses...), "status", "invalid status"); !v.Valid() {
+			h.FailedValidationResponse(w, r, v.Errors)
+			return
+		}
+
+		opt.Status = &status
+	}
+
+	returns, rowCount, err := h.returnSvc.ListReturns(r.Context(), opt)
+
+	if err != nil {
+		h.ServerErrorResponse(w, r, err)
+		return
+	}
+
+	err = h.WriteJson(w, http.StatusOK, ResponseBody{Payload: returns, Metadata: PaginationMetadata{Page: int(page), PageSize: int(pageSize), RowsTotal: rowCount}}, nil)
+
+	if err != nil {
+		h.ServerErrorResponse(w, r, err)
+	}
+}
+
+func (h *ReturnHandler) GetReturn(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	returnId := ps.ByName("id")
+
+	if !validator.IsValidUUID(returnId) {
+		h.NotFoundResponse(w, r)
+		return
+	}
+
+	orderReturn, err := h.returnSvc.GetReturn(r.Context(), returnId)
+
+	if err != nil {
+		h.returnErrorResponse(w, r, err)
+		return
+	}
+
+	err = h.WriteJson(w, http.StatusOK, ResponseBody{Payload: Envelope{"return": orderReturn}}, nil)
+
+	if err != nil {
+		h.ServerErrorResponse(w, r, err)
+	}
+}
+
+func (h *ReturnHandler) ApproveReturn(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	h.reviewReturn(w, r, ps, h.returnSvc.ApproveReturn)
+}
+
+func (h *ReturnHandler) RejectReturn(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	h.reviewReturn(w, r, ps, h.returnSvc.RejectReturn)
+}
+
+func (h *ReturnHandler) reviewReturn(w http.ResponseWriter, r *http.Request, ps httprouter.Params, review func(context.Context, string, *string, *service.ReturnTransitionInput) (*service.ReturnDTO, error)) {
+	returnId := ps.ByName("id")
+	user := contextGetUser(r)
+
+	if !validator.IsValidUUID(returnId) {
+		h.NotFoundResponse(w, r)
+		return
+	}
+
+	var input service.ReturnTransitionInput
+
+	err := h.ReadJSON(w, r, &input)
+
+	if err != nil {
+		h.BadRequestResponse(w, r, err)
+		return
+	}
+
+	orderReturn, err := review(r.Context(), returnId, &user.Id, &input)
+
+	if err != nil {
+		h.returnErrorResponse(w, r, err)
+		return
+	}
+
+	err = h.WriteJson(w, http.StatusOK, ResponseBody{Payload: Envelope{"return": orderReturn}}, nil)
+
+	if err != nil {
+		h.ServerErrorResponse(w, r, err)
+	}
+}
+
+func (h *ReturnHandler) ReceiveReturn(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	returnId := ps.ByName("id")
+	user := contextGetUser(r)
+
+	if !validator.IsValidUUID(returnId) {
+		h.NotFoundResponse(w, r)
+		return
+	}
+
+	var input service.ReceiveReturnInput
+
+	err := h.ReadJSON(w, r, &input)
+
+	if err != nil {
+		h.BadRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+
+	if input.Validate(v); !v.Valid() {
+		h.FailedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	orderReturn, err := h.returnSvc.ReceiveReturn(r.Context(), returnId, &user.Id, &input)
+
+	if err != nil {
+		h.returnErrorResponse(w, r, err)
+		return
+	}
+
+	err = h.WriteJson(w, http.StatusOK, ResponseBody{Payload: Envelope{"return": orderReturn}}, nil)
+
+	if err != nil {
+		h.ServerErrorResponse(w, r, err)
+	}
+}
+
+func (h *ReturnHandler) RefundReturn(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	returnId := ps.ByName("id")
+	user := contextGetUser(r)
+
+	if !validator.IsValidUUID(returnId) {
+		h.NotFoundResponse(w, r)
+		return
+	}
+
+	var input service.RefundReturnInput
+
+	err := h.ReadJSON(w, r, &input)
+
+	if err != nil {
+		h.BadRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+
+	if input.Validate(v); !v.Valid() {
+		h.FailedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	orderReturn, err := h.returnSvc.RefundReturn(r.Context(), returnId, &user.Id, &input)
+
+	if err != nil {
+		h.returnErrorResponse(w, r, err)
+		return
+	}
+
+	err = h.WriteJson(w, http.StatusOK, ResponseBody{Payload: Envelope{"return": orderReturn}}, nil)
+
+	if err != nil {
+		h.ServerErrorResponse(w, r, err)
+	}
+}
+
+func (h *ReturnHandler) returnErrorResponse(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, model.ErrRecordNotFound):
+		h.NotFoundResponse(w, r)
+	case errors.Is(err, service.ErrUnauthorizedRequest):
+		h.UnauthorizedResponse(w, r)
+	case errors.Is(err, service.ErrOrderNotReturnable),
+		errors.Is(err, service.ErrInvalidReturnTransition),
+		errors.Is(err, service.ErrInvalidPaymentOperation),
+		errors.Is(err, service.ErrInvalidOrderTransition):
+		h.ErrorResponse(w, r, http.StatusConflict, err.Error())
+	case errors.Is(err, service.ErrOrderLineItemNotReturned),
+		errors.Is(err, service.ErrReturnItemNotFound),
+		errors.Is(err, service.ErrInvalidReturnQuantity),
+		errors.Is(err, service.ErrGiftCardNotReturnable):
+		h.FailedValidationResponse(w, r, map[string]string{"items": err.Error()})
+	case errors.Is(err, model.ErrStockLocationNotFound):
+		h.FailedValidationResponse(w, r, map[string]string{"location_id": "stock location not found"})
+	case errors.Is(err, service.ErrInvalidRefundAmount):
+		h.BadRequestResponse(w, r, err)
+	case errors.Is(err, service.ErrRefundAmountDecimals):
+		h.FailedValidationResponse(w, r, map[string]string{"amount": err.Error()})
+	default:
+		h.ServerErrorResponse(w, r, err)
+	}
+}
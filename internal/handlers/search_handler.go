@@ -0,0 +1,62 @@
+package handlers
+
+import (
+	"ecom-backend/internal/jsonlog"
+	"ecom-backend/internal/service"
+	"ecom-backend/internal/validator"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+type SearchHandler struct {
+	BaseHandler
+	searchSvc *service.SearchService
+}
+
+func NewSearchHandler(logger *jsonlog.Logger, searchSvc *service.SearchService) *SearchHandler {
+	return &SearchHandler{BaseHandler: BaseHandler{logger: logger}, searchSvc: searchSvc}
+}
+
+// Suggest returns the suggestions for a search-as-you-type box, the query is read from q and the number of suggestions
+// of every kind from limit
+func (h *SearchHandler) Suggest(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	input := service.SuggestInput{Query: strings.TrimSpace(r.URL.Query().Get("q"))}
+
+	if limit := r.URL.Query().Get("limit"); limit != "" {
+		value, err := strconv.Atoi(limit)
+
+		if err != nil {
+			h.BadRequestResponse(w, r, err)
+			return
+		}
+
+		input.Limit = &value
+	}
+
+	v := validator.New()
+
+	if input.Validate(v); !v.Valid() {
+		h.FailedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	suggestions, err := h.searchSvc.Suggest(r.Context(), &input)
+
+	if err != nil {
+		h.ServerErrorResponse(w, r, err)
+		return
+	}
+
+	// the catalog rarely changes between two keystrokes, the browser can reuse the suggestions for a while
+	headers := http.Header{}
+	headers.Set("Cache-Control", "public, max-age=60")
+
+	err = h.WriteJson(w, http.StatusOK, ResponseBody{Payload: Envelope{"suggestions": suggestions}}, headers)
+
+	if err != nil {
+		h.ServerErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,261 @@
+package handlers
+
+import (
+	"ecom-backend/internal/jsonlog"
+	"ecom-backend/internal/model"
+	"ecom-backend/internal/service"
+	"ecom-backend/internal/validator"
+	"errors"
+	"net/http"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+type ShippingHandler struct {
+	BaseHandler
+	shippingSvc *service.ShippingService
+}
+
+func NewShippingHandler(logger *jsonlog.Logger, shippingSvc *service.ShippingService) *ShippingHandler {
+	return &ShippingHandler{BaseHandler: BaseHandler{logger: logger}, shippingSvc: shippingSvc}
+}
+
+func (h *ShippingHandler) CreateShippingZone(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var input service.CreateShippingZoneInput
+
+	err := h.ReadJSON(w, r, &input)
+
+	if err != nil {
+		h.BadRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+
+	if input.Validate(v); !v.Valid() {
+		h.FailedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	zone, err := h.shippingSvc.CreateShippingZone(r.Context(), &input)
+
+	if err != nil {
+		h.shippingErrorResponse(w, r, err)
+		return
+	}
+
+	err = h.WriteJson(w, http.StatusCreated, ResponseBody{Payload: Envelope{"shipping_zone": zone}}, nil)
+
+	if err != nil {
+		h.ServerErrorResponse(w, r, err)
+	}
+}
+
+func (h *ShippingHandler) ListShippingZones(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	zones, err := h.shippingSvc.ListShippingZones(r.Context())
+
+	if err != nil {
+		h.ServerErrorResponse(w, r, err)
+		return
+	}
+
+	err = h.WriteJson(w, http.StatusOK, ResponseBody{Payload: Envelope{"shipping_zones": zones}}, nil)
+
+	if err != nil {
+		h.ServerErrorResponse(w, r, err)
+	}
+}
+
+func (h *ShippingHandler) GetShippingZone(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	zoneId := ps.ByName("id")
+
+	if !validator.IsValidUUID(zoneId) {
+		h.NotFoundResponse(w, r)
+		return
+	}
+
+	zone, err := h.shippingSvc.GetShippingZone(r.Context(), zoneId)
+
+	if err != nil {
+		h.shippingErrorResponse(w, r, err)
+		return
+	}
+
+	err = h.WriteJson(w, http.StatusOK, ResponseBody{Payload: Envelope{"shipping_zone": zone}}, nil)
+
+	if err != nil {
+		h.ServerErrorResponse(w, r, err)
+	}
+}
+
+func (h *ShippingHandler) UpdateShippingZone(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	zoneId := ps.ByName("id")
+
+	if !validator.IsValidUUID(zoneId) {
+		h.NotFoundResponse(w, r)
+		return
+	}
+
+	var input service.UpdateShippingZoneInput
+
+	err := h.ReadJSON(w, r, &input)
+
+	if err != nil {
+		h.BadRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+
+	if input.Validate(v); !v.Valid() {
+		h.FailedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	zone, err := h.shippingSvc.UpdateShippingZone(r.Context(), zoneId, &input)
+
+	if err != nil {
+		h.shippingErrorResponse(w, r, err)
+		return
+	}
+
+	err = h.WriteJson(w, http.StatusOK, ResponseBody{Payload: Envelope{"shipping_zone": zone}}, nil)
+
+	if err != nil {
+		h.ServerErrorResponse(w, r, err)
+	}
+}
+
+func (h *ShippingHandler) DeleteShippingZone(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	zoneId := ps.ByName("id")
+
+	if !validator.IsValidUUID(zoneId) {
+		h.NotFoundResponse(w, r)
+		return
+	}
+
+	err := h.shippingSvc.DeleteShippingZone(r.Context(), zoneId)
+
+	if err != nil {
+		h.shippingErrorResponse(w, r, err)
+		return
+	}
+
+	err = h.WriteJson(w, http.StatusOK, ResponseBody{Payload: Envelope{"success": true}}, nil)
+
+	if err != nil {
+		h.ServerErrorResponse(w, r, err)
+	}
+}
+
+func (h *ShippingHandler) CreateShippingMethod(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	zoneId := ps.ByName("id")
+
+	if !validator.IsValidUUID(zoneId) {
+		h.NotFoundResponse(w, r)
+		return
+	}
+
+	var input service.CreateShippingMethodInput
+
+	err := h.ReadJSON(w, r, &input)
+
+	if err != nil {
+		h.BadRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+
+	if input.Validate(v); !v.Valid() {
+		h.FailedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	method, err := h.shippingSvc.CreateShippingMethod(r.Context(), zoneId, &input)
+
+	if err != nil {
+		h.shippingErrorResponse(w, r, err)
+		return
+	}
+
+	err = h.WriteJson(w, http.StatusCreated, ResponseBody{Payload: Envelope{"shipping_method": method}}, nil)
+
+	if err != nil {
+		h.ServerErrorResponse(w, r, err)
+	}
+}
+
+func (h *ShippingHandler) UpdateShippingMethod(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	methodId := ps.ByName("id")
+
+	if !validator.IsValidUUID(methodId) {
+		h.NotFoundResponse(w, r)
+		return
+	}
+
+	var input service.UpdateShippingMethodInput
+
+	err := h.ReadJSON(w, r, &input)
+
+	if err != nil {
+		h.BadRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+
+	if input.Validate(v); !v.Valid() {
+		h.FailedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	method, err := h.shippingSvc.UpdateShippingMethod(r.Context(), methodId, &input)
+
+	if err != nil {
+		h.shippingErrorResponse(w, r, err)
+		return
+	}
+
+	err = h.WriteJson(w, http.StatusOK, ResponseBody{Payload: Envelope{"shipping_method": method}}, nil)
+
+	if err != nil {
+		h.ServerErrorResponse(w, r, err)
+	}
+}
+
+func (h *ShippingHandler) DeleteShippingMethod(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	methodId := ps.ByName("id")
+
+	if !validator.IsValidUUID(methodId) {
+		h.NotFoundResponse(w, r)
+		return
+	}
+
+	err := h.shippingSvc.DeleteShippingMethod(r.Context(), methodId)
+
+	if err != nil {
+		h.shippingErrorResponse(w, r, err)
+		return
+	}
+
+	err = h.WriteJson(w, http.StatusOK, ResponseBody{Payload: Envelope{"success": true}}, nil)
+
+	if err != nil {
+		h.ServerErrorResponse(w, r, err)
+	}
+}
+
+func (h *ShippingHandler) shippingErrorResponse(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, model.ErrRecordNotFound),
+		errors.Is(err, model.ErrShippingZoneNotFound):
+		h.NotFoundResponse(w, r)
+	case errors.Is(err, model.ErrCountryInOtherShippingZone):
+		h.FailedValidationResponse(w, r, map[string]string{"countries": err.Error()})
+	case errors.Is(err, model.ErrInvalidValue):
+		h.FailedValidationResponse(w, r, map[string]string{"rates": "invalid rate"})
+	default:
+		h.ServerErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"ecom-backend/internal/jsonlog"
+	"ecom-backend/internal/model"
+	"ecom-backend/internal/service"
+	"ecom-backend/internal/validator"
+	"errors"
+	"net/http"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+type StoreCreditHandler struct {
+	BaseHandler
+	storeCreditSvc *service.StoreCreditService
+}
+
+func NewStoreCreditHandler(logger *jsonlog.Logger, storeCreditSvc *service.StoreCreditService) *StoreCreditHandler {
+	return &StoreCreditHandler{BaseHandler: BaseHandler{logger: logger}, storeCreditSvc: storeCreditSvc}
+}
+
+// GetStoreCredit returns the store credit of the authenticated user
+func (h *StoreCreditHandler) GetStoreCredit(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	h.writeStoreCredit(w, r, contextGetUser(r).Id)
+}
+
+// GetUserStoreCredit returns the store credit of any user
+func (h *StoreCreditHandler) GetUserStoreCredit(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	userId := ps.ByName("id")
+
+	if !validator.IsValidUUID(userId) {
+		h.NotFoundResponse(w, r)
+		return
+	}
+
+	h.writeStoreCredit(w, r, userId)
+}
+
+func (h *StoreCreditHandler) writeStoreCredit(w http.ResponseWriter, r *http.Request, userId string) {
+	storeCredit, err := h.storeCreditSvc.GetStoreCredit(r.Context(), userId)
+
+	if err != nil {
+		h.storeCreditErrorResponse(w, r, err)
+		return
+	}
+
+	err = h.WriteJson(w, http.StatusOK, ResponseBody{Payload: Envelope{"store_credit": storeCredit}}, nil)
+
+	if err != nil {
+		h.ServerErrorResponse(w, r, err)
+	}
+}
+
+func (h *StoreCreditHandler) AdjustUserStoreCredit(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	userId := ps.ByName("id")
+	user := contextGetUser(r)
+
+	if !validator.IsValidUUID(userId) {
+		h.NotFoundResponse(w, r)
+		return
+	}
+
+	var input service.AdjustStoreCreditInput
+
+	err := h.ReadJSON(w, r, &input)
+
+	if err != nil {
+		h.BadRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+
+	if input.Validate(v); !v.Valid() {
+		h.FailedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	storeCredit, err := h.storeCreditSvc.AdjustStoreCredit(r.Context(), userId, &user.Id, &input)
+
+	if err != nil {
+		h.storeCreditErrorResponse(w, r, err)
+		return
+	}
+
+	err = h.WriteJson(w, http.StatusOK, ResponseBody{Payload: Envelope{"store_credit": storeCredit}}, nil)
+
+	if err != nil {
+		h.ServerErrorResponse(w, r, err)
+	}
+}
+
+func (h *StoreCreditHandler) storeCreditErrorResponse(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, model.ErrRecordNotFound):
+		h.NotFoundResponse(w, r)
+	case errors.Is(err, model.ErrCurrencyNotFound):
+		h.FailedValidationResponse(w, r, map[string]string{"currency_code": "currency not found"})
+	case errors.Is(err, service.ErrInsufficientStoreCredit):
+		h.FailedValidationResponse(w, r, map[string]string{"amount": "the store credit can't go below zero"})
+	default:
+		h.ServerErrorResponse(w, r, err)
+	}
+}
@@ -0,0 +1,164 @@
+package handlers
+
+import (
+	"ecom-backend/internal/jsonlog"
+	"ecom-backend/internal/model"
+	"ecom-backend/internal/service"
+	"ecom-backend/internal/validator"
+	"errors"
+	"net/http"
+
+	"github.com/julienschmidt/httprouter"
+)
+
+type TaxHandler struct {
+	BaseHandler
+	taxSvc *service.TaxService
+}
+
+func NewTaxHandler(logger *jsonlog.Logger, taxSvc *service.TaxService) *TaxHandler {
+	return &TaxHandler{BaseHandler: BaseHandler{logger: logger}, taxSvc: taxSvc}
+}
+
+func (h *TaxHandler) CreateTaxRegion(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	var input service.CreateTaxRegionInput
+
+	err := h.ReadJSON(w, r, &input)
+
+	if err != nil {
+		h.BadRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+
+	if input.Validate(v); !v.Valid() {
+		h.FailedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	region, err := h.taxSvc.CreateTaxRegion(r.Context(), &input)
+
+	if err != nil {
+		h.taxRegionErrorResponse(w, r, err)
+		return
+	}
+
+	err = h.WriteJson(w, http.StatusCreated, ResponseBody{Payload: Envelope{"tax_region": region}}, nil)
+
+	if err != nil {
+		h.ServerErrorResponse(w, r, err)
+	}
+}
+
+func (h *TaxHandler) ListTaxRegions(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	regions, err := h.taxSvc.ListTaxRegions(r.Context())
+
+	if err != nil {
+		h.ServerErrorResponse(w, r, err)
+		return
+	}
+
+	err = h.WriteJson(w, http.StatusOK, ResponseBody{Payload: Envelope{"tax_regions": regions}}, nil)
+
+	if err != nil {
+		h.ServerErrorResponse(w, r, err)
+	}
+}
+
+func (h *TaxHandler) GetTaxRegion(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	regionId := ps.ByName("id")
+
+	if !validator.IsValidUUID(regionId) {
+		h.NotFoundResponse(w, r)
+		return
+	}
+
+	region, err := h.taxSvc.GetTaxRegion(r.Context(), regionId)
+
+	if err != nil {
+		h.taxRegionErrorResponse(w, r, err)
+		return
+	}
+
+	err = h.WriteJson(w, http.StatusOK, ResponseBody{Payload: Envelope{"tax_region": region}}, nil)
+
+	if err != nil {
+		h.ServerErrorResponse(w, r, err)
+	}
+}
+
+func (h *TaxHandler) UpdateTaxRegion(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	regionId := ps.ByName("id")
+
+	if !validator.IsValidUUID(regionId) {
+		h.NotFoundResponse(w, r)
+		return
+	}
+
+	var input service.UpdateTaxRegionInput
+
+	err := h.ReadJSON(w, r, &input)
+
+	if err != nil {
+		h.BadRequestResponse(w, r, err)
+		return
+	}
+
+	v := validator.New()
+
+	if input.Validate(v); !v.Valid() {
+		h.FailedValidationResponse(w, r, v.Errors)
+		return
+	}
+
+	region, err := h.taxSvc.UpdateTaxRegion(r.Context(), regionId, &input)
+
+	if err != nil {
+		h.taxRegionErrorResponse(w, r, err)
+		return
+	}
+
+	err = h.WriteJson(w, http.StatusOK, ResponseBody{Payload: Envelope{"tax_region": region}}, nil)
+
+	if err != nil {
+		h.ServerErrorResponse(w, r, err)
+	}
+}
+
+func (h *TaxHandler) DeleteTaxRegion(w http.ResponseWriter, r *http.Request, ps httprouter.Params) {
+	regionId := ps.ByName("id")
+
+	if !validator.IsValidUUID(regionId) {
+		h.NotFoundResponse(w, r)
+		return
+	}
+
+	err := h.taxSvc.DeleteTaxRegion(r.Context(), regionId)
+
+	if err != nil {
+		h.taxRegionErrorResponse(w, r, err)
+		return
+	}
+
+	err = h.WriteJson(w, http.StatusOK, ResponseBody{Payload: Envelope{"success": true}}, nil)
+
+	if err != nil {
+		h.ServerErrorResponse(w, r, err)
+	}
+}
+
+func (h *TaxHandler) taxRegionErrorResponse(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, model.ErrRecordNotFound):
+		h.NotFoundResponse(w, r)
+	case errors.Is(err, model.ErrDuplicatedTaxRegion):
+		h.FailedValidationResponse(w, r, map[string]string{"country_code": "a tax region already exists for this location"})
+	case errors.Is(err, model.ErrProductCategoryNotFound):
+		h.FailedValidationResponse(w, r, map[string]string{"overrides.category_id": "product category not found"})
+	case errors.Is(err, model.ErrInvalidValue):
+		h.FailedValidationResponse(w, r, map[string]string{"overrides": "invalid override"})
+	default:
+		h.ServerErrorResponse(w, r, err)
+	}
+}
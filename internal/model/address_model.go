@@ -0,0 +1,71 @@
+package model
+
+import (
+	"context"
+	"ecom-backend/pkg/sqldb"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type AddressRecord struct {
+	Id          string    `json:"id"`
+	FirstName   string    `json:"first_name"`
+	LastName    string    `json:"last_name"`
+	Address1    string    `json:"address_1"`
+	Address2    *string   `json:"address_2"`
+	City        string    `json:"city"`
+	PostalCode  string    `json:"postal_code"`
+	Province    *string   `json:"province"`
+	CountryCode string    `json:"country_code"`
+	Phone       *string   `json:"phone"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type AddressModel struct{}
+
+func NewAddressModel() *AddressModel {
+	return &AddressModel{}
+}
+
+func (m *AddressModel) Insert(ctx context.Context, conn sqldb.Connection, record *AddressRecord) (*AddressRecord, error) {
+	q := `INSERT INTO address (first_name, last_name, address_1, address_2, city, postal_code, province, country_code, phone)
+		  VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING id, created_at`
+
+	err := conn.QueryRowContext(ctx, q, record.FirstName, record.LastName, record.Address1, record.Address2, record.City, record.PostalCode, record.Province, record.CountryCode, record.Phone).Scan(&record.Id, &record.CreatedAt)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return record, nil
+}
+
+func (m *AddressModel) FindAllByIds(ctx context.Context, conn sqldb.Connection, ids []string) (map[string]*AddressRecord, error) {
+	q := `SELECT id, first_name, last_name, address_1, address_2, city, postal_code, province, country_code, phone, created_at
+		  FROM address WHERE id = ANY($1)`
+
+	rows, err := conn.QueryContext(ctx, q, pq.Array(ids))
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	resultMap := make(map[string]*AddressRecord)
+
+	for rows.Next() {
+		var record AddressRecord
+
+		err := rows.Scan(&record.Id, &record.FirstName, &record.LastName, &record.Address1, &record.Address2, &record.City, &record.PostalCode, &record.Province, &record.CountryCode, &record.Phone, &record.CreatedAt)
+
+		if err != nil {
+			return nil, err
+		}
+
+		resultMap[record.Id] = &record
+	}
+
+	return resultMap, nil
+}
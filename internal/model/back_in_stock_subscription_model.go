@@ -0,0 +1,103 @@
+package model
+
+import (
+	"context"
+	"ecom-backend/pkg/sqldb"
+	"time"
+)
+
+type BackInStockSubscriptionRecord struct {
+	Id             string     `json:"id"`
+	VariantId      string     `json:"variant_id"`
+	UserIdentifier string     `json:"-"`
+	Email          string     `json:"email"`
+	NotifiedAt     *time.Time `json:"notified_at"`
+	CreatedAt      time.Time  `json:"created_at"`
+}
+
+type BackInStockSubscriptionModel struct{}
+
+func NewBackInStockSubscriptionModel() *BackInStockSubscriptionModel {
+	return &BackInStockSubscriptionModel{}
+}
+
+// Upsert subscribes the client to the variant, subscribing again renews a subscription that was already notified
+func (m *BackInStockSubscriptionModel) Upsert(ctx context.Context, conn sqldb.Connection, record *BackInStockSubscriptionRecord) (*BackInStockSubscriptionRecord, error) {
+	q := `INSERT INTO back_in_stock_subscription (variant_id, user_identifier, email) VALUES ($1, $2, $3)
+		  ON CONFLICT (user_identifier, variant_id) DO UPDATE SET email = EXCLUDED.email, notified_at = NULL, created_at = now()
+		  RETURNING id, notified_at, created_at`
+
+	err := conn.QueryRowContext(ctx, q, record.VariantId, record.UserIdentifier, record.Email).Scan(&record.Id, &record.NotifiedAt, &record.CreatedAt)
+
+	if err != nil {
+		if err.Error() == `pq: insert or update on table "back_in_stock_subscription" violates foreign key constraint "back_in_stock_subscription_variant_id_fkey"` {
+			return nil, ErrVariantNotFound
+		}
+		return nil, err
+	}
+
+	return record, nil
+}
+
+func (m *BackInStockSubscriptionModel) FindAllByUserIdentifier(ctx context.Context, conn sqldb.Connection, userIdentifier string) ([]*BackInStockSubscriptionRecord, error) {
+	q := `SELECT id, variant_id, user_identifier, email, notified_at, created_at FROM back_in_stock_subscription WHERE user_identifier = $1 ORDER BY created_at DESC`
+
+	return m.findAll(ctx, conn, q, userIdentifier)
+}
+
+// FindAllPendingByVariantId returns the subscriptions of the variant that weren't notified yet
+func (m *BackInStockSubscriptionModel) FindAllPendingByVariantId(ctx context.Context, conn sqldb.Connection, variantId string) ([]*BackInStockSubscriptionRecord, error) {
+	q := `SELECT id, variant_id, user_identifier, email, notified_at, created_at FROM back_in_stock_subscription WHERE variant_id = $1 AND notified_at IS NULL`
+
+	return m.findAll(ctx, conn, q, variantId)
+}
+
+func (m *BackInStockSubscriptionModel) findAll(ctx context.Context, conn sqldb.Connection, q string, args ...any) ([]*BackInStockSubscriptionRecord, error) {
+	rows, err := conn.QueryContext(ctx, q, args...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	records := []*BackInStockSubscriptionRecord{}
+
+	for rows.Next() {
+		var record BackInStockSubscriptionRecord
+
+		err := rows.Scan(&record.Id, &record.VariantId, &record.UserIdentifier, &record.Email, &record.NotifiedAt, &record.CreatedAt)
+
+		if err != nil {
+			return nil, err
+		}
+
+		records = append(records, &record)
+	}
+
+	return records, nil
+}
+
+func (m *BackInStockSubscriptionModel) MarkNotified(ctx context.Context, conn sqldb.Connection, id string) error {
+	q := `UPDATE back_in_stock_subscription SET notified_at = $1 WHERE id = $2`
+
+	_, err := conn.ExecContext(ctx, q, time.Now(), id)
+
+	return err
+}
+
+func (m *BackInStockSubscriptionModel) DeleteByVariantId(ctx context.Context, conn sqldb.Connection, userIdentifier string, variantId string) error {
+	q := `DELETE FROM back_in_stock_subscription WHERE user_identifier = $1 AND variant_id = $2`
+
+	res, err := conn.ExecContext(ctx, q, userIdentifier, variantId)
+
+	if err != nil {
+		return err
+	}
+
+	if rowsAff, _ := res.RowsAffected(); rowsAff == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
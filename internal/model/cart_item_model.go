@@ -0,0 +1,132 @@
+package model
+
+import (
+	"context"
+	"database/sql"
+	"ecom-backend/pkg/sqldb"
+	"errors"
+	"time"
+)
+
+type CartItemRecord struct {
+	Id        string
+	CartId    string
+	VariantId string
+	Quantity  int
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+type CartItemModel struct{}
+
+func NewCartItemModel() *CartItemModel {
+	return &CartItemModel{}
+}
+
+// Insert adds a new item to the cart, if the variant is already in the cart the quantities are summed up
+func (m *CartItemModel) Insert(ctx context.Context, conn sqldb.Connection, record *CartItemRecord) (*CartItemRecord, error) {
+	q := `INSERT INTO cart_item (cart_id, variant_id, quantity) VALUES ($1, $2, $3)
+		  ON CONFLICT ON CONSTRAINT duplicate_variant_in_cart_not_allowed
+		  DO UPDATE SET quantity = cart_item.quantity + EXCLUDED.quantity, updated_at = now()
+		  RETURNING id, quantity, created_at, updated_at`
+
+	err := conn.QueryRowContext(ctx, q, record.CartId, record.VariantId, record.Quantity).Scan(&record.Id, &record.Quantity, &record.CreatedAt, &record.UpdatedAt)
+
+	if err != nil {
+		if err.Error() == `pq: insert or update on table "cart_item" violates foreign key constraint "cart_item_variant_id_fkey"` {
+			return nil, ErrVariantNotFound
+		}
+		return nil, err
+	}
+
+	return record, nil
+}
+
+func (m *CartItemModel) FindById(ctx context.Context, conn sqldb.Connection, id string) (*CartItemRecord, error) {
+	q := `SELECT id, cart_id, variant_id, quantity, created_at, updated_at FROM cart_item WHERE id = $1`
+
+	var record CartItemRecord
+
+	err := conn.QueryRowContext(ctx, q, id).Scan(&record.Id, &record.CartId, &record.VariantId, &record.Quantity, &record.CreatedAt, &record.UpdatedAt)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrRecordNotFound
+		}
+		return nil, err
+	}
+
+	return &record, nil
+}
+
+func (m *CartItemModel) FindAllByCartId(ctx context.Context, conn sqldb.Connection, cartId string) ([]*CartItemRecord, error) {
+	q := `SELECT id, cart_id, variant_id, quantity, created_at, updated_at FROM cart_item WHERE cart_id = $1 ORDER BY created_at`
+
+	rows, err := conn.QueryContext(ctx, q, cartId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	items := []*CartItemRecord{}
+
+	for rows.Next() {
+		var record CartItemRecord
+
+		err := rows.Scan(&record.Id, &record.CartId, &record.VariantId, &record.Quantity, &record.CreatedAt, &record.UpdatedAt)
+
+		if err != nil {
+			return nil, err
+		}
+
+		items = append(items, &record)
+	}
+
+	return items, nil
+}
+
+func (m *CartItemModel) UpdateQuantity(ctx context.Context, conn sqldb.Connection, id string, quantity int) error {
+	q := `UPDATE cart_item SET quantity = $1, updated_at = $2 WHERE id = $3`
+
+	res, err := conn.ExecContext(ctx, q, quantity, time.Now(), id)
+
+	if err != nil {
+		return err
+	}
+
+	if rowsAff, _ := res.RowsAffected(); rowsAff == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+func (m *CartItemModel) DeleteById(ctx context.Context, conn sqldb.Connection, id string) error {
+	q := `DELETE FROM cart_item WHERE id = $1`
+
+	res, err := conn.ExecContext(ctx, q, id)
+
+	if err != nil {
+		return err
+	}
+
+	if rowsAff, _ := res.RowsAffected(); rowsAff == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+func (m *CartItemModel) DeleteAllByCartId(ctx context.Context, conn sqldb.Connection, cartId string) error {
+	q := `DELETE FROM cart_item WHERE cart_id = $1`
+
+	_, err := conn.ExecContext(ctx, q, cartId)
+
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,91 @@
+package model
+
+import (
+	"context"
+	"database/sql"
+	"ecom-backend/pkg/sqldb"
+	"errors"
+	"time"
+)
+
+type CartRecord struct {
+	Id             string
+	UserIdentifier string  // user id for registered users and session id for guests
+	PromotionCode  *string // discount code applied to the cart
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+}
+
+type CartModel struct{}
+
+func NewCartModel() *CartModel {
+	return &CartModel{}
+}
+
+// Upsert creates the cart for the given client identifier, or returns the existing one if it was already created
+func (m *CartModel) Upsert(ctx context.Context, conn sqldb.Connection, userIdentifier string) (*CartRecord, error) {
+	q := `INSERT INTO cart (user_identifier) VALUES ($1)
+		  ON CONFLICT (user_identifier) DO UPDATE SET updated_at = now()
+		  RETURNING id, user_identifier, promotion_code, created_at, updated_at`
+
+	var record CartRecord
+
+	err := conn.QueryRowContext(ctx, q, userIdentifier).Scan(&record.Id, &record.UserIdentifier, &record.PromotionCode, &record.CreatedAt, &record.UpdatedAt)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &record, nil
+}
+
+func (m *CartModel) FindByUserIdentifier(ctx context.Context, conn sqldb.Connection, userIdentifier string) (*CartRecord, error) {
+	q := `SELECT id, user_identifier, promotion_code, created_at, updated_at FROM cart WHERE user_identifier = $1`
+
+	var record CartRecord
+
+	err := conn.QueryRowContext(ctx, q, userIdentifier).Scan(&record.Id, &record.UserIdentifier, &record.PromotionCode, &record.CreatedAt, &record.UpdatedAt)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrRecordNotFound
+		}
+		return nil, err
+	}
+
+	return &record, nil
+}
+
+// Touch bumps the updated_at timestamp of the cart, it should be called every time the cart items change
+func (m *CartModel) Touch(ctx context.Context, conn sqldb.Connection, id string) error {
+	q := `UPDATE cart SET updated_at = $1 WHERE id = $2`
+
+	res, err := conn.ExecContext(ctx, q, time.Now(), id)
+
+	if err != nil {
+		return err
+	}
+
+	if rowsAff, _ := res.RowsAffected(); rowsAff == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// SetPromotionCode applies a discount code to the cart, nil removes it
+func (m *CartModel) SetPromotionCode(ctx context.Context, conn sqldb.Connection, id string, code *string) error {
+	q := `UPDATE cart SET promotion_code = $1, updated_at = $2 WHERE id = $3`
+
+	res, err := conn.ExecContext(ctx, q, code, time.Now(), id)
+
+	if err != nil {
+		return err
+	}
+
+	if rowsAff, _ := res.RowsAffected(); rowsAff == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
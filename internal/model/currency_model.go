@@ -0,0 +1,48 @@
+package model
+
+import (
+	"context"
+	"ecom-backend/pkg/sqldb"
+)
+
+type CurrencyRecord struct {
+	Code         string `json:"code"`
+	Symbol       string `json:"symbol"`
+	SymbolNative string `json:"symbol_native"`
+	Name         string `json:"name"`
+	Exponent     int    `json:"exponent"` // number of decimals of the currency, amounts are stored in its minor units
+}
+
+type CurrencyModel struct{}
+
+func NewCurrencyModel() *CurrencyModel {
+	return &CurrencyModel{}
+}
+
+func (m *CurrencyModel) FindAll(ctx context.Context, conn sqldb.Connection) ([]*CurrencyRecord, error) {
+	q := `SELECT code, symbol, symbol_native, name, exponent FROM currency ORDER BY code`
+
+	rows, err := conn.QueryContext(ctx, q)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	records := []*CurrencyRecord{}
+
+	for rows.Next() {
+		var record CurrencyRecord
+
+		err := rows.Scan(&record.Code, &record.Symbol, &record.SymbolNative, &record.Name, &record.Exponent)
+
+		if err != nil {
+			return nil, err
+		}
+
+		records = append(records, &record)
+	}
+
+	return records, nil
+}
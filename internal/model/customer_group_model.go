@@ -0,0 +1,216 @@
+package model
+
+import (
+	"context"
+	"database/sql"
+	"ecom-backend/pkg/sqldb"
+	"errors"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type CustomerGroupRecord struct {
+	Id          string    `json:"id"`
+	Name        string    `json:"name"`
+	Description *string   `json:"description"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+type CustomerGroupModel struct{}
+
+func NewCustomerGroupModel() *CustomerGroupModel {
+	return &CustomerGroupModel{}
+}
+
+const customerGroupColumns = `id, name, description, created_at, updated_at`
+
+func scanCustomerGroup(row interface{ Scan(...any) error }, group *CustomerGroupRecord) error {
+	return row.Scan(&group.Id, &group.Name, &group.Description, &group.CreatedAt, &group.UpdatedAt)
+}
+
+func (m *CustomerGroupModel) Insert(ctx context.Context, conn sqldb.Connection, group *CustomerGroupRecord) (*CustomerGroupRecord, error) {
+	q := `INSERT INTO customer_group (name, description) VALUES ($1, $2) RETURNING id, created_at, updated_at`
+
+	err := conn.QueryRowContext(ctx, q, group.Name, group.Description).Scan(&group.Id, &group.CreatedAt, &group.UpdatedAt)
+
+	if err != nil {
+		if err.Error() == `pq: duplicate key value violates unique constraint "customer_group_name_key"` {
+			return nil, ErrDuplicatedCustomerGroup
+		}
+		return nil, err
+	}
+
+	return group, nil
+}
+
+func (m *CustomerGroupModel) FindById(ctx context.Context, conn sqldb.Connection, id string) (*CustomerGroupRecord, error) {
+	q := `SELECT ` + customerGroupColumns + ` FROM customer_group WHERE id = $1`
+
+	var group CustomerGroupRecord
+
+	err := scanCustomerGroup(conn.QueryRowContext(ctx, q, id), &group)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrRecordNotFound
+		}
+		return nil, err
+	}
+
+	return &group, nil
+}
+
+func (m *CustomerGroupModel) FindAll(ctx context.Context, conn sqldb.Connection) ([]*CustomerGroupRecord, error) {
+	q := `SELECT ` + customerGroupColumns + ` FROM customer_group ORDER BY name`
+
+	rows, err := conn.QueryContext(ctx, q)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	groups := []*CustomerGroupRecord{}
+
+	for rows.Next() {
+		var group CustomerGroupRecord
+
+		err := scanCustomerGroup(rows, &group)
+
+		if err != nil {
+			return nil, err
+		}
+
+		groups = append(groups, &group)
+	}
+
+	return groups, nil
+}
+
+func (m *CustomerGroupModel) Update(ctx context.Context, conn sqldb.Connection, group *CustomerGroupRecord) (*CustomerGroupRecord, error) {
+	q := `UPDATE customer_group SET name = $1, description = $2, updated_at = $3 WHERE id = $4`
+
+	group.UpdatedAt = time.Now()
+
+	res, err := conn.ExecContext(ctx, q, group.Name, group.Description, group.UpdatedAt, group.Id)
+
+	if err != nil {
+		if err.Error() == `pq: duplicate key value violates unique constraint "customer_group_name_key"` {
+			return nil, ErrDuplicatedCustomerGroup
+		}
+		return nil, err
+	}
+
+	if rowsAff, _ := res.RowsAffected(); rowsAff == 0 {
+		return nil, ErrRecordNotFound
+	}
+
+	return group, nil
+}
+
+// Delete removes the group, its members stay but lose the prices and promotions of the group
+func (m *CustomerGroupModel) Delete(ctx context.Context, conn sqldb.Connection, id string) error {
+	q := `DELETE FROM customer_group WHERE id = $1`
+
+	res, err := conn.ExecContext(ctx, q, id)
+
+	if err != nil {
+		return err
+	}
+
+	if rowsAff, _ := res.RowsAffected(); rowsAff == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// FindMembers returns the users of the group
+func (m *CustomerGroupModel) FindMembers(ctx context.Context, conn sqldb.Connection, groupId string) ([]*UserRecord, error) {
+	q := `SELECT u.id, u.name, u.email, u.created_at, u.updated_at FROM users AS u
+		  INNER JOIN customer_group_member AS cgm ON cgm.user_id = u.id
+		  WHERE cgm.customer_group_id = $1
+		  ORDER BY u.email`
+
+	rows, err := conn.QueryContext(ctx, q, groupId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	users := []*UserRecord{}
+
+	for rows.Next() {
+		var user UserRecord
+
+		err := rows.Scan(&user.Id, &user.Name, &user.Email, &user.CreatedAt, &user.UpdatedAt)
+
+		if err != nil {
+			return nil, err
+		}
+
+		users = append(users, &user)
+	}
+
+	return users, nil
+}
+
+// FindIdsByUserId returns the ids of the groups the user belongs to
+func (m *CustomerGroupModel) FindIdsByUserId(ctx context.Context, conn sqldb.Connection, userId string) ([]string, error) {
+	q := `SELECT customer_group_id FROM customer_group_member WHERE user_id = $1 ORDER BY customer_group_id`
+
+	groupIds := []string{}
+
+	rows, err := conn.QueryContext(ctx, q, userId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var groupId string
+
+		err := rows.Scan(&groupId)
+
+		if err != nil {
+			return nil, err
+		}
+
+		groupIds = append(groupIds, groupId)
+	}
+
+	return groupIds, nil
+}
+
+// ReplaceUserGroups swaps the groups the user belongs to
+func (m *CustomerGroupModel) ReplaceUserGroups(ctx context.Context, conn sqldb.Connection, userId string, groupIds []string) error {
+	_, err := conn.ExecContext(ctx, `DELETE FROM customer_group_member WHERE user_id = $1`, userId)
+
+	if err != nil {
+		return err
+	}
+
+	q := `INSERT INTO customer_group_member (customer_group_id, user_id) SELECT unnest($1::uuid[]), $2`
+
+	_, err = conn.ExecContext(ctx, q, pq.Array(groupIds), userId)
+
+	if err != nil {
+		switch {
+		case err.Error() == `pq: insert or update on table "customer_group_member" violates foreign key constraint "customer_group_member_customer_group_id_fkey"`:
+			return ErrCustomerGroupNotFound
+		case err.Error() == `pq: insert or update on table "customer_group_member" violates foreign key constraint "customer_group_member_user_id_fkey"`:
+			return ErrRecordNotFound
+		default:
+			return err
+		}
+	}
+
+	return nil
+}
@@ -13,4 +13,17 @@ var (
 	ErrDuplicatedEmail                     = errors.New("duplicated email")
 	ErrInvalidValue                        = errors.New("invalid value")
 	ErrProductAlreadyWishlisted            = errors.New("product already wishlisted")
+	ErrVariantNotFound                     = errors.New("product variant not found")
+	ErrInsufficientInventory               = errors.New("insufficient inventory")
+	ErrStockLocationNotFound               = errors.New("stock location not found")
+	ErrDuplicatedPromotionCode             = errors.New("duplicated promotion code")
+	ErrDuplicatedTaxRegion                 = errors.New("duplicated tax region")
+	ErrShippingZoneNotFound                = errors.New("shipping zone not found")
+	ErrCountryInOtherShippingZone          = errors.New("country already belongs to another shipping zone")
+	ErrCurrencyNotFound                    = errors.New("currency not found")
+	ErrCountryInOtherRegion                = errors.New("country already belongs to another region")
+	ErrDuplicatedCustomerGroup             = errors.New("duplicated customer group")
+	ErrCustomerGroupNotFound               = errors.New("customer group not found")
+	ErrDuplicatedGiftCardCode              = errors.New("duplicated gift card code")
+	ErrOpenPaymentSessionExists            = errors.New("order already has an open payment session")
 )
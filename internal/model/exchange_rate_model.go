@@ -0,0 +1,139 @@
+package model
+
+import (
+	"context"
+	"ecom-backend/internal/money"
+	"ecom-backend/pkg/sqldb"
+	"time"
+)
+
+type ExchangeRateRecord struct {
+	BaseCurrencyCode  string  `json:"base_currency_code"`
+	QuoteCurrencyCode string  `json:"quote_currency_code"`
+	Rate              float64 `json:"rate"` // units of the quote currency for one unit of the base currency
+	// the converted prices are rounded up to the next multiple of the increment plus the ending, e.g. 1 and 0.99
+	RoundingIncrement money.Money `json:"rounding_increment"`
+	RoundingEnding    money.Money `json:"rounding_ending"`
+	CreatedAt         time.Time   `json:"created_at"`
+	UpdatedAt         time.Time   `json:"updated_at"`
+}
+
+// Convert turns an amount in the base currency into a rounded amount in the quote currency
+func (rate *ExchangeRateRecord) Convert(amount money.Money) money.Money {
+	return amount.Convert(rate.Rate, rate.QuoteCurrencyCode).RoundUp(rate.RoundingIncrement.Amount, rate.RoundingEnding.Amount)
+}
+
+type ExchangeRateModel struct{}
+
+func NewExchangeRateModel() *ExchangeRateModel {
+	return &ExchangeRateModel{}
+}
+
+const exchangeRateColumns = `base_currency_code, quote_currency_code, rate, rounding_increment, rounding_ending, created_at, updated_at`
+
+func scanExchangeRate(row interface{ Scan(...any) error }, rate *ExchangeRateRecord) error {
+	err := row.Scan(&rate.BaseCurrencyCode, &rate.QuoteCurrencyCode, &rate.Rate, &rate.RoundingIncrement, &rate.RoundingEnding, &rate.CreatedAt, &rate.UpdatedAt)
+
+	if err != nil {
+		return err
+	}
+
+	money.SetCurrency(rate.QuoteCurrencyCode, &rate.RoundingIncrement, &rate.RoundingEnding)
+
+	return nil
+}
+
+// Upsert sets the rate between the two currencies, replacing the previous one
+func (m *ExchangeRateModel) Upsert(ctx context.Context, conn sqldb.Connection, rate *ExchangeRateRecord) (*ExchangeRateRecord, error) {
+	q := `INSERT INTO exchange_rate (base_currency_code, quote_currency_code, rate, rounding_increment, rounding_ending) VALUES ($1, $2, $3, $4, $5)
+		  ON CONFLICT (base_currency_code, quote_currency_code) DO UPDATE
+		  SET rate = EXCLUDED.rate, rounding_increment = EXCLUDED.rounding_increment, rounding_ending = EXCLUDED.rounding_ending, updated_at = now()
+		  RETURNING created_at, updated_at`
+
+	err := conn.QueryRowContext(ctx, q, rate.BaseCurrencyCode, rate.QuoteCurrencyCode, rate.Rate, rate.RoundingIncrement, rate.RoundingEnding).
+		Scan(&rate.CreatedAt, &rate.UpdatedAt)
+
+	if err != nil {
+		switch {
+		case err.Error() == `pq: insert or update on table "exchange_rate" violates foreign key constraint "exchange_rate_base_currency_code_fkey"`,
+			err.Error() == `pq: insert or update on table "exchange_rate" violates foreign key constraint "exchange_rate_quote_currency_code_fkey"`:
+			return nil, ErrCurrencyNotFound
+		case err.Error() == `pq: new row for relation "exchange_rate" violates check constraint "exchange_rate_currencies_check"`,
+			err.Error() == `pq: new row for relation "exchange_rate" violates check constraint "exchange_rate_rounding_check"`,
+			err.Error() == `pq: new row for relation "exchange_rate" violates check constraint "exchange_rate_rate_check"`,
+			err.Error() == `pq: new row for relation "exchange_rate" violates check constraint "exchange_rate_rounding_increment_check"`:
+			return nil, ErrInvalidValue
+		default:
+			return nil, err
+		}
+	}
+
+	return rate, nil
+}
+
+func (m *ExchangeRateModel) FindAll(ctx context.Context, conn sqldb.Connection) ([]*ExchangeRateRecord, error) {
+	q := `SELECT ` + exchangeRateColumns + ` FROM exchange_rate ORDER BY base_currency_code, quote_currency_code`
+
+	return m.findMany(ctx, conn, q)
+}
+
+// FindAllByBaseCurrency returns the rates from the currency by quote currency
+func (m *ExchangeRateModel) FindAllByBaseCurrency(ctx context.Context, conn sqldb.Connection, baseCurrencyCode string) (map[string]*ExchangeRateRecord, error) {
+	q := `SELECT ` + exchangeRateColumns + ` FROM exchange_rate WHERE base_currency_code = $1`
+
+	rates, err := m.findMany(ctx, conn, q, baseCurrencyCode)
+
+	if err != nil {
+		return nil, err
+	}
+
+	resultMap := make(map[string]*ExchangeRateRecord)
+
+	for _, rate := range rates {
+		resultMap[rate.QuoteCurrencyCode] = rate
+	}
+
+	return resultMap, nil
+}
+
+func (m *ExchangeRateModel) findMany(ctx context.Context, conn sqldb.Connection, q string, args ...any) ([]*ExchangeRateRecord, error) {
+	rows, err := conn.QueryContext(ctx, q, args...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	rates := []*ExchangeRateRecord{}
+
+	for rows.Next() {
+		var rate ExchangeRateRecord
+
+		err := scanExchangeRate(rows, &rate)
+
+		if err != nil {
+			return nil, err
+		}
+
+		rates = append(rates, &rate)
+	}
+
+	return rates, nil
+}
+
+func (m *ExchangeRateModel) Delete(ctx context.Context, conn sqldb.Connection, baseCurrencyCode string, quoteCurrencyCode string) error {
+	q := `DELETE FROM exchange_rate WHERE base_currency_code = $1 AND quote_currency_code = $2`
+
+	res, err := conn.ExecContext(ctx, q, baseCurrencyCode, quoteCurrencyCode)
+
+	if err != nil {
+		return err
+	}
+
+	if rowsAff, _ := res.RowsAffected(); rowsAff == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,300 @@
+package model
+
+import (
+	"context"
+	"database/sql"
+	"ecom-backend/internal/money"
+	"ecom-backend/pkg/sqldb"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type GiftCardRecord struct {
+	Id             string      `json:"id"`
+	Code           string      `json:"code"`
+	CurrencyCode   string      `json:"currency_code"`
+	InitialBalance money.Money `json:"initial_balance"`
+	Balance        money.Money `json:"balance"`
+	Email          *string     `json:"email"`    // the recipient of the card
+	OrderId        *string     `json:"order_id"` // the order the card was bought with, nil for the ones issued by an admin
+	Note           *string     `json:"note"`
+	IsActive       bool        `json:"is_active"`
+	ExpiresAt      *time.Time  `json:"expires_at"`
+	CreatedAt      time.Time   `json:"created_at"`
+	UpdatedAt      time.Time   `json:"updated_at"`
+}
+
+// IsUsable tells if the card can pay for an order at the given time
+func (card *GiftCardRecord) IsUsable(at time.Time) bool {
+	return card.IsActive && (card.ExpiresAt == nil || card.ExpiresAt.After(at))
+}
+
+type GiftCardModel struct{}
+
+func NewGiftCardModel() *GiftCardModel {
+	return &GiftCardModel{}
+}
+
+const giftCardColumns = `id, code, currency_code, initial_balance, balance, email, order_id, note, is_active, expires_at, created_at, updated_at`
+
+func scanGiftCard(row interface{ Scan(...any) error }, card *GiftCardRecord) error {
+	err := row.Scan(&card.Id, &card.Code, &card.CurrencyCode, &card.InitialBalance, &card.Balance, &card.Email, &card.OrderId, &card.Note, &card.IsActive, &card.ExpiresAt, &card.CreatedAt, &card.UpdatedAt)
+
+	if err != nil {
+		return err
+	}
+
+	money.SetCurrency(card.CurrencyCode, &card.InitialBalance, &card.Balance)
+
+	return nil
+}
+
+func (m *GiftCardModel) Insert(ctx context.Context, conn sqldb.Connection, card *GiftCardRecord) (*GiftCardRecord, error) {
+	q := `INSERT INTO gift_card (code, currency_code, initial_balance, balance, email, order_id, note, is_active, expires_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		  RETURNING id, created_at, updated_at`
+
+	err := conn.QueryRowContext(ctx, q, card.Code, card.CurrencyCode, card.InitialBalance, card.Balance, card.Email, card.OrderId, card.Note, card.IsActive, card.ExpiresAt).
+		Scan(&card.Id, &card.CreatedAt, &card.UpdatedAt)
+
+	if err != nil {
+		switch {
+		case err.Error() == `pq: duplicate key value violates unique constraint "gift_card_code_key"`:
+			return nil, ErrDuplicatedGiftCardCode
+		case err.Error() == `pq: insert or update on table "gift_card" violates foreign key constraint "gift_card_currency_code_fkey"`:
+			return nil, ErrCurrencyNotFound
+		case err.Error() == `pq: new row for relation "gift_card" violates check constraint "gift_card_initial_balance_check"`,
+			err.Error() == `pq: new row for relation "gift_card" violates check constraint "gift_card_balance_check"`:
+			return nil, ErrInvalidValue
+		default:
+			return nil, err
+		}
+	}
+
+	return card, nil
+}
+
+func (m *GiftCardModel) FindById(ctx context.Context, conn sqldb.Connection, id string) (*GiftCardRecord, error) {
+	q := `SELECT ` + giftCardColumns + ` FROM gift_card WHERE id = $1`
+
+	return m.findOne(ctx, conn, q, id)
+}
+
+// FindByIdForUpdate locks the card until the end of the transaction
+func (m *GiftCardModel) FindByIdForUpdate(ctx context.Context, conn sqldb.Connection, id string) (*GiftCardRecord, error) {
+	q := `SELECT ` + giftCardColumns + ` FROM gift_card WHERE id = $1 FOR UPDATE`
+
+	return m.findOne(ctx, conn, q, id)
+}
+
+// FindByCode returns the card with the code, the codes are case insensitive
+func (m *GiftCardModel) FindByCode(ctx context.Context, conn sqldb.Connection, code string) (*GiftCardRecord, error) {
+	q := `SELECT ` + giftCardColumns + ` FROM gift_card WHERE code = $1`
+
+	return m.findOne(ctx, conn, q, code)
+}
+
+func (m *GiftCardModel) findOne(ctx context.Context, conn sqldb.Connection, q string, args ...any) (*GiftCardRecord, error) {
+	var card GiftCardRecord
+
+	err := scanGiftCard(conn.QueryRowContext(ctx, q, args...), &card)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrRecordNotFound
+		}
+		return nil, err
+	}
+
+	return &card, nil
+}
+
+// FindAllByCodesForUpdate locks the cards with the codes until the end of the transaction and returns them by
+// lower cased code
+func (m *GiftCardModel) FindAllByCodesForUpdate(ctx context.Context, conn sqldb.Connection, codes []string) (map[string]*GiftCardRecord, error) {
+	q := `SELECT ` + giftCardColumns + ` FROM gift_card WHERE code = ANY($1::citext[]) ORDER BY id FOR UPDATE`
+
+	cards, err := m.findMany(ctx, conn, q, pq.Array(codes))
+
+	if err != nil {
+		return nil, err
+	}
+
+	resultMap := make(map[string]*GiftCardRecord)
+
+	for _, card := range cards {
+		resultMap[strings.ToLower(card.Code)] = card
+	}
+
+	return resultMap, nil
+}
+
+// FindAllByOrderId returns the cards bought with the order
+func (m *GiftCardModel) FindAllByOrderId(ctx context.Context, conn sqldb.Connection, orderId string) ([]*GiftCardRecord, error) {
+	q := `SELECT ` + giftCardColumns + ` FROM gift_card WHERE order_id = $1 ORDER BY created_at`
+
+	return m.findMany(ctx, conn, q, orderId)
+}
+
+func (m *GiftCardModel) Count(ctx context.Context, conn sqldb.Connection) (int, error) {
+	var count int
+
+	err := conn.QueryRowContext(ctx, `SELECT COUNT(*) FROM gift_card`).Scan(&count)
+
+	if err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+func (m *GiftCardModel) FindAll(ctx context.Context, conn sqldb.Connection, limit uint, offset uint) ([]*GiftCardRecord, error) {
+	q := `SELECT ` + giftCardColumns + ` FROM gift_card ORDER BY created_at DESC LIMIT $1 OFFSET $2`
+
+	return m.findMany(ctx, conn, q, limit, offset)
+}
+
+func (m *GiftCardModel) findMany(ctx context.Context, conn sqldb.Connection, q string, args ...any) ([]*GiftCardRecord, error) {
+	rows, err := conn.QueryContext(ctx, q, args...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	cards := []*GiftCardRecord{}
+
+	for rows.Next() {
+		var card GiftCardRecord
+
+		err := scanGiftCard(rows, &card)
+
+		if err != nil {
+			return nil, err
+		}
+
+		cards = append(cards, &card)
+	}
+
+	return cards, nil
+}
+
+func (m *GiftCardModel) Update(ctx context.Context, conn sqldb.Connection, card *GiftCardRecord) (*GiftCardRecord, error) {
+	q := `UPDATE gift_card SET balance = $1, email = $2, note = $3, is_active = $4, expires_at = $5, updated_at = $6 WHERE id = $7`
+
+	card.UpdatedAt = time.Now()
+
+	res, err := conn.ExecContext(ctx, q, card.Balance, card.Email, card.Note, card.IsActive, card.ExpiresAt, card.UpdatedAt, card.Id)
+
+	if err != nil {
+		if err.Error() == `pq: new row for relation "gift_card" violates check constraint "gift_card_balance_check"` {
+			return nil, ErrInvalidValue
+		}
+		return nil, err
+	}
+
+	if rowsAff, _ := res.RowsAffected(); rowsAff == 0 {
+		return nil, ErrRecordNotFound
+	}
+
+	return card, nil
+}
+
+// GiftCardTransactionRecord is a change of the balance of a gift card, the redeemed amounts are negative
+type GiftCardTransactionRecord struct {
+	Id         string      `json:"id"`
+	GiftCardId string      `json:"gift_card_id"`
+	Type       string      `json:"type"`
+	Amount     money.Money `json:"amount"`
+	OrderId    *string     `json:"order_id"`
+	CreatedAt  time.Time   `json:"created_at"`
+}
+
+type GiftCardTransactionModel struct{}
+
+func NewGiftCardTransactionModel() *GiftCardTransactionModel {
+	return &GiftCardTransactionModel{}
+}
+
+func (m *GiftCardTransactionModel) Insert(ctx context.Context, conn sqldb.Connection, transaction *GiftCardTransactionRecord) (*GiftCardTransactionRecord, error) {
+	q := `INSERT INTO gift_card_transaction (gift_card_id, type, amount, order_id) VALUES ($1, $2, $3, $4) RETURNING id, created_at`
+
+	err := conn.QueryRowContext(ctx, q, transaction.GiftCardId, transaction.Type, transaction.Amount, transaction.OrderId).Scan(&transaction.Id, &transaction.CreatedAt)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return transaction, nil
+}
+
+// FindAllByGiftCardId returns the history of the card, the latest first
+func (m *GiftCardTransactionModel) FindAllByGiftCardId(ctx context.Context, conn sqldb.Connection, giftCardId string) ([]*GiftCardTransactionRecord, error) {
+	q := `SELECT gct.id, gct.gift_card_id, gct.type, gct.amount, gct.order_id, gct.created_at, gc.currency_code
+		  FROM gift_card_transaction AS gct
+		  INNER JOIN gift_card AS gc ON gc.id = gct.gift_card_id
+		  WHERE gct.gift_card_id = $1 ORDER BY gct.created_at DESC`
+
+	rows, err := conn.QueryContext(ctx, q, giftCardId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	transactions := []*GiftCardTransactionRecord{}
+
+	for rows.Next() {
+		var transaction GiftCardTransactionRecord
+		var currencyCode string
+
+		err := rows.Scan(&transaction.Id, &transaction.GiftCardId, &transaction.Type, &transaction.Amount, &transaction.OrderId, &transaction.CreatedAt, &currencyCode)
+
+		if err != nil {
+			return nil, err
+		}
+
+		money.SetCurrency(currencyCode, &transaction.Amount)
+
+		transactions = append(transactions, &transaction)
+	}
+
+	return transactions, nil
+}
+
+// SumRedeemedByOrderId returns, by gift card id, the amount still taken from the cards by the order, i.e. the redeemed
+// amounts minus the restored ones. The amounts are positive.
+func (m *GiftCardTransactionModel) SumRedeemedByOrderId(ctx context.Context, conn sqldb.Connection, orderId string) (map[string]int64, error) {
+	q := `SELECT gift_card_id, -SUM(amount) FROM gift_card_transaction
+		  WHERE order_id = $1 AND type IN ('redeem', 'restore')
+		  GROUP BY gift_card_id HAVING SUM(amount) < 0`
+
+	rows, err := conn.QueryContext(ctx, q, orderId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	resultMap := make(map[string]int64)
+
+	for rows.Next() {
+		var giftCardId string
+		var amount int64
+
+		err := rows.Scan(&giftCardId, &amount)
+
+		if err != nil {
+			return nil, err
+		}
+
+		resultMap[giftCardId] = amount
+	}
+
+	return resultMap, nil
+}
@@ -0,0 +1,98 @@
+package model
+
+import (
+	"context"
+	"ecom-backend/pkg/sqldb"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type InventoryLevelRecord struct {
+	VariantId  string
+	LocationId string
+	Quantity   int
+	UpdatedAt  time.Time
+
+	// details of the location, filled only when reading the levels
+	LocationName     string
+	LocationPriority int
+	LocationIsActive bool
+}
+
+type InventoryLevelModel struct{}
+
+func NewInventoryLevelModel() *InventoryLevelModel {
+	return &InventoryLevelModel{}
+}
+
+// Sync recomputes the quantity of the variant at the location from its stock movements and returns it.
+// It fails with ErrInsufficientInventory when the location would go below zero.
+func (m *InventoryLevelModel) Sync(ctx context.Context, conn sqldb.Connection, variantId string, locationId string) (int, error) {
+	q := `INSERT INTO inventory_level (variant_id, location_id, quantity, updated_at)
+		  VALUES ($1, $2, (SELECT COALESCE(SUM(quantity), 0) FROM stock_movement WHERE variant_id = $1 AND location_id = $2), $3)
+		  ON CONFLICT (variant_id, location_id) DO UPDATE SET quantity = EXCLUDED.quantity, updated_at = EXCLUDED.updated_at
+		  RETURNING quantity`
+
+	var quantity int
+
+	err := conn.QueryRowContext(ctx, q, variantId, locationId, time.Now()).Scan(&quantity)
+
+	if err != nil {
+		return 0, err
+	}
+
+	if quantity < 0 {
+		return 0, ErrInsufficientInventory
+	}
+
+	return quantity, nil
+}
+
+// SumByLocationId returns the total stock held by the location, across all variants
+func (m *InventoryLevelModel) SumByLocationId(ctx context.Context, conn sqldb.Connection, locationId string) (int, error) {
+	q := `SELECT COALESCE(SUM(quantity), 0) FROM inventory_level WHERE location_id = $1`
+
+	var quantity int
+
+	err := conn.QueryRowContext(ctx, q, locationId).Scan(&quantity)
+
+	if err != nil {
+		return 0, err
+	}
+
+	return quantity, nil
+}
+
+// FindAllByVariantIds returns the levels of the variants at the locations that weren't deleted, grouped by variant id
+func (m *InventoryLevelModel) FindAllByVariantIds(ctx context.Context, conn sqldb.Connection, variantIds []string) (map[string][]*InventoryLevelRecord, error) {
+	q := `SELECT il.variant_id, il.location_id, il.quantity, il.updated_at, sl.name, sl.priority, sl.is_active
+		  FROM inventory_level il
+		  JOIN stock_location sl ON sl.id = il.location_id AND sl.deleted_at IS NULL
+		  WHERE il.variant_id = ANY($1)
+		  ORDER BY sl.priority, sl.created_at`
+
+	rows, err := conn.QueryContext(ctx, q, pq.Array(variantIds))
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	resultMap := make(map[string][]*InventoryLevelRecord)
+
+	for rows.Next() {
+		var level InventoryLevelRecord
+
+		err := rows.Scan(&level.VariantId, &level.LocationId, &level.Quantity, &level.UpdatedAt, &level.LocationName, &level.LocationPriority, &level.LocationIsActive)
+
+		if err != nil {
+			return nil, err
+		}
+
+		resultMap[level.VariantId] = append(resultMap[level.VariantId], &level)
+	}
+
+	return resultMap, nil
+}
@@ -0,0 +1,122 @@
+package model
+
+import (
+	"context"
+	"ecom-backend/pkg/sqldb"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type InventoryReservationRecord struct {
+	Id        string    `json:"id"`
+	VariantId string    `json:"variant_id"`
+	CartId    string    `json:"cart_id"`
+	Quantity  int       `json:"quantity"`
+	Status    string    `json:"status"`
+	ExpiresAt time.Time `json:"expires_at"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type InventoryReservationModel struct{}
+
+func NewInventoryReservationModel() *InventoryReservationModel {
+	return &InventoryReservationModel{}
+}
+
+func (m *InventoryReservationModel) Insert(ctx context.Context, conn sqldb.Connection, record *InventoryReservationRecord) (*InventoryReservationRecord, error) {
+	q := `INSERT INTO inventory_reservation (variant_id, cart_id, quantity, expires_at) VALUES ($1, $2, $3, $4) RETURNING id, status, created_at, updated_at`
+
+	err := conn.QueryRowContext(ctx, q, record.VariantId, record.CartId, record.Quantity, record.ExpiresAt).Scan(&record.Id, &record.Status, &record.CreatedAt, &record.UpdatedAt)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return record, nil
+}
+
+// SumActiveByVariantIds returns the quantity currently held by reservations for each variant.
+// The reservations of excludedCartId are left out so a cart doesn't compete with its own reservations.
+func (m *InventoryReservationModel) SumActiveByVariantIds(ctx context.Context, conn sqldb.Connection, variantIds []string, excludedCartId *string) (map[string]int, error) {
+	q := `SELECT variant_id, SUM(quantity) FROM inventory_reservation
+		  WHERE variant_id = ANY($1) AND status = 'active' AND expires_at > $2 AND ($3::uuid IS NULL OR cart_id != $3::uuid)
+		  GROUP BY variant_id`
+
+	rows, err := conn.QueryContext(ctx, q, pq.Array(variantIds), time.Now(), excludedCartId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	resultMap := make(map[string]int)
+
+	for rows.Next() {
+		var variantId string
+		var quantity int
+
+		err := rows.Scan(&variantId, &quantity)
+
+		if err != nil {
+			return nil, err
+		}
+
+		resultMap[variantId] = quantity
+	}
+
+	return resultMap, nil
+}
+
+// UpdateActiveStatusByCartId closes all the active reservations of the cart with the given status
+func (m *InventoryReservationModel) UpdateActiveStatusByCartId(ctx context.Context, conn sqldb.Connection, cartId string, status string) error {
+	q := `UPDATE inventory_reservation SET status = $1, updated_at = $2 WHERE cart_id = $3 AND status = 'active'`
+
+	_, err := conn.ExecContext(ctx, q, status, time.Now(), cartId)
+
+	return err
+}
+
+// UpdateActiveQuantity sets the quantity held by the active reservation of the cart for the variant and tells whether
+// the cart had one that didn't expire
+func (m *InventoryReservationModel) UpdateActiveQuantity(ctx context.Context, conn sqldb.Connection, cartId string, variantId string, quantity int) (bool, error) {
+	q := `UPDATE inventory_reservation SET quantity = $1, updated_at = $2 WHERE cart_id = $3 AND variant_id = $4 AND status = 'active' AND expires_at > $2`
+
+	res, err := conn.ExecContext(ctx, q, quantity, time.Now(), cartId, variantId)
+
+	if err != nil {
+		return false, err
+	}
+
+	rowsAff, err := res.RowsAffected()
+
+	if err != nil {
+		return false, err
+	}
+
+	return rowsAff > 0, nil
+}
+
+// UpdateActiveStatusByCartIdAndVariantId closes the active reservations of the cart for the variant with the given status
+func (m *InventoryReservationModel) UpdateActiveStatusByCartIdAndVariantId(ctx context.Context, conn sqldb.Connection, cartId string, variantId string, status string) error {
+	q := `UPDATE inventory_reservation SET status = $1, updated_at = $2 WHERE cart_id = $3 AND variant_id = $4 AND status = 'active'`
+
+	_, err := conn.ExecContext(ctx, q, status, time.Now(), cartId, variantId)
+
+	return err
+}
+
+// ExpireStale marks as expired the active reservations past their expiry time and returns how many were expired
+func (m *InventoryReservationModel) ExpireStale(ctx context.Context, conn sqldb.Connection, now time.Time) (int64, error) {
+	q := `UPDATE inventory_reservation SET status = 'expired', updated_at = $1 WHERE status = 'active' AND expires_at <= $1`
+
+	res, err := conn.ExecContext(ctx, q, now)
+
+	if err != nil {
+		return 0, err
+	}
+
+	return res.RowsAffected()
+}
@@ -16,6 +16,46 @@ type Models struct {
 	UserModel                      *UserModel
 	TokenModel                     *TokenModel
 	WishlistModel                  *WishlistModel
+	CartModel                      *CartModel
+	CartItemModel                  *CartItemModel
+	AddressModel                   *AddressModel
+	OrderModel                     *OrderModel
+	OrderLineItemModel             *OrderLineItemModel
+	OrderEventModel                *OrderEventModel
+	PaymentSessionModel            *PaymentSessionModel
+	PaymentModel                   *PaymentModel
+	PaymentRefundModel             *PaymentRefundModel
+	InventoryReservationModel      *InventoryReservationModel
+	StockMovementModel             *StockMovementModel
+	StockLocationModel             *StockLocationModel
+	InventoryLevelModel            *InventoryLevelModel
+	BackInStockSubscriptionModel   *BackInStockSubscriptionModel
+	NotificationModel              *NotificationModel
+	PromotionModel                 *PromotionModel
+	PromotionCurrencyRuleModel     *PromotionCurrencyRuleModel
+	PromotionTargetModel           *PromotionTargetModel
+	PromotionTierModel             *PromotionTierModel
+	PromotionCustomerGroupModel    *PromotionCustomerGroupModel
+	PromotionRedemptionModel       *PromotionRedemptionModel
+	TaxRegionModel                 *TaxRegionModel
+	TaxRateOverrideModel           *TaxRateOverrideModel
+	ShippingZoneModel              *ShippingZoneModel
+	ShippingMethodModel            *ShippingMethodModel
+	ShippingRateModel              *ShippingRateModel
+	ShippingWeightRateModel        *ShippingWeightRateModel
+	RegionModel                    *RegionModel
+	PriceListModel                 *PriceListModel
+	PriceListPriceModel            *PriceListPriceModel
+	CurrencyModel                  *CurrencyModel
+	ExchangeRateModel              *ExchangeRateModel
+	CustomerGroupModel             *CustomerGroupModel
+	GiftCardModel                  *GiftCardModel
+	GiftCardTransactionModel       *GiftCardTransactionModel
+	StoreCreditModel               *StoreCreditModel
+	ReturnModel                    *ReturnModel
+	ReturnItemModel                *ReturnItemModel
+	ReturnEventModel               *ReturnEventModel
+	SearchSuggestionModel          *SearchSuggestionModel
 }
 
 func NewModels(conn sqldb.Connection) *Models {
@@ -33,5 +73,45 @@ func NewModels(conn sqldb.Connection) *Models {
 		UserModel:                      NewUserModel(),
 		TokenModel:                     NewTokenModel(),
 		WishlistModel:                  NewWishlistModel(),
+		CartModel:                      NewCartModel(),
+		CartItemModel:                  NewCartItemModel(),
+		AddressModel:                   NewAddressModel(),
+		OrderModel:                     NewOrderModel(),
+		OrderLineItemModel:             NewOrderLineItemModel(),
+		OrderEventModel:                NewOrderEventModel(),
+		PaymentSessionModel:            NewPaymentSessionModel(),
+		PaymentModel:                   NewPaymentModel(),
+		PaymentRefundModel:             NewPaymentRefundModel(),
+		InventoryReservationModel:      NewInventoryReservationModel(),
+		StockMovementModel:             NewStockMovementModel(),
+		StockLocationModel:             NewStockLocationModel(),
+		InventoryLevelModel:            NewInventoryLevelModel(),
+		BackInStockSubscriptionModel:   NewBackInStockSubscriptionModel(),
+		NotificationModel:              NewNotificationModel(),
+		PromotionModel:                 NewPromotionModel(),
+		PromotionCurrencyRuleModel:     NewPromotionCurrencyRuleModel(),
+		PromotionTargetModel:           NewPromotionTargetModel(),
+		PromotionTierModel:             NewPromotionTierModel(),
+		PromotionCustomerGroupModel:    NewPromotionCustomerGroupModel(),
+		PromotionRedemptionModel:       NewPromotionRedemptionModel(),
+		TaxRegionModel:                 NewTaxRegionModel(),
+		TaxRateOverrideModel:           NewTaxRateOverrideModel(),
+		ShippingZoneModel:              NewShippingZoneModel(),
+		ShippingMethodModel:            NewShippingMethodModel(),
+		ShippingRateModel:              NewShippingRateModel(),
+		ShippingWeightRateModel:        NewShippingWeightRateModel(),
+		RegionModel:                    NewRegionModel(),
+		PriceListModel:                 NewPriceListModel(),
+		PriceListPriceModel:            NewPriceListPriceModel(),
+		CurrencyModel:                  NewCurrencyModel(),
+		ExchangeRateModel:              NewExchangeRateModel(),
+		CustomerGroupModel:             NewCustomerGroupModel(),
+		GiftCardModel:                  NewGiftCardModel(),
+		GiftCardTransactionModel:       NewGiftCardTransactionModel(),
+		StoreCreditModel:               NewStoreCreditModel(),
+		ReturnModel:                    NewReturnModel(),
+		ReturnItemModel:                NewReturnItemModel(),
+		ReturnEventModel:               NewReturnEventModel(),
+		SearchSuggestionModel:          NewSearchSuggestionModel(),
 	}
 }
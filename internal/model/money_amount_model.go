@@ -2,19 +2,29 @@ package model
 
 import (
 	"context"
+	"ecom-backend/internal/money"
 	"ecom-backend/pkg/sqldb"
 	"time"
+
+	"github.com/lib/pq"
 )
 
 type MoneyAmountRecord struct {
 	Id           string
 	CurrencyCode string
-	Amount       float32
+	Amount       money.Money
+	MinQuantity  int  // the price applies from this quantity
+	MaxQuantity  *int // up to this one, nil when there's no upper bound
 	CreatedAt    time.Time
 	UpdatedAt    time.Time
 	DeletedAt    *time.Time
 }
 
+// CoversQuantity reports whether the price applies when buying the quantity
+func (moneyAmount *MoneyAmountRecord) CoversQuantity(quantity int) bool {
+	return quantity >= moneyAmount.MinQuantity && (moneyAmount.MaxQuantity == nil || quantity <= *moneyAmount.MaxQuantity)
+}
+
 type MoneyAmountModel struct{}
 
 func NewMoneyAmountModel() *MoneyAmountModel {
@@ -22,9 +32,13 @@ func NewMoneyAmountModel() *MoneyAmountModel {
 }
 
 func (m *MoneyAmountModel) Insert(ctx context.Context, conn sqldb.Connection, moneyAmount *MoneyAmountRecord) (*MoneyAmountRecord, error) {
-	q := `INSERT INTO money_amount (currency_code, amount) VALUES ($1, $2) RETURNING id, created_at, updated_at`
+	q := `INSERT INTO money_amount (currency_code, amount, min_quantity, max_quantity) VALUES ($1, $2, $3, $4) RETURNING id, created_at, updated_at`
 
-	err := conn.QueryRowContext(ctx, q, moneyAmount.CurrencyCode, moneyAmount.Amount).Scan(&moneyAmount.Id, &moneyAmount.CreatedAt, &moneyAmount.UpdatedAt)
+	if moneyAmount.MinQuantity == 0 {
+		moneyAmount.MinQuantity = 1
+	}
+
+	err := conn.QueryRowContext(ctx, q, moneyAmount.CurrencyCode, moneyAmount.Amount, moneyAmount.MinQuantity, moneyAmount.MaxQuantity).Scan(&moneyAmount.Id, &moneyAmount.CreatedAt, &moneyAmount.UpdatedAt)
 
 	if err != nil {
 		return nil, err
@@ -44,3 +58,40 @@ func (m *MoneyAmountModel) DeleteById(ctx context.Context, conn sqldb.Connection
 
 	return nil
 }
+
+// FindAllByVariantIds returns the base prices of the variants by variant id, only the ones in the currencies when some are given.
+// The prices are sorted by currency and by quantity tier.
+func (m *MoneyAmountModel) FindAllByVariantIds(ctx context.Context, conn sqldb.Connection, variantIds []string, currencyCodes []string) (map[string][]*MoneyAmountRecord, error) {
+	q := `SELECT ma.id, ma.currency_code, ma.amount, ma.min_quantity, ma.max_quantity, ma.created_at, ma.updated_at, ma.deleted_at, pvma.variant_id
+		  FROM money_amount AS ma
+		  INNER JOIN product_variant_money_amount AS pvma ON pvma.money_amount_id = ma.id
+		  WHERE pvma.variant_id = ANY($1) AND ($2::text[] IS NULL OR ma.currency_code = ANY($2))
+		  ORDER BY ma.currency_code, ma.min_quantity`
+
+	rows, err := conn.QueryContext(ctx, q, pq.Array(variantIds), pq.Array(currencyCodes))
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	resultMap := make(map[string][]*MoneyAmountRecord)
+
+	for rows.Next() {
+		var record MoneyAmountRecord
+		var variantId string
+
+		err := rows.Scan(&record.Id, &record.CurrencyCode, &record.Amount, &record.MinQuantity, &record.MaxQuantity, &record.CreatedAt, &record.UpdatedAt, &record.DeletedAt, &variantId)
+
+		if err != nil {
+			return nil, err
+		}
+
+		money.SetCurrency(record.CurrencyCode, &record.Amount)
+
+		resultMap[variantId] = append(resultMap[variantId], &record)
+	}
+
+	return resultMap, nil
+}
@@ -0,0 +1,44 @@
+package model
+
+import (
+	"context"
+	"ecom-backend/pkg/sqldb"
+	"encoding/json"
+	"time"
+)
+
+type NotificationRecord struct {
+	Id             string
+	Type           string
+	Email          string
+	UserIdentifier *string
+	Payload        map[string]any
+	Status         string
+	SentAt         *time.Time
+	CreatedAt      time.Time
+}
+
+type NotificationModel struct{}
+
+func NewNotificationModel() *NotificationModel {
+	return &NotificationModel{}
+}
+
+// Insert enqueues the notification, the delivery happens outside of the request that produced it
+func (m *NotificationModel) Insert(ctx context.Context, conn sqldb.Connection, record *NotificationRecord) (*NotificationRecord, error) {
+	q := `INSERT INTO notification (type, email, user_identifier, payload) VALUES ($1, $2, $3, $4) RETURNING id, status, created_at`
+
+	payload, err := json.Marshal(record.Payload)
+
+	if err != nil {
+		return nil, err
+	}
+
+	err = conn.QueryRowContext(ctx, q, record.Type, record.Email, record.UserIdentifier, payload).Scan(&record.Id, &record.Status, &record.CreatedAt)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return record, nil
+}
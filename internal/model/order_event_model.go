@@ -0,0 +1,63 @@
+package model
+
+import (
+	"context"
+	"ecom-backend/pkg/sqldb"
+	"time"
+)
+
+type OrderEventRecord struct {
+	Id          string    `json:"id"`
+	OrderId     string    `json:"order_id"`
+	FromStatus  *string   `json:"from_status"` // nil for the event created when the order is placed
+	ToStatus    string    `json:"to_status"`
+	ActorUserId *string   `json:"actor_user_id"`
+	Note        *string   `json:"note"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type OrderEventModel struct{}
+
+func NewOrderEventModel() *OrderEventModel {
+	return &OrderEventModel{}
+}
+
+func (m *OrderEventModel) Insert(ctx context.Context, conn sqldb.Connection, record *OrderEventRecord) (*OrderEventRecord, error) {
+	q := `INSERT INTO order_event (order_id, from_status, to_status, actor_user_id, note) VALUES ($1, $2, $3, $4, $5) RETURNING id, created_at`
+
+	err := conn.QueryRowContext(ctx, q, record.OrderId, record.FromStatus, record.ToStatus, record.ActorUserId, record.Note).Scan(&record.Id, &record.CreatedAt)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return record, nil
+}
+
+func (m *OrderEventModel) FindAllByOrderId(ctx context.Context, conn sqldb.Connection, orderId string) ([]*OrderEventRecord, error) {
+	q := `SELECT id, order_id, from_status, to_status, actor_user_id, note, created_at FROM order_event WHERE order_id = $1 ORDER BY created_at`
+
+	rows, err := conn.QueryContext(ctx, q, orderId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	events := []*OrderEventRecord{}
+
+	for rows.Next() {
+		var record OrderEventRecord
+
+		err := rows.Scan(&record.Id, &record.OrderId, &record.FromStatus, &record.ToStatus, &record.ActorUserId, &record.Note, &record.CreatedAt)
+
+		if err != nil {
+			return nil, err
+		}
+
+		events = append(events, &record)
+	}
+
+	return events, nil
+}
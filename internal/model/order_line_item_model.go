@@ -0,0 +1,110 @@
+package model
+
+import (
+	"context"
+	"ecom-backend/internal/money"
+	"ecom-backend/pkg/sqldb"
+	"encoding/json"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type OrderLineItemRecord struct {
+	Id            string                `json:"id"`
+	OrderId       string                `json:"order_id"`
+	VariantId     *string               `json:"variant_id"` // nil if the variant was removed after the order was placed
+	ProductId     string                `json:"product_id"`
+	ProductTitle  string                `json:"product_title"`
+	VariantTitle  string                `json:"variant_title"`
+	Sku           *string               `json:"sku"`
+	ThumbnailId   *string               `json:"thumbnail_id"`
+	Options       []OrderLineItemOption `json:"options"`
+	UnitPrice     money.Money           `json:"unit_price"`
+	Quantity      int                   `json:"quantity"`
+	Subtotal      money.Money           `json:"subtotal"`
+	DiscountTotal money.Money           `json:"discount_total"`
+	TaxRate       float32               `json:"tax_rate"`
+	TaxTotal      money.Money           `json:"tax_total"`
+	LocationId    *string               `json:"location_id"`  // stock location the item was allocated from
+	IsGiftCard    bool                  `json:"is_gift_card"` // a gift card worth the unit price is issued per unit once the order is paid
+	CreatedAt     time.Time             `json:"created_at"`
+}
+
+// snapshot of the option value of the purchased variant ( ex: size: M )
+type OrderLineItemOption struct {
+	OptionId    string `json:"option_id"`
+	OptionTitle string `json:"option_title"`
+	Value       string `json:"value"`
+}
+
+type OrderLineItemModel struct{}
+
+func NewOrderLineItemModel() *OrderLineItemModel {
+	return &OrderLineItemModel{}
+}
+
+func (m *OrderLineItemModel) Insert(ctx context.Context, conn sqldb.Connection, record *OrderLineItemRecord) (*OrderLineItemRecord, error) {
+	q := `INSERT INTO order_line_item (order_id, variant_id, product_id, product_title, variant_title, sku, thumbnail_id, options, unit_price, quantity, subtotal, discount_total,
+		  tax_rate, tax_total, location_id, is_gift_card)
+		  VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16) RETURNING id, created_at`
+
+	options, err := json.Marshal(record.Options)
+
+	if err != nil {
+		return nil, err
+	}
+
+	err = conn.QueryRowContext(ctx, q, record.OrderId, record.VariantId, record.ProductId, record.ProductTitle, record.VariantTitle, record.Sku, record.ThumbnailId, options, record.UnitPrice, record.Quantity, record.Subtotal, record.DiscountTotal,
+		record.TaxRate, record.TaxTotal, record.LocationId, record.IsGiftCard).Scan(&record.Id, &record.CreatedAt)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return record, nil
+}
+
+// FindAllByOrderIds returns the line items by order id, their amounts are in the currency of the order
+func (m *OrderLineItemModel) FindAllByOrderIds(ctx context.Context, conn sqldb.Connection, orderIds []string) (map[string][]*OrderLineItemRecord, error) {
+	q := `SELECT oli.id, oli.order_id, oli.variant_id, oli.product_id, oli.product_title, oli.variant_title, oli.sku, oli.thumbnail_id, oli.options, oli.unit_price, oli.quantity, oli.subtotal,
+		  oli.discount_total, oli.tax_rate, oli.tax_total, oli.location_id, oli.is_gift_card, oli.created_at, o.currency_code
+		  FROM order_line_item AS oli
+		  INNER JOIN orders AS o ON o.id = oli.order_id
+		  WHERE oli.order_id = ANY($1) ORDER BY oli.created_at`
+
+	rows, err := conn.QueryContext(ctx, q, pq.Array(orderIds))
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	resultMap := make(map[string][]*OrderLineItemRecord)
+
+	for rows.Next() {
+		var record OrderLineItemRecord
+		var options []byte
+		var currencyCode string
+
+		err := rows.Scan(&record.Id, &record.OrderId, &record.VariantId, &record.ProductId, &record.ProductTitle, &record.VariantTitle, &record.Sku, &record.ThumbnailId, &options, &record.UnitPrice, &record.Quantity, &record.Subtotal, &record.DiscountTotal,
+			&record.TaxRate, &record.TaxTotal, &record.LocationId, &record.IsGiftCard, &record.CreatedAt, &currencyCode)
+
+		if err != nil {
+			return nil, err
+		}
+
+		money.SetCurrency(currencyCode, &record.UnitPrice, &record.Subtotal, &record.DiscountTotal, &record.TaxTotal)
+
+		err = json.Unmarshal(options, &record.Options)
+
+		if err != nil {
+			return nil, err
+		}
+
+		resultMap[record.OrderId] = append(resultMap[record.OrderId], &record)
+	}
+
+	return resultMap, nil
+}
@@ -0,0 +1,193 @@
+package model
+
+import (
+	"context"
+	"database/sql"
+	"ecom-backend/internal/money"
+	"ecom-backend/pkg/sqldb"
+	"errors"
+	"time"
+)
+
+type OrderRecord struct {
+	Id                 string
+	UserIdentifier     string  // user id for registered users and session id for guests
+	UserId             *string // set only when the order was placed by a registered user
+	Email              string
+	CurrencyCode       string
+	RegionId           *string // the region the order was placed in, nil when none was selected or it was removed since
+	ShippingAddressId  string
+	BillingAddressId   string
+	Subtotal           money.Money
+	DiscountTotal      money.Money
+	TaxTotal           money.Money
+	ShippingMethodId   *string // nil when no shipping method was chosen or it was removed since
+	ShippingMethodName *string
+	ShippingTotal      money.Money
+	IsTaxInclusive     bool // the taxes are part of the subtotal instead of being added to the total
+	Total              money.Money
+	GiftCardTotal      money.Money // part of the total paid with gift cards
+	StoreCreditTotal   money.Money // part of the total paid with the store credit of the user
+	Status             string
+	PaidAt             *time.Time
+	FulfilledAt        *time.Time
+	ShippedAt          *time.Time
+	DeliveredAt        *time.Time
+	CancelledAt        *time.Time
+	RefundedAt         *time.Time
+	CreatedAt          time.Time
+	UpdatedAt          time.Time
+}
+
+type OrderModel struct{}
+
+func NewOrderModel() *OrderModel {
+	return &OrderModel{}
+}
+
+const orderColumns = `id, user_identifier, user_id, email, currency_code, region_id, shipping_address_id, billing_address_id, subtotal, discount_total, tax_total, is_tax_inclusive, shipping_method_id,
+	shipping_method_name, shipping_total, total, gift_card_total, store_credit_total, status, paid_at, fulfilled_at, shipped_at, delivered_at, cancelled_at, refunded_at, created_at, updated_at`
+
+func scanOrder(row interface{ Scan(...any) error }, order *OrderRecord) error {
+	err := row.Scan(&order.Id, &order.UserIdentifier, &order.UserId, &order.Email, &order.CurrencyCode, &order.RegionId, &order.ShippingAddressId, &order.BillingAddressId, &order.Subtotal, &order.DiscountTotal, &order.TaxTotal, &order.IsTaxInclusive,
+		&order.ShippingMethodId, &order.ShippingMethodName, &order.ShippingTotal, &order.Total, &order.GiftCardTotal, &order.StoreCreditTotal, &order.Status, &order.PaidAt, &order.FulfilledAt, &order.ShippedAt, &order.DeliveredAt, &order.CancelledAt, &order.RefundedAt, &order.CreatedAt, &order.UpdatedAt)
+
+	if err != nil {
+		return err
+	}
+
+	money.SetCurrency(order.CurrencyCode, &order.Subtotal, &order.DiscountTotal, &order.TaxTotal, &order.ShippingTotal, &order.Total, &order.GiftCardTotal, &order.StoreCreditTotal)
+
+	return nil
+}
+
+func (m *OrderModel) Insert(ctx context.Context, conn sqldb.Connection, order *OrderRecord) (*OrderRecord, error) {
+	q := `INSERT INTO orders (user_identifier, user_id, email, currency_code, region_id, shipping_address_id, billing_address_id, subtotal, discount_total, tax_total, is_tax_inclusive,
+		  shipping_method_id, shipping_method_name, shipping_total, total, gift_card_total, store_credit_total)
+		  VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17) RETURNING id, status, created_at, updated_at`
+
+	err := conn.QueryRowContext(ctx, q, order.UserIdentifier, order.UserId, order.Email, order.CurrencyCode, order.RegionId, order.ShippingAddressId, order.BillingAddressId, order.Subtotal, order.DiscountTotal,
+		order.TaxTotal, order.IsTaxInclusive, order.ShippingMethodId, order.ShippingMethodName, order.ShippingTotal, order.Total, order.GiftCardTotal, order.StoreCreditTotal).Scan(&order.Id, &order.Status, &order.CreatedAt, &order.UpdatedAt)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return order, nil
+}
+
+// AmountDue returns the part of the total left to pay with a payment provider
+func (order *OrderRecord) AmountDue() money.Money {
+	return order.Total.Sub(order.GiftCardTotal).Sub(order.StoreCreditTotal)
+}
+
+func (m *OrderModel) FindById(ctx context.Context, conn sqldb.Connection, id string) (*OrderRecord, error) {
+	q := `SELECT ` + orderColumns + ` FROM orders WHERE id = $1`
+
+	var order OrderRecord
+
+	err := scanOrder(conn.QueryRowContext(ctx, q, id), &order)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrRecordNotFound
+		}
+		return nil, err
+	}
+
+	return &order, nil
+}
+
+// FindByIdForUpdate locks the order row until the end of the transaction
+func (m *OrderModel) FindByIdForUpdate(ctx context.Context, conn sqldb.Connection, id string) (*OrderRecord, error) {
+	q := `SELECT ` + orderColumns + ` FROM orders WHERE id = $1 FOR UPDATE`
+
+	var order OrderRecord
+
+	err := scanOrder(conn.QueryRowContext(ctx, q, id), &order)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrRecordNotFound
+		}
+		return nil, err
+	}
+
+	return &order, nil
+}
+
+func (m *OrderModel) UpdateStatus(ctx context.Context, conn sqldb.Connection, order *OrderRecord) (*OrderRecord, error) {
+	q := `UPDATE orders SET status = $1, paid_at = $2, fulfilled_at = $3, shipped_at = $4, delivered_at = $5, cancelled_at = $6, refunded_at = $7, updated_at = $8 WHERE id = $9`
+
+	order.UpdatedAt = time.Now()
+
+	res, err := conn.ExecContext(ctx, q, order.Status, order.PaidAt, order.FulfilledAt, order.ShippedAt, order.DeliveredAt, order.CancelledAt, order.RefundedAt, order.UpdatedAt, order.Id)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if rowsAff, _ := res.RowsAffected(); rowsAff == 0 {
+		return nil, ErrRecordNotFound
+	}
+
+	return order, nil
+}
+
+func (m *OrderModel) Count(ctx context.Context, conn sqldb.Connection) (int, error) {
+	q := `SELECT COUNT(*) FROM orders`
+
+	var count int
+
+	err := conn.QueryRowContext(ctx, q).Scan(&count)
+
+	if err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+func (m *OrderModel) FindAll(ctx context.Context, conn sqldb.Connection, limit uint, offset uint) ([]*OrderRecord, error) {
+	q := `SELECT ` + orderColumns + ` FROM orders ORDER BY created_at DESC, id DESC LIMIT $1 OFFSET $2`
+
+	return m.findMany(ctx, conn, q, limit, offset)
+}
+
+// FindAllFrom returns the orders placed before the position, the newest first, or the ones placed after it, the oldest
+// first, when after is set. The id tells apart the orders placed at the same time.
+func (m *OrderModel) FindAllFrom(ctx context.Context, conn sqldb.Connection, createdAt time.Time, id string, after bool, limit uint) ([]*OrderRecord, error) {
+	q := `SELECT ` + orderColumns + ` FROM orders WHERE (created_at, id) < ($1, $2) ORDER BY created_at DESC, id DESC LIMIT $3`
+
+	if after {
+		q = `SELECT ` + orderColumns + ` FROM orders WHERE (created_at, id) > ($1, $2) ORDER BY created_at ASC, id ASC LIMIT $3`
+	}
+
+	return m.findMany(ctx, conn, q, createdAt, id, limit)
+}
+
+func (m *OrderModel) findMany(ctx context.Context, conn sqldb.Connection, q string, args ...any) ([]*OrderRecord, error) {
+	rows, err := conn.QueryContext(ctx, q, args...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	orders := []*OrderRecord{}
+
+	for rows.Next() {
+		var order OrderRecord
+
+		err := scanOrder(rows, &order)
+
+		if err != nil {
+			return nil, err
+		}
+
+		orders = append(orders, &order)
+	}
+
+	return orders, nil
+}
@@ -0,0 +1,140 @@
+package model
+
+import (
+	"context"
+	"database/sql"
+	"ecom-backend/internal/money"
+	"ecom-backend/pkg/sqldb"
+	"errors"
+	"time"
+)
+
+type PaymentRecord struct {
+	Id                string      `json:"id"`
+	OrderId           string      `json:"order_id"`
+	PaymentSessionId  string      `json:"payment_session_id"`
+	ProviderId        string      `json:"provider_id"`
+	ProviderReference string      `json:"-"`
+	Status            string      `json:"status"`
+	Amount            money.Money `json:"amount"`
+	AmountCaptured    money.Money `json:"amount_captured"`
+	AmountRefunded    money.Money `json:"amount_refunded"`
+	CurrencyCode      string      `json:"currency_code"`
+	CapturedAt        *time.Time  `json:"captured_at"`
+	VoidedAt          *time.Time  `json:"voided_at"`
+	CreatedAt         time.Time   `json:"created_at"`
+	UpdatedAt         time.Time   `json:"updated_at"`
+}
+
+type PaymentModel struct{}
+
+func NewPaymentModel() *PaymentModel {
+	return &PaymentModel{}
+}
+
+const paymentColumns = `id, order_id, payment_session_id, provider_id, provider_reference, status, amount, amount_captured, amount_refunded, currency_code, captured_at, voided_at, created_at, updated_at`
+
+func scanPayment(row interface{ Scan(...any) error }, record *PaymentRecord) error {
+	err := row.Scan(&record.Id, &record.OrderId, &record.PaymentSessionId, &record.ProviderId, &record.ProviderReference, &record.Status, &record.Amount, &record.AmountCaptured, &record.AmountRefunded, &record.CurrencyCode, &record.CapturedAt, &record.VoidedAt, &record.CreatedAt, &record.UpdatedAt)
+
+	if err != nil {
+		return err
+	}
+
+	money.SetCurrency(record.CurrencyCode, &record.Amount, &record.AmountCaptured, &record.AmountRefunded)
+
+	return nil
+}
+
+func (m *PaymentModel) Insert(ctx context.Context, conn sqldb.Connection, record *PaymentRecord) (*PaymentRecord, error) {
+	q := `INSERT INTO payment (order_id, payment_session_id, provider_id, provider_reference, amount, currency_code)
+		  VALUES ($1, $2, $3, $4, $5, $6) RETURNING id, status, created_at, updated_at`
+
+	err := conn.QueryRowContext(ctx, q, record.OrderId, record.PaymentSessionId, record.ProviderId, record.ProviderReference, record.Amount, record.CurrencyCode).Scan(&record.Id, &record.Status, &record.CreatedAt, &record.UpdatedAt)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return record, nil
+}
+
+func (m *PaymentModel) FindById(ctx context.Context, conn sqldb.Connection, id string) (*PaymentRecord, error) {
+	q := `SELECT ` + paymentColumns + ` FROM payment WHERE id = $1`
+
+	return m.findOne(ctx, conn, q, id)
+}
+
+func (m *PaymentModel) FindByIdForUpdate(ctx context.Context, conn sqldb.Connection, id string) (*PaymentRecord, error) {
+	q := `SELECT ` + paymentColumns + ` FROM payment WHERE id = $1 FOR UPDATE`
+
+	return m.findOne(ctx, conn, q, id)
+}
+
+// FindActiveByOrderIdForUpdate returns the payment of the order that wasn't voided yet, locking it until the end of the transaction
+func (m *PaymentModel) FindActiveByOrderIdForUpdate(ctx context.Context, conn sqldb.Connection, orderId string) (*PaymentRecord, error) {
+	q := `SELECT ` + paymentColumns + ` FROM payment WHERE order_id = $1 AND status != 'voided' ORDER BY created_at DESC LIMIT 1 FOR UPDATE`
+
+	return m.findOne(ctx, conn, q, orderId)
+}
+
+func (m *PaymentModel) findOne(ctx context.Context, conn sqldb.Connection, q string, args ...any) (*PaymentRecord, error) {
+	var record PaymentRecord
+
+	err := scanPayment(conn.QueryRowContext(ctx, q, args...), &record)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrRecordNotFound
+		}
+		return nil, err
+	}
+
+	return &record, nil
+}
+
+func (m *PaymentModel) FindAllByOrderId(ctx context.Context, conn sqldb.Connection, orderId string) ([]*PaymentRecord, error) {
+	q := `SELECT ` + paymentColumns + ` FROM payment WHERE order_id = $1 ORDER BY created_at`
+
+	rows, err := conn.QueryContext(ctx, q, orderId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	payments := []*PaymentRecord{}
+
+	for rows.Next() {
+		var record PaymentRecord
+
+		err := scanPayment(rows, &record)
+
+		if err != nil {
+			return nil, err
+		}
+
+		payments = append(payments, &record)
+	}
+
+	return payments, nil
+}
+
+func (m *PaymentModel) Update(ctx context.Context, conn sqldb.Connection, record *PaymentRecord) (*PaymentRecord, error) {
+	q := `UPDATE payment SET status = $1, amount_captured = $2, amount_refunded = $3, captured_at = $4, voided_at = $5, updated_at = $6 WHERE id = $7`
+
+	record.UpdatedAt = time.Now()
+
+	res, err := conn.ExecContext(ctx, q, record.Status, record.AmountCaptured, record.AmountRefunded, record.CapturedAt, record.VoidedAt, record.UpdatedAt, record.Id)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if rowsAff, _ := res.RowsAffected(); rowsAff == 0 {
+		return nil, ErrRecordNotFound
+	}
+
+	return record, nil
+}
@@ -0,0 +1,128 @@
+package model
+
+import (
+	"context"
+	"database/sql"
+	"ecom-backend/internal/money"
+	"ecom-backend/pkg/sqldb"
+	"errors"
+	"time"
+)
+
+// PaymentRefundRecord is a refund asked to the payment provider, its id is the idempotency key of the request
+type PaymentRefundRecord struct {
+	Id           string      `json:"id"`
+	PaymentId    string      `json:"payment_id"`
+	ReturnId     *string     `json:"return_id"` // nil for the refunds of the payment itself
+	Status       string      `json:"status"`
+	Amount       money.Money `json:"amount"`
+	CurrencyCode string      `json:"currency_code"`
+	CreatedAt    time.Time   `json:"created_at"`
+	UpdatedAt    time.Time   `json:"updated_at"`
+}
+
+type PaymentRefundModel struct{}
+
+func NewPaymentRefundModel() *PaymentRefundModel {
+	return &PaymentRefundModel{}
+}
+
+const paymentRefundColumns = `id, payment_id, return_id, status, amount, currency_code, created_at, updated_at`
+
+func scanPaymentRefund(row interface{ Scan(...any) error }, record *PaymentRefundRecord) error {
+	err := row.Scan(&record.Id, &record.PaymentId, &record.ReturnId, &record.Status, &record.Amount, &record.CurrencyCode, &record.CreatedAt, &record.UpdatedAt)
+
+	if err != nil {
+		return err
+	}
+
+	money.SetCurrency(record.CurrencyCode, &record.Amount)
+
+	return nil
+}
+
+func (m *PaymentRefundModel) Insert(ctx context.Context, conn sqldb.Connection, record *PaymentRefundRecord) (*PaymentRefundRecord, error) {
+	q := `INSERT INTO payment_refund (payment_id, return_id, amount, currency_code) VALUES ($1, $2, $3, $4) RETURNING id, status, created_at, updated_at`
+
+	err := conn.QueryRowContext(ctx, q, record.PaymentId, record.ReturnId, record.Amount, record.CurrencyCode).Scan(&record.Id, &record.Status, &record.CreatedAt, &record.UpdatedAt)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return record, nil
+}
+
+func (m *PaymentRefundModel) FindByIdForUpdate(ctx context.Context, conn sqldb.Connection, id string) (*PaymentRefundRecord, error) {
+	q := `SELECT ` + paymentRefundColumns + ` FROM payment_refund WHERE id = $1 FOR UPDATE`
+
+	return m.findOne(ctx, conn, q, id)
+}
+
+func (m *PaymentRefundModel) FindByReturnId(ctx context.Context, conn sqldb.Connection, returnId string) (*PaymentRefundRecord, error) {
+	q := `SELECT ` + paymentRefundColumns + ` FROM payment_refund WHERE return_id = $1`
+
+	return m.findOne(ctx, conn, q, returnId)
+}
+
+func (m *PaymentRefundModel) findOne(ctx context.Context, conn sqldb.Connection, q string, args ...any) (*PaymentRefundRecord, error) {
+	var record PaymentRefundRecord
+
+	err := scanPaymentRefund(conn.QueryRowContext(ctx, q, args...), &record)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrRecordNotFound
+		}
+		return nil, err
+	}
+
+	return &record, nil
+}
+
+// FindAllPendingByPaymentId returns the refunds of the payment the provider didn't refund yet, the oldest first
+func (m *PaymentRefundModel) FindAllPendingByPaymentId(ctx context.Context, conn sqldb.Connection, paymentId string) ([]*PaymentRefundRecord, error) {
+	q := `SELECT ` + paymentRefundColumns + ` FROM payment_refund WHERE payment_id = $1 AND status = 'pending' ORDER BY created_at`
+
+	rows, err := conn.QueryContext(ctx, q, paymentId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	refunds := []*PaymentRefundRecord{}
+
+	for rows.Next() {
+		var record PaymentRefundRecord
+
+		err := scanPaymentRefund(rows, &record)
+
+		if err != nil {
+			return nil, err
+		}
+
+		refunds = append(refunds, &record)
+	}
+
+	return refunds, nil
+}
+
+func (m *PaymentRefundModel) Update(ctx context.Context, conn sqldb.Connection, record *PaymentRefundRecord) (*PaymentRefundRecord, error) {
+	q := `UPDATE payment_refund SET status = $1, updated_at = $2 WHERE id = $3`
+
+	record.UpdatedAt = time.Now()
+
+	res, err := conn.ExecContext(ctx, q, record.Status, record.UpdatedAt, record.Id)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if rowsAff, _ := res.RowsAffected(); rowsAff == 0 {
+		return nil, ErrRecordNotFound
+	}
+
+	return record, nil
+}
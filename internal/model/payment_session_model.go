@@ -0,0 +1,120 @@
+package model
+
+import (
+	"context"
+	"database/sql"
+	"ecom-backend/internal/money"
+	"ecom-backend/pkg/sqldb"
+	"errors"
+	"time"
+)
+
+type PaymentSessionRecord struct {
+	Id                string      `json:"id"`
+	OrderId           string      `json:"order_id"`
+	ProviderId        string      `json:"provider_id"`
+	ProviderReference *string     `json:"-"`
+	Status            string      `json:"status"`
+	Amount            money.Money `json:"amount"`
+	CurrencyCode      string      `json:"currency_code"`
+	CreatedAt         time.Time   `json:"created_at"`
+	UpdatedAt         time.Time   `json:"updated_at"`
+}
+
+type PaymentSessionModel struct{}
+
+func NewPaymentSessionModel() *PaymentSessionModel {
+	return &PaymentSessionModel{}
+}
+
+const paymentSessionColumns = `id, order_id, provider_id, provider_reference, status, amount, currency_code, created_at, updated_at`
+
+func scanPaymentSession(row interface{ Scan(...any) error }, record *PaymentSessionRecord) error {
+	err := row.Scan(&record.Id, &record.OrderId, &record.ProviderId, &record.ProviderReference, &record.Status, &record.Amount, &record.CurrencyCode, &record.CreatedAt, &record.UpdatedAt)
+
+	if err != nil {
+		return err
+	}
+
+	money.SetCurrency(record.CurrencyCode, &record.Amount)
+
+	return nil
+}
+
+func (m *PaymentSessionModel) Insert(ctx context.Context, conn sqldb.Connection, record *PaymentSessionRecord) (*PaymentSessionRecord, error) {
+	q := `INSERT INTO payment_session (order_id, provider_id, amount, currency_code) VALUES ($1, $2, $3, $4) RETURNING id, status, created_at, updated_at`
+
+	err := conn.QueryRowContext(ctx, q, record.OrderId, record.ProviderId, record.Amount, record.CurrencyCode).Scan(&record.Id, &record.Status, &record.CreatedAt, &record.UpdatedAt)
+
+	if err != nil {
+		if err.Error() == `pq: duplicate key value violates unique constraint "idx_payment_session_open_order_id"` {
+			return nil, ErrOpenPaymentSessionExists
+		}
+		return nil, err
+	}
+
+	return record, nil
+}
+
+func (m *PaymentSessionModel) FindByProviderReferenceForUpdate(ctx context.Context, conn sqldb.Connection, providerId string, reference string) (*PaymentSessionRecord, error) {
+	q := `SELECT ` + paymentSessionColumns + ` FROM payment_session WHERE provider_id = $1 AND provider_reference = $2 FOR UPDATE`
+
+	var record PaymentSessionRecord
+
+	err := scanPaymentSession(conn.QueryRowContext(ctx, q, providerId, reference), &record)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrRecordNotFound
+		}
+		return nil, err
+	}
+
+	return &record, nil
+}
+
+func (m *PaymentSessionModel) FindAllByOrderId(ctx context.Context, conn sqldb.Connection, orderId string) ([]*PaymentSessionRecord, error) {
+	q := `SELECT ` + paymentSessionColumns + ` FROM payment_session WHERE order_id = $1 ORDER BY created_at`
+
+	rows, err := conn.QueryContext(ctx, q, orderId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	sessions := []*PaymentSessionRecord{}
+
+	for rows.Next() {
+		var record PaymentSessionRecord
+
+		err := scanPaymentSession(rows, &record)
+
+		if err != nil {
+			return nil, err
+		}
+
+		sessions = append(sessions, &record)
+	}
+
+	return sessions, nil
+}
+
+func (m *PaymentSessionModel) Update(ctx context.Context, conn sqldb.Connection, record *PaymentSessionRecord) (*PaymentSessionRecord, error) {
+	q := `UPDATE payment_session SET provider_reference = $1, status = $2, updated_at = $3 WHERE id = $4`
+
+	record.UpdatedAt = time.Now()
+
+	res, err := conn.ExecContext(ctx, q, record.ProviderReference, record.Status, record.UpdatedAt, record.Id)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if rowsAff, _ := res.RowsAffected(); rowsAff == 0 {
+		return nil, ErrRecordNotFound
+	}
+
+	return record, nil
+}
@@ -0,0 +1,299 @@
+package model
+
+import (
+	"context"
+	"database/sql"
+	"ecom-backend/internal/money"
+	"ecom-backend/pkg/sqldb"
+	"errors"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type PriceListRecord struct {
+	Id          string     `json:"id"`
+	Name        string     `json:"name"`
+	Description *string    `json:"description"`
+	Type        string     `json:"type"`
+	Priority    int        `json:"priority"` // the list with the highest priority wins when several apply to a variant
+	StartsAt    *time.Time `json:"starts_at"`
+	EndsAt      *time.Time `json:"ends_at"`
+	IsActive    bool       `json:"is_active"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+type PriceListPriceRecord struct {
+	PriceListId  string      `json:"price_list_id"`
+	VariantId    string      `json:"variant_id"`
+	CurrencyCode string      `json:"currency_code"`
+	Amount       money.Money `json:"amount"`
+}
+
+type PriceListModel struct{}
+
+func NewPriceListModel() *PriceListModel {
+	return &PriceListModel{}
+}
+
+const priceListColumns = `id, name, description, type, priority, starts_at, ends_at, is_active, created_at, updated_at`
+
+func scanPriceList(row interface{ Scan(...any) error }, priceList *PriceListRecord) error {
+	return row.Scan(&priceList.Id, &priceList.Name, &priceList.Description, &priceList.Type, &priceList.Priority, &priceList.StartsAt, &priceList.EndsAt, &priceList.IsActive,
+		&priceList.CreatedAt, &priceList.UpdatedAt)
+}
+
+func (m *PriceListModel) Insert(ctx context.Context, conn sqldb.Connection, priceList *PriceListRecord) (*PriceListRecord, error) {
+	q := `INSERT INTO price_list (name, description, type, priority, starts_at, ends_at, is_active) VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id, created_at, updated_at`
+
+	err := conn.QueryRowContext(ctx, q, priceList.Name, priceList.Description, priceList.Type, priceList.Priority, priceList.StartsAt, priceList.EndsAt, priceList.IsActive).
+		Scan(&priceList.Id, &priceList.CreatedAt, &priceList.UpdatedAt)
+
+	if err != nil {
+		if err.Error() == `pq: new row for relation "price_list" violates check constraint "price_list_window_check"` {
+			return nil, ErrInvalidValue
+		}
+		return nil, err
+	}
+
+	return priceList, nil
+}
+
+func (m *PriceListModel) FindById(ctx context.Context, conn sqldb.Connection, id string) (*PriceListRecord, error) {
+	q := `SELECT ` + priceListColumns + ` FROM price_list WHERE id = $1`
+
+	var priceList PriceListRecord
+
+	err := scanPriceList(conn.QueryRowContext(ctx, q, id), &priceList)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrRecordNotFound
+		}
+		return nil, err
+	}
+
+	return &priceList, nil
+}
+
+func (m *PriceListModel) FindAll(ctx context.Context, conn sqldb.Connection) ([]*PriceListRecord, error) {
+	q := `SELECT ` + priceListColumns + ` FROM price_list ORDER BY priority DESC, created_at`
+
+	rows, err := conn.QueryContext(ctx, q)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	priceLists := []*PriceListRecord{}
+
+	for rows.Next() {
+		var priceList PriceListRecord
+
+		err := scanPriceList(rows, &priceList)
+
+		if err != nil {
+			return nil, err
+		}
+
+		priceLists = append(priceLists, &priceList)
+	}
+
+	return priceLists, nil
+}
+
+func (m *PriceListModel) Update(ctx context.Context, conn sqldb.Connection, priceList *PriceListRecord) (*PriceListRecord, error) {
+	q := `UPDATE price_list SET name = $1, description = $2, priority = $3, starts_at = $4, ends_at = $5, is_active = $6, updated_at = $7 WHERE id = $8`
+
+	priceList.UpdatedAt = time.Now()
+
+	res, err := conn.ExecContext(ctx, q, priceList.Name, priceList.Description, priceList.Priority, priceList.StartsAt, priceList.EndsAt, priceList.IsActive, priceList.UpdatedAt, priceList.Id)
+
+	if err != nil {
+		if err.Error() == `pq: new row for relation "price_list" violates check constraint "price_list_window_check"` {
+			return nil, ErrInvalidValue
+		}
+		return nil, err
+	}
+
+	if rowsAff, _ := res.RowsAffected(); rowsAff == 0 {
+		return nil, ErrRecordNotFound
+	}
+
+	return priceList, nil
+}
+
+func (m *PriceListModel) Delete(ctx context.Context, conn sqldb.Connection, id string) error {
+	q := `DELETE FROM price_list WHERE id = $1`
+
+	res, err := conn.ExecContext(ctx, q, id)
+
+	if err != nil {
+		return err
+	}
+
+	if rowsAff, _ := res.RowsAffected(); rowsAff == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// ReplaceCustomerGroups swaps the groups the list is restricted to, the list applies to everyone without groups
+func (m *PriceListModel) ReplaceCustomerGroups(ctx context.Context, conn sqldb.Connection, priceListId string, groupIds []string) error {
+	_, err := conn.ExecContext(ctx, `DELETE FROM price_list_customer_group WHERE price_list_id = $1`, priceListId)
+
+	if err != nil {
+		return err
+	}
+
+	q := `INSERT INTO price_list_customer_group (price_list_id, customer_group_id) SELECT $1, unnest($2::uuid[])`
+
+	_, err = conn.ExecContext(ctx, q, priceListId, pq.Array(groupIds))
+
+	if err != nil {
+		if err.Error() == `pq: insert or update on table "price_list_customer_group" violates foreign key constraint "price_list_customer_group_customer_group_id_fkey"` {
+			return ErrCustomerGroupNotFound
+		}
+		return err
+	}
+
+	return nil
+}
+
+// FindCustomerGroupIds returns the ids of the groups the lists are restricted to by list id
+func (m *PriceListModel) FindCustomerGroupIds(ctx context.Context, conn sqldb.Connection, priceListIds []string) (map[string][]string, error) {
+	q := `SELECT price_list_id, customer_group_id FROM price_list_customer_group WHERE price_list_id = ANY($1) ORDER BY customer_group_id`
+
+	rows, err := conn.QueryContext(ctx, q, pq.Array(priceListIds))
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	resultMap := make(map[string][]string)
+
+	for rows.Next() {
+		var priceListId, groupId string
+
+		err := rows.Scan(&priceListId, &groupId)
+
+		if err != nil {
+			return nil, err
+		}
+
+		resultMap[priceListId] = append(resultMap[priceListId], groupId)
+	}
+
+	return resultMap, nil
+}
+
+type PriceListPriceModel struct{}
+
+func NewPriceListPriceModel() *PriceListPriceModel {
+	return &PriceListPriceModel{}
+}
+
+func (m *PriceListPriceModel) Insert(ctx context.Context, conn sqldb.Connection, record *PriceListPriceRecord) (*PriceListPriceRecord, error) {
+	q := `INSERT INTO price_list_price (price_list_id, variant_id, currency_code, amount) VALUES ($1, $2, $3, $4)`
+
+	_, err := conn.ExecContext(ctx, q, record.PriceListId, record.VariantId, record.CurrencyCode, record.Amount)
+
+	if err != nil {
+		switch {
+		case err.Error() == `pq: insert or update on table "price_list_price" violates foreign key constraint "price_list_price_variant_id_fkey"`:
+			return nil, ErrVariantNotFound
+		case err.Error() == `pq: insert or update on table "price_list_price" violates foreign key constraint "price_list_price_currency_code_fkey"`:
+			return nil, ErrCurrencyNotFound
+		case err.Error() == `pq: duplicate key value violates unique constraint "price_list_price_pkey"`:
+			return nil, ErrInvalidValue
+		default:
+			return nil, err
+		}
+	}
+
+	return record, nil
+}
+
+func (m *PriceListPriceModel) FindAllByPriceListIds(ctx context.Context, conn sqldb.Connection, priceListIds []string) (map[string][]*PriceListPriceRecord, error) {
+	q := `SELECT price_list_id, variant_id, currency_code, amount FROM price_list_price WHERE price_list_id = ANY($1) ORDER BY variant_id, currency_code`
+
+	rows, err := conn.QueryContext(ctx, q, pq.Array(priceListIds))
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	resultMap := make(map[string][]*PriceListPriceRecord)
+
+	for rows.Next() {
+		var record PriceListPriceRecord
+
+		err := rows.Scan(&record.PriceListId, &record.VariantId, &record.CurrencyCode, &record.Amount)
+
+		if err != nil {
+			return nil, err
+		}
+
+		money.SetCurrency(record.CurrencyCode, &record.Amount)
+
+		resultMap[record.PriceListId] = append(resultMap[record.PriceListId], &record)
+	}
+
+	return resultMap, nil
+}
+
+// FindActiveByVariantIds returns the prices of the variants from the lists that are active at the given time and apply
+// to a customer of the groups, the lists restricted to other groups are left out. Only the sale lists without groups
+// apply to everyone, the wholesale and customer group lists left without one apply to nobody. For every variant and currency the
+// price of the list with the highest priority comes first, the lowest one on equal priority.
+func (m *PriceListPriceModel) FindActiveByVariantIds(ctx context.Context, conn sqldb.Connection, variantIds []string, at time.Time, customerGroupIds []string) ([]*PriceListPriceRecord, error) {
+	q := `SELECT plp.price_list_id, plp.variant_id, plp.currency_code, plp.amount FROM price_list_price AS plp
+		  INNER JOIN price_list AS pl ON pl.id = plp.price_list_id
+		  WHERE plp.variant_id = ANY($1) AND pl.is_active
+		  AND (pl.starts_at IS NULL OR pl.starts_at <= $2) AND (pl.ends_at IS NULL OR pl.ends_at > $2)
+		  AND ((pl.type = 'sale' AND NOT EXISTS (SELECT 1 FROM price_list_customer_group AS plcg WHERE plcg.price_list_id = pl.id))
+		  	OR EXISTS (SELECT 1 FROM price_list_customer_group AS plcg WHERE plcg.price_list_id = pl.id AND plcg.customer_group_id = ANY($3::uuid[])))
+		  ORDER BY plp.variant_id, plp.currency_code, pl.priority DESC, plp.amount`
+
+	rows, err := conn.QueryContext(ctx, q, pq.Array(variantIds), at, pq.Array(customerGroupIds))
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	records := []*PriceListPriceRecord{}
+
+	for rows.Next() {
+		var record PriceListPriceRecord
+
+		err := rows.Scan(&record.PriceListId, &record.VariantId, &record.CurrencyCode, &record.Amount)
+
+		if err != nil {
+			return nil, err
+		}
+
+		money.SetCurrency(record.CurrencyCode, &record.Amount)
+
+		records = append(records, &record)
+	}
+
+	return records, nil
+}
+
+func (m *PriceListPriceModel) DeleteAllByPriceListId(ctx context.Context, conn sqldb.Connection, priceListId string) error {
+	q := `DELETE FROM price_list_price WHERE price_list_id = $1`
+
+	_, err := conn.ExecContext(ctx, q, priceListId)
+
+	return err
+}
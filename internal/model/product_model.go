@@ -6,6 +6,8 @@ import (
 	"ecom-backend/pkg/sqldb"
 	"errors"
 	"time"
+
+	"github.com/lib/pq"
 )
 
 type ProductRecord struct {
@@ -15,6 +17,7 @@ type ProductRecord struct {
 	Description string
 	ThumbnailId *string
 	Status      string
+	IsGiftCard  bool // buying one of its variants issues a gift card worth the variant price
 	CreatedAt   time.Time
 	UpdatedAt   time.Time
 	DeletedAt   *time.Time
@@ -28,9 +31,9 @@ func NewProductModel() *ProductModel {
 }
 
 func (p *ProductModel) Insert(ctx context.Context, conn sqldb.Connection, product *ProductRecord) (*ProductRecord, error) {
-	q := `INSERT INTO product (title, subtitle, description, thumbnail_id, status) VALUES ($1, $2, $3, $4, $5) RETURNING id, created_at, updated_at`
+	q := `INSERT INTO product (title, subtitle, description, thumbnail_id, status, is_gift_card) VALUES ($1, $2, $3, $4, $5, $6) RETURNING id, created_at, updated_at`
 
-	err := conn.QueryRowContext(ctx, q, product.Title, product.Subtitle, product.Description, product.ThumbnailId, product.Status).Scan(&product.Id, &product.CreatedAt, &product.UpdatedAt)
+	err := conn.QueryRowContext(ctx, q, product.Title, product.Subtitle, product.Description, product.ThumbnailId, product.Status, product.IsGiftCard).Scan(&product.Id, &product.CreatedAt, &product.UpdatedAt)
 
 	if err != nil {
 		return nil, err
@@ -40,11 +43,11 @@ func (p *ProductModel) Insert(ctx context.Context, conn sqldb.Connection, produc
 }
 
 func (p *ProductModel) FindById(ctx context.Context, conn sqldb.Connection, id string) (*ProductRecord, error) {
-	q := `SELECT id, title, subtitle, description, thumbnail_id, status, created_at, updated_at, deleted_at FROM product WHERE id = $1`
+	q := `SELECT id, title, subtitle, description, thumbnail_id, status, is_gift_card, created_at, updated_at, deleted_at FROM product WHERE id = $1`
 
 	product := &ProductRecord{}
 
-	err := conn.QueryRowContext(ctx, q, id).Scan(&product.Id, &product.Title, &product.Subtitle, &product.Description, &product.ThumbnailId, &product.Status, &product.CreatedAt, &product.UpdatedAt, &product.DeletedAt)
+	err := conn.QueryRowContext(ctx, q, id).Scan(&product.Id, &product.Title, &product.Subtitle, &product.Description, &product.ThumbnailId, &product.Status, &product.IsGiftCard, &product.CreatedAt, &product.UpdatedAt, &product.DeletedAt)
 
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
@@ -57,11 +60,11 @@ func (p *ProductModel) FindById(ctx context.Context, conn sqldb.Connection, id s
 }
 
 func (p *ProductModel) Update(ctx context.Context, conn sqldb.Connection, product *ProductRecord) (*ProductRecord, error) {
-	q := `UPDATE product SET title = $1, subtitle = $2, description = $3, thumbnail_id = $4, status = $5, updated_at = $6 WHERE id = $7`
+	q := `UPDATE product SET title = $1, subtitle = $2, description = $3, thumbnail_id = $4, status = $5, is_gift_card = $6, updated_at = $7 WHERE id = $8`
 
 	product.UpdatedAt = time.Now()
 
-	_, err := conn.ExecContext(ctx, q, product.Title, product.Subtitle, product.Description, product.ThumbnailId, product.Status, product.UpdatedAt, product.Id)
+	_, err := conn.ExecContext(ctx, q, product.Title, product.Subtitle, product.Description, product.ThumbnailId, product.Status, product.IsGiftCard, product.UpdatedAt, product.Id)
 
 	if err != nil {
 		switch {
@@ -76,6 +79,16 @@ func (p *ProductModel) Update(ctx context.Context, conn sqldb.Connection, produc
 	return product, nil
 }
 
+// RefreshSearchVector rebuilds the full-text search document of the product from its details, its variants and their
+// option values
+func (p *ProductModel) RefreshSearchVector(ctx context.Context, conn sqldb.Connection, id string) error {
+	q := `UPDATE product SET search_vector = product_search_vector(id) WHERE id = $1`
+
+	_, err := conn.ExecContext(ctx, q, id)
+
+	return err
+}
+
 func (p *ProductModel) MarkAsDeleted(ctx context.Context, conn sqldb.Connection, id string) error {
 	q := `UPDATE product SET deleted_at = $1 WHERE id = $2`
 
@@ -92,3 +105,31 @@ func (p *ProductModel) MarkAsDeleted(ctx context.Context, conn sqldb.Connection,
 	return nil
 
 }
+
+func (p *ProductModel) FindAllByIds(ctx context.Context, conn sqldb.Connection, ids []string) (map[string]*ProductRecord, error) {
+	q := `SELECT id, title, subtitle, description, thumbnail_id, status, is_gift_card, created_at, updated_at, deleted_at FROM product WHERE id = ANY($1)`
+
+	rows, err := conn.QueryContext(ctx, q, pq.Array(ids))
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	productsMap := make(map[string]*ProductRecord)
+
+	for rows.Next() {
+		var product ProductRecord
+
+		err := rows.Scan(&product.Id, &product.Title, &product.Subtitle, &product.Description, &product.ThumbnailId, &product.Status, &product.IsGiftCard, &product.CreatedAt, &product.UpdatedAt, &product.DeletedAt)
+
+		if err != nil {
+			return nil, err
+		}
+
+		productsMap[product.Id] = &product
+	}
+
+	return productsMap, nil
+}
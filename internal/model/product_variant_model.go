@@ -22,6 +22,7 @@ type ProductVariantRecord struct {
 	Width             *float32
 	Height            *float32
 	InventoryQuantity int
+	LowStockThreshold *int // the variant is reported as low on stock when its quantity drops to this value
 	CreatedAt         time.Time
 	UpdatedAt         time.Time
 	DeletedAt         *time.Time
@@ -34,9 +35,9 @@ func NewProductVariantModel() *ProductVariantModel {
 }
 
 func (p *ProductVariantModel) Insert(ctx context.Context, conn sqldb.Connection, variant *ProductVariantRecord) (*ProductVariantRecord, error) {
-	q := `INSERT INTO product_variant (product_id, title, sku, barcode, material, weight, length, width,height, inventory_quantity) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10) RETURNING id, created_at, updated_at`
+	q := `INSERT INTO product_variant (product_id, title, sku, barcode, material, weight, length, width,height) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING id, inventory_quantity, low_stock_threshold, created_at, updated_at`
 
-	err := conn.QueryRowContext(ctx, q, variant.ProductId, variant.Title, variant.Sku, variant.Barcode, variant.Material, variant.Weight, variant.Length, variant.Width, variant.Height, variant.InventoryQuantity).Scan(&variant.Id, &variant.CreatedAt, &variant.UpdatedAt)
+	err := conn.QueryRowContext(ctx, q, variant.ProductId, variant.Title, variant.Sku, variant.Barcode, variant.Material, variant.Weight, variant.Length, variant.Width, variant.Height).Scan(&variant.Id, &variant.InventoryQuantity, &variant.LowStockThreshold, &variant.CreatedAt, &variant.UpdatedAt)
 
 	if err != nil {
 		return nil, err
@@ -46,11 +47,11 @@ func (p *ProductVariantModel) Insert(ctx context.Context, conn sqldb.Connection,
 }
 
 func (p *ProductVariantModel) FindById(ctx context.Context, conn sqldb.Connection, id string) (*ProductVariantRecord, error) {
-	q := `SELECT id, product_id, title, sku, barcode, material, weight, length, width, height, inventory_quantity, created_at, updated_at, deleted_at FROM product_variant WHERE id = $1`
+	q := `SELECT id, product_id, title, sku, barcode, material, weight, length, width, height, inventory_quantity, low_stock_threshold, created_at, updated_at, deleted_at FROM product_variant WHERE id = $1`
 
 	var variant ProductVariantRecord
 
-	err := conn.QueryRowContext(ctx, q, id).Scan(&variant.Id, &variant.ProductId, &variant.Title, &variant.Sku, &variant.Barcode, &variant.Material, &variant.Weight, &variant.Length, &variant.Width, &variant.Height, &variant.InventoryQuantity, &variant.CreatedAt, &variant.UpdatedAt, &variant.DeletedAt)
+	err := conn.QueryRowContext(ctx, q, id).Scan(&variant.Id, &variant.ProductId, &variant.Title, &variant.Sku, &variant.Barcode, &variant.Material, &variant.Weight, &variant.Length, &variant.Width, &variant.Height, &variant.InventoryQuantity, &variant.LowStockThreshold, &variant.CreatedAt, &variant.UpdatedAt, &variant.DeletedAt)
 
 	if err != nil {
 		switch {
@@ -65,12 +66,30 @@ func (p *ProductVariantModel) FindById(ctx context.Context, conn sqldb.Connectio
 	return &variant, nil
 }
 
+// FindByIdForUpdate locks the variant row until the end of the transaction
+func (p *ProductVariantModel) FindByIdForUpdate(ctx context.Context, conn sqldb.Connection, id string) (*ProductVariantRecord, error) {
+	q := `SELECT id, product_id, title, sku, barcode, material, weight, length, width, height, inventory_quantity, low_stock_threshold, created_at, updated_at, deleted_at FROM product_variant WHERE id = $1 FOR UPDATE`
+
+	var variant ProductVariantRecord
+
+	err := conn.QueryRowContext(ctx, q, id).Scan(&variant.Id, &variant.ProductId, &variant.Title, &variant.Sku, &variant.Barcode, &variant.Material, &variant.Weight, &variant.Length, &variant.Width, &variant.Height, &variant.InventoryQuantity, &variant.LowStockThreshold, &variant.CreatedAt, &variant.UpdatedAt, &variant.DeletedAt)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrRecordNotFound
+		}
+		return nil, err
+	}
+
+	return &variant, nil
+}
+
 func (p *ProductVariantModel) Update(ctx context.Context, conn sqldb.Connection, variant *ProductVariantRecord) (*ProductVariantRecord, error) {
-	q := `UPDATE product_variant SET title = $1, sku = $2, barcode = $3, material = $4, weight = $5, length = $6, width = $7, height = $8, inventory_quantity = $9, updated_at = $10 WHERE id = $11`
+	q := `UPDATE product_variant SET title = $1, sku = $2, barcode = $3, material = $4, weight = $5, length = $6, width = $7, height = $8, low_stock_threshold = $9, updated_at = $10 WHERE id = $11`
 
 	variant.UpdatedAt = time.Now()
 
-	_, err := conn.ExecContext(ctx, q, variant.Title, variant.Sku, variant.Barcode, variant.Material, variant.Weight, variant.Length, variant.Width, variant.Height, variant.InventoryQuantity, variant.UpdatedAt, variant.Id)
+	_, err := conn.ExecContext(ctx, q, variant.Title, variant.Sku, variant.Barcode, variant.Material, variant.Weight, variant.Length, variant.Width, variant.Height, variant.LowStockThreshold, variant.UpdatedAt, variant.Id)
 
 	if err != nil {
 		switch {
@@ -86,7 +105,7 @@ func (p *ProductVariantModel) Update(ctx context.Context, conn sqldb.Connection,
 }
 
 func (p *ProductVariantModel) FindAllByProductIds(ctx context.Context, conn sqldb.Connection, productIds []string) (map[string][]*ProductVariantRecord, error) {
-	q := `SELECT id, product_id, title, sku, barcode, material, weight, length, width, height, inventory_quantity, created_at, updated_at, deleted_at FROM product_variant WHERE product_id = ANY($1)`
+	q := `SELECT id, product_id, title, sku, barcode, material, weight, length, width, height, inventory_quantity, low_stock_threshold, created_at, updated_at, deleted_at FROM product_variant WHERE product_id = ANY($1)`
 
 	rows, err := conn.QueryContext(ctx, q, pq.Array(productIds))
 
@@ -101,7 +120,7 @@ func (p *ProductVariantModel) FindAllByProductIds(ctx context.Context, conn sqld
 	for rows.Next() {
 		var variant ProductVariantRecord
 
-		err := rows.Scan(&variant.Id, &variant.ProductId, &variant.Title, &variant.Sku, &variant.Barcode, &variant.Material, &variant.Weight, &variant.Length, &variant.Width, &variant.Height, &variant.InventoryQuantity, &variant.CreatedAt, &variant.UpdatedAt, &variant.DeletedAt)
+		err := rows.Scan(&variant.Id, &variant.ProductId, &variant.Title, &variant.Sku, &variant.Barcode, &variant.Material, &variant.Weight, &variant.Length, &variant.Width, &variant.Height, &variant.InventoryQuantity, &variant.LowStockThreshold, &variant.CreatedAt, &variant.UpdatedAt, &variant.DeletedAt)
 
 		if err != nil {
 			return nil, err
@@ -116,3 +135,102 @@ func (p *ProductVariantModel) FindAllByProductIds(ctx context.Context, conn sqld
 
 	return variantsMap, nil
 }
+
+// FindAllByIdsForUpdate locks the variant rows until the end of the transaction so concurrent
+// checkouts can't sell the same stock twice. Rows are locked in id order to avoid deadlocks.
+func (p *ProductVariantModel) FindAllByIdsForUpdate(ctx context.Context, conn sqldb.Connection, ids []string) (map[string]*ProductVariantRecord, error) {
+	q := `SELECT id, product_id, title, sku, barcode, material, weight, length, width, height, inventory_quantity, low_stock_threshold, created_at, updated_at, deleted_at FROM product_variant WHERE id = ANY($1) ORDER BY id FOR UPDATE`
+
+	rows, err := conn.QueryContext(ctx, q, pq.Array(ids))
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	variantsMap := make(map[string]*ProductVariantRecord)
+
+	for rows.Next() {
+		var variant ProductVariantRecord
+
+		err := rows.Scan(&variant.Id, &variant.ProductId, &variant.Title, &variant.Sku, &variant.Barcode, &variant.Material, &variant.Weight, &variant.Length, &variant.Width, &variant.Height, &variant.InventoryQuantity, &variant.LowStockThreshold, &variant.CreatedAt, &variant.UpdatedAt, &variant.DeletedAt)
+
+		if err != nil {
+			return nil, err
+		}
+
+		variantsMap[variant.Id] = &variant
+	}
+
+	return variantsMap, nil
+}
+
+// SyncInventoryQuantity recomputes the cached inventory quantity of the variant from its stock movements
+// and returns the new value. It fails with ErrInsufficientInventory when the ledger would go negative.
+func (p *ProductVariantModel) SyncInventoryQuantity(ctx context.Context, conn sqldb.Connection, id string) (int, error) {
+	q := `UPDATE product_variant SET inventory_quantity = (SELECT COALESCE(SUM(quantity), 0) FROM stock_movement WHERE variant_id = $1), updated_at = $2
+		  WHERE id = $1 RETURNING inventory_quantity`
+
+	var quantity int
+
+	err := conn.QueryRowContext(ctx, q, id, time.Now()).Scan(&quantity)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, ErrRecordNotFound
+		}
+		return 0, err
+	}
+
+	if quantity < 0 {
+		return 0, ErrInsufficientInventory
+	}
+
+	return quantity, nil
+}
+
+func (p *ProductVariantModel) CountUnderThreshold(ctx context.Context, conn sqldb.Connection) (int, error) {
+	q := `SELECT COUNT(*) FROM product_variant WHERE deleted_at IS NULL AND low_stock_threshold IS NOT NULL AND inventory_quantity <= low_stock_threshold`
+
+	var count int
+
+	err := conn.QueryRowContext(ctx, q).Scan(&count)
+
+	if err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+// FindAllUnderThreshold returns the variants whose quantity dropped to their low stock threshold, emptiest first
+func (p *ProductVariantModel) FindAllUnderThreshold(ctx context.Context, conn sqldb.Connection, limit uint, offset uint) ([]*ProductVariantRecord, error) {
+	q := `SELECT id, product_id, title, sku, barcode, material, weight, length, width, height, inventory_quantity, low_stock_threshold, created_at, updated_at, deleted_at FROM product_variant
+		  WHERE deleted_at IS NULL AND low_stock_threshold IS NOT NULL AND inventory_quantity <= low_stock_threshold
+		  ORDER BY inventory_quantity - low_stock_threshold, id LIMIT $1 OFFSET $2`
+
+	rows, err := conn.QueryContext(ctx, q, limit, offset)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	variants := []*ProductVariantRecord{}
+
+	for rows.Next() {
+		var variant ProductVariantRecord
+
+		err := rows.Scan(&variant.Id, &variant.ProductId, &variant.Title, &variant.Sku, &variant.Barcode, &variant.Material, &variant.Weight, &variant.Length, &variant.Width, &variant.Height, &variant.InventoryQuantity, &variant.LowStockThreshold, &variant.CreatedAt, &variant.UpdatedAt, &variant.DeletedAt)
+
+		if err != nil {
+			return nil, err
+		}
+
+		variants = append(variants, &variant)
+	}
+
+	return variants, nil
+}
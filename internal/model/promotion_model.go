@@ -0,0 +1,207 @@
+package model
+
+import (
+	"context"
+	"database/sql"
+	"ecom-backend/pkg/sqldb"
+	"errors"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type PromotionRecord struct {
+	Id                    string     `json:"id"`
+	Code                  *string    `json:"code"` // nil for automatic promotions
+	Description           *string    `json:"description"`
+	Type                  string     `json:"type"`
+	Percentage            *float32   `json:"percentage"`
+	BuyQuantity           *int       `json:"buy_quantity"`
+	GetQuantity           *int       `json:"get_quantity"`
+	IsAutomatic           bool       `json:"is_automatic"`
+	Priority              int        `json:"priority"`
+	IsCombinable          bool       `json:"is_combinable"`
+	UsageLimit            *int       `json:"usage_limit"`
+	UsageLimitPerCustomer *int       `json:"usage_limit_per_customer"`
+	UsageCount            int        `json:"usage_count"` // only kept on the row of the promotions with a usage limit, counted from the redemptions of the others
+	StartsAt              *time.Time `json:"starts_at"`
+	EndsAt                *time.Time `json:"ends_at"`
+	IsActive              bool       `json:"is_active"`
+	CreatedAt             time.Time  `json:"created_at"`
+	UpdatedAt             time.Time  `json:"updated_at"`
+	DeletedAt             *time.Time `json:"-"`
+}
+
+type PromotionModel struct{}
+
+func NewPromotionModel() *PromotionModel {
+	return &PromotionModel{}
+}
+
+const promotionColumns = `id, code, description, type, percentage, buy_quantity, get_quantity, is_automatic, priority, is_combinable, usage_limit, usage_limit_per_customer,
+	CASE WHEN usage_limit IS NULL THEN (SELECT COUNT(*) FROM promotion_redemption AS pr WHERE pr.promotion_id = promotion.id) ELSE usage_count END,
+	starts_at, ends_at, is_active, created_at, updated_at, deleted_at`
+
+func scanPromotion(row interface{ Scan(...any) error }, promotion *PromotionRecord) error {
+	return row.Scan(&promotion.Id, &promotion.Code, &promotion.Description, &promotion.Type, &promotion.Percentage, &promotion.BuyQuantity, &promotion.GetQuantity,
+		&promotion.IsAutomatic, &promotion.Priority, &promotion.IsCombinable, &promotion.UsageLimit, &promotion.UsageLimitPerCustomer, &promotion.UsageCount,
+		&promotion.StartsAt, &promotion.EndsAt, &promotion.IsActive, &promotion.CreatedAt, &promotion.UpdatedAt, &promotion.DeletedAt)
+}
+
+func (m *PromotionModel) Insert(ctx context.Context, conn sqldb.Connection, promotion *PromotionRecord) (*PromotionRecord, error) {
+	q := `INSERT INTO promotion (code, description, type, percentage, buy_quantity, get_quantity, is_automatic, priority, is_combinable, usage_limit, usage_limit_per_customer,
+		  starts_at, ends_at, is_active)
+		  VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14) RETURNING id, usage_count, created_at, updated_at`
+
+	err := conn.QueryRowContext(ctx, q, promotion.Code, promotion.Description, promotion.Type, promotion.Percentage, promotion.BuyQuantity, promotion.GetQuantity,
+		promotion.IsAutomatic, promotion.Priority, promotion.IsCombinable, promotion.UsageLimit, promotion.UsageLimitPerCustomer, promotion.StartsAt, promotion.EndsAt, promotion.IsActive).Scan(&promotion.Id, &promotion.UsageCount, &promotion.CreatedAt, &promotion.UpdatedAt)
+
+	if err != nil {
+		if err.Error() == `pq: duplicate key value violates unique constraint "promotion_code_key"` {
+			return nil, ErrDuplicatedPromotionCode
+		}
+		return nil, err
+	}
+
+	return promotion, nil
+}
+
+func (m *PromotionModel) FindById(ctx context.Context, conn sqldb.Connection, id string) (*PromotionRecord, error) {
+	q := `SELECT ` + promotionColumns + ` FROM promotion WHERE id = $1 AND deleted_at IS NULL`
+
+	return m.findOne(ctx, conn, q, id)
+}
+
+// FindByCode looks the code up case insensitively
+func (m *PromotionModel) FindByCode(ctx context.Context, conn sqldb.Connection, code string) (*PromotionRecord, error) {
+	q := `SELECT ` + promotionColumns + ` FROM promotion WHERE code = $1 AND deleted_at IS NULL`
+
+	return m.findOne(ctx, conn, q, code)
+}
+
+// FindAllAutomatic returns the active automatic promotions in evaluation order, the ones out of their
+// date range are included so the evaluation can explain why they didn't apply
+func (m *PromotionModel) FindAllAutomatic(ctx context.Context, conn sqldb.Connection) ([]*PromotionRecord, error) {
+	q := `SELECT ` + promotionColumns + ` FROM promotion WHERE is_automatic AND is_active AND deleted_at IS NULL ORDER BY priority, created_at`
+
+	return m.findAll(ctx, conn, q)
+}
+
+// FindAllByIdsForUpdate locks the promotion rows until the end of the transaction, redemptions of the same promotion
+// are serialized on this lock so the usage limits can't be exceeded by concurrent checkouts. The rows are locked in id
+// order so concurrent checkouts always take the locks in the same order.
+func (m *PromotionModel) FindAllByIdsForUpdate(ctx context.Context, conn sqldb.Connection, ids []string) ([]*PromotionRecord, error) {
+	q := `SELECT ` + promotionColumns + ` FROM promotion WHERE id = ANY($1) AND deleted_at IS NULL ORDER BY id FOR UPDATE`
+
+	return m.findAll(ctx, conn, q, pq.Array(ids))
+}
+
+func (m *PromotionModel) findOne(ctx context.Context, conn sqldb.Connection, q string, args ...any) (*PromotionRecord, error) {
+	var promotion PromotionRecord
+
+	err := scanPromotion(conn.QueryRowContext(ctx, q, args...), &promotion)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrRecordNotFound
+		}
+		return nil, err
+	}
+
+	return &promotion, nil
+}
+
+func (m *PromotionModel) Count(ctx context.Context, conn sqldb.Connection) (int, error) {
+	q := `SELECT COUNT(*) FROM promotion WHERE deleted_at IS NULL`
+
+	var count int
+
+	err := conn.QueryRowContext(ctx, q).Scan(&count)
+
+	if err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+func (m *PromotionModel) FindAll(ctx context.Context, conn sqldb.Connection, limit uint, offset uint) ([]*PromotionRecord, error) {
+	q := `SELECT ` + promotionColumns + ` FROM promotion WHERE deleted_at IS NULL ORDER BY created_at DESC LIMIT $1 OFFSET $2`
+
+	return m.findAll(ctx, conn, q, limit, offset)
+}
+
+func (m *PromotionModel) findAll(ctx context.Context, conn sqldb.Connection, q string, args ...any) ([]*PromotionRecord, error) {
+	rows, err := conn.QueryContext(ctx, q, args...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	promotions := []*PromotionRecord{}
+
+	for rows.Next() {
+		var promotion PromotionRecord
+
+		err := scanPromotion(rows, &promotion)
+
+		if err != nil {
+			return nil, err
+		}
+
+		promotions = append(promotions, &promotion)
+	}
+
+	return promotions, nil
+}
+
+func (m *PromotionModel) Update(ctx context.Context, conn sqldb.Connection, promotion *PromotionRecord) (*PromotionRecord, error) {
+	// the usage count starts from the redemptions once a usage limit is set, it's only kept up to date for limited promotions
+	q := `UPDATE promotion SET description = $1, percentage = $2, buy_quantity = $3, get_quantity = $4, priority = $5, is_combinable = $6, usage_limit = $7,
+		  usage_limit_per_customer = $8, starts_at = $9, ends_at = $10, is_active = $11, updated_at = $12,
+		  usage_count = CASE WHEN usage_limit IS NULL AND $7::int IS NOT NULL THEN (SELECT COUNT(*) FROM promotion_redemption AS pr WHERE pr.promotion_id = promotion.id) ELSE usage_count END
+		  WHERE id = $13 AND deleted_at IS NULL`
+
+	promotion.UpdatedAt = time.Now()
+
+	res, err := conn.ExecContext(ctx, q, promotion.Description, promotion.Percentage, promotion.BuyQuantity, promotion.GetQuantity, promotion.Priority, promotion.IsCombinable,
+		promotion.UsageLimit, promotion.UsageLimitPerCustomer, promotion.StartsAt, promotion.EndsAt, promotion.IsActive, promotion.UpdatedAt, promotion.Id)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if rowsAff, _ := res.RowsAffected(); rowsAff == 0 {
+		return nil, ErrRecordNotFound
+	}
+
+	return promotion, nil
+}
+
+// IncrementUsage counts a use of the promotion when it has a usage limit, the others aren't updated so the checkouts
+// don't wait on each other for their row
+func (m *PromotionModel) IncrementUsage(ctx context.Context, conn sqldb.Connection, id string) error {
+	q := `UPDATE promotion SET usage_count = usage_count + 1, updated_at = $1 WHERE id = $2 AND usage_limit IS NOT NULL`
+
+	_, err := conn.ExecContext(ctx, q, time.Now(), id)
+
+	return err
+}
+
+func (m *PromotionModel) SoftDelete(ctx context.Context, conn sqldb.Connection, id string) error {
+	q := `UPDATE promotion SET deleted_at = $1 WHERE id = $2 AND deleted_at IS NULL`
+
+	res, err := conn.ExecContext(ctx, q, time.Now(), id)
+
+	if err != nil {
+		return err
+	}
+
+	if rowsAff, _ := res.RowsAffected(); rowsAff == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,53 @@
+package model
+
+import (
+	"context"
+	"ecom-backend/internal/money"
+	"ecom-backend/pkg/sqldb"
+	"time"
+)
+
+type PromotionRedemptionRecord struct {
+	Id             string      `json:"id"`
+	PromotionId    string      `json:"promotion_id"`
+	OrderId        string      `json:"order_id"`
+	UserIdentifier string      `json:"-"`
+	Email          string      `json:"email"`
+	CurrencyCode   string      `json:"currency_code"`
+	Amount         money.Money `json:"amount"`
+	CreatedAt      time.Time   `json:"created_at"`
+}
+
+type PromotionRedemptionModel struct{}
+
+func NewPromotionRedemptionModel() *PromotionRedemptionModel {
+	return &PromotionRedemptionModel{}
+}
+
+func (m *PromotionRedemptionModel) Insert(ctx context.Context, conn sqldb.Connection, record *PromotionRedemptionRecord) (*PromotionRedemptionRecord, error) {
+	q := `INSERT INTO promotion_redemption (promotion_id, order_id, user_identifier, email, currency_code, amount) VALUES ($1, $2, $3, $4, $5, $6) RETURNING id, created_at`
+
+	err := conn.QueryRowContext(ctx, q, record.PromotionId, record.OrderId, record.UserIdentifier, record.Email, record.CurrencyCode, record.Amount).Scan(&record.Id, &record.CreatedAt)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return record, nil
+}
+
+// CountByCustomer counts the redemptions of the promotion made by the client or with the given email,
+// so guests can't get around the per customer limit by starting a new session
+func (m *PromotionRedemptionModel) CountByCustomer(ctx context.Context, conn sqldb.Connection, promotionId string, userIdentifier string, email *string) (int, error) {
+	q := `SELECT COUNT(*) FROM promotion_redemption WHERE promotion_id = $1 AND (user_identifier = $2 OR email = $3)`
+
+	var count int
+
+	err := conn.QueryRowContext(ctx, q, promotionId, userIdentifier, email).Scan(&count)
+
+	if err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
@@ -0,0 +1,263 @@
+package model
+
+import (
+	"context"
+	"ecom-backend/internal/money"
+	"ecom-backend/pkg/sqldb"
+
+	"github.com/lib/pq"
+)
+
+type PromotionCurrencyRuleRecord struct {
+	PromotionId  string       `json:"-"`
+	CurrencyCode string       `json:"currency_code"`
+	Amount       *money.Money `json:"amount"`       // amount taken off by fixed promotions
+	MinSubtotal  *money.Money `json:"min_subtotal"` // minimum cart subtotal for the promotion to apply
+}
+
+type PromotionTargetRecord struct {
+	PromotionId string `json:"-"`
+	TargetType  string `json:"target_type"`
+	TargetId    string `json:"target_id"`
+}
+
+type PromotionTierRecord struct {
+	PromotionId string  `json:"-"`
+	MinQuantity int     `json:"min_quantity"` // quantity of eligible units needed to reach the tier
+	Percentage  float32 `json:"percentage"`
+}
+
+type PromotionCurrencyRuleModel struct{}
+
+func NewPromotionCurrencyRuleModel() *PromotionCurrencyRuleModel {
+	return &PromotionCurrencyRuleModel{}
+}
+
+func (m *PromotionCurrencyRuleModel) Insert(ctx context.Context, conn sqldb.Connection, record *PromotionCurrencyRuleRecord) (*PromotionCurrencyRuleRecord, error) {
+	q := `INSERT INTO promotion_currency_rule (promotion_id, currency_code, amount, min_subtotal) VALUES ($1, $2, $3, $4)`
+
+	_, err := conn.ExecContext(ctx, q, record.PromotionId, record.CurrencyCode, record.Amount, record.MinSubtotal)
+
+	if err != nil {
+		switch {
+		case err.Error() == `pq: insert or update on table "promotion_currency_rule" violates foreign key constraint "promotion_currency_rule_currency_code_fkey"`:
+			return nil, ErrInvalidValue
+		case err.Error() == `pq: duplicate key value violates unique constraint "promotion_currency_rule_pkey"`:
+			return nil, ErrInvalidValue
+		default:
+			return nil, err
+		}
+	}
+
+	return record, nil
+}
+
+func (m *PromotionCurrencyRuleModel) FindAllByPromotionIds(ctx context.Context, conn sqldb.Connection, promotionIds []string) (map[string][]*PromotionCurrencyRuleRecord, error) {
+	q := `SELECT promotion_id, currency_code, amount, min_subtotal FROM promotion_currency_rule WHERE promotion_id = ANY($1) ORDER BY currency_code`
+
+	rows, err := conn.QueryContext(ctx, q, pq.Array(promotionIds))
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	resultMap := make(map[string][]*PromotionCurrencyRuleRecord)
+
+	for rows.Next() {
+		var record PromotionCurrencyRuleRecord
+
+		err := rows.Scan(&record.PromotionId, &record.CurrencyCode, &record.Amount, &record.MinSubtotal)
+
+		if err != nil {
+			return nil, err
+		}
+
+		money.SetCurrency(record.CurrencyCode, record.Amount, record.MinSubtotal)
+
+		resultMap[record.PromotionId] = append(resultMap[record.PromotionId], &record)
+	}
+
+	return resultMap, nil
+}
+
+func (m *PromotionCurrencyRuleModel) DeleteAllByPromotionId(ctx context.Context, conn sqldb.Connection, promotionId string) error {
+	q := `DELETE FROM promotion_currency_rule WHERE promotion_id = $1`
+
+	_, err := conn.ExecContext(ctx, q, promotionId)
+
+	return err
+}
+
+type PromotionTargetModel struct{}
+
+func NewPromotionTargetModel() *PromotionTargetModel {
+	return &PromotionTargetModel{}
+}
+
+func (m *PromotionTargetModel) Insert(ctx context.Context, conn sqldb.Connection, record *PromotionTargetRecord) (*PromotionTargetRecord, error) {
+	q := `INSERT INTO promotion_target (promotion_id, target_type, target_id) VALUES ($1, $2, $3) ON CONFLICT DO NOTHING`
+
+	_, err := conn.ExecContext(ctx, q, record.PromotionId, record.TargetType, record.TargetId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return record, nil
+}
+
+func (m *PromotionTargetModel) FindAllByPromotionIds(ctx context.Context, conn sqldb.Connection, promotionIds []string) (map[string][]*PromotionTargetRecord, error) {
+	q := `SELECT promotion_id, target_type, target_id FROM promotion_target WHERE promotion_id = ANY($1)`
+
+	rows, err := conn.QueryContext(ctx, q, pq.Array(promotionIds))
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	resultMap := make(map[string][]*PromotionTargetRecord)
+
+	for rows.Next() {
+		var record PromotionTargetRecord
+
+		err := rows.Scan(&record.PromotionId, &record.TargetType, &record.TargetId)
+
+		if err != nil {
+			return nil, err
+		}
+
+		resultMap[record.PromotionId] = append(resultMap[record.PromotionId], &record)
+	}
+
+	return resultMap, nil
+}
+
+func (m *PromotionTargetModel) DeleteAllByPromotionId(ctx context.Context, conn sqldb.Connection, promotionId string) error {
+	q := `DELETE FROM promotion_target WHERE promotion_id = $1`
+
+	_, err := conn.ExecContext(ctx, q, promotionId)
+
+	return err
+}
+
+type PromotionTierModel struct{}
+
+func NewPromotionTierModel() *PromotionTierModel {
+	return &PromotionTierModel{}
+}
+
+func (m *PromotionTierModel) Insert(ctx context.Context, conn sqldb.Connection, record *PromotionTierRecord) (*PromotionTierRecord, error) {
+	q := `INSERT INTO promotion_tier (promotion_id, min_quantity, percentage) VALUES ($1, $2, $3)`
+
+	_, err := conn.ExecContext(ctx, q, record.PromotionId, record.MinQuantity, record.Percentage)
+
+	if err != nil {
+		if err.Error() == `pq: duplicate key value violates unique constraint "promotion_tier_pkey"` {
+			return nil, ErrInvalidValue
+		}
+		return nil, err
+	}
+
+	return record, nil
+}
+
+// FindAllByPromotionIds returns the tiers of each promotion from the lowest to the highest
+func (m *PromotionTierModel) FindAllByPromotionIds(ctx context.Context, conn sqldb.Connection, promotionIds []string) (map[string][]*PromotionTierRecord, error) {
+	q := `SELECT promotion_id, min_quantity, percentage FROM promotion_tier WHERE promotion_id = ANY($1) ORDER BY min_quantity`
+
+	rows, err := conn.QueryContext(ctx, q, pq.Array(promotionIds))
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	resultMap := make(map[string][]*PromotionTierRecord)
+
+	for rows.Next() {
+		var record PromotionTierRecord
+
+		err := rows.Scan(&record.PromotionId, &record.MinQuantity, &record.Percentage)
+
+		if err != nil {
+			return nil, err
+		}
+
+		resultMap[record.PromotionId] = append(resultMap[record.PromotionId], &record)
+	}
+
+	return resultMap, nil
+}
+
+func (m *PromotionTierModel) DeleteAllByPromotionId(ctx context.Context, conn sqldb.Connection, promotionId string) error {
+	q := `DELETE FROM promotion_tier WHERE promotion_id = $1`
+
+	_, err := conn.ExecContext(ctx, q, promotionId)
+
+	return err
+}
+
+// PromotionCustomerGroupModel restricts promotions to the members of customer groups, a promotion without groups
+// applies to everyone
+type PromotionCustomerGroupModel struct{}
+
+func NewPromotionCustomerGroupModel() *PromotionCustomerGroupModel {
+	return &PromotionCustomerGroupModel{}
+}
+
+func (m *PromotionCustomerGroupModel) Insert(ctx context.Context, conn sqldb.Connection, promotionId string, groupId string) error {
+	q := `INSERT INTO promotion_customer_group (promotion_id, customer_group_id) VALUES ($1, $2) ON CONFLICT DO NOTHING`
+
+	_, err := conn.ExecContext(ctx, q, promotionId, groupId)
+
+	if err != nil {
+		if err.Error() == `pq: insert or update on table "promotion_customer_group" violates foreign key constraint "promotion_customer_group_customer_group_id_fkey"` {
+			return ErrCustomerGroupNotFound
+		}
+		return err
+	}
+
+	return nil
+}
+
+// FindAllByPromotionIds returns the ids of the groups of each promotion
+func (m *PromotionCustomerGroupModel) FindAllByPromotionIds(ctx context.Context, conn sqldb.Connection, promotionIds []string) (map[string][]string, error) {
+	q := `SELECT promotion_id, customer_group_id FROM promotion_customer_group WHERE promotion_id = ANY($1) ORDER BY customer_group_id`
+
+	rows, err := conn.QueryContext(ctx, q, pq.Array(promotionIds))
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	resultMap := make(map[string][]string)
+
+	for rows.Next() {
+		var promotionId, groupId string
+
+		err := rows.Scan(&promotionId, &groupId)
+
+		if err != nil {
+			return nil, err
+		}
+
+		resultMap[promotionId] = append(resultMap[promotionId], groupId)
+	}
+
+	return resultMap, nil
+}
+
+func (m *PromotionCustomerGroupModel) DeleteAllByPromotionId(ctx context.Context, conn sqldb.Connection, promotionId string) error {
+	q := `DELETE FROM promotion_customer_group WHERE promotion_id = $1`
+
+	_, err := conn.ExecContext(ctx, q, promotionId)
+
+	return err
+}
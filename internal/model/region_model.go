@@ -0,0 +1,205 @@
+package model
+
+import (
+	"context"
+	"database/sql"
+	"ecom-backend/pkg/sqldb"
+	"errors"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type RegionRecord struct {
+	Id             string    `json:"id"`
+	Name           string    `json:"name"`
+	CurrencyCode   string    `json:"currency_code"`
+	AutomaticTaxes bool      `json:"automatic_taxes"` // the cart estimates the taxes, otherwise they're only worked out at checkout
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+type RegionModel struct{}
+
+func NewRegionModel() *RegionModel {
+	return &RegionModel{}
+}
+
+const regionColumns = `id, name, currency_code, automatic_taxes, created_at, updated_at`
+
+func scanRegion(row interface{ Scan(...any) error }, region *RegionRecord) error {
+	return row.Scan(&region.Id, &region.Name, &region.CurrencyCode, &region.AutomaticTaxes, &region.CreatedAt, &region.UpdatedAt)
+}
+
+func (m *RegionModel) Insert(ctx context.Context, conn sqldb.Connection, region *RegionRecord) (*RegionRecord, error) {
+	q := `INSERT INTO region (name, currency_code, automatic_taxes) VALUES ($1, $2, $3) RETURNING id, created_at, updated_at`
+
+	err := conn.QueryRowContext(ctx, q, region.Name, region.CurrencyCode, region.AutomaticTaxes).Scan(&region.Id, &region.CreatedAt, &region.UpdatedAt)
+
+	if err != nil {
+		if err.Error() == `pq: insert or update on table "region" violates foreign key constraint "region_currency_code_fkey"` {
+			return nil, ErrCurrencyNotFound
+		}
+		return nil, err
+	}
+
+	return region, nil
+}
+
+func (m *RegionModel) FindById(ctx context.Context, conn sqldb.Connection, id string) (*RegionRecord, error) {
+	q := `SELECT ` + regionColumns + ` FROM region WHERE id = $1`
+
+	var region RegionRecord
+
+	err := scanRegion(conn.QueryRowContext(ctx, q, id), &region)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrRecordNotFound
+		}
+		return nil, err
+	}
+
+	return &region, nil
+}
+
+func (m *RegionModel) FindAll(ctx context.Context, conn sqldb.Connection) ([]*RegionRecord, error) {
+	q := `SELECT ` + regionColumns + ` FROM region ORDER BY name`
+
+	rows, err := conn.QueryContext(ctx, q)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	regions := []*RegionRecord{}
+
+	for rows.Next() {
+		var region RegionRecord
+
+		err := scanRegion(rows, &region)
+
+		if err != nil {
+			return nil, err
+		}
+
+		regions = append(regions, &region)
+	}
+
+	return regions, nil
+}
+
+func (m *RegionModel) Update(ctx context.Context, conn sqldb.Connection, region *RegionRecord) (*RegionRecord, error) {
+	q := `UPDATE region SET name = $1, currency_code = $2, automatic_taxes = $3, updated_at = $4 WHERE id = $5`
+
+	region.UpdatedAt = time.Now()
+
+	res, err := conn.ExecContext(ctx, q, region.Name, region.CurrencyCode, region.AutomaticTaxes, region.UpdatedAt, region.Id)
+
+	if err != nil {
+		if err.Error() == `pq: insert or update on table "region" violates foreign key constraint "region_currency_code_fkey"` {
+			return nil, ErrCurrencyNotFound
+		}
+		return nil, err
+	}
+
+	if rowsAff, _ := res.RowsAffected(); rowsAff == 0 {
+		return nil, ErrRecordNotFound
+	}
+
+	return region, nil
+}
+
+// Delete removes the region, the orders placed in it keep their currency but lose the link to it
+func (m *RegionModel) Delete(ctx context.Context, conn sqldb.Connection, id string) error {
+	q := `DELETE FROM region WHERE id = $1`
+
+	res, err := conn.ExecContext(ctx, q, id)
+
+	if err != nil {
+		return err
+	}
+
+	if rowsAff, _ := res.RowsAffected(); rowsAff == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// ReplaceCountries swaps the countries of the region
+func (m *RegionModel) ReplaceCountries(ctx context.Context, conn sqldb.Connection, regionId string, countryCodes []string) error {
+	_, err := conn.ExecContext(ctx, `DELETE FROM region_country WHERE region_id = $1`, regionId)
+
+	if err != nil {
+		return err
+	}
+
+	q := `INSERT INTO region_country (region_id, country_code) SELECT $1, unnest($2::text[])`
+
+	_, err = conn.ExecContext(ctx, q, regionId, pq.Array(countryCodes))
+
+	if err != nil {
+		if err.Error() == `pq: duplicate key value violates unique constraint "region_country_code_key"` {
+			return ErrCountryInOtherRegion
+		}
+		return err
+	}
+
+	return nil
+}
+
+func (m *RegionModel) FindCountriesByRegionIds(ctx context.Context, conn sqldb.Connection, regionIds []string) (map[string][]string, error) {
+	q := `SELECT region_id, country_code FROM region_country WHERE region_id = ANY($1) ORDER BY country_code`
+
+	return m.findValuesByRegionIds(ctx, conn, q, regionIds)
+}
+
+// ReplacePaymentProviders swaps the payment providers allowed in the region
+func (m *RegionModel) ReplacePaymentProviders(ctx context.Context, conn sqldb.Connection, regionId string, providerIds []string) error {
+	_, err := conn.ExecContext(ctx, `DELETE FROM region_payment_provider WHERE region_id = $1`, regionId)
+
+	if err != nil {
+		return err
+	}
+
+	q := `INSERT INTO region_payment_provider (region_id, provider_id) SELECT $1, unnest($2::text[])`
+
+	_, err = conn.ExecContext(ctx, q, regionId, pq.Array(providerIds))
+
+	return err
+}
+
+func (m *RegionModel) FindPaymentProvidersByRegionIds(ctx context.Context, conn sqldb.Connection, regionIds []string) (map[string][]string, error) {
+	q := `SELECT region_id, provider_id FROM region_payment_provider WHERE region_id = ANY($1) ORDER BY provider_id`
+
+	return m.findValuesByRegionIds(ctx, conn, q, regionIds)
+}
+
+func (m *RegionModel) findValuesByRegionIds(ctx context.Context, conn sqldb.Connection, q string, regionIds []string) (map[string][]string, error) {
+	rows, err := conn.QueryContext(ctx, q, pq.Array(regionIds))
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	resultMap := make(map[string][]string)
+
+	for rows.Next() {
+		var regionId, value string
+
+		err := rows.Scan(&regionId, &value)
+
+		if err != nil {
+			return nil, err
+		}
+
+		resultMap[regionId] = append(resultMap[regionId], value)
+	}
+
+	return resultMap, nil
+}
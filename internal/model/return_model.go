@@ -0,0 +1,372 @@
+package model
+
+import (
+	"context"
+	"database/sql"
+	"ecom-backend/internal/money"
+	"ecom-backend/pkg/sqldb"
+	"errors"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type ReturnRecord struct {
+	Id             string
+	OrderId        string
+	CurrencyCode   string // the currency of the order
+	Status         string
+	Note           *string // from the customer
+	LocationId     *string // where the returned items were received
+	ShippingRefund money.Money
+	RefundTotal    money.Money // the refunded lines plus the shipping refund
+	PaymentRefund  money.Money // the part of the refund total refunded by the payment provider
+	ApprovedAt     *time.Time
+	RejectedAt     *time.Time
+	ReceivedAt     *time.Time
+	RefundedAt     *time.Time
+	CancelledAt    *time.Time
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+}
+
+type ReturnModel struct{}
+
+func NewReturnModel() *ReturnModel {
+	return &ReturnModel{}
+}
+
+const returnColumns = `r.id, r.order_id, o.currency_code, r.status, r.note, r.location_id, r.shipping_refund, r.refund_total, r.payment_refund, r.approved_at, r.rejected_at, r.received_at,
+	r.refunded_at, r.cancelled_at, r.created_at, r.updated_at`
+
+func scanReturn(row interface{ Scan(...any) error }, record *ReturnRecord) error {
+	err := row.Scan(&record.Id, &record.OrderId, &record.CurrencyCode, &record.Status, &record.Note, &record.LocationId, &record.ShippingRefund, &record.RefundTotal, &record.PaymentRefund, &record.ApprovedAt,
+		&record.RejectedAt, &record.ReceivedAt, &record.RefundedAt, &record.CancelledAt, &record.CreatedAt, &record.UpdatedAt)
+
+	if err != nil {
+		return err
+	}
+
+	money.SetCurrency(record.CurrencyCode, &record.ShippingRefund, &record.RefundTotal, &record.PaymentRefund)
+
+	return nil
+}
+
+func (m *ReturnModel) Insert(ctx context.Context, conn sqldb.Connection, record *ReturnRecord) (*ReturnRecord, error) {
+	q := `INSERT INTO order_return (order_id, note) VALUES ($1, $2) RETURNING id, status, shipping_refund, refund_total, payment_refund, created_at, updated_at`
+
+	err := conn.QueryRowContext(ctx, q, record.OrderId, record.Note).Scan(&record.Id, &record.Status, &record.ShippingRefund, &record.RefundTotal, &record.PaymentRefund, &record.CreatedAt, &record.UpdatedAt)
+
+	if err != nil {
+		return nil, err
+	}
+
+	money.SetCurrency(record.CurrencyCode, &record.ShippingRefund, &record.RefundTotal, &record.PaymentRefund)
+
+	return record, nil
+}
+
+func (m *ReturnModel) FindById(ctx context.Context, conn sqldb.Connection, id string) (*ReturnRecord, error) {
+	q := `SELECT ` + returnColumns + ` FROM order_return AS r INNER JOIN orders AS o ON o.id = r.order_id WHERE r.id = $1`
+
+	return m.findOne(ctx, conn, q, id)
+}
+
+// FindByIdForUpdate locks the return until the end of the transaction
+func (m *ReturnModel) FindByIdForUpdate(ctx context.Context, conn sqldb.Connection, id string) (*ReturnRecord, error) {
+	q := `SELECT ` + returnColumns + ` FROM order_return AS r INNER JOIN orders AS o ON o.id = r.order_id WHERE r.id = $1 FOR UPDATE OF r`
+
+	return m.findOne(ctx, conn, q, id)
+}
+
+func (m *ReturnModel) findOne(ctx context.Context, conn sqldb.Connection, q string, args ...any) (*ReturnRecord, error) {
+	var record ReturnRecord
+
+	err := scanReturn(conn.QueryRowContext(ctx, q, args...), &record)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrRecordNotFound
+		}
+		return nil, err
+	}
+
+	return &record, nil
+}
+
+func (m *ReturnModel) FindAllByOrderId(ctx context.Context, conn sqldb.Connection, orderId string) ([]*ReturnRecord, error) {
+	q := `SELECT ` + returnColumns + ` FROM order_return AS r INNER JOIN orders AS o ON o.id = r.order_id WHERE r.order_id = $1 ORDER BY r.created_at`
+
+	return m.findMany(ctx, conn, q, orderId)
+}
+
+// Count returns the number of returns, only the ones in the status when one is given
+func (m *ReturnModel) Count(ctx context.Context, conn sqldb.Connection, status *string) (int, error) {
+	q := `SELECT COUNT(*) FROM order_return WHERE $1::return_status IS NULL OR status = $1`
+
+	var count int
+
+	err := conn.QueryRowContext(ctx, q, status).Scan(&count)
+
+	if err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+// FindAll returns the returns from the latest, only the ones in the status when one is given
+func (m *ReturnModel) FindAll(ctx context.Context, conn sqldb.Connection, status *string, limit uint, offset uint) ([]*ReturnRecord, error) {
+	q := `SELECT ` + returnColumns + ` FROM order_return AS r INNER JOIN orders AS o ON o.id = r.order_id
+		  WHERE $1::return_status IS NULL OR r.status = $1
+		  ORDER BY r.created_at DESC LIMIT $2 OFFSET $3`
+
+	return m.findMany(ctx, conn, q, status, limit, offset)
+}
+
+func (m *ReturnModel) findMany(ctx context.Context, conn sqldb.Connection, q string, args ...any) ([]*ReturnRecord, error) {
+	rows, err := conn.QueryContext(ctx, q, args...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	records := []*ReturnRecord{}
+
+	for rows.Next() {
+		var record ReturnRecord
+
+		err := scanReturn(rows, &record)
+
+		if err != nil {
+			return nil, err
+		}
+
+		records = append(records, &record)
+	}
+
+	return records, nil
+}
+
+func (m *ReturnModel) Update(ctx context.Context, conn sqldb.Connection, record *ReturnRecord) (*ReturnRecord, error) {
+	q := `UPDATE order_return SET status = $1, location_id = $2, shipping_refund = $3, refund_total = $4, payment_refund = $5, approved_at = $6, rejected_at = $7, received_at = $8,
+		  refunded_at = $9, cancelled_at = $10, updated_at = $11 WHERE id = $12`
+
+	record.UpdatedAt = time.Now()
+
+	res, err := conn.ExecContext(ctx, q, record.Status, record.LocationId, record.ShippingRefund, record.RefundTotal, record.PaymentRefund, record.ApprovedAt, record.RejectedAt, record.ReceivedAt,
+		record.RefundedAt, record.CancelledAt, record.UpdatedAt, record.Id)
+
+	if err != nil {
+		if err.Error() == `pq: insert or update on table "order_return" violates foreign key constraint "order_return_location_id_fkey"` {
+			return nil, ErrStockLocationNotFound
+		}
+		return nil, err
+	}
+
+	if rowsAff, _ := res.RowsAffected(); rowsAff == 0 {
+		return nil, ErrRecordNotFound
+	}
+
+	return record, nil
+}
+
+// SumShippingRefundByOrderId returns the shipping already refunded by the returns of the order, in minor units
+func (m *ReturnModel) SumShippingRefundByOrderId(ctx context.Context, conn sqldb.Connection, orderId string) (int64, error) {
+	q := `SELECT COALESCE(SUM(shipping_refund), 0) FROM order_return WHERE order_id = $1`
+
+	var amount int64
+
+	err := conn.QueryRowContext(ctx, q, orderId).Scan(&amount)
+
+	if err != nil {
+		return 0, err
+	}
+
+	return amount, nil
+}
+
+type ReturnItemRecord struct {
+	Id                string      `json:"id"`
+	ReturnId          string      `json:"return_id"`
+	OrderLineItemId   string      `json:"order_line_item_id"`
+	Quantity          int         `json:"quantity"`
+	Reason            string      `json:"reason"`
+	Note              *string     `json:"note"`
+	ReceivedQuantity  int         `json:"received_quantity"`
+	RestockedQuantity int         `json:"restocked_quantity"` // the damaged units aren't put back in stock
+	RefundAmount      money.Money `json:"refund_amount"`
+}
+
+type ReturnItemModel struct{}
+
+func NewReturnItemModel() *ReturnItemModel {
+	return &ReturnItemModel{}
+}
+
+func (m *ReturnItemModel) Insert(ctx context.Context, conn sqldb.Connection, record *ReturnItemRecord) (*ReturnItemRecord, error) {
+	q := `INSERT INTO order_return_item (return_id, order_line_item_id, quantity, reason, note) VALUES ($1, $2, $3, $4, $5) RETURNING id`
+
+	err := conn.QueryRowContext(ctx, q, record.ReturnId, record.OrderLineItemId, record.Quantity, record.Reason, record.Note).Scan(&record.Id)
+
+	if err != nil {
+		if err.Error() == `pq: duplicate key value violates unique constraint "order_return_item_line_key"` {
+			return nil, ErrInvalidValue
+		}
+		return nil, err
+	}
+
+	return record, nil
+}
+
+// FindAllByReturnIds returns the items by return id, their amounts are in the currency of the order
+func (m *ReturnItemModel) FindAllByReturnIds(ctx context.Context, conn sqldb.Connection, returnIds []string) (map[string][]*ReturnItemRecord, error) {
+	q := `SELECT ri.id, ri.return_id, ri.order_line_item_id, ri.quantity, ri.reason, ri.note, ri.received_quantity, ri.restocked_quantity, ri.refund_amount, o.currency_code
+		  FROM order_return_item AS ri
+		  INNER JOIN order_return AS r ON r.id = ri.return_id
+		  INNER JOIN orders AS o ON o.id = r.order_id
+		  WHERE ri.return_id = ANY($1) ORDER BY ri.id`
+
+	rows, err := conn.QueryContext(ctx, q, pq.Array(returnIds))
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	resultMap := make(map[string][]*ReturnItemRecord)
+
+	for rows.Next() {
+		var record ReturnItemRecord
+		var currencyCode string
+
+		err := rows.Scan(&record.Id, &record.ReturnId, &record.OrderLineItemId, &record.Quantity, &record.Reason, &record.Note, &record.ReceivedQuantity, &record.RestockedQuantity,
+			&record.RefundAmount, &currencyCode)
+
+		if err != nil {
+			return nil, err
+		}
+
+		money.SetCurrency(currencyCode, &record.RefundAmount)
+
+		resultMap[record.ReturnId] = append(resultMap[record.ReturnId], &record)
+	}
+
+	return resultMap, nil
+}
+
+func (m *ReturnItemModel) Update(ctx context.Context, conn sqldb.Connection, record *ReturnItemRecord) (*ReturnItemRecord, error) {
+	q := `UPDATE order_return_item SET received_quantity = $1, restocked_quantity = $2, refund_amount = $3 WHERE id = $4`
+
+	res, err := conn.ExecContext(ctx, q, record.ReceivedQuantity, record.RestockedQuantity, record.RefundAmount, record.Id)
+
+	if err != nil {
+		switch {
+		case err.Error() == `pq: new row for relation "order_return_item" violates check constraint "order_return_item_quantities_check"`,
+			err.Error() == `pq: new row for relation "order_return_item" violates check constraint "order_return_item_refund_amount_check"`:
+			return nil, ErrInvalidValue
+		default:
+			return nil, err
+		}
+	}
+
+	if rowsAff, _ := res.RowsAffected(); rowsAff == 0 {
+		return nil, ErrRecordNotFound
+	}
+
+	return record, nil
+}
+
+// SumReturnedQuantitiesByOrderId returns, by order line item id, the quantities of the returns of the order that
+// weren't rejected or cancelled
+func (m *ReturnItemModel) SumReturnedQuantitiesByOrderId(ctx context.Context, conn sqldb.Connection, orderId string) (map[string]int, error) {
+	q := `SELECT ri.order_line_item_id, SUM(ri.quantity) FROM order_return_item AS ri
+		  INNER JOIN order_return AS r ON r.id = ri.return_id
+		  WHERE r.order_id = $1 AND r.status NOT IN ('rejected', 'cancelled')
+		  GROUP BY ri.order_line_item_id`
+
+	rows, err := conn.QueryContext(ctx, q, orderId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	resultMap := make(map[string]int)
+
+	for rows.Next() {
+		var lineItemId string
+		var quantity int
+
+		err := rows.Scan(&lineItemId, &quantity)
+
+		if err != nil {
+			return nil, err
+		}
+
+		resultMap[lineItemId] = quantity
+	}
+
+	return resultMap, nil
+}
+
+type ReturnEventRecord struct {
+	Id          string    `json:"id"`
+	ReturnId    string    `json:"return_id"`
+	FromStatus  *string   `json:"from_status"` // nil for the event created when the return is requested
+	ToStatus    string    `json:"to_status"`
+	ActorUserId *string   `json:"actor_user_id"`
+	Note        *string   `json:"note"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type ReturnEventModel struct{}
+
+func NewReturnEventModel() *ReturnEventModel {
+	return &ReturnEventModel{}
+}
+
+func (m *ReturnEventModel) Insert(ctx context.Context, conn sqldb.Connection, record *ReturnEventRecord) (*ReturnEventRecord, error) {
+	q := `INSERT INTO order_return_event (return_id, from_status, to_status, actor_user_id, note) VALUES ($1, $2, $3, $4, $5) RETURNING id, created_at`
+
+	err := conn.QueryRowContext(ctx, q, record.ReturnId, record.FromStatus, record.ToStatus, record.ActorUserId, record.Note).Scan(&record.Id, &record.CreatedAt)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return record, nil
+}
+
+// FindAllByReturnIds returns the history of the returns by return id, from the oldest event
+func (m *ReturnEventModel) FindAllByReturnIds(ctx context.Context, conn sqldb.Connection, returnIds []string) (map[string][]*ReturnEventRecord, error) {
+	q := `SELECT id, return_id, from_status, to_status, actor_user_id, note, created_at FROM order_return_event WHERE return_id = ANY($1) ORDER BY created_at`
+
+	rows, err := conn.QueryContext(ctx, q, pq.Array(returnIds))
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	resultMap := make(map[string][]*ReturnEventRecord)
+
+	for rows.Next() {
+		var record ReturnEventRecord
+
+		err := rows.Scan(&record.Id, &record.ReturnId, &record.FromStatus, &record.ToStatus, &record.ActorUserId, &record.Note, &record.CreatedAt)
+
+		if err != nil {
+			return nil, err
+		}
+
+		resultMap[record.ReturnId] = append(resultMap[record.ReturnId], &record)
+	}
+
+	return resultMap, nil
+}
@@ -0,0 +1,104 @@
+package model
+
+import (
+	"context"
+	"ecom-backend/pkg/sqldb"
+	"strconv"
+	"strings"
+)
+
+// SearchSuggestionRecord is a product title, a category name or a sku close to what the user typed
+type SearchSuggestionRecord struct {
+	Id        string  `json:"id"`                   // the product, category or variant id
+	ProductId *string `json:"product_id,omitempty"` // the product of the variant for the skus
+	Text      string  `json:"text"`
+	Score     float64 `json:"score"` // the word similarity with what the user typed, 1 for a perfect match
+}
+
+// SearchSuggestionModel matches prefixes of what the storefront shows with pg_trgm: the texts starting with the query
+// come first, then the closest ones so typos are tolerated
+type SearchSuggestionModel struct {
+}
+
+func NewSearchSuggestionModel() *SearchSuggestionModel {
+	return &SearchSuggestionModel{}
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+// prefixPattern returns the ILIKE pattern matching the texts starting with the query
+func prefixPattern(query string) string {
+	return likeEscaper.Replace(query) + "%"
+}
+
+// SetWordSimilarityThreshold sets how close a word must be to the query to match until the end of the transaction
+func (m *SearchSuggestionModel) SetWordSimilarityThreshold(ctx context.Context, conn sqldb.Connection, threshold float64) error {
+	q := `SELECT set_config('pg_trgm.word_similarity_threshold', $1, true)`
+
+	_, err := conn.ExecContext(ctx, q, strconv.FormatFloat(threshold, 'f', -1, 64))
+
+	return err
+}
+
+// FindProductTitles returns the titles of the published products matching the query
+func (m *SearchSuggestionModel) FindProductTitles(ctx context.Context, conn sqldb.Connection, query string, limit int) ([]*SearchSuggestionRecord, error) {
+	q := `SELECT p.id, NULL::uuid, p.title, word_similarity($1, p.title) AS score FROM product AS p
+		  WHERE p.deleted_at IS NULL AND p.status = 'published' AND (p.title ILIKE $2 OR $1 <% p.title)
+		  ORDER BY p.title ILIKE $2 DESC, score DESC, p.title LIMIT $3`
+
+	return m.findMany(ctx, conn, q, query, prefixPattern(query), limit)
+}
+
+// FindCategories returns the names of the categories matching the query that have published products
+func (m *SearchSuggestionModel) FindCategories(ctx context.Context, conn sqldb.Connection, query string, limit int) ([]*SearchSuggestionRecord, error) {
+	q := `SELECT c.id, NULL::uuid, c.name, word_similarity($1, c.name) AS score FROM product_category AS c
+		  WHERE c.deleted_at IS NULL AND (c.name ILIKE $2 OR $1 <% c.name)
+		  AND EXISTS (
+			  SELECT 1 FROM product_category_product AS pcp
+			  INNER JOIN product AS p ON p.id = pcp.product_id
+			  WHERE pcp.category_id = c.id AND p.deleted_at IS NULL AND p.status = 'published'
+		  )
+		  ORDER BY c.name ILIKE $2 DESC, score DESC, c.name LIMIT $3`
+
+	return m.findMany(ctx, conn, q, query, prefixPattern(query), limit)
+}
+
+// FindSkus returns the skus of the variants of the published products matching the query
+func (m *SearchSuggestionModel) FindSkus(ctx context.Context, conn sqldb.Connection, query string, limit int) ([]*SearchSuggestionRecord, error) {
+	q := `SELECT pv.id, pv.product_id, pv.sku, word_similarity($1, pv.sku) AS score FROM product_variant AS pv
+		  INNER JOIN product AS p ON p.id = pv.product_id
+		  WHERE pv.deleted_at IS NULL AND p.deleted_at IS NULL AND p.status = 'published' AND (pv.sku ILIKE $2 OR $1 <% pv.sku)
+		  ORDER BY pv.sku ILIKE $2 DESC, score DESC, pv.sku LIMIT $3`
+
+	return m.findMany(ctx, conn, q, query, prefixPattern(query), limit)
+}
+
+func (m *SearchSuggestionModel) findMany(ctx context.Context, conn sqldb.Connection, q string, args ...any) ([]*SearchSuggestionRecord, error) {
+	rows, err := conn.QueryContext(ctx, q, args...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	suggestions := []*SearchSuggestionRecord{}
+
+	for rows.Next() {
+		var suggestion SearchSuggestionRecord
+
+		err := rows.Scan(&suggestion.Id, &suggestion.ProductId, &suggestion.Text, &suggestion.Score)
+
+		if err != nil {
+			return nil, err
+		}
+
+		suggestions = append(suggestions, &suggestion)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return suggestions, nil
+}
@@ -0,0 +1,270 @@
+package model
+
+import (
+	"context"
+	"database/sql"
+	"ecom-backend/internal/money"
+	"ecom-backend/pkg/sqldb"
+	"errors"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type ShippingMethodRecord struct {
+	Id          string    `json:"id"`
+	ZoneId      string    `json:"zone_id"`
+	Name        string    `json:"name"`
+	Description *string   `json:"description"`
+	RateType    string    `json:"rate_type"`
+	IsActive    bool      `json:"is_active"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+type ShippingRateRecord struct {
+	MethodId     string       `json:"-"`
+	CurrencyCode string       `json:"currency_code"`
+	Amount       *money.Money `json:"amount"`     // price of flat methods
+	FreeAbove    *money.Money `json:"free_above"` // shipping is free once the discounted subtotal reaches it
+}
+
+type ShippingWeightRateRecord struct {
+	MethodId     string      `json:"-"`
+	CurrencyCode string      `json:"currency_code"`
+	MaxWeight    float32     `json:"max_weight"`
+	Amount       money.Money `json:"amount"`
+}
+
+type ShippingMethodModel struct{}
+
+func NewShippingMethodModel() *ShippingMethodModel {
+	return &ShippingMethodModel{}
+}
+
+const shippingMethodColumns = `id, zone_id, name, description, rate_type, is_active, created_at, updated_at`
+
+func scanShippingMethod(row interface{ Scan(...any) error }, method *ShippingMethodRecord) error {
+	return row.Scan(&method.Id, &method.ZoneId, &method.Name, &method.Description, &method.RateType, &method.IsActive, &method.CreatedAt, &method.UpdatedAt)
+}
+
+func (m *ShippingMethodModel) Insert(ctx context.Context, conn sqldb.Connection, method *ShippingMethodRecord) (*ShippingMethodRecord, error) {
+	q := `INSERT INTO shipping_method (zone_id, name, description, rate_type, is_active) VALUES ($1, $2, $3, $4, $5) RETURNING id, created_at, updated_at`
+
+	err := conn.QueryRowContext(ctx, q, method.ZoneId, method.Name, method.Description, method.RateType, method.IsActive).Scan(&method.Id, &method.CreatedAt, &method.UpdatedAt)
+
+	if err != nil {
+		if err.Error() == `pq: insert or update on table "shipping_method" violates foreign key constraint "shipping_method_zone_id_fkey"` {
+			return nil, ErrShippingZoneNotFound
+		}
+		return nil, err
+	}
+
+	return method, nil
+}
+
+func (m *ShippingMethodModel) FindById(ctx context.Context, conn sqldb.Connection, id string) (*ShippingMethodRecord, error) {
+	q := `SELECT ` + shippingMethodColumns + ` FROM shipping_method WHERE id = $1`
+
+	var method ShippingMethodRecord
+
+	err := scanShippingMethod(conn.QueryRowContext(ctx, q, id), &method)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrRecordNotFound
+		}
+		return nil, err
+	}
+
+	return &method, nil
+}
+
+func (m *ShippingMethodModel) FindAllByZoneIds(ctx context.Context, conn sqldb.Connection, zoneIds []string) (map[string][]*ShippingMethodRecord, error) {
+	q := `SELECT ` + shippingMethodColumns + ` FROM shipping_method WHERE zone_id = ANY($1) ORDER BY created_at`
+
+	rows, err := conn.QueryContext(ctx, q, pq.Array(zoneIds))
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	resultMap := make(map[string][]*ShippingMethodRecord)
+
+	for rows.Next() {
+		var method ShippingMethodRecord
+
+		err := scanShippingMethod(rows, &method)
+
+		if err != nil {
+			return nil, err
+		}
+
+		resultMap[method.ZoneId] = append(resultMap[method.ZoneId], &method)
+	}
+
+	return resultMap, nil
+}
+
+func (m *ShippingMethodModel) Update(ctx context.Context, conn sqldb.Connection, method *ShippingMethodRecord) (*ShippingMethodRecord, error) {
+	q := `UPDATE shipping_method SET name = $1, description = $2, is_active = $3, updated_at = $4 WHERE id = $5`
+
+	method.UpdatedAt = time.Now()
+
+	res, err := conn.ExecContext(ctx, q, method.Name, method.Description, method.IsActive, method.UpdatedAt, method.Id)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if rowsAff, _ := res.RowsAffected(); rowsAff == 0 {
+		return nil, ErrRecordNotFound
+	}
+
+	return method, nil
+}
+
+func (m *ShippingMethodModel) Delete(ctx context.Context, conn sqldb.Connection, id string) error {
+	q := `DELETE FROM shipping_method WHERE id = $1`
+
+	res, err := conn.ExecContext(ctx, q, id)
+
+	if err != nil {
+		return err
+	}
+
+	if rowsAff, _ := res.RowsAffected(); rowsAff == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+type ShippingRateModel struct{}
+
+func NewShippingRateModel() *ShippingRateModel {
+	return &ShippingRateModel{}
+}
+
+func (m *ShippingRateModel) Insert(ctx context.Context, conn sqldb.Connection, record *ShippingRateRecord) (*ShippingRateRecord, error) {
+	q := `INSERT INTO shipping_rate (method_id, currency_code, amount, free_above) VALUES ($1, $2, $3, $4)`
+
+	_, err := conn.ExecContext(ctx, q, record.MethodId, record.CurrencyCode, record.Amount, record.FreeAbove)
+
+	if err != nil {
+		switch {
+		case err.Error() == `pq: insert or update on table "shipping_rate" violates foreign key constraint "shipping_rate_currency_code_fkey"`:
+			return nil, ErrInvalidValue
+		case err.Error() == `pq: duplicate key value violates unique constraint "shipping_rate_pkey"`:
+			return nil, ErrInvalidValue
+		default:
+			return nil, err
+		}
+	}
+
+	return record, nil
+}
+
+func (m *ShippingRateModel) FindAllByMethodIds(ctx context.Context, conn sqldb.Connection, methodIds []string) (map[string][]*ShippingRateRecord, error) {
+	q := `SELECT method_id, currency_code, amount, free_above FROM shipping_rate WHERE method_id = ANY($1) ORDER BY currency_code`
+
+	rows, err := conn.QueryContext(ctx, q, pq.Array(methodIds))
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	resultMap := make(map[string][]*ShippingRateRecord)
+
+	for rows.Next() {
+		var record ShippingRateRecord
+
+		err := rows.Scan(&record.MethodId, &record.CurrencyCode, &record.Amount, &record.FreeAbove)
+
+		if err != nil {
+			return nil, err
+		}
+
+		money.SetCurrency(record.CurrencyCode, record.Amount, record.FreeAbove)
+
+		resultMap[record.MethodId] = append(resultMap[record.MethodId], &record)
+	}
+
+	return resultMap, nil
+}
+
+func (m *ShippingRateModel) DeleteAllByMethodId(ctx context.Context, conn sqldb.Connection, methodId string) error {
+	q := `DELETE FROM shipping_rate WHERE method_id = $1`
+
+	_, err := conn.ExecContext(ctx, q, methodId)
+
+	return err
+}
+
+type ShippingWeightRateModel struct{}
+
+func NewShippingWeightRateModel() *ShippingWeightRateModel {
+	return &ShippingWeightRateModel{}
+}
+
+func (m *ShippingWeightRateModel) Insert(ctx context.Context, conn sqldb.Connection, record *ShippingWeightRateRecord) (*ShippingWeightRateRecord, error) {
+	q := `INSERT INTO shipping_weight_rate (method_id, currency_code, max_weight, amount) VALUES ($1, $2, $3, $4)`
+
+	_, err := conn.ExecContext(ctx, q, record.MethodId, record.CurrencyCode, record.MaxWeight, record.Amount)
+
+	if err != nil {
+		switch {
+		case err.Error() == `pq: insert or update on table "shipping_weight_rate" violates foreign key constraint "shipping_weight_rate_currency_code_fkey"`:
+			return nil, ErrInvalidValue
+		case err.Error() == `pq: duplicate key value violates unique constraint "shipping_weight_rate_pkey"`:
+			return nil, ErrInvalidValue
+		default:
+			return nil, err
+		}
+	}
+
+	return record, nil
+}
+
+// FindAllByMethodIds returns the brackets of each method from the lightest to the heaviest
+func (m *ShippingWeightRateModel) FindAllByMethodIds(ctx context.Context, conn sqldb.Connection, methodIds []string) (map[string][]*ShippingWeightRateRecord, error) {
+	q := `SELECT method_id, currency_code, max_weight, amount FROM shipping_weight_rate WHERE method_id = ANY($1) ORDER BY currency_code, max_weight`
+
+	rows, err := conn.QueryContext(ctx, q, pq.Array(methodIds))
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	resultMap := make(map[string][]*ShippingWeightRateRecord)
+
+	for rows.Next() {
+		var record ShippingWeightRateRecord
+
+		err := rows.Scan(&record.MethodId, &record.CurrencyCode, &record.MaxWeight, &record.Amount)
+
+		if err != nil {
+			return nil, err
+		}
+
+		money.SetCurrency(record.CurrencyCode, &record.Amount)
+
+		resultMap[record.MethodId] = append(resultMap[record.MethodId], &record)
+	}
+
+	return resultMap, nil
+}
+
+func (m *ShippingWeightRateModel) DeleteAllByMethodId(ctx context.Context, conn sqldb.Connection, methodId string) error {
+	q := `DELETE FROM shipping_weight_rate WHERE method_id = $1`
+
+	_, err := conn.ExecContext(ctx, q, methodId)
+
+	return err
+}
@@ -0,0 +1,186 @@
+package model
+
+import (
+	"context"
+	"database/sql"
+	"ecom-backend/pkg/sqldb"
+	"errors"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type ShippingZoneRecord struct {
+	Id        string    `json:"id"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type ShippingZoneModel struct{}
+
+func NewShippingZoneModel() *ShippingZoneModel {
+	return &ShippingZoneModel{}
+}
+
+func (m *ShippingZoneModel) Insert(ctx context.Context, conn sqldb.Connection, zone *ShippingZoneRecord) (*ShippingZoneRecord, error) {
+	q := `INSERT INTO shipping_zone (name) VALUES ($1) RETURNING id, created_at, updated_at`
+
+	err := conn.QueryRowContext(ctx, q, zone.Name).Scan(&zone.Id, &zone.CreatedAt, &zone.UpdatedAt)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return zone, nil
+}
+
+func (m *ShippingZoneModel) FindById(ctx context.Context, conn sqldb.Connection, id string) (*ShippingZoneRecord, error) {
+	q := `SELECT id, name, created_at, updated_at FROM shipping_zone WHERE id = $1`
+
+	var zone ShippingZoneRecord
+
+	err := conn.QueryRowContext(ctx, q, id).Scan(&zone.Id, &zone.Name, &zone.CreatedAt, &zone.UpdatedAt)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrRecordNotFound
+		}
+		return nil, err
+	}
+
+	return &zone, nil
+}
+
+// FindByCountryCode returns the zone the country belongs to
+func (m *ShippingZoneModel) FindByCountryCode(ctx context.Context, conn sqldb.Connection, countryCode string) (*ShippingZoneRecord, error) {
+	q := `SELECT sz.id, sz.name, sz.created_at, sz.updated_at FROM shipping_zone AS sz
+		  INNER JOIN shipping_zone_country AS szc ON szc.zone_id = sz.id
+		  WHERE szc.country_code = $1`
+
+	var zone ShippingZoneRecord
+
+	err := conn.QueryRowContext(ctx, q, countryCode).Scan(&zone.Id, &zone.Name, &zone.CreatedAt, &zone.UpdatedAt)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrRecordNotFound
+		}
+		return nil, err
+	}
+
+	return &zone, nil
+}
+
+func (m *ShippingZoneModel) FindAll(ctx context.Context, conn sqldb.Connection) ([]*ShippingZoneRecord, error) {
+	q := `SELECT id, name, created_at, updated_at FROM shipping_zone ORDER BY name`
+
+	rows, err := conn.QueryContext(ctx, q)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	zones := []*ShippingZoneRecord{}
+
+	for rows.Next() {
+		var zone ShippingZoneRecord
+
+		err := rows.Scan(&zone.Id, &zone.Name, &zone.CreatedAt, &zone.UpdatedAt)
+
+		if err != nil {
+			return nil, err
+		}
+
+		zones = append(zones, &zone)
+	}
+
+	return zones, nil
+}
+
+func (m *ShippingZoneModel) Update(ctx context.Context, conn sqldb.Connection, zone *ShippingZoneRecord) (*ShippingZoneRecord, error) {
+	q := `UPDATE shipping_zone SET name = $1, updated_at = $2 WHERE id = $3`
+
+	zone.UpdatedAt = time.Now()
+
+	res, err := conn.ExecContext(ctx, q, zone.Name, zone.UpdatedAt, zone.Id)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if rowsAff, _ := res.RowsAffected(); rowsAff == 0 {
+		return nil, ErrRecordNotFound
+	}
+
+	return zone, nil
+}
+
+// Delete removes the zone together with its methods, the orders keep a snapshot of the method they were shipped with
+func (m *ShippingZoneModel) Delete(ctx context.Context, conn sqldb.Connection, id string) error {
+	q := `DELETE FROM shipping_zone WHERE id = $1`
+
+	res, err := conn.ExecContext(ctx, q, id)
+
+	if err != nil {
+		return err
+	}
+
+	if rowsAff, _ := res.RowsAffected(); rowsAff == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// ReplaceCountries swaps the countries of the zone
+func (m *ShippingZoneModel) ReplaceCountries(ctx context.Context, conn sqldb.Connection, zoneId string, countryCodes []string) error {
+	_, err := conn.ExecContext(ctx, `DELETE FROM shipping_zone_country WHERE zone_id = $1`, zoneId)
+
+	if err != nil {
+		return err
+	}
+
+	q := `INSERT INTO shipping_zone_country (zone_id, country_code) SELECT $1, unnest($2::text[])`
+
+	_, err = conn.ExecContext(ctx, q, zoneId, pq.Array(countryCodes))
+
+	if err != nil {
+		if err.Error() == `pq: duplicate key value violates unique constraint "shipping_zone_country_code_key"` {
+			return ErrCountryInOtherShippingZone
+		}
+		return err
+	}
+
+	return nil
+}
+
+func (m *ShippingZoneModel) FindCountriesByZoneIds(ctx context.Context, conn sqldb.Connection, zoneIds []string) (map[string][]string, error) {
+	q := `SELECT zone_id, country_code FROM shipping_zone_country WHERE zone_id = ANY($1) ORDER BY country_code`
+
+	rows, err := conn.QueryContext(ctx, q, pq.Array(zoneIds))
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	resultMap := make(map[string][]string)
+
+	for rows.Next() {
+		var zoneId, countryCode string
+
+		err := rows.Scan(&zoneId, &countryCode)
+
+		if err != nil {
+			return nil, err
+		}
+
+		resultMap[zoneId] = append(resultMap[zoneId], countryCode)
+	}
+
+	return resultMap, nil
+}
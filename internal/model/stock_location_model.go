@@ -0,0 +1,136 @@
+package model
+
+import (
+	"context"
+	"database/sql"
+	"ecom-backend/pkg/sqldb"
+	"errors"
+	"time"
+)
+
+type StockLocationRecord struct {
+	Id        string     `json:"id"`
+	Name      string     `json:"name"`
+	Priority  int        `json:"priority"`
+	IsActive  bool       `json:"is_active"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	DeletedAt *time.Time `json:"-"`
+}
+
+type StockLocationModel struct{}
+
+func NewStockLocationModel() *StockLocationModel {
+	return &StockLocationModel{}
+}
+
+func (m *StockLocationModel) Insert(ctx context.Context, conn sqldb.Connection, location *StockLocationRecord) (*StockLocationRecord, error) {
+	q := `INSERT INTO stock_location (name, priority, is_active) VALUES ($1, $2, $3) RETURNING id, created_at, updated_at`
+
+	err := conn.QueryRowContext(ctx, q, location.Name, location.Priority, location.IsActive).Scan(&location.Id, &location.CreatedAt, &location.UpdatedAt)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return location, nil
+}
+
+func (m *StockLocationModel) FindById(ctx context.Context, conn sqldb.Connection, id string) (*StockLocationRecord, error) {
+	q := `SELECT id, name, priority, is_active, created_at, updated_at, deleted_at FROM stock_location WHERE id = $1 AND deleted_at IS NULL`
+
+	var location StockLocationRecord
+
+	err := conn.QueryRowContext(ctx, q, id).Scan(&location.Id, &location.Name, &location.Priority, &location.IsActive, &location.CreatedAt, &location.UpdatedAt, &location.DeletedAt)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrRecordNotFound
+		}
+		return nil, err
+	}
+
+	return &location, nil
+}
+
+// FindDefault returns the active location with the highest priority, stock without an explicit location goes there
+func (m *StockLocationModel) FindDefault(ctx context.Context, conn sqldb.Connection) (*StockLocationRecord, error) {
+	q := `SELECT id, name, priority, is_active, created_at, updated_at, deleted_at FROM stock_location
+		  WHERE is_active AND deleted_at IS NULL ORDER BY priority, created_at LIMIT 1`
+
+	var location StockLocationRecord
+
+	err := conn.QueryRowContext(ctx, q).Scan(&location.Id, &location.Name, &location.Priority, &location.IsActive, &location.CreatedAt, &location.UpdatedAt, &location.DeletedAt)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrRecordNotFound
+		}
+		return nil, err
+	}
+
+	return &location, nil
+}
+
+func (m *StockLocationModel) FindAll(ctx context.Context, conn sqldb.Connection) ([]*StockLocationRecord, error) {
+	q := `SELECT id, name, priority, is_active, created_at, updated_at, deleted_at FROM stock_location WHERE deleted_at IS NULL ORDER BY priority, created_at`
+
+	rows, err := conn.QueryContext(ctx, q)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	locations := []*StockLocationRecord{}
+
+	for rows.Next() {
+		var location StockLocationRecord
+
+		err := rows.Scan(&location.Id, &location.Name, &location.Priority, &location.IsActive, &location.CreatedAt, &location.UpdatedAt, &location.DeletedAt)
+
+		if err != nil {
+			return nil, err
+		}
+
+		locations = append(locations, &location)
+	}
+
+	return locations, nil
+}
+
+func (m *StockLocationModel) Update(ctx context.Context, conn sqldb.Connection, location *StockLocationRecord) (*StockLocationRecord, error) {
+	q := `UPDATE stock_location SET name = $1, priority = $2, is_active = $3, updated_at = $4 WHERE id = $5 AND deleted_at IS NULL`
+
+	location.UpdatedAt = time.Now()
+
+	res, err := conn.ExecContext(ctx, q, location.Name, location.Priority, location.IsActive, location.UpdatedAt, location.Id)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if rowsAff, _ := res.RowsAffected(); rowsAff == 0 {
+		return nil, ErrRecordNotFound
+	}
+
+	return location, nil
+}
+
+// SoftDelete keeps the row around since the stock ledger still references it
+func (m *StockLocationModel) SoftDelete(ctx context.Context, conn sqldb.Connection, id string) error {
+	q := `UPDATE stock_location SET deleted_at = $1 WHERE id = $2 AND deleted_at IS NULL`
+
+	res, err := conn.ExecContext(ctx, q, time.Now(), id)
+
+	if err != nil {
+		return err
+	}
+
+	if rowsAff, _ := res.RowsAffected(); rowsAff == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,88 @@
+package model
+
+import (
+	"context"
+	"ecom-backend/pkg/sqldb"
+	"time"
+)
+
+type StockMovementRecord struct {
+	Id          string    `json:"id"`
+	VariantId   string    `json:"variant_id"`
+	LocationId  string    `json:"location_id"`
+	Type        string    `json:"type"`
+	Quantity    int       `json:"quantity"` // signed, negative when stock leaves the inventory
+	Reason      *string   `json:"reason"`
+	OrderId     *string   `json:"order_id"`
+	ActorUserId *string   `json:"actor_user_id"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type StockMovementModel struct{}
+
+func NewStockMovementModel() *StockMovementModel {
+	return &StockMovementModel{}
+}
+
+func (m *StockMovementModel) Insert(ctx context.Context, conn sqldb.Connection, movement *StockMovementRecord) (*StockMovementRecord, error) {
+	q := `INSERT INTO stock_movement (variant_id, location_id, type, quantity, reason, order_id, actor_user_id) VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id, created_at`
+
+	err := conn.QueryRowContext(ctx, q, movement.VariantId, movement.LocationId, movement.Type, movement.Quantity, movement.Reason, movement.OrderId, movement.ActorUserId).Scan(&movement.Id, &movement.CreatedAt)
+
+	if err != nil {
+		switch {
+		case err.Error() == `pq: insert or update on table "stock_movement" violates foreign key constraint "stock_movement_variant_id_fkey"`:
+			return nil, ErrVariantNotFound
+		case err.Error() == `pq: insert or update on table "stock_movement" violates foreign key constraint "stock_movement_location_id_fkey"`:
+			return nil, ErrStockLocationNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return movement, nil
+}
+
+func (m *StockMovementModel) Count(ctx context.Context, conn sqldb.Connection, variantId string) (int, error) {
+	q := `SELECT COUNT(*) FROM stock_movement WHERE variant_id = $1`
+
+	var count int
+
+	err := conn.QueryRowContext(ctx, q, variantId).Scan(&count)
+
+	if err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+// FindAllByVariantId returns the history of the variant stock, newest movements first
+func (m *StockMovementModel) FindAllByVariantId(ctx context.Context, conn sqldb.Connection, variantId string, limit uint, offset uint) ([]*StockMovementRecord, error) {
+	q := `SELECT id, variant_id, location_id, type, quantity, reason, order_id, actor_user_id, created_at FROM stock_movement
+		  WHERE variant_id = $1 ORDER BY created_at DESC LIMIT $2 OFFSET $3`
+
+	rows, err := conn.QueryContext(ctx, q, variantId, limit, offset)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	movements := []*StockMovementRecord{}
+
+	for rows.Next() {
+		var movement StockMovementRecord
+
+		err := rows.Scan(&movement.Id, &movement.VariantId, &movement.LocationId, &movement.Type, &movement.Quantity, &movement.Reason, &movement.OrderId, &movement.ActorUserId, &movement.CreatedAt)
+
+		if err != nil {
+			return nil, err
+		}
+
+		movements = append(movements, &movement)
+	}
+
+	return movements, nil
+}
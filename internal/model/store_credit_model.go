@@ -0,0 +1,163 @@
+package model
+
+import (
+	"context"
+	"database/sql"
+	"ecom-backend/internal/money"
+	"ecom-backend/pkg/sqldb"
+	"errors"
+	"time"
+)
+
+// StoreCreditTransactionRecord is an entry of the store credit ledger of a user, the amounts spent are negative
+type StoreCreditTransactionRecord struct {
+	Id           string      `json:"id"`
+	UserId       string      `json:"user_id"`
+	CurrencyCode string      `json:"currency_code"`
+	Type         string      `json:"type"`
+	Amount       money.Money `json:"amount"`
+	OrderId      *string     `json:"order_id"`
+	ActorUserId  *string     `json:"actor_user_id"` // the admin who adjusted the credit
+	Note         *string     `json:"note"`
+	CreatedAt    time.Time   `json:"created_at"`
+}
+
+type StoreCreditModel struct{}
+
+func NewStoreCreditModel() *StoreCreditModel {
+	return &StoreCreditModel{}
+}
+
+// LockUser serializes the changes of the store credit of the user until the end of the transaction, so the balance
+// read afterwards can't be spent twice. The lock doesn't block the rows referencing the user.
+func (m *StoreCreditModel) LockUser(ctx context.Context, conn sqldb.Connection, userId string) error {
+	var id string
+
+	err := conn.QueryRowContext(ctx, `SELECT id FROM users WHERE id = $1 FOR NO KEY UPDATE`, userId).Scan(&id)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrRecordNotFound
+		}
+		return err
+	}
+
+	return nil
+}
+
+func (m *StoreCreditModel) Insert(ctx context.Context, conn sqldb.Connection, transaction *StoreCreditTransactionRecord) (*StoreCreditTransactionRecord, error) {
+	q := `INSERT INTO store_credit_transaction (user_id, currency_code, type, amount, order_id, actor_user_id, note) VALUES ($1, $2, $3, $4, $5, $6, $7)
+		  RETURNING id, created_at`
+
+	err := conn.QueryRowContext(ctx, q, transaction.UserId, transaction.CurrencyCode, transaction.Type, transaction.Amount, transaction.OrderId, transaction.ActorUserId, transaction.Note).
+		Scan(&transaction.Id, &transaction.CreatedAt)
+
+	if err != nil {
+		switch {
+		case err.Error() == `pq: insert or update on table "store_credit_transaction" violates foreign key constraint "store_credit_transaction_currency_code_fkey"`:
+			return nil, ErrCurrencyNotFound
+		case err.Error() == `pq: insert or update on table "store_credit_transaction" violates foreign key constraint "store_credit_transaction_user_id_fkey"`:
+			return nil, ErrRecordNotFound
+		case err.Error() == `pq: new row for relation "store_credit_transaction" violates check constraint "store_credit_transaction_amount_check"`:
+			return nil, ErrInvalidValue
+		default:
+			return nil, err
+		}
+	}
+
+	return transaction, nil
+}
+
+// FindAllByUserId returns the ledger of the user, the latest entries first
+func (m *StoreCreditModel) FindAllByUserId(ctx context.Context, conn sqldb.Connection, userId string) ([]*StoreCreditTransactionRecord, error) {
+	q := `SELECT id, user_id, currency_code, type, amount, order_id, actor_user_id, note, created_at FROM store_credit_transaction
+		  WHERE user_id = $1 ORDER BY created_at DESC`
+
+	rows, err := conn.QueryContext(ctx, q, userId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	transactions := []*StoreCreditTransactionRecord{}
+
+	for rows.Next() {
+		var transaction StoreCreditTransactionRecord
+
+		err := rows.Scan(&transaction.Id, &transaction.UserId, &transaction.CurrencyCode, &transaction.Type, &transaction.Amount, &transaction.OrderId, &transaction.ActorUserId,
+			&transaction.Note, &transaction.CreatedAt)
+
+		if err != nil {
+			return nil, err
+		}
+
+		money.SetCurrency(transaction.CurrencyCode, &transaction.Amount)
+
+		transactions = append(transactions, &transaction)
+	}
+
+	return transactions, nil
+}
+
+// FindBalancesByUserId returns the store credit of the user in every currency they have some
+func (m *StoreCreditModel) FindBalancesByUserId(ctx context.Context, conn sqldb.Connection, userId string) ([]money.Money, error) {
+	q := `SELECT currency_code, SUM(amount) FROM store_credit_transaction
+		  WHERE user_id = $1 GROUP BY currency_code HAVING SUM(amount) <> 0 ORDER BY currency_code`
+
+	rows, err := conn.QueryContext(ctx, q, userId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	balances := []money.Money{}
+
+	for rows.Next() {
+		var balance money.Money
+
+		err := rows.Scan(&balance.CurrencyCode, &balance.Amount)
+
+		if err != nil {
+			return nil, err
+		}
+
+		balances = append(balances, balance)
+	}
+
+	return balances, nil
+}
+
+// FindBalance returns the store credit of the user in the currency
+func (m *StoreCreditModel) FindBalance(ctx context.Context, conn sqldb.Connection, userId string, currencyCode string) (money.Money, error) {
+	q := `SELECT COALESCE(SUM(amount), 0) FROM store_credit_transaction WHERE user_id = $1 AND currency_code = $2`
+
+	balance := money.Zero(currencyCode)
+
+	err := conn.QueryRowContext(ctx, q, userId, currencyCode).Scan(&balance.Amount)
+
+	if err != nil {
+		return money.Money{}, err
+	}
+
+	return balance, nil
+}
+
+// SumRedeemedByOrderId returns the store credit still taken by the order, i.e. the redeemed amounts minus the restored
+// ones, as a positive amount
+func (m *StoreCreditModel) SumRedeemedByOrderId(ctx context.Context, conn sqldb.Connection, orderId string) (int64, error) {
+	q := `SELECT COALESCE(-SUM(amount), 0) FROM store_credit_transaction WHERE order_id = $1 AND type IN ('redeem', 'restore')`
+
+	var amount int64
+
+	err := conn.QueryRowContext(ctx, q, orderId).Scan(&amount)
+
+	if err != nil {
+		return 0, err
+	}
+
+	return amount, nil
+}
@@ -0,0 +1,208 @@
+package model
+
+import (
+	"context"
+	"database/sql"
+	"ecom-backend/pkg/sqldb"
+	"errors"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type TaxRegionRecord struct {
+	Id             string    `json:"id"`
+	Name           string    `json:"name"`
+	CountryCode    string    `json:"country_code"`
+	Province       *string   `json:"province"` // nil when the region covers the whole country
+	Rate           float32   `json:"rate"`
+	IsTaxInclusive bool      `json:"is_tax_inclusive"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+type TaxRateOverrideRecord struct {
+	TaxRegionId string  `json:"-"`
+	CategoryId  string  `json:"category_id"`
+	Rate        float32 `json:"rate"`
+}
+
+type TaxRegionModel struct{}
+
+func NewTaxRegionModel() *TaxRegionModel {
+	return &TaxRegionModel{}
+}
+
+const taxRegionColumns = `id, name, country_code, province, rate, is_tax_inclusive, created_at, updated_at`
+
+func scanTaxRegion(row interface{ Scan(...any) error }, region *TaxRegionRecord) error {
+	return row.Scan(&region.Id, &region.Name, &region.CountryCode, &region.Province, &region.Rate, &region.IsTaxInclusive, &region.CreatedAt, &region.UpdatedAt)
+}
+
+func (m *TaxRegionModel) Insert(ctx context.Context, conn sqldb.Connection, region *TaxRegionRecord) (*TaxRegionRecord, error) {
+	q := `INSERT INTO tax_region (name, country_code, province, rate, is_tax_inclusive) VALUES ($1, $2, $3, $4, $5) RETURNING id, created_at, updated_at`
+
+	err := conn.QueryRowContext(ctx, q, region.Name, region.CountryCode, region.Province, region.Rate, region.IsTaxInclusive).Scan(&region.Id, &region.CreatedAt, &region.UpdatedAt)
+
+	if err != nil {
+		if err.Error() == `pq: duplicate key value violates unique constraint "tax_region_location_key"` {
+			return nil, ErrDuplicatedTaxRegion
+		}
+		return nil, err
+	}
+
+	return region, nil
+}
+
+func (m *TaxRegionModel) FindById(ctx context.Context, conn sqldb.Connection, id string) (*TaxRegionRecord, error) {
+	q := `SELECT ` + taxRegionColumns + ` FROM tax_region WHERE id = $1`
+
+	return m.findOne(ctx, conn, q, id)
+}
+
+// FindByLocation returns the region of the province when there's one, the region of the whole country otherwise
+func (m *TaxRegionModel) FindByLocation(ctx context.Context, conn sqldb.Connection, countryCode string, province *string) (*TaxRegionRecord, error) {
+	q := `SELECT ` + taxRegionColumns + ` FROM tax_region WHERE country_code = $1 AND (province = $2 OR province IS NULL)
+		  ORDER BY province IS NULL LIMIT 1`
+
+	return m.findOne(ctx, conn, q, countryCode, province)
+}
+
+func (m *TaxRegionModel) findOne(ctx context.Context, conn sqldb.Connection, q string, args ...any) (*TaxRegionRecord, error) {
+	var region TaxRegionRecord
+
+	err := scanTaxRegion(conn.QueryRowContext(ctx, q, args...), &region)
+
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrRecordNotFound
+		}
+		return nil, err
+	}
+
+	return &region, nil
+}
+
+func (m *TaxRegionModel) FindAll(ctx context.Context, conn sqldb.Connection) ([]*TaxRegionRecord, error) {
+	q := `SELECT ` + taxRegionColumns + ` FROM tax_region ORDER BY country_code, province NULLS FIRST`
+
+	rows, err := conn.QueryContext(ctx, q)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	regions := []*TaxRegionRecord{}
+
+	for rows.Next() {
+		var region TaxRegionRecord
+
+		err := scanTaxRegion(rows, &region)
+
+		if err != nil {
+			return nil, err
+		}
+
+		regions = append(regions, &region)
+	}
+
+	return regions, nil
+}
+
+func (m *TaxRegionModel) Update(ctx context.Context, conn sqldb.Connection, region *TaxRegionRecord) (*TaxRegionRecord, error) {
+	q := `UPDATE tax_region SET name = $1, rate = $2, is_tax_inclusive = $3, updated_at = $4 WHERE id = $5`
+
+	region.UpdatedAt = time.Now()
+
+	res, err := conn.ExecContext(ctx, q, region.Name, region.Rate, region.IsTaxInclusive, region.UpdatedAt, region.Id)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if rowsAff, _ := res.RowsAffected(); rowsAff == 0 {
+		return nil, ErrRecordNotFound
+	}
+
+	return region, nil
+}
+
+// Delete removes the region for good, orders keep their own copy of the rates they were taxed at
+func (m *TaxRegionModel) Delete(ctx context.Context, conn sqldb.Connection, id string) error {
+	q := `DELETE FROM tax_region WHERE id = $1`
+
+	res, err := conn.ExecContext(ctx, q, id)
+
+	if err != nil {
+		return err
+	}
+
+	if rowsAff, _ := res.RowsAffected(); rowsAff == 0 {
+		return ErrRecordNotFound
+	}
+
+	return nil
+}
+
+type TaxRateOverrideModel struct{}
+
+func NewTaxRateOverrideModel() *TaxRateOverrideModel {
+	return &TaxRateOverrideModel{}
+}
+
+func (m *TaxRateOverrideModel) Insert(ctx context.Context, conn sqldb.Connection, record *TaxRateOverrideRecord) (*TaxRateOverrideRecord, error) {
+	q := `INSERT INTO tax_rate_override (tax_region_id, category_id, rate) VALUES ($1, $2, $3)`
+
+	_, err := conn.ExecContext(ctx, q, record.TaxRegionId, record.CategoryId, record.Rate)
+
+	if err != nil {
+		switch {
+		case err.Error() == `pq: insert or update on table "tax_rate_override" violates foreign key constraint "tax_rate_override_category_id_fkey"`:
+			return nil, ErrProductCategoryNotFound
+		case err.Error() == `pq: duplicate key value violates unique constraint "tax_rate_override_pkey"`:
+			return nil, ErrInvalidValue
+		default:
+			return nil, err
+		}
+	}
+
+	return record, nil
+}
+
+func (m *TaxRateOverrideModel) FindAllByTaxRegionIds(ctx context.Context, conn sqldb.Connection, taxRegionIds []string) (map[string][]*TaxRateOverrideRecord, error) {
+	q := `SELECT tax_region_id, category_id, rate FROM tax_rate_override WHERE tax_region_id = ANY($1)`
+
+	rows, err := conn.QueryContext(ctx, q, pq.Array(taxRegionIds))
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	resultMap := make(map[string][]*TaxRateOverrideRecord)
+
+	for rows.Next() {
+		var record TaxRateOverrideRecord
+
+		err := rows.Scan(&record.TaxRegionId, &record.CategoryId, &record.Rate)
+
+		if err != nil {
+			return nil, err
+		}
+
+		resultMap[record.TaxRegionId] = append(resultMap[record.TaxRegionId], &record)
+	}
+
+	return resultMap, nil
+}
+
+func (m *TaxRateOverrideModel) DeleteAllByTaxRegionId(ctx context.Context, conn sqldb.Connection, taxRegionId string) error {
+	q := `DELETE FROM tax_rate_override WHERE tax_region_id = $1`
+
+	_, err := conn.ExecContext(ctx, q, taxRegionId)
+
+	return err
+}
@@ -6,6 +6,8 @@ import (
 	"ecom-backend/pkg/sqldb"
 	"errors"
 	"time"
+
+	"github.com/lib/pq"
 )
 
 type TokenRecord struct {
@@ -33,11 +35,14 @@ func (m *TokenModel) Insert(ctx context.Context, conn sqldb.Connection, record *
 func (m *TokenModel) GetUserByToken(ctx context.Context, conn sqldb.Connection, hash []byte, scope string, expiry time.Time) (*UserRecord, error) {
 	var user UserRecord
 
-	q := `SELECT u.id, u.name, u.email, u.is_admin, u.activated, u.created_at, u.updated_at FROM users as u
+	q := `SELECT u.id, u.name, u.email, u.is_admin, u.activated, u.created_at, u.updated_at,
+	ARRAY(SELECT cgm.customer_group_id FROM customer_group_member AS cgm WHERE cgm.user_id = u.id ORDER BY cgm.customer_group_id)
+	FROM users as u
 	INNER JOIN token ON token.user_id = u.id
 	WHERE token.hash = $1 AND token.scope = $2 AND token.expiry > $3`
 
-	err := conn.QueryRowContext(ctx, q, hash, scope, expiry).Scan(&user.Id, &user.Name, &user.Email, &user.IsAdmin, &user.Activated, &user.CreatedAt, &user.UpdatedAt)
+	err := conn.QueryRowContext(ctx, q, hash, scope, expiry).Scan(&user.Id, &user.Name, &user.Email, &user.IsAdmin, &user.Activated, &user.CreatedAt, &user.UpdatedAt,
+		pq.Array(&user.CustomerGroupIds))
 
 	if err != nil {
 		switch {
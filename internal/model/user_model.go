@@ -17,6 +17,8 @@ type UserRecord struct {
 	UpdatedAt    time.Time `json:"updated_at"`
 	Activated    bool      `json:"-"`
 	IsAdmin      bool      `json:"-"`
+	// CustomerGroupIds are the groups the user belongs to, loaded with the user of an authentication token
+	CustomerGroupIds []string `json:"customer_group_ids,omitempty"`
 }
 
 type UserModel struct{}
@@ -65,3 +67,24 @@ func (m *UserModel) FindByEmail(ctx context.Context, conn sqldb.Connection, emai
 
 	return &user, nil
 }
+
+func (m *UserModel) FindById(ctx context.Context, conn sqldb.Connection, id string) (*UserRecord, error) {
+	q := `SELECT id, name, email, activated, created_at, updated_at FROM users WHERE id = $1`
+
+	row := conn.QueryRowContext(ctx, q, id)
+
+	var user UserRecord
+
+	err := row.Scan(&user.Id, &user.Name, &user.Email, &user.Activated, &user.CreatedAt, &user.UpdatedAt)
+
+	if err != nil {
+		switch {
+		case errors.Is(err, sql.ErrNoRows):
+			return nil, ErrRecordNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	return &user, nil
+}
@@ -0,0 +1,420 @@
+// Package money handles amounts of money as integers in the minor units of their currency (cents for usd, yen for jpy),
+// so adding up prices, discounts and taxes never drifts the way floats do.
+package money
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultExponent is the number of decimals of a currency missing from the currency table
+const DefaultExponent = 2
+
+var (
+	exponentsMu sync.RWMutex
+	exponents   = map[string]int{}
+)
+
+// SetExponents registers the number of decimals of every currency, it's loaded from the currency table at startup
+func SetExponents(currencyExponents map[string]int) {
+	exponentsMu.Lock()
+	defer exponentsMu.Unlock()
+
+	exponents = map[string]int{}
+
+	for code, exponent := range currencyExponents {
+		exponents[strings.ToLower(code)] = exponent
+	}
+}
+
+// Exponent returns the number of decimals of the currency
+func Exponent(currencyCode string) int {
+	exponentsMu.RLock()
+	defer exponentsMu.RUnlock()
+
+	if exponent, ok := exponents[strings.ToLower(currencyCode)]; ok {
+		return exponent
+	}
+
+	return DefaultExponent
+}
+
+// Money is an amount in the minor units of a currency. The zero value has no currency, it can be added to any amount
+// which makes it usable as the starting point of a sum.
+type Money struct {
+	Amount       int64  // in minor units
+	CurrencyCode string // lowercase ISO 4217 code
+}
+
+func New(amount int64, currencyCode string) Money {
+	return Money{Amount: amount, CurrencyCode: currencyCode}
+}
+
+func Zero(currencyCode string) Money {
+	return Money{CurrencyCode: currencyCode}
+}
+
+// SetCurrency sets the currency of amounts scanned from the database, the rows only store the minor units
+func SetCurrency(currencyCode string, amounts ...*Money) {
+	for _, amount := range amounts {
+		if amount != nil {
+			amount.CurrencyCode = currencyCode
+		}
+	}
+}
+
+// currencyOf returns the currency of an operation on both amounts, mixing currencies is a programming error
+func currencyOf(a, b Money) string {
+	switch {
+	case a.CurrencyCode == "":
+		return b.CurrencyCode
+	case b.CurrencyCode == "" || strings.EqualFold(a.CurrencyCode, b.CurrencyCode):
+		return a.CurrencyCode
+	default:
+		panic(fmt.Sprintf("money: mixing %s and %s amounts", a.CurrencyCode, b.CurrencyCode))
+	}
+}
+
+func (m Money) Add(other Money) Money {
+	return Money{Amount: m.Amount + other.Amount, CurrencyCode: currencyOf(m, other)}
+}
+
+func (m Money) Sub(other Money) Money {
+	return Money{Amount: m.Amount - other.Amount, CurrencyCode: currencyOf(m, other)}
+}
+
+func (m Money) Mul(quantity int) Money {
+	return Money{Amount: m.Amount * int64(quantity), CurrencyCode: m.CurrencyCode}
+}
+
+// Div splits the amount in equal parts, rounded to the nearest minor unit
+func (m Money) Div(parts int) Money {
+	return m.Scale(1 / float64(parts))
+}
+
+// Scale multiplies the amount by the factor and rounds the result to the nearest minor unit, halves away from zero
+func (m Money) Scale(factor float64) Money {
+	return Money{Amount: int64(math.Round(float64(m.Amount) * factor)), CurrencyCode: m.CurrencyCode}
+}
+
+// Percentage returns the rate percent of the amount, rounded like Scale
+func (m Money) Percentage(rate float32) Money {
+	return m.Scale(float64(rate) / 100)
+}
+
+// Allocate splits the amount in parts proportional to the weights without losing a minor unit: the parts always add up
+// to the amount, the units left over by the rounding go to the parts with the largest remainders.
+// The amount is split in equal parts when every weight is zero.
+func (m Money) Allocate(weights []int64) []Money {
+	parts := make([]Money, len(weights))
+
+	if len(weights) == 0 {
+		return parts
+	}
+
+	var totalWeight int64
+
+	for _, weight := range weights {
+		totalWeight += weight
+	}
+
+	if totalWeight == 0 {
+		weights = make([]int64, len(weights))
+
+		for i := range weights {
+			weights[i] = 1
+		}
+
+		totalWeight = int64(len(weights))
+	}
+
+	remainders := make([]int64, len(weights))
+	allocated := int64(0)
+
+	for i, weight := range weights {
+		parts[i] = Money{Amount: m.Amount * weight / totalWeight, CurrencyCode: m.CurrencyCode}
+		remainders[i] = m.Amount * weight % totalWeight
+		allocated += parts[i].Amount
+	}
+
+	unit := int64(1)
+
+	if m.Amount < 0 {
+		unit = -1
+	}
+
+	for left := m.Amount - allocated; left != 0; left -= unit {
+		largest := 0
+
+		for i := range remainders {
+			if remainders[i]*unit > remainders[largest]*unit {
+				largest = i
+			}
+		}
+
+		parts[largest].Amount += unit
+		remainders[largest] = 0
+	}
+
+	return parts
+}
+
+// Convert turns the amount into another currency at the rate, the number of units of the other currency for one unit
+// of this one. The rate is taken as the decimal it's written with, like the exchange rates stored in the database, and
+// the result is rounded halves away from zero, so the amount is the one the database computes from the same rate.
+func (m Money) Convert(rate float64, currencyCode string) Money {
+	converted, ok := new(big.Rat).SetString(strconv.FormatFloat(rate, 'f', -1, 64))
+
+	if !ok {
+		return Money{Amount: m.Amount, CurrencyCode: currencyCode}.Scale(rate * math.Pow10(Exponent(currencyCode)-Exponent(m.CurrencyCode)))
+	}
+
+	converted.Mul(converted, new(big.Rat).SetInt64(m.Amount))
+
+	shift := Exponent(currencyCode) - Exponent(m.CurrencyCode)
+	power := new(big.Rat).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(max(shift, -shift))), nil))
+
+	if shift >= 0 {
+		converted.Mul(converted, power)
+	} else {
+		converted.Quo(converted, power)
+	}
+
+	// |x| + 1/2 truncated is |x| rounded halves up
+	numerator := new(big.Int).Abs(converted.Num())
+	numerator.Add(numerator.Lsh(numerator, 1), converted.Denom())
+	rounded := numerator.Quo(numerator, new(big.Int).Lsh(converted.Denom(), 1)).Int64()
+
+	if converted.Sign() < 0 {
+		rounded = -rounded
+	}
+
+	return Money{Amount: rounded, CurrencyCode: currencyCode}
+}
+
+// RoundUp raises the amount to the closest one that ends with ending in steps of increment, both in minor units.
+// An increment of 100 and an ending of 99 turns 12.34 into 12.99, an increment of 5 and no ending rounds up to the next 0.05.
+func (m Money) RoundUp(increment int64, ending int64) Money {
+	if increment <= 1 {
+		return m
+	}
+
+	steps := (m.Amount - ending) / increment
+
+	// the division truncates towards zero, it has to go up
+	if (m.Amount-ending)%increment > 0 {
+		steps++
+	}
+
+	return Money{Amount: steps*increment + ending, CurrencyCode: m.CurrencyCode}
+}
+
+// Cmp compares the amounts, -1 when m is lower than other, 1 when it's greater and 0 when they're equal
+func (m Money) Cmp(other Money) int {
+	currencyOf(m, other)
+
+	switch {
+	case m.Amount < other.Amount:
+		return -1
+	case m.Amount > other.Amount:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func (m Money) LessThan(other Money) bool {
+	return m.Cmp(other) < 0
+}
+
+func (m Money) IsZero() bool {
+	return m.Amount == 0
+}
+
+func (m Money) IsPositive() bool {
+	return m.Amount > 0
+}
+
+func (m Money) IsNegative() bool {
+	return m.Amount < 0
+}
+
+func Min(a, b Money) Money {
+	if b.LessThan(a) {
+		return b
+	}
+
+	return a
+}
+
+func Max(a, b Money) Money {
+	if a.LessThan(b) {
+		return b
+	}
+
+	return a
+}
+
+// String formats the amount in major units with the decimals of its currency, e.g. 19.99 or 1500 for jpy
+func (m Money) String() string {
+	return m.format(Exponent(m.CurrencyCode))
+}
+
+// MarshalJSON writes the amount as a number in major units, the way clients already read prices
+func (m Money) MarshalJSON() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+// Scan reads the minor units stored in a bigint column, the currency comes from another column and is set by the caller
+func (m *Money) Scan(src any) error {
+	switch value := src.(type) {
+	case int64:
+		m.Amount = value
+	case []byte:
+		amount, err := strconv.ParseInt(string(value), 10, 64)
+
+		if err != nil {
+			return fmt.Errorf("money: cannot scan %q: %w", value, err)
+		}
+
+		m.Amount = amount
+	default:
+		return fmt.Errorf("money: cannot scan %T", src)
+	}
+
+	return nil
+}
+
+// Value stores the minor units
+func (m Money) Value() (driver.Value, error) {
+	return m.Amount, nil
+}
+
+var ErrInvalidDecimal = errors.New("amounts of money must be decimal numbers")
+
+// Decimal is an amount in major units as sent by clients, e.g. 19.99. It's kept exact until its currency is known
+// and it can be turned into Money, so it never goes through a float.
+type Decimal struct {
+	value int64 // the digits without the decimal point
+	scale int   // the number of decimals in value
+}
+
+// ParseDecimal reads a number written with digits, an optional decimal point and an optional leading "-"
+func ParseDecimal(s string) (Decimal, error) {
+	var d Decimal
+
+	negative := strings.HasPrefix(s, "-")
+	s = strings.TrimPrefix(s, "-")
+
+	integerPart, fractionPart, _ := strings.Cut(s, ".")
+
+	// the parts are checked digit by digit since strconv also takes signs, exponents and underscores
+	if integerPart == "" || !isDigits(integerPart) || !isDigits(fractionPart) {
+		return d, ErrInvalidDecimal
+	}
+
+	fractionPart = strings.TrimRight(fractionPart, "0")
+
+	value, err := strconv.ParseInt(integerPart+fractionPart, 10, 64)
+
+	if err != nil {
+		return d, ErrInvalidDecimal
+	}
+
+	if negative {
+		value = -value
+	}
+
+	return Decimal{value: value, scale: len(fractionPart)}, nil
+}
+
+func isDigits(s string) bool {
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (d *Decimal) UnmarshalJSON(data []byte) error {
+	// like the other types, null leaves the value untouched
+	if string(data) == "null" {
+		return nil
+	}
+
+	parsed, err := ParseDecimal(string(data))
+
+	if err != nil {
+		return err
+	}
+
+	*d = parsed
+
+	return nil
+}
+
+func (d Decimal) MarshalJSON() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+func (d Decimal) String() string {
+	return Money{Amount: d.value}.format(d.scale)
+}
+
+// format is String with an explicit number of decimals
+func (m Money) format(exponent int) string {
+	digits := strconv.FormatInt(m.Amount, 10)
+	sign := ""
+
+	if m.Amount < 0 {
+		sign = "-"
+		digits = digits[1:]
+	}
+
+	if exponent == 0 {
+		return sign + digits
+	}
+
+	if len(digits) <= exponent {
+		digits = strings.Repeat("0", exponent-len(digits)+1) + digits
+	}
+
+	return sign + digits[:len(digits)-exponent] + "." + digits[len(digits)-exponent:]
+}
+
+// Sign returns -1, 0 or 1 depending on the sign of the amount
+func (d Decimal) Sign() int {
+	switch {
+	case d.value < 0:
+		return -1
+	case d.value > 0:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// FitsCurrency reports whether the amount has no more decimals than the currency, 0.5 jpy can't be paid
+func (d Decimal) FitsCurrency(currencyCode string) bool {
+	return d.scale <= Exponent(currencyCode)
+}
+
+// Money converts the amount to the minor units of the currency, the extra decimals are rounded like Scale
+// when the amount doesn't fit the currency
+func (d Decimal) Money(currencyCode string) Money {
+	shift := Exponent(currencyCode) - d.scale
+
+	if shift >= 0 {
+		return Money{Amount: d.value * int64(math.Pow10(shift)), CurrencyCode: currencyCode}
+	}
+
+	return Money{Amount: d.value, CurrencyCode: currencyCode}.Scale(math.Pow10(shift))
+}
@@ -0,0 +1,209 @@
+package money
+
+import (
+	"errors"
+	"testing"
+)
+
+func setTestExponents(t *testing.T) {
+	t.Helper()
+
+	SetExponents(map[string]int{"usd": 2, "eur": 2, "jpy": 0, "bhd": 3})
+	t.Cleanup(func() { SetExponents(nil) })
+}
+
+func TestAllocate(t *testing.T) {
+	tests := []struct {
+		name    string
+		amount  int64
+		weights []int64
+		want    []int64
+	}{
+		{"no weights", 100, []int64{}, []int64{}},
+		{"even split", 90, []int64{1, 1, 1}, []int64{30, 30, 30}},
+		{"remainder goes to the first of equal remainders", 100, []int64{1, 1, 1}, []int64{34, 33, 33}},
+		{"remainder goes to the largest remainder", 10, []int64{1, 2}, []int64{3, 7}},
+		{"proportional", 100, []int64{1, 3}, []int64{25, 75}},
+		{"zero weight gets nothing", 10, []int64{0, 1}, []int64{0, 10}},
+		{"all zero weights split evenly", 5, []int64{0, 0}, []int64{3, 2}},
+		{"negative amount", -100, []int64{1, 1, 1}, []int64{-34, -33, -33}},
+		{"negative amount with weights", -10, []int64{1, 2}, []int64{-3, -7}},
+		{"zero amount", 0, []int64{1, 2}, []int64{0, 0}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			parts := New(tt.amount, "usd").Allocate(tt.weights)
+
+			if len(parts) != len(tt.want) {
+				t.Fatalf("got %d parts, want %d", len(parts), len(tt.want))
+			}
+
+			var sum int64
+
+			for i, part := range parts {
+				sum += part.Amount
+
+				if part.Amount != tt.want[i] {
+					t.Errorf("part %d = %d, want %d", i, part.Amount, tt.want[i])
+				}
+
+				if part.CurrencyCode != "usd" {
+					t.Errorf("part %d currency = %q, want usd", i, part.CurrencyCode)
+				}
+			}
+
+			if len(parts) > 0 && sum != tt.amount {
+				t.Errorf("parts add up to %d, want %d", sum, tt.amount)
+			}
+		})
+	}
+}
+
+func TestConvert(t *testing.T) {
+	setTestExponents(t)
+
+	tests := []struct {
+		name     string
+		amount   Money
+		rate     float64
+		currency string
+		want     int64
+	}{
+		{"same exponent", New(1999, "usd"), 0.92, "eur", 1839},
+		{"to a currency without decimals", New(1000, "usd"), 150, "jpy", 1500},
+		{"from a currency without decimals", New(1500, "jpy"), 0.0067, "usd", 1005},
+		{"to a currency with 3 decimals", New(1000, "usd"), 0.376, "bhd", 3760},
+		{"halves round away from zero", New(5, "usd"), 0.5, "eur", 3},
+		{"negative halves round away from zero", New(-5, "usd"), 0.5, "eur", -3},
+		{"halves the float product misses", New(25, "usd"), 0.58, "eur", 15},
+		{"negative halves the float product misses", New(-25, "usd"), 0.58, "eur", -15},
+		{"to a currency without decimals rounding a half", New(150, "usd"), 1.01, "jpy", 2},
+		{"from a currency without decimals with 8 decimal rate", New(1, "jpy"), 0.00673125, "usd", 1},
+		{"large rate", New(199, "usd"), 9999999.5, "eur", 1989999901},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.amount.Convert(tt.rate, tt.currency)
+
+			if got.Amount != tt.want || got.CurrencyCode != tt.currency {
+				t.Errorf("got %d %s, want %d %s", got.Amount, got.CurrencyCode, tt.want, tt.currency)
+			}
+		})
+	}
+}
+
+func TestRoundUp(t *testing.T) {
+	tests := []struct {
+		name      string
+		amount    int64
+		increment int64
+		ending    int64
+		want      int64
+	}{
+		{"no increment", 1234, 0, 0, 1234},
+		{"increment of one unit", 1234, 1, 0, 1234},
+		{"up to the ending", 1234, 100, 99, 1299},
+		{"already ending with it", 1299, 100, 99, 1299},
+		{"just past the ending", 1300, 100, 99, 1399},
+		{"below the first ending", 50, 100, 99, 99},
+		{"up to the next step", 1234, 5, 0, 1235},
+		{"already on a step", 1235, 5, 0, 1235},
+		{"negative amount goes up", -150, 100, 0, -100},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := New(tt.amount, "usd").RoundUp(tt.increment, tt.ending)
+
+			if got.Amount != tt.want {
+				t.Errorf("got %d, want %d", got.Amount, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseDecimal(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"19.99", "19.99"},
+		{"0", "0"},
+		{"-5", "-5"},
+		{"5.", "5"},
+		{"0.10", "0.1"},
+		{"-0.05", "-0.05"},
+		{"007", "7"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			d, err := ParseDecimal(tt.input)
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if d.String() != tt.want {
+				t.Errorf("got %s, want %s", d, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseDecimalInvalid(t *testing.T) {
+	inputs := []string{"", "-", ".5", "-.5", "+5", "--5", "-+5", "+-5", "5-", "1e3", "1E3", "1.2.3", "1.-2", "1.+2", "1_000", " 5", "abc", "0x10", `"19.99"`, "99999999999999999999"}
+
+	for _, input := range inputs {
+		t.Run(input, func(t *testing.T) {
+			_, err := ParseDecimal(input)
+
+			if !errors.Is(err, ErrInvalidDecimal) {
+				t.Errorf("got error %v, want ErrInvalidDecimal", err)
+			}
+		})
+	}
+}
+
+func TestDecimalMoney(t *testing.T) {
+	setTestExponents(t)
+
+	tests := []struct {
+		input    string
+		currency string
+		want     int64
+		fits     bool
+	}{
+		{"19.99", "usd", 1999, true},
+		{"19.9", "usd", 1990, true},
+		{"19", "usd", 1900, true},
+		{"1500", "jpy", 1500, true},
+		{"12.3", "bhd", 12300, true},
+		{"-2.50", "usd", -250, true},
+		{"0.5", "jpy", 1, false},
+		{"-2.5", "jpy", -3, false},
+		{"1.005", "usd", 101, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input+" "+tt.currency, func(t *testing.T) {
+			d, err := ParseDecimal(tt.input)
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if d.FitsCurrency(tt.currency) != tt.fits {
+				t.Errorf("FitsCurrency = %v, want %v", !tt.fits, tt.fits)
+			}
+
+			got := d.Money(tt.currency)
+
+			if got.Amount != tt.want || got.CurrencyCode != tt.currency {
+				t.Errorf("got %d %s, want %d %s", got.Amount, got.CurrencyCode, tt.want, tt.currency)
+			}
+		})
+	}
+}
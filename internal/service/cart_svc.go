@@ -0,0 +1,602 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"ecom-backend/internal/consts"
+	"ecom-backend/internal/model"
+	"ecom-backend/internal/money"
+	"ecom-backend/internal/validator"
+	"ecom-backend/pkg/sqldb"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+type CartService struct {
+	db            *sql.DB
+	models        *model.Models
+	promotionSvc  *PromotionService
+	shippingSvc   *ShippingService
+	taxCalculator TaxCalculator
+	priceResolver *PriceResolver
+}
+
+func NewCartService(db *sql.DB, models *model.Models, promotionSvc *PromotionService, shippingSvc *ShippingService, taxCalculator TaxCalculator, priceResolver *PriceResolver) *CartService {
+	return &CartService{db: db, models: models, promotionSvc: promotionSvc, shippingSvc: shippingSvc, taxCalculator: taxCalculator, priceResolver: priceResolver}
+}
+
+type CartDTO struct {
+	Id             string                 `json:"id"`
+	CurrencyCode   string                 `json:"currency_code"`
+	Items          []*CartItemDTO         `json:"items"`
+	Subtotal       money.Money            `json:"subtotal"`
+	PromotionCode  *string                `json:"promotion_code"`
+	Promotions     []*AppliedPromotionDTO `json:"promotions"`      // the automatic promotions and the code that apply
+	PromotionError *string                `json:"promotion_error"` // why the applied code gives no discount at the moment
+	DiscountTotal  money.Money            `json:"discount_total"`
+	TaxTotal       money.Money            `json:"tax_total"`        // estimated, only when the shipping location is known
+	IsTaxInclusive bool                   `json:"is_tax_inclusive"` // the taxes are part of the subtotal instead of being added to the total
+	Total          money.Money            `json:"total"`
+}
+
+type CartItemDTO struct {
+	Id                string                  `json:"id"`
+	ProductId         string                  `json:"product_id"`
+	ProductTitle      string                  `json:"product_title"`
+	ThumbnailId       *string                 `json:"thumbnail_id"`
+	VariantId         string                  `json:"variant_id"`
+	VariantTitle      string                  `json:"variant_title"`
+	Sku               *string                 `json:"sku"`
+	Quantity          int                     `json:"quantity"`
+	UnitPrice         *money.Money            `json:"unit_price"`          // nil when the variant has no price in the requested currency
+	OriginalUnitPrice *money.Money            `json:"original_unit_price"` // the "compare at" price, set only when a price list applies
+	Subtotal          money.Money             `json:"subtotal"`
+	Discount          money.Money             `json:"discount"`
+	TaxRate           float32                 `json:"tax_rate"`
+	TaxTotal          money.Money             `json:"tax_total"`
+	Options           []VariantOptionValueDTO `json:"options"`
+
+	weight *float32 // of a single unit, used to price the shipping
+}
+
+type AddCartItemInput struct {
+	VariantId string `json:"variant_id"`
+	Quantity  int    `json:"quantity"`
+}
+
+func (input *AddCartItemInput) Validate(v *validator.Validator) {
+	v.Check(input.VariantId != "", "variant_id", "must be provided")
+	v.Check(validator.IsValidUUID(input.VariantId), "variant_id", "is not valid uuid")
+	v.Check(input.Quantity > 0, "quantity", "must be greater than zero")
+}
+
+type UpdateCartItemInput struct {
+	Quantity int `json:"quantity"`
+}
+
+func (input *UpdateCartItemInput) Validate(v *validator.Validator) {
+	v.Check(input.Quantity > 0, "quantity", "must be greater than zero")
+}
+
+func (svc *CartService) AddItem(ctx context.Context, userIdentifier string, input *AddCartItemInput) (*model.CartItemRecord, error) {
+	tx, err := svc.db.BeginTx(ctx, nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer tx.Rollback()
+
+	// only variants of published products can be added to the cart
+	variant, err := svc.models.ProductVariantModel.FindById(ctx, tx, input.VariantId)
+
+	if err != nil {
+		if errors.Is(err, model.ErrRecordNotFound) {
+			return nil, model.ErrVariantNotFound
+		}
+		return nil, err
+	}
+
+	product, err := svc.models.ProductModel.FindById(ctx, tx, variant.ProductId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if variant.DeletedAt != nil || product.DeletedAt != nil || product.Status != consts.StatusPublished {
+		return nil, model.ErrVariantNotFound
+	}
+
+	cart, err := svc.models.CartModel.Upsert(ctx, tx, userIdentifier)
+
+	if err != nil {
+		return nil, err
+	}
+
+	item, err := svc.models.CartItemModel.Insert(ctx, tx, &model.CartItemRecord{CartId: cart.Id, VariantId: input.VariantId, Quantity: input.Quantity})
+
+	if err != nil {
+		return nil, err
+	}
+
+	// the quantity is added to the item when the variant is already in the cart
+	err = svc.resizeItemReservation(ctx, tx, item, item.Quantity-input.Quantity)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return item, nil
+}
+
+func (svc *CartService) UpdateItemQuantity(ctx context.Context, userIdentifier string, itemId string, input *UpdateCartItemInput) error {
+	tx, err := svc.db.BeginTx(ctx, nil)
+
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	item, err := svc.findOwnedItem(ctx, tx, userIdentifier, itemId)
+
+	if err != nil {
+		return err
+	}
+
+	err = svc.models.CartItemModel.UpdateQuantity(ctx, tx, item.Id, input.Quantity)
+
+	if err != nil {
+		return err
+	}
+
+	previousQuantity := item.Quantity
+	item.Quantity = input.Quantity
+
+	err = svc.resizeItemReservation(ctx, tx, item, previousQuantity)
+
+	if err != nil {
+		return err
+	}
+
+	err = svc.models.CartModel.Touch(ctx, tx, item.CartId)
+
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// resizeItemReservation makes the stock the cart holds for the item, once reserved, follow the quantity of the item
+// changed from the previous one. A larger quantity must be available next to what the other carts hold, the variant is
+// locked like ReserveCart does.
+func (svc *CartService) resizeItemReservation(ctx context.Context, tx *sql.Tx, item *model.CartItemRecord, previousQuantity int) error {
+	quantity := item.Quantity
+
+	if quantity <= previousQuantity {
+		_, err := svc.models.InventoryReservationModel.UpdateActiveQuantity(ctx, tx, item.CartId, item.VariantId, quantity)
+
+		return err
+	}
+
+	variantsMap, err := svc.models.ProductVariantModel.FindAllByIdsForUpdate(ctx, tx, []string{item.VariantId})
+
+	if err != nil {
+		return err
+	}
+
+	reserved, err := svc.models.InventoryReservationModel.UpdateActiveQuantity(ctx, tx, item.CartId, item.VariantId, quantity)
+
+	if err != nil || !reserved {
+		return err
+	}
+
+	variant, ok := variantsMap[item.VariantId]
+
+	if !ok {
+		return model.ErrVariantNotFound
+	}
+
+	reservedByOthers, err := svc.models.InventoryReservationModel.SumActiveByVariantIds(ctx, tx, []string{variant.Id}, &item.CartId)
+
+	if err != nil {
+		return err
+	}
+
+	if variant.InventoryQuantity-reservedByOthers[variant.Id] < quantity {
+		return fmt.Errorf("%w for %s", model.ErrInsufficientInventory, variant.Title)
+	}
+
+	return nil
+}
+
+func (svc *CartService) RemoveItem(ctx context.Context, userIdentifier string, itemId string) error {
+	tx, err := svc.db.BeginTx(ctx, nil)
+
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	item, err := svc.findOwnedItem(ctx, tx, userIdentifier, itemId)
+
+	if err != nil {
+		return err
+	}
+
+	err = svc.models.CartItemModel.DeleteById(ctx, tx, item.Id)
+
+	if err != nil {
+		return err
+	}
+
+	// the removed item has nothing to hold stock for
+	err = svc.models.InventoryReservationModel.UpdateActiveStatusByCartIdAndVariantId(ctx, tx, item.CartId, item.VariantId, consts.ReservationStatusReleased)
+
+	if err != nil {
+		return err
+	}
+
+	err = svc.models.CartModel.Touch(ctx, tx, item.CartId)
+
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func (svc *CartService) Clear(ctx context.Context, userIdentifier string) error {
+	cart, err := svc.models.CartModel.FindByUserIdentifier(ctx, svc.db, userIdentifier)
+
+	if err != nil {
+		// nothing to clear
+		if errors.Is(err, model.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	tx, err := svc.db.BeginTx(ctx, nil)
+
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	// an empty cart has nothing to hold stock for
+	err = svc.models.InventoryReservationModel.UpdateActiveStatusByCartId(ctx, tx, cart.Id, consts.ReservationStatusReleased)
+
+	if err != nil {
+		return err
+	}
+
+	err = svc.models.CartItemModel.DeleteAllByCartId(ctx, tx, cart.Id)
+
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// findOwnedItem returns the cart item only if it belongs to the cart of the given client
+func (svc *CartService) findOwnedItem(ctx context.Context, conn sqldb.Connection, userIdentifier string, itemId string) (*model.CartItemRecord, error) {
+	item, err := svc.models.CartItemModel.FindById(ctx, conn, itemId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	cart, err := svc.models.CartModel.FindByUserIdentifier(ctx, conn, userIdentifier)
+
+	if err != nil {
+		if errors.Is(err, model.ErrRecordNotFound) {
+			return nil, ErrUnauthorizedRequest
+		}
+		return nil, err
+	}
+
+	if item.CartId != cart.Id {
+		return nil, ErrUnauthorizedRequest
+	}
+
+	return item, nil
+}
+
+// GetCart returns the cart of the client with every line priced in the requested currency for the groups of the
+// customer. The taxes are estimated only when the location the cart will be shipped to is given.
+func (svc *CartService) GetCart(ctx context.Context, userIdentifier string, customerGroupIds []string, currencyCode string, taxLocation *TaxLocation) (*CartDTO, error) {
+	zero := money.Zero(currencyCode)
+	cartDto := &CartDTO{CurrencyCode: currencyCode, Items: []*CartItemDTO{}, Promotions: []*AppliedPromotionDTO{}, Subtotal: zero, DiscountTotal: zero, TaxTotal: zero, Total: zero}
+
+	cart, err := svc.models.CartModel.FindByUserIdentifier(ctx, svc.db, userIdentifier)
+
+	if err != nil {
+		// the cart is created when the first item is added, until then it's just empty
+		if errors.Is(err, model.ErrRecordNotFound) {
+			return cartDto, nil
+		}
+		return nil, err
+	}
+
+	cartDto.Id = cart.Id
+
+	q := `SELECT ci.id, ci.quantity, p.id, p.title, p.thumbnail_id, pv.id, pv.title, pv.sku, pv.weight
+		  FROM cart_item AS ci
+		  INNER JOIN product_variant AS pv ON pv.id = ci.variant_id
+		  INNER JOIN product AS p ON p.id = pv.product_id
+		  WHERE ci.cart_id = $1
+		  ORDER BY ci.created_at`
+
+	rows, err := svc.db.QueryContext(ctx, q, cart.Id)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	variantIds := []string{}
+	quantities := map[string]int{}
+
+	for rows.Next() {
+		item := CartItemDTO{Options: []VariantOptionValueDTO{}, Subtotal: zero, Discount: zero, TaxTotal: zero}
+
+		err := rows.Scan(&item.Id, &item.Quantity, &item.ProductId, &item.ProductTitle, &item.ThumbnailId, &item.VariantId, &item.VariantTitle, &item.Sku, &item.weight)
+
+		if err != nil {
+			return nil, err
+		}
+
+		cartDto.Items = append(cartDto.Items, &item)
+		variantIds = append(variantIds, item.VariantId)
+		quantities[item.VariantId] = item.Quantity
+	}
+
+	pricesMap, err := svc.priceResolver.resolveVariantPrices(ctx, svc.db, PriceContext{CurrencyCode: &currencyCode, At: time.Now(), Quantities: quantities, CustomerGroupIds: customerGroupIds}, variantIds)
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, item := range cartDto.Items {
+		price, ok := pricesMap[item.VariantId]
+
+		if !ok {
+			continue
+		}
+
+		unitPrice := price.EffectiveAmount()
+		item.UnitPrice = &unitPrice
+
+		if price.ListPrice != nil {
+			item.OriginalUnitPrice = &price.Amount
+		}
+
+		item.Subtotal = unitPrice.Mul(item.Quantity)
+		cartDto.Subtotal = cartDto.Subtotal.Add(item.Subtotal)
+	}
+
+	optionValuesMap, err := findVariantOptionValues(ctx, svc.db, variantIds)
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, item := range cartDto.Items {
+		if options, ok := optionValuesMap[item.VariantId]; ok {
+			item.Options = options
+		}
+	}
+
+	cartDto.Total = cartDto.Subtotal
+
+	cartDto.PromotionCode = cart.PromotionCode
+
+	if len(cartDto.Items) > 0 {
+		err := svc.applyCartPromotions(ctx, cartDto, promotionCustomer{UserIdentifier: userIdentifier, CustomerGroupIds: customerGroupIds})
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if len(cartDto.Items) > 0 && taxLocation != nil {
+		err := svc.applyCartTaxes(ctx, cartDto, *taxLocation)
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return cartDto, nil
+}
+
+// applyCartTaxes estimates the taxes of the cart lines once the discounts are taken off
+func (svc *CartService) applyCartTaxes(ctx context.Context, cartDto *CartDTO, location TaxLocation) error {
+	lines := []TaxLine{}
+
+	for _, item := range cartDto.Items {
+		lines = append(lines, TaxLine{VariantId: item.VariantId, ProductId: item.ProductId, Quantity: item.Quantity, Amount: item.Subtotal.Sub(item.Discount)})
+	}
+
+	taxes, err := svc.taxCalculator.Calculate(ctx, TaxCalculationRequest{CurrencyCode: cartDto.CurrencyCode, Location: location, Lines: lines})
+
+	if err != nil {
+		return err
+	}
+
+	for _, item := range cartDto.Items {
+		if lineTax := taxes.findLineTax(item.VariantId); lineTax != nil {
+			item.TaxRate = lineTax.Rate
+			item.TaxTotal = lineTax.Amount
+		}
+	}
+
+	cartDto.TaxTotal = taxes.TaxTotal
+	cartDto.IsTaxInclusive = taxes.IsTaxInclusive
+
+	if !taxes.IsTaxInclusive {
+		cartDto.Total = cartDto.Total.Add(taxes.TaxTotal)
+	}
+
+	return nil
+}
+
+// applyCartPromotions previews the discounts of the automatic promotions and of the code on the cart. A code that
+// stopped being applicable stays on the cart with the reason, so the client can tell the customer instead of silently dropping it.
+func (svc *CartService) applyCartPromotions(ctx context.Context, cartDto *CartDTO, customer promotionCustomer) error {
+	evaluation, err := svc.promotionSvc.EvaluateCart(ctx, svc.db, cartDto.PromotionCode, cartDto.CurrencyCode, cartPromotionLines(cartDto), customer)
+
+	if err != nil {
+		return err
+	}
+
+	if evaluation.CodeError != nil {
+		reason := evaluation.CodeError.Error()
+		cartDto.PromotionError = &reason
+	}
+
+	for _, applied := range evaluation.Applied {
+		for _, item := range cartDto.Items {
+			for _, lineDiscount := range applied.Items {
+				if lineDiscount.VariantId == item.VariantId {
+					item.Discount = item.Discount.Add(lineDiscount.Amount)
+				}
+			}
+		}
+	}
+
+	cartDto.Promotions = evaluation.Applied
+	cartDto.DiscountTotal = evaluation.DiscountTotal
+	cartDto.Total = cartDto.Subtotal.Sub(evaluation.DiscountTotal)
+
+	return nil
+}
+
+func cartPromotionLines(cartDto *CartDTO) []*promotionLine {
+	lines := []*promotionLine{}
+
+	for _, item := range cartDto.Items {
+		lines = append(lines, &promotionLine{VariantId: item.VariantId, ProductId: item.ProductId, Quantity: item.Quantity, Subtotal: item.Subtotal})
+	}
+
+	return lines
+}
+
+// ApplyPromotionCode checks the code against the current cart and keeps it on the cart
+func (svc *CartService) ApplyPromotionCode(ctx context.Context, userIdentifier string, customerGroupIds []string, code string, currencyCode string, taxLocation *TaxLocation) (*CartDTO, error) {
+	cartDto, err := svc.GetCart(ctx, userIdentifier, customerGroupIds, currencyCode, nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(cartDto.Items) == 0 {
+		return nil, ErrEmptyCart
+	}
+
+	evaluation, err := svc.promotionSvc.EvaluateCart(ctx, svc.db, &code, currencyCode, cartPromotionLines(cartDto), promotionCustomer{UserIdentifier: userIdentifier, CustomerGroupIds: customerGroupIds})
+
+	if err != nil {
+		return nil, err
+	}
+
+	if evaluation.CodeError != nil {
+		return nil, evaluation.CodeError
+	}
+
+	// stored as defined on the promotion so the cart shows it the same way
+	for _, applied := range evaluation.Applied {
+		if !applied.IsAutomatic {
+			code = *applied.Code
+		}
+	}
+
+	err = svc.models.CartModel.SetPromotionCode(ctx, svc.db, cartDto.Id, &code)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return svc.GetCart(ctx, userIdentifier, customerGroupIds, currencyCode, taxLocation)
+}
+
+func (svc *CartService) RemovePromotionCode(ctx context.Context, userIdentifier string) error {
+	cart, err := svc.models.CartModel.FindByUserIdentifier(ctx, svc.db, userIdentifier)
+
+	if err != nil {
+		if errors.Is(err, model.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	return svc.models.CartModel.SetPromotionCode(ctx, svc.db, cart.Id, nil)
+}
+
+// ListShippingOptions returns the shipping methods that can ship the cart to the country, priced for the cart
+func (svc *CartService) ListShippingOptions(ctx context.Context, userIdentifier string, customerGroupIds []string, currencyCode string, countryCode string) ([]*ShippingOptionDTO, error) {
+	cartDto, err := svc.GetCart(ctx, userIdentifier, customerGroupIds, currencyCode, nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(cartDto.Items) == 0 {
+		return nil, ErrEmptyCart
+	}
+
+	var weight float32
+
+	for _, item := range cartDto.Items {
+		if item.weight != nil {
+			weight += *item.weight * float32(item.Quantity)
+		}
+	}
+
+	return svc.shippingSvc.findShippingOptions(ctx, svc.db, countryCode, currencyCode, cartDto.Subtotal.Sub(cartDto.DiscountTotal), weight)
+}
+
+// findVariantOptionValues returns the option values (ex: size: M, color: red) of each variant, grouped by variant id
+func findVariantOptionValues(ctx context.Context, conn sqldb.Connection, variantIds []string) (map[string][]VariantOptionValueDTO, error) {
+	q := `SELECT pov.variant_id, pov.id, pov.title, po.id, po.title FROM product_option_value AS pov
+		  LEFT JOIN product_option AS po ON po.id = pov.option_id
+		  WHERE variant_id = ANY($1)`
+
+	rows, err := conn.QueryContext(ctx, q, pq.Array(variantIds))
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	optionValuesMap := map[string][]VariantOptionValueDTO{}
+
+	for rows.Next() {
+		var optionValueItem VariantOptionValueDTO
+		var variantId string
+
+		err := rows.Scan(&variantId, &optionValueItem.Id, &optionValueItem.Value, &optionValueItem.OptionId, &optionValueItem.OptionTitle)
+
+		if err != nil {
+			return nil, err
+		}
+
+		optionValuesMap[variantId] = append(optionValuesMap[variantId], optionValueItem)
+	}
+
+	return optionValuesMap, nil
+}
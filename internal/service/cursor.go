@@ -0,0 +1,186 @@
+package service
+
+import (
+	"ecom-backend/internal/validator"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math"
+	"strconv"
+	"time"
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// cursorTimeLayout keeps the microseconds the timestamps are stored with
+const cursorTimeLayout = "2006-01-02 15:04:05.999999"
+
+// Cursor is a position in a listing paged with keysets: the sort key and the id of a row. The page after the row is
+// listed, or the one before it when Before is set.
+type Cursor struct {
+	Sort   string  `json:"s"`
+	Key    *string `json:"k"` // nil when the sort key of the row is NULL
+	Id     string  `json:"i"`
+	Before bool    `json:"b,omitempty"`
+}
+
+// Encode returns the opaque token of the cursor given to the clients
+func (c *Cursor) Encode() string {
+	data, _ := json.Marshal(c)
+
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeCursor returns the cursor of a token made by Encode
+func DecodeCursor(token string) (*Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	var cursor Cursor
+
+	err = json.Unmarshal(data, &cursor)
+
+	if err != nil || !validator.IsValidUUID(cursor.Id) {
+		return nil, ErrInvalidCursor
+	}
+
+	return &cursor, nil
+}
+
+// PageInfo describes a page of a listing that can be paged by page number or with cursors
+type PageInfo struct {
+	TotalCount *int    // only counted when paging by page number
+	NextCursor *string // nil on the last page
+	PrevCursor *string // nil on the first page
+}
+
+// encodeCursorKey returns the text of a sort key scanned from the database, it's cast back to its type in the queries
+func encodeCursorKey(key any) *string {
+	var text string
+
+	switch value := key.(type) {
+	case nil:
+		return nil
+	case time.Time:
+		text = value.Format(cursorTimeLayout)
+	case string:
+		text = value
+	case []byte:
+		text = string(value)
+	case int64:
+		text = strconv.FormatInt(value, 10)
+	case float64:
+		text = strconv.FormatFloat(value, 'g', -1, 64)
+	default:
+		return nil
+	}
+
+	return &text
+}
+
+// parseCursorKey reads the sort key of a cursor back as a value of the type the key is cast to in the queries, nil when
+// the key is NULL. The keys that aren't of the type fail with ErrInvalidCursor.
+func parseCursorKey(key *string, castType string) (any, error) {
+	if key == nil {
+		return nil, nil
+	}
+
+	switch castType {
+	case "text":
+		return *key, nil
+	case "bigint":
+		value, err := strconv.ParseInt(*key, 10, 64)
+
+		if err != nil {
+			return nil, ErrInvalidCursor
+		}
+
+		return value, nil
+	case "real":
+		value, err := strconv.ParseFloat(*key, 32)
+
+		if err != nil || math.IsNaN(value) || math.IsInf(value, 0) {
+			return nil, ErrInvalidCursor
+		}
+
+		return value, nil
+	case "timestamp":
+		value, err := time.Parse(cursorTimeLayout, *key)
+
+		if err != nil {
+			return nil, ErrInvalidCursor
+		}
+
+		return value.Format(cursorTimeLayout), nil
+	default:
+		return nil, ErrInvalidCursor
+	}
+}
+
+// newPageInfo returns the cursors around the rows of a page, given as the sort key and the id of every row in the
+// order of the listing. One more row than the page size is queried to know whether there's a page past the rows.
+// The cursor of the previous page is also given when paging by page number past the first one.
+func newPageInfo(sort string, keys []any, ids []string, cursor *Cursor, hasMore bool, pageNumber uint) *PageInfo {
+	info := &PageInfo{}
+
+	if len(ids) == 0 {
+		return info
+	}
+
+	first := &Cursor{Sort: sort, Key: encodeCursorKey(keys[0]), Id: ids[0], Before: true}
+	last := &Cursor{Sort: sort, Key: encodeCursorKey(keys[len(keys)-1]), Id: ids[len(ids)-1]}
+
+	hasNext := hasMore
+	hasPrev := pageNumber > 1
+
+	if cursor != nil {
+		// the rows past a page listed backwards are before it
+		hasNext = cursor.Before || hasMore
+		hasPrev = !cursor.Before || hasMore
+	}
+
+	if hasNext {
+		next := last.Encode()
+		info.NextCursor = &next
+	}
+
+	if hasPrev {
+		prev := first.Encode()
+		info.PrevCursor = &prev
+	}
+
+	return info
+}
+
+// keysetCondition returns the condition matching the rows after the cursor in the order of the sort expression and the
+// id, or before it when the cursor looks backwards. The key is the sort key of the cursor read by parseCursorKey, the
+// rows whose sort key is NULL come last.
+func keysetCondition(expression string, castType string, idColumn string, descending bool, cursor *Cursor, key any, args *queryArgs) string {
+	operator := ">"
+
+	if descending != cursor.Before {
+		operator = "<"
+	}
+
+	id := args.add(cursor.Id) + `::uuid`
+
+	if key == nil {
+		if cursor.Before {
+			return `(` + expression + ` IS NOT NULL OR ` + idColumn + ` ` + operator + ` ` + id + `)`
+		}
+
+		return `(` + expression + ` IS NULL AND ` + idColumn + ` ` + operator + ` ` + id + `)`
+	}
+
+	keyArg := args.add(key) + `::` + castType
+	condition := expression + ` ` + operator + ` ` + keyArg + ` OR (` + expression + ` = ` + keyArg + ` AND ` + idColumn + ` ` + operator + ` ` + id + `)`
+
+	if !cursor.Before {
+		condition += ` OR ` + expression + ` IS NULL`
+	}
+
+	return `(` + condition + `)`
+}
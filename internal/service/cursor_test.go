@@ -0,0 +1,144 @@
+package service
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseCursorKey(t *testing.T) {
+	text := func(s string) *string { return &s }
+
+	tests := []struct {
+		name     string
+		key      *string
+		castType string
+		want     any
+		wantErr  bool
+	}{
+		{"null key", nil, "bigint", nil, false},
+		{"text", text("Blue shirt"), "text", "Blue shirt", false},
+		{"bigint", text("-42"), "bigint", int64(-42), false},
+		{"bigint with decimals", text("4.2"), "bigint", nil, true},
+		{"bigint out of range", text("99999999999999999999"), "bigint", nil, true},
+		{"bigint injection", text("1; DROP TABLE product"), "bigint", nil, true},
+		{"real", text("0.5"), "real", 0.5, false},
+		{"real not a number", text("NaN"), "real", nil, true},
+		{"real infinite", text("Inf"), "real", nil, true},
+		{"real out of range", text("1e39"), "real", nil, true},
+		{"timestamp", text("2024-01-02 03:04:05.123456"), "timestamp", "2024-01-02 03:04:05.123456", false},
+		{"timestamp without fraction", text("2024-01-02 03:04:05"), "timestamp", "2024-01-02 03:04:05", false},
+		{"timestamp without time", text("2024-01-02"), "timestamp", nil, true},
+		{"timestamp garbage", text("yesterday"), "timestamp", nil, true},
+		{"unknown type", text("1"), "uuid", nil, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseCursorKey(tt.key, tt.castType)
+
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidCursor) {
+					t.Fatalf("got error %v, want ErrInvalidCursor", err)
+				}
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseCursorKeyReadsEncodedKeys(t *testing.T) {
+	at := time.Date(2024, 1, 2, 3, 4, 5, 123456000, time.UTC)
+
+	tests := []struct {
+		key      any
+		castType string
+		want     any
+	}{
+		{at, "timestamp", "2024-01-02 03:04:05.123456"},
+		{int64(1999), "bigint", int64(1999)},
+		{float64(0.25), "real", 0.25},
+		{"title", "text", "title"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.castType, func(t *testing.T) {
+			got, err := parseCursorKey(encodeCursorKey(tt.key), tt.castType)
+
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestKeysetCondition(t *testing.T) {
+	const id = "6f1c2a52-3a8e-4d8b-9d43-5b0f3f6f7e1a"
+
+	tests := []struct {
+		name       string
+		descending bool
+		before     bool
+		key        any
+		want       string
+		wantArgs   []any
+	}{
+		{
+			name: "ascending after the key, the NULL keys come last",
+			key:  int64(10),
+			want: `(e > $2::bigint OR (e = $2::bigint AND p.id > $1::uuid) OR e IS NULL)`, wantArgs: []any{id, int64(10)},
+		},
+		{
+			name: "descending after the key", descending: true,
+			key:  int64(10),
+			want: `(e < $2::bigint OR (e = $2::bigint AND p.id < $1::uuid) OR e IS NULL)`, wantArgs: []any{id, int64(10)},
+		},
+		{
+			name: "ascending before the key leaves out the NULL keys", before: true,
+			key:  int64(10),
+			want: `(e < $2::bigint OR (e = $2::bigint AND p.id < $1::uuid))`, wantArgs: []any{id, int64(10)},
+		},
+		{
+			name: "descending before the key", descending: true, before: true,
+			key:  int64(10),
+			want: `(e > $2::bigint OR (e = $2::bigint AND p.id > $1::uuid))`, wantArgs: []any{id, int64(10)},
+		},
+		{
+			name: "after a NULL key only NULL keys are left",
+			want: `(e IS NULL AND p.id > $1::uuid)`, wantArgs: []any{id},
+		},
+		{
+			name: "before a NULL key every key comes first", before: true,
+			want: `(e IS NOT NULL OR p.id < $1::uuid)`, wantArgs: []any{id},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := queryArgs{}
+
+			got := keysetCondition("e", "bigint", "p.id", tt.descending, &Cursor{Id: id, Before: tt.before}, tt.key, &args)
+
+			if got != tt.want {
+				t.Errorf("got %s\nwant %s", got, tt.want)
+			}
+
+			if !reflect.DeepEqual([]any(args), tt.wantArgs) {
+				t.Errorf("got args %#v, want %#v", args, tt.wantArgs)
+			}
+		})
+	}
+}
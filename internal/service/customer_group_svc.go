@@ -0,0 +1,146 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"ecom-backend/internal/model"
+	"ecom-backend/internal/validator"
+)
+
+// CustomerGroupService manages the groups of customers (vip, wholesale, staff...) that price lists and promotions
+// can be restricted to
+type CustomerGroupService struct {
+	db     *sql.DB
+	models *model.Models
+}
+
+func NewCustomerGroupService(db *sql.DB, models *model.Models) *CustomerGroupService {
+	return &CustomerGroupService{db: db, models: models}
+}
+
+type CreateCustomerGroupInput struct {
+	Name        string  `json:"name"`
+	Description *string `json:"description"`
+}
+
+func (input *CreateCustomerGroupInput) Validate(v *validator.Validator) {
+	v.Check(input.Name != "", "name", "must be provided")
+}
+
+type UpdateCustomerGroupInput struct {
+	Name        *string `json:"name"`
+	Description *string `json:"description"`
+}
+
+func (input *UpdateCustomerGroupInput) Validate(v *validator.Validator) {
+	if input.Name != nil {
+		v.Check(*input.Name != "", "name", "must not be empty")
+	}
+}
+
+type SetUserCustomerGroupsInput struct {
+	CustomerGroupIds []string `json:"customer_group_ids"`
+}
+
+func (input *SetUserCustomerGroupsInput) Validate(v *validator.Validator) {
+	v.Check(input.CustomerGroupIds != nil, "customer_group_ids", "must be provided")
+	validateCustomerGroupIds(v, input.CustomerGroupIds)
+}
+
+func (svc *CustomerGroupService) CreateCustomerGroup(ctx context.Context, input *CreateCustomerGroupInput) (*model.CustomerGroupRecord, error) {
+	return svc.models.CustomerGroupModel.Insert(ctx, svc.db, &model.CustomerGroupRecord{Name: input.Name, Description: input.Description})
+}
+
+func (svc *CustomerGroupService) ListCustomerGroups(ctx context.Context) ([]*model.CustomerGroupRecord, error) {
+	return svc.models.CustomerGroupModel.FindAll(ctx, svc.db)
+}
+
+func (svc *CustomerGroupService) GetCustomerGroup(ctx context.Context, id string) (*model.CustomerGroupRecord, error) {
+	return svc.models.CustomerGroupModel.FindById(ctx, svc.db, id)
+}
+
+func (svc *CustomerGroupService) UpdateCustomerGroup(ctx context.Context, id string, input *UpdateCustomerGroupInput) (*model.CustomerGroupRecord, error) {
+	tx, err := svc.db.BeginTx(ctx, nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer tx.Rollback()
+
+	group, err := svc.models.CustomerGroupModel.FindById(ctx, tx, id)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if input.Name != nil {
+		group.Name = *input.Name
+	}
+
+	if input.Description != nil {
+		group.Description = input.Description
+	}
+
+	group, err = svc.models.CustomerGroupModel.Update(ctx, tx, group)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return group, nil
+}
+
+func (svc *CustomerGroupService) DeleteCustomerGroup(ctx context.Context, id string) error {
+	return svc.models.CustomerGroupModel.Delete(ctx, svc.db, id)
+}
+
+// ListMembers returns the users of the group
+func (svc *CustomerGroupService) ListMembers(ctx context.Context, id string) ([]*model.UserRecord, error) {
+	_, err := svc.models.CustomerGroupModel.FindById(ctx, svc.db, id)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return svc.models.CustomerGroupModel.FindMembers(ctx, svc.db, id)
+}
+
+// SetUserCustomerGroups replaces the groups the user belongs to and returns their ids
+func (svc *CustomerGroupService) SetUserCustomerGroups(ctx context.Context, userId string, input *SetUserCustomerGroupsInput) ([]string, error) {
+	tx, err := svc.db.BeginTx(ctx, nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer tx.Rollback()
+
+	_, err = svc.models.UserModel.FindById(ctx, tx, userId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	err = svc.models.CustomerGroupModel.ReplaceUserGroups(ctx, tx, userId, input.CustomerGroupIds)
+
+	if err != nil {
+		return nil, err
+	}
+
+	groupIds, err := svc.models.CustomerGroupModel.FindIdsByUserId(ctx, tx, userId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return groupIds, nil
+}
@@ -0,0 +1,224 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"ecom-backend/internal/model"
+	"ecom-backend/internal/money"
+	"ecom-backend/internal/validator"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var ErrInvalidExchangeRateFile = errors.New("invalid exchange rate file")
+
+// maxExchangeRate is the first rate the DECIMAL(18, 8) rate column can't store
+const maxExchangeRate = 1e10
+
+// exchangeRateFileHeader are the columns of an exchange rate file, the rounding ones are optional
+var exchangeRateFileHeader = []string{"base_currency_code", "quote_currency_code", "rate", "rounding_increment", "rounding_ending"}
+
+type ExchangeRateService struct {
+	db     *sql.DB
+	models *model.Models
+}
+
+func NewExchangeRateService(db *sql.DB, models *model.Models) *ExchangeRateService {
+	return &ExchangeRateService{db: db, models: models}
+}
+
+type SetExchangeRateInput struct {
+	BaseCurrencyCode  string         `json:"-"` // set from the path
+	QuoteCurrencyCode string         `json:"-"`
+	Rate              float64        `json:"rate"`
+	RoundingIncrement *money.Decimal `json:"rounding_increment"` // optional, in major units of the quote currency, no rounding when missing
+	RoundingEnding    *money.Decimal `json:"rounding_ending"`    // optional, what the rounded prices end with, e.g. 0.99
+}
+
+func (input *SetExchangeRateInput) Validate(v *validator.Validator) {
+	v.Check(input.BaseCurrencyCode != "", "base_currency_code", "must be provided")
+	v.Check(input.QuoteCurrencyCode != "", "quote_currency_code", "must be provided")
+	v.Check(!strings.EqualFold(input.BaseCurrencyCode, input.QuoteCurrencyCode), "quote_currency_code", "must differ from the base currency")
+	v.Check(input.Rate > 0, "rate", "must be greater than zero")
+	v.Check(!math.IsInf(input.Rate, 0) && input.Rate < maxExchangeRate, "rate", "must be lower than 10000000000")
+
+	if input.RoundingIncrement != nil {
+		v.Check(input.RoundingIncrement.Sign() > 0, "rounding_increment", "must be greater than zero")
+		validateDecimals(v, *input.RoundingIncrement, input.QuoteCurrencyCode, "rounding_increment")
+	}
+
+	if input.RoundingEnding != nil {
+		v.Check(input.RoundingEnding.Sign() >= 0, "rounding_ending", "should not be negative")
+		validateDecimals(v, *input.RoundingEnding, input.QuoteCurrencyCode, "rounding_ending")
+		v.Check(input.roundingEnding().LessThan(input.roundingIncrement()), "rounding_ending", "must be lower than the rounding increment")
+	}
+}
+
+// roundingIncrement returns the increment in minor units of the quote currency, a single unit when missing
+func (input *SetExchangeRateInput) roundingIncrement() money.Money {
+	if input.RoundingIncrement == nil {
+		return money.New(1, input.QuoteCurrencyCode)
+	}
+
+	return input.RoundingIncrement.Money(input.QuoteCurrencyCode)
+}
+
+func (input *SetExchangeRateInput) roundingEnding() money.Money {
+	if input.RoundingEnding == nil {
+		return money.Zero(input.QuoteCurrencyCode)
+	}
+
+	return input.RoundingEnding.Money(input.QuoteCurrencyCode)
+}
+
+func (svc *ExchangeRateService) ListExchangeRates(ctx context.Context) ([]*model.ExchangeRateRecord, error) {
+	return svc.models.ExchangeRateModel.FindAll(ctx, svc.db)
+}
+
+// SetExchangeRate creates the rate between the two currencies or replaces it
+func (svc *ExchangeRateService) SetExchangeRate(ctx context.Context, input *SetExchangeRateInput) (*model.ExchangeRateRecord, error) {
+	return svc.models.ExchangeRateModel.Upsert(ctx, svc.db, &model.ExchangeRateRecord{
+		BaseCurrencyCode:  strings.ToLower(input.BaseCurrencyCode),
+		QuoteCurrencyCode: strings.ToLower(input.QuoteCurrencyCode),
+		Rate:              input.Rate,
+		RoundingIncrement: input.roundingIncrement(),
+		RoundingEnding:    input.roundingEnding(),
+	})
+}
+
+func (svc *ExchangeRateService) DeleteExchangeRate(ctx context.Context, baseCurrencyCode string, quoteCurrencyCode string) error {
+	return svc.models.ExchangeRateModel.Delete(ctx, svc.db, strings.ToLower(baseCurrencyCode), strings.ToLower(quoteCurrencyCode))
+}
+
+// ImportExchangeRates sets the rates of a CSV file with a header line and the exchangeRateFileHeader columns, the
+// rounding ones can be left out or empty. Either every rate of the file is set or none when a line is invalid.
+func (svc *ExchangeRateService) ImportExchangeRates(ctx context.Context, file io.Reader) ([]*model.ExchangeRateRecord, error) {
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	lines, err := reader.ReadAll()
+
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidExchangeRateFile, err)
+	}
+
+	if len(lines) == 0 || len(lines[0]) < 3 || len(lines[0]) > len(exchangeRateFileHeader) {
+		return nil, fmt.Errorf("%w: the header must be %s", ErrInvalidExchangeRateFile, strings.Join(exchangeRateFileHeader, ","))
+	}
+
+	for i, column := range lines[0] {
+		if strings.TrimSpace(column) != exchangeRateFileHeader[i] {
+			return nil, fmt.Errorf("%w: the header must be %s", ErrInvalidExchangeRateFile, strings.Join(exchangeRateFileHeader, ","))
+		}
+	}
+
+	tx, err := svc.db.BeginTx(ctx, nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer tx.Rollback()
+
+	rates := []*model.ExchangeRateRecord{}
+
+	for i, line := range lines[1:] {
+		// the header is the first line
+		lineNumber := i + 2
+
+		input, err := parseExchangeRateLine(line, len(lines[0]))
+
+		if err != nil {
+			return nil, fmt.Errorf("%w: line %d: %w", ErrInvalidExchangeRateFile, lineNumber, err)
+		}
+
+		v := validator.New()
+
+		if input.Validate(v); !v.Valid() {
+			return nil, fmt.Errorf("%w: line %d: %s", ErrInvalidExchangeRateFile, lineNumber, formatValidationErrors(v.Errors))
+		}
+
+		rate, err := svc.models.ExchangeRateModel.Upsert(ctx, tx, &model.ExchangeRateRecord{
+			BaseCurrencyCode:  strings.ToLower(input.BaseCurrencyCode),
+			QuoteCurrencyCode: strings.ToLower(input.QuoteCurrencyCode),
+			Rate:              input.Rate,
+			RoundingIncrement: input.roundingIncrement(),
+			RoundingEnding:    input.roundingEnding(),
+		})
+
+		if err != nil {
+			if errors.Is(err, model.ErrCurrencyNotFound) || errors.Is(err, model.ErrInvalidValue) {
+				return nil, fmt.Errorf("%w: line %d: %w", ErrInvalidExchangeRateFile, lineNumber, err)
+			}
+			return nil, err
+		}
+
+		rates = append(rates, rate)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return rates, nil
+}
+
+func parseExchangeRateLine(line []string, columns int) (*SetExchangeRateInput, error) {
+	if len(line) != columns {
+		return nil, fmt.Errorf("expected %d columns, got %d", columns, len(line))
+	}
+
+	for i := range line {
+		line[i] = strings.TrimSpace(line[i])
+	}
+
+	rate, err := strconv.ParseFloat(line[2], 64)
+
+	if err != nil {
+		return nil, errors.New("rate must be a number")
+	}
+
+	input := &SetExchangeRateInput{BaseCurrencyCode: line[0], QuoteCurrencyCode: line[1], Rate: rate}
+
+	if columns > 3 && line[3] != "" {
+		increment, err := money.ParseDecimal(line[3])
+
+		if err != nil {
+			return nil, fmt.Errorf("rounding_increment: %w", err)
+		}
+
+		input.RoundingIncrement = &increment
+	}
+
+	if columns > 4 && line[4] != "" {
+		ending, err := money.ParseDecimal(line[4])
+
+		if err != nil {
+			return nil, fmt.Errorf("rounding_ending: %w", err)
+		}
+
+		input.RoundingEnding = &ending
+	}
+
+	return input, nil
+}
+
+// formatValidationErrors joins the errors of a validator sorted by key, e.g. "rate: must be greater than zero"
+func formatValidationErrors(errs map[string]string) string {
+	messages := []string{}
+
+	for key, message := range errs {
+		messages = append(messages, key+": "+message)
+	}
+
+	sort.Strings(messages)
+
+	return strings.Join(messages, ", ")
+}
@@ -0,0 +1,506 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"ecom-backend/internal/consts"
+	"ecom-backend/internal/model"
+	"ecom-backend/internal/money"
+	"ecom-backend/internal/validator"
+	"ecom-backend/pkg/sqldb"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+var (
+	ErrGiftCardNotFound  = errors.New("gift card not found")
+	ErrGiftCardNotUsable = errors.New("gift card can't be used")
+)
+
+// giftCardCodeAlphabet leaves out the characters that are easily mistaken for each other ( 0 and O, 1 and I )
+const giftCardCodeAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZ23456789"
+
+type GiftCardService struct {
+	db     *sql.DB
+	models *model.Models
+}
+
+func NewGiftCardService(db *sql.DB, models *model.Models) *GiftCardService {
+	return &GiftCardService{db: db, models: models}
+}
+
+// generateGiftCardCode returns a random code made of 4 groups of 4 characters, ex: HK7Q-2MZP-9XWA-E4TN
+func generateGiftCardCode() (string, error) {
+	randomBytes := make([]byte, 16)
+
+	_, err := rand.Read(randomBytes)
+
+	if err != nil {
+		return "", err
+	}
+
+	var code strings.Builder
+
+	for i, b := range randomBytes {
+		if i > 0 && i%4 == 0 {
+			code.WriteByte('-')
+		}
+
+		code.WriteByte(giftCardCodeAlphabet[int(b)%len(giftCardCodeAlphabet)])
+	}
+
+	return code.String(), nil
+}
+
+type IssueGiftCardInput struct {
+	Code         *string       `json:"code"` // optional, a random one is generated when missing
+	Amount       money.Decimal `json:"amount"`
+	CurrencyCode string        `json:"currency_code"`
+	Email        *string       `json:"email"` // optional, the recipient is notified when given
+	Note         *string       `json:"note"`
+	ExpiresAt    *time.Time    `json:"expires_at"`
+}
+
+func (input *IssueGiftCardInput) Validate(v *validator.Validator) {
+	if input.Code != nil {
+		v.Check(len(*input.Code) >= 6, "code", "must be at least 6 characters long")
+	}
+
+	v.Check(input.Amount.Sign() > 0, "amount", "must be greater than zero")
+	v.Check(input.CurrencyCode != "", "currency_code", "must be provided")
+	validateDecimals(v, input.Amount, input.CurrencyCode, "amount")
+
+	if input.Email != nil {
+		v.Check(validator.Matches(*input.Email, validator.EmailRX), "email", "must be valid")
+	}
+
+	if input.ExpiresAt != nil {
+		v.Check(input.ExpiresAt.After(time.Now()), "expires_at", "must be in the future")
+	}
+}
+
+type UpdateGiftCardInput struct {
+	Balance   *money.Decimal `json:"balance"` // the change is recorded as an adjustment
+	Email     *string        `json:"email"`
+	Note      *string        `json:"note"`
+	IsActive  *bool          `json:"is_active"`
+	ExpiresAt *time.Time     `json:"expires_at"`
+}
+
+func (input *UpdateGiftCardInput) Validate(v *validator.Validator) {
+	if input.Balance != nil {
+		v.Check(input.Balance.Sign() >= 0, "balance", "should not be negative")
+	}
+
+	if input.Email != nil {
+		v.Check(validator.Matches(*input.Email, validator.EmailRX), "email", "must be valid")
+	}
+}
+
+// GiftCardBalanceDTO is what a customer can see of a card from its code
+type GiftCardBalanceDTO struct {
+	Code      string      `json:"code"`
+	Balance   money.Money `json:"balance"`
+	IsUsable  bool        `json:"is_usable"`
+	ExpiresAt *time.Time  `json:"expires_at"`
+}
+
+// IssueGiftCard creates a card on behalf of an admin, the recipient is notified when an email is given
+func (svc *GiftCardService) IssueGiftCard(ctx context.Context, input *IssueGiftCardInput) (*model.GiftCardRecord, error) {
+	code := ""
+
+	if input.Code != nil {
+		code = strings.ToUpper(strings.TrimSpace(*input.Code))
+	}
+
+	tx, err := svc.db.BeginTx(ctx, nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer tx.Rollback()
+
+	currencyCode := strings.ToLower(input.CurrencyCode)
+	amount := input.Amount.Money(currencyCode)
+
+	card, err := issueGiftCard(ctx, tx, svc.models, &model.GiftCardRecord{
+		Code:           code,
+		CurrencyCode:   currencyCode,
+		InitialBalance: amount,
+		Balance:        amount,
+		Email:          input.Email,
+		Note:           input.Note,
+		IsActive:       true,
+		ExpiresAt:      input.ExpiresAt,
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return card, nil
+}
+
+// issueGiftCard stores the card with a generated code when it has none, records its initial balance and notifies the
+// recipient
+func issueGiftCard(ctx context.Context, conn sqldb.Connection, models *model.Models, card *model.GiftCardRecord) (*model.GiftCardRecord, error) {
+	if card.Code == "" {
+		code, err := generateGiftCardCode()
+
+		if err != nil {
+			return nil, err
+		}
+
+		card.Code = code
+	}
+
+	card, err := models.GiftCardModel.Insert(ctx, conn, card)
+
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = models.GiftCardTransactionModel.Insert(ctx, conn, &model.GiftCardTransactionRecord{GiftCardId: card.Id, Type: consts.BalanceTransactionIssue, Amount: card.InitialBalance, OrderId: card.OrderId})
+
+	if err != nil {
+		return nil, err
+	}
+
+	if card.Email != nil {
+		_, err := models.NotificationModel.Insert(ctx, conn, &model.NotificationRecord{
+			Type:    consts.NotificationTypeGiftCard,
+			Email:   *card.Email,
+			Payload: map[string]any{"gift_card_id": card.Id, "code": card.Code, "amount": card.InitialBalance.String(), "currency_code": card.CurrencyCode},
+		})
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return card, nil
+}
+
+type GiftCardListingOptions struct {
+	Page     uint
+	PageSize uint
+}
+
+func (svc *GiftCardService) ListGiftCards(ctx context.Context, opt GiftCardListingOptions) ([]*model.GiftCardRecord, int, error) {
+	totalCount, err := svc.models.GiftCardModel.Count(ctx, svc.db)
+
+	if err != nil {
+		return nil, 0, err
+	}
+
+	cards, err := svc.models.GiftCardModel.FindAll(ctx, svc.db, opt.PageSize, (opt.Page-1)*opt.PageSize)
+
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return cards, totalCount, nil
+}
+
+// GetGiftCard returns the card with the history of its balance
+func (svc *GiftCardService) GetGiftCard(ctx context.Context, id string) (*model.GiftCardRecord, []*model.GiftCardTransactionRecord, error) {
+	card, err := svc.models.GiftCardModel.FindById(ctx, svc.db, id)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	transactions, err := svc.models.GiftCardTransactionModel.FindAllByGiftCardId(ctx, svc.db, id)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return card, transactions, nil
+}
+
+func (svc *GiftCardService) UpdateGiftCard(ctx context.Context, id string, input *UpdateGiftCardInput) (*model.GiftCardRecord, error) {
+	tx, err := svc.db.BeginTx(ctx, nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer tx.Rollback()
+
+	card, err := svc.models.GiftCardModel.FindByIdForUpdate(ctx, tx, id)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var adjustment money.Money
+
+	if input.Balance != nil {
+		if !input.Balance.FitsCurrency(card.CurrencyCode) {
+			return nil, fmt.Errorf("%w: balance has more decimals than %s", model.ErrInvalidValue, card.CurrencyCode)
+		}
+
+		balance := input.Balance.Money(card.CurrencyCode)
+
+		if card.InitialBalance.LessThan(balance) {
+			return nil, fmt.Errorf("%w: balance can't be more than the initial balance of %s", model.ErrInvalidValue, card.InitialBalance)
+		}
+
+		adjustment = balance.Sub(card.Balance)
+		card.Balance = balance
+	}
+
+	if input.Email != nil {
+		card.Email = input.Email
+	}
+
+	if input.Note != nil {
+		card.Note = input.Note
+	}
+
+	if input.IsActive != nil {
+		card.IsActive = *input.IsActive
+	}
+
+	if input.ExpiresAt != nil {
+		card.ExpiresAt = input.ExpiresAt
+	}
+
+	card, err = svc.models.GiftCardModel.Update(ctx, tx, card)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if !adjustment.IsZero() {
+		_, err := svc.models.GiftCardTransactionModel.Insert(ctx, tx, &model.GiftCardTransactionRecord{GiftCardId: card.Id, Type: consts.BalanceTransactionAdjustment, Amount: adjustment})
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return card, nil
+}
+
+// CheckGiftCardBalance lets a customer look up the balance of a card before paying with it
+func (svc *GiftCardService) CheckGiftCardBalance(ctx context.Context, code string) (*GiftCardBalanceDTO, error) {
+	card, err := svc.models.GiftCardModel.FindByCode(ctx, svc.db, strings.TrimSpace(code))
+
+	if err != nil {
+		if errors.Is(err, model.ErrRecordNotFound) {
+			return nil, ErrGiftCardNotFound
+		}
+		return nil, err
+	}
+
+	return &GiftCardBalanceDTO{Code: card.Code, Balance: card.Balance, IsUsable: card.IsUsable(time.Now()) && card.Balance.IsPositive(), ExpiresAt: card.ExpiresAt}, nil
+}
+
+// giftCardRedemption is the part of an order paid with a gift card
+type giftCardRedemption struct {
+	card   *model.GiftCardRecord
+	amount money.Money
+}
+
+// lockGiftCards locks the cards with the codes until the end of the transaction and takes from each one, in the order
+// of the codes, as much of the amount as its balance covers. The cards left once the amount is covered aren't used.
+func lockGiftCards(ctx context.Context, conn sqldb.Connection, models *model.Models, codes []string, amount money.Money) ([]*giftCardRedemption, error) {
+	if len(codes) == 0 {
+		return nil, nil
+	}
+
+	// looked up the way CheckGiftCardBalance does, the codes are case insensitive
+	normalizedCodes := make([]string, len(codes))
+
+	for i, code := range codes {
+		normalizedCodes[i] = strings.ToLower(strings.TrimSpace(code))
+	}
+
+	cardsMap, err := models.GiftCardModel.FindAllByCodesForUpdate(ctx, conn, normalizedCodes)
+
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	redemptions := []*giftCardRedemption{}
+
+	for _, code := range normalizedCodes {
+		card, ok := cardsMap[code]
+
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrGiftCardNotFound, code)
+		}
+
+		switch {
+		case !card.IsUsable(now):
+			return nil, fmt.Errorf("%w: %s is disabled or expired", ErrGiftCardNotUsable, card.Code)
+		case !strings.EqualFold(card.CurrencyCode, amount.CurrencyCode):
+			return nil, fmt.Errorf("%w: %s is in %s, the order is in %s", ErrGiftCardNotUsable, card.Code, card.CurrencyCode, amount.CurrencyCode)
+		case !card.Balance.IsPositive():
+			return nil, fmt.Errorf("%w: %s has no balance left", ErrGiftCardNotUsable, card.Code)
+		}
+
+		if !amount.IsPositive() {
+			continue
+		}
+
+		redeemed := money.Min(card.Balance, amount)
+		amount = amount.Sub(redeemed)
+
+		redemptions = append(redemptions, &giftCardRedemption{card: card, amount: redeemed})
+	}
+
+	return redemptions, nil
+}
+
+// redeemGiftCards takes the redeemed amounts from the balances of the cards on behalf of the order
+func redeemGiftCards(ctx context.Context, conn sqldb.Connection, models *model.Models, redemptions []*giftCardRedemption, orderId string) error {
+	for _, redemption := range redemptions {
+		redemption.card.Balance = redemption.card.Balance.Sub(redemption.amount)
+
+		_, err := models.GiftCardModel.Update(ctx, conn, redemption.card)
+
+		if err != nil {
+			return err
+		}
+
+		_, err = models.GiftCardTransactionModel.Insert(ctx, conn, &model.GiftCardTransactionRecord{
+			GiftCardId: redemption.card.Id,
+			Type:       consts.BalanceTransactionRedeem,
+			Amount:     redemption.amount.Mul(-1),
+			OrderId:    &orderId,
+		})
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// issueOrderGiftCards issues a card per unit of the gift card line items of the paid order, sent to the customer
+func issueOrderGiftCards(ctx context.Context, conn sqldb.Connection, models *model.Models, order *model.OrderRecord) error {
+	lineItemsMap, err := models.OrderLineItemModel.FindAllByOrderIds(ctx, conn, []string{order.Id})
+
+	if err != nil {
+		return err
+	}
+
+	for _, lineItem := range lineItemsMap[order.Id] {
+		if !lineItem.IsGiftCard {
+			continue
+		}
+
+		for i := 0; i < lineItem.Quantity; i++ {
+			_, err := issueGiftCard(ctx, conn, models, &model.GiftCardRecord{
+				CurrencyCode:   order.CurrencyCode,
+				InitialBalance: lineItem.UnitPrice,
+				Balance:        lineItem.UnitPrice,
+				Email:          &order.Email,
+				OrderId:        &order.Id,
+				IsActive:       true,
+			})
+
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// restoreOrderGiftCards gives back to the cards at most the amount out of what the order still takes from them and
+// returns what was given back. Restoring twice never gives back more than the order took.
+func restoreOrderGiftCards(ctx context.Context, conn sqldb.Connection, models *model.Models, order *model.OrderRecord, amount money.Money) (money.Money, error) {
+	redeemedMap, err := models.GiftCardTransactionModel.SumRedeemedByOrderId(ctx, conn, order.Id)
+
+	if err != nil {
+		return money.Money{}, err
+	}
+
+	giftCardIds := []string{}
+
+	for giftCardId := range redeemedMap {
+		giftCardIds = append(giftCardIds, giftCardId)
+	}
+
+	// always lock the cards in the same order
+	sort.Strings(giftCardIds)
+
+	restoredTotal := money.Zero(order.CurrencyCode)
+
+	for _, giftCardId := range giftCardIds {
+		if !amount.IsPositive() {
+			break
+		}
+
+		card, err := models.GiftCardModel.FindByIdForUpdate(ctx, conn, giftCardId)
+
+		if err != nil {
+			return money.Money{}, err
+		}
+
+		restored := money.Min(money.New(redeemedMap[giftCardId], card.CurrencyCode), amount)
+		amount = amount.Sub(restored)
+		restoredTotal = restoredTotal.Add(restored)
+
+		card.Balance = card.Balance.Add(restored)
+
+		_, err = models.GiftCardModel.Update(ctx, conn, card)
+
+		if err != nil {
+			return money.Money{}, err
+		}
+
+		_, err = models.GiftCardTransactionModel.Insert(ctx, conn, &model.GiftCardTransactionRecord{GiftCardId: card.Id, Type: consts.BalanceTransactionRestore, Amount: restored, OrderId: &order.Id})
+
+		if err != nil {
+			return money.Money{}, err
+		}
+	}
+
+	return restoredTotal, nil
+}
+
+// disableOrderGiftCards disables the cards bought with the order
+func disableOrderGiftCards(ctx context.Context, conn sqldb.Connection, models *model.Models, orderId string) error {
+	issuedCards, err := models.GiftCardModel.FindAllByOrderId(ctx, conn, orderId)
+
+	if err != nil {
+		return err
+	}
+
+	for _, card := range issuedCards {
+		if !card.IsActive {
+			continue
+		}
+
+		card.IsActive = false
+
+		_, err := models.GiftCardModel.Update(ctx, conn, card)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,555 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"ecom-backend/internal/consts"
+	"ecom-backend/internal/model"
+	"ecom-backend/internal/validator"
+	"ecom-backend/pkg/sqldb"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	ErrStockLocationNotEmpty = errors.New("stock location still holds stock")
+	ErrVariantInStock        = errors.New("variant is in stock")
+)
+
+// Strategies used to pick the stock location an order line item is fulfilled from
+const (
+	AllocationStrategyPriority  = "priority"   // the first location in priority order with enough stock
+	AllocationStrategyMostStock = "most_stock" // the location with the most stock of the variant
+)
+
+type InventoryService struct {
+	db             *sql.DB
+	models         *model.Models
+	reservationTTL time.Duration
+}
+
+func NewInventoryService(db *sql.DB, models *model.Models, reservationTTL time.Duration) *InventoryService {
+	return &InventoryService{db: db, models: models, reservationTTL: reservationTTL}
+}
+
+type CartReservationDTO struct {
+	CartId       string                              `json:"cart_id"`
+	ExpiresAt    time.Time                           `json:"expires_at"`
+	Reservations []*model.InventoryReservationRecord `json:"reservations"`
+}
+
+// ReserveCart holds the stock of every item in the cart for the configured TTL.
+// Reserving again replaces the previous reservations of the cart, so the client can call it
+// each time the checkout starts or the cart changes and the expiry gets extended.
+func (svc *InventoryService) ReserveCart(ctx context.Context, userIdentifier string) (*CartReservationDTO, error) {
+	tx, err := svc.db.BeginTx(ctx, nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer tx.Rollback()
+
+	cart, err := svc.models.CartModel.FindByUserIdentifier(ctx, tx, userIdentifier)
+
+	if err != nil {
+		if errors.Is(err, model.ErrRecordNotFound) {
+			return nil, ErrEmptyCart
+		}
+		return nil, err
+	}
+
+	cartItems, err := svc.models.CartItemModel.FindAllByCartId(ctx, tx, cart.Id)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(cartItems) == 0 {
+		return nil, ErrEmptyCart
+	}
+
+	variantIds := []string{}
+
+	for _, item := range cartItems {
+		variantIds = append(variantIds, item.VariantId)
+	}
+
+	// the same lock is taken by checkout, so the availability can't change until the reservations are in place
+	variantsMap, err := svc.models.ProductVariantModel.FindAllByIdsForUpdate(ctx, tx, variantIds)
+
+	if err != nil {
+		return nil, err
+	}
+
+	reservedByOthers, err := svc.models.InventoryReservationModel.SumActiveByVariantIds(ctx, tx, variantIds, &cart.Id)
+
+	if err != nil {
+		return nil, err
+	}
+
+	err = svc.models.InventoryReservationModel.UpdateActiveStatusByCartId(ctx, tx, cart.Id, consts.ReservationStatusReleased)
+
+	if err != nil {
+		return nil, err
+	}
+
+	expiresAt := time.Now().Add(svc.reservationTTL)
+	reservations := []*model.InventoryReservationRecord{}
+
+	for _, item := range cartItems {
+		variant, ok := variantsMap[item.VariantId]
+
+		if !ok || variant.DeletedAt != nil {
+			return nil, model.ErrVariantNotFound
+		}
+
+		if variant.InventoryQuantity-reservedByOthers[variant.Id] < item.Quantity {
+			return nil, fmt.Errorf("%w for %s", model.ErrInsufficientInventory, variant.Title)
+		}
+
+		reservation, err := svc.models.InventoryReservationModel.Insert(ctx, tx, &model.InventoryReservationRecord{
+			VariantId: variant.Id,
+			CartId:    cart.Id,
+			Quantity:  item.Quantity,
+			ExpiresAt: expiresAt,
+		})
+
+		if err != nil {
+			return nil, err
+		}
+
+		reservations = append(reservations, reservation)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return &CartReservationDTO{CartId: cart.Id, ExpiresAt: expiresAt, Reservations: reservations}, nil
+}
+
+// ReleaseCart gives back the stock held by the cart before its reservations expire
+func (svc *InventoryService) ReleaseCart(ctx context.Context, userIdentifier string) error {
+	cart, err := svc.models.CartModel.FindByUserIdentifier(ctx, svc.db, userIdentifier)
+
+	if err != nil {
+		if errors.Is(err, model.ErrRecordNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	return svc.models.InventoryReservationModel.UpdateActiveStatusByCartId(ctx, svc.db, cart.Id, consts.ReservationStatusReleased)
+}
+
+// ExpireReservations marks the stale reservations as expired, it's called periodically by the sweeper.
+// Expired reservations already stop counting against the available quantity, this only keeps the table tidy.
+func (svc *InventoryService) ExpireReservations(ctx context.Context) (int64, error) {
+	return svc.models.InventoryReservationModel.ExpireStale(ctx, svc.db, time.Now())
+}
+
+type StockMovementInput struct {
+	Type       string  `json:"type"`
+	Quantity   int     `json:"quantity"`
+	Reason     *string `json:"reason"`
+	LocationId *string `json:"location_id"` // the default location when missing
+}
+
+func (input *StockMovementInput) Validate(v *validator.Validator) {
+	if input.LocationId != nil {
+		v.Check(validator.IsValidUUID(*input.LocationId), "location_id", "invalid location id")
+	}
+
+	// sales and returns are recorded only by the order flows
+	v.Check(validator.In(input.Type, consts.StockMovementReceipt, consts.StockMovementAdjustment, consts.StockMovementCorrection), "type", "invalid stock movement type")
+	v.Check(input.Quantity != 0, "quantity", "must not be zero")
+
+	if input.Type == consts.StockMovementReceipt {
+		v.Check(input.Quantity > 0, "quantity", "must be greater than zero for a receipt")
+	}
+
+	if input.Type != consts.StockMovementReceipt {
+		v.Check(input.Reason != nil && *input.Reason != "", "reason", "must be provided")
+	}
+}
+
+// recordStockMovement appends the movement to the ledger and refreshes the cached quantity of the variant.
+// Every change to the stock goes through here so the ledger and inventory_quantity never drift apart. The variant is
+// locked first so the quantities are summed once the movements of the other transactions are committed, the callers
+// moving the stock of several variants lock them all beforehand in the order of their ids.
+func recordStockMovement(ctx context.Context, conn sqldb.Connection, models *model.Models, movement *model.StockMovementRecord) (*model.StockMovementRecord, error) {
+	_, err := models.ProductVariantModel.FindByIdForUpdate(ctx, conn, movement.VariantId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	// stock without an explicit location is booked to the default one
+	if movement.LocationId == "" {
+		location, err := models.StockLocationModel.FindDefault(ctx, conn)
+
+		if err != nil {
+			if errors.Is(err, model.ErrRecordNotFound) {
+				return nil, model.ErrStockLocationNotFound
+			}
+			return nil, err
+		}
+
+		movement.LocationId = location.Id
+	}
+
+	movement, err = models.StockMovementModel.Insert(ctx, conn, movement)
+
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = models.InventoryLevelModel.Sync(ctx, conn, movement.VariantId, movement.LocationId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	quantity, err := models.ProductVariantModel.SyncInventoryQuantity(ctx, conn, movement.VariantId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	// the variant just came back in stock
+	if quantity > 0 && quantity-movement.Quantity <= 0 {
+		err := enqueueBackInStockNotifications(ctx, conn, models, movement.VariantId)
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return movement, nil
+}
+
+// enqueueBackInStockNotifications queues a notification for every client waiting on the variant,
+// each subscription is notified only once
+func enqueueBackInStockNotifications(ctx context.Context, conn sqldb.Connection, models *model.Models, variantId string) error {
+	subscriptions, err := models.BackInStockSubscriptionModel.FindAllPendingByVariantId(ctx, conn, variantId)
+
+	if err != nil {
+		return err
+	}
+
+	for _, subscription := range subscriptions {
+		_, err := models.NotificationModel.Insert(ctx, conn, &model.NotificationRecord{
+			Type:           consts.NotificationTypeBackInStock,
+			Email:          subscription.Email,
+			UserIdentifier: &subscription.UserIdentifier,
+			Payload:        map[string]any{"variant_id": variantId, "subscription_id": subscription.Id},
+		})
+
+		if err != nil {
+			return err
+		}
+
+		err = models.BackInStockSubscriptionModel.MarkNotified(ctx, conn, subscription.Id)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (svc *InventoryService) RecordStockMovement(ctx context.Context, variantId string, actorUserId *string, input *StockMovementInput) (*model.StockMovementRecord, error) {
+	tx, err := svc.db.BeginTx(ctx, nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer tx.Rollback()
+
+	// lock the variant so the movement is serialized with checkouts of the same stock
+	_, err = svc.models.ProductVariantModel.FindByIdForUpdate(ctx, tx, variantId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var locationId string
+
+	if input.LocationId != nil {
+		location, err := svc.models.StockLocationModel.FindById(ctx, tx, *input.LocationId)
+
+		if err != nil {
+			if errors.Is(err, model.ErrRecordNotFound) {
+				return nil, model.ErrStockLocationNotFound
+			}
+			return nil, err
+		}
+
+		locationId = location.Id
+	}
+
+	movement, err := recordStockMovement(ctx, tx, svc.models, &model.StockMovementRecord{
+		VariantId:   variantId,
+		LocationId:  locationId,
+		Type:        input.Type,
+		Quantity:    input.Quantity,
+		Reason:      input.Reason,
+		ActorUserId: actorUserId,
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return movement, nil
+}
+
+type StockMovementListingOptions struct {
+	Page     uint
+	PageSize uint
+}
+
+func (svc *InventoryService) ListStockMovements(ctx context.Context, variantId string, opt StockMovementListingOptions) ([]*model.StockMovementRecord, int, error) {
+	_, err := svc.models.ProductVariantModel.FindById(ctx, svc.db, variantId)
+
+	if err != nil {
+		return nil, 0, err
+	}
+
+	count, err := svc.models.StockMovementModel.Count(ctx, svc.db, variantId)
+
+	if err != nil {
+		return nil, 0, err
+	}
+
+	movements, err := svc.models.StockMovementModel.FindAllByVariantId(ctx, svc.db, variantId, opt.PageSize, (opt.Page-1)*opt.PageSize)
+
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return movements, count, nil
+}
+
+// allocateStockLocation picks the location a line item is fulfilled from, a line item is never split across locations.
+// The levels are expected in priority order, as returned by the inventory level model.
+func allocateStockLocation(levels []*model.InventoryLevelRecord, quantity int, strategy string) (string, error) {
+	var allocated *model.InventoryLevelRecord
+
+	for _, level := range levels {
+		if !level.LocationIsActive || level.Quantity < quantity {
+			continue
+		}
+
+		switch strategy {
+		case AllocationStrategyMostStock:
+			if allocated == nil || level.Quantity > allocated.Quantity {
+				allocated = level
+			}
+		default:
+			if allocated == nil {
+				allocated = level
+			}
+		}
+	}
+
+	if allocated == nil {
+		return "", model.ErrInsufficientInventory
+	}
+
+	return allocated.LocationId, nil
+}
+
+type CreateStockLocationInput struct {
+	Name     string `json:"name"`
+	Priority int    `json:"priority"`
+	IsActive *bool  `json:"is_active"`
+}
+
+func (input *CreateStockLocationInput) Validate(v *validator.Validator) {
+	v.Check(input.Name != "", "name", "must be provided")
+}
+
+type UpdateStockLocationInput struct {
+	Name     *string `json:"name"`
+	Priority *int    `json:"priority"`
+	IsActive *bool   `json:"is_active"`
+}
+
+func (input *UpdateStockLocationInput) Validate(v *validator.Validator) {
+	if input.Name != nil {
+		v.Check(*input.Name != "", "name", "must not be empty")
+	}
+}
+
+func (svc *InventoryService) CreateStockLocation(ctx context.Context, input *CreateStockLocationInput) (*model.StockLocationRecord, error) {
+	location := &model.StockLocationRecord{Name: input.Name, Priority: input.Priority, IsActive: true}
+
+	if input.IsActive != nil {
+		location.IsActive = *input.IsActive
+	}
+
+	return svc.models.StockLocationModel.Insert(ctx, svc.db, location)
+}
+
+func (svc *InventoryService) ListStockLocations(ctx context.Context) ([]*model.StockLocationRecord, error) {
+	return svc.models.StockLocationModel.FindAll(ctx, svc.db)
+}
+
+func (svc *InventoryService) GetStockLocation(ctx context.Context, id string) (*model.StockLocationRecord, error) {
+	return svc.models.StockLocationModel.FindById(ctx, svc.db, id)
+}
+
+func (svc *InventoryService) UpdateStockLocation(ctx context.Context, id string, input *UpdateStockLocationInput) (*model.StockLocationRecord, error) {
+	location, err := svc.models.StockLocationModel.FindById(ctx, svc.db, id)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if input.Name != nil {
+		location.Name = *input.Name
+	}
+
+	if input.Priority != nil {
+		location.Priority = *input.Priority
+	}
+
+	if input.IsActive != nil {
+		location.IsActive = *input.IsActive
+	}
+
+	return svc.models.StockLocationModel.Update(ctx, svc.db, location)
+}
+
+// DeleteStockLocation removes a location only once its stock was moved or written off
+func (svc *InventoryService) DeleteStockLocation(ctx context.Context, id string) error {
+	_, err := svc.models.StockLocationModel.FindById(ctx, svc.db, id)
+
+	if err != nil {
+		return err
+	}
+
+	quantity, err := svc.models.InventoryLevelModel.SumByLocationId(ctx, svc.db, id)
+
+	if err != nil {
+		return err
+	}
+
+	if quantity != 0 {
+		return ErrStockLocationNotEmpty
+	}
+
+	return svc.models.StockLocationModel.SoftDelete(ctx, svc.db, id)
+}
+
+type BackInStockSubscriptionInput struct {
+	Email string `json:"email"`
+}
+
+func (input *BackInStockSubscriptionInput) Validate(v *validator.Validator) {
+	v.Check(input.Email != "", "email", "must be provided")
+	v.Check(validator.Matches(input.Email, validator.EmailRX), "email", "must be valid")
+}
+
+// SubscribeBackInStock registers the client to be notified when the variant is restocked,
+// only variants that are currently out of stock can be subscribed to
+func (svc *InventoryService) SubscribeBackInStock(ctx context.Context, userIdentifier string, variantId string, input *BackInStockSubscriptionInput) (*model.BackInStockSubscriptionRecord, error) {
+	variant, err := svc.models.ProductVariantModel.FindById(ctx, svc.db, variantId)
+
+	if err != nil {
+		if errors.Is(err, model.ErrRecordNotFound) {
+			return nil, model.ErrVariantNotFound
+		}
+		return nil, err
+	}
+
+	if variant.DeletedAt != nil {
+		return nil, model.ErrVariantNotFound
+	}
+
+	if variant.InventoryQuantity > 0 {
+		return nil, ErrVariantInStock
+	}
+
+	return svc.models.BackInStockSubscriptionModel.Upsert(ctx, svc.db, &model.BackInStockSubscriptionRecord{VariantId: variantId, UserIdentifier: userIdentifier, Email: input.Email})
+}
+
+func (svc *InventoryService) UnsubscribeBackInStock(ctx context.Context, userIdentifier string, variantId string) error {
+	return svc.models.BackInStockSubscriptionModel.DeleteByVariantId(ctx, svc.db, userIdentifier, variantId)
+}
+
+func (svc *InventoryService) ListBackInStockSubscriptions(ctx context.Context, userIdentifier string) ([]*model.BackInStockSubscriptionRecord, error) {
+	return svc.models.BackInStockSubscriptionModel.FindAllByUserIdentifier(ctx, svc.db, userIdentifier)
+}
+
+type LowStockVariantDTO struct {
+	VariantId         string  `json:"variant_id"`
+	ProductId         string  `json:"product_id"`
+	ProductTitle      string  `json:"product_title"`
+	VariantTitle      string  `json:"variant_title"`
+	Sku               *string `json:"sku"`
+	InventoryQuantity int     `json:"inventory_quantity"`
+	LowStockThreshold int     `json:"low_stock_threshold"`
+}
+
+type LowStockReportOptions struct {
+	Page     uint
+	PageSize uint
+}
+
+// LowStockReport lists the variants whose quantity is at or below their low stock threshold
+func (svc *InventoryService) LowStockReport(ctx context.Context, opt LowStockReportOptions) ([]*LowStockVariantDTO, int, error) {
+	count, err := svc.models.ProductVariantModel.CountUnderThreshold(ctx, svc.db)
+
+	if err != nil {
+		return nil, 0, err
+	}
+
+	variants, err := svc.models.ProductVariantModel.FindAllUnderThreshold(ctx, svc.db, opt.PageSize, (opt.Page-1)*opt.PageSize)
+
+	if err != nil {
+		return nil, 0, err
+	}
+
+	productIds := []string{}
+
+	for _, variant := range variants {
+		productIds = append(productIds, variant.ProductId)
+	}
+
+	productsMap, err := svc.models.ProductModel.FindAllByIds(ctx, svc.db, productIds)
+
+	if err != nil {
+		return nil, 0, err
+	}
+
+	report := []*LowStockVariantDTO{}
+
+	for _, variant := range variants {
+		dto := &LowStockVariantDTO{
+			VariantId:         variant.Id,
+			ProductId:         variant.ProductId,
+			VariantTitle:      variant.Title,
+			Sku:               variant.Sku,
+			InventoryQuantity: variant.InventoryQuantity,
+			LowStockThreshold: *variant.LowStockThreshold,
+		}
+
+		if product, ok := productsMap[variant.ProductId]; ok {
+			dto.ProductTitle = product.Title
+		}
+
+		report = append(report, dto)
+	}
+
+	return report, count, nil
+}
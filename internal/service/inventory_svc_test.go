@@ -0,0 +1,53 @@
+package service
+
+import (
+	"ecom-backend/internal/model"
+	"errors"
+	"testing"
+)
+
+func TestAllocateStockLocation(t *testing.T) {
+	level := func(locationId string, quantity int, isActive bool) *model.InventoryLevelRecord {
+		return &model.InventoryLevelRecord{LocationId: locationId, Quantity: quantity, LocationIsActive: isActive}
+	}
+
+	// in priority order
+	levels := []*model.InventoryLevelRecord{
+		level("inactive", 100, false),
+		level("first", 5, true),
+		level("second", 20, true),
+		level("third", 20, true),
+	}
+
+	tests := []struct {
+		name     string
+		levels   []*model.InventoryLevelRecord
+		quantity int
+		strategy string
+		want     string
+		wantErr  error
+	}{
+		{"priority picks the first active location covering the quantity", levels, 5, AllocationStrategyPriority, "first", nil},
+		{"priority skips the locations short of the quantity", levels, 6, AllocationStrategyPriority, "second", nil},
+		{"unknown strategy falls back to priority", levels, 5, "", "first", nil},
+		{"most stock picks the largest active level", levels, 1, AllocationStrategyMostStock, "second", nil},
+		{"most stock keeps the priority order on ties", levels, 20, AllocationStrategyMostStock, "second", nil},
+		{"inactive locations aren't used", levels, 21, AllocationStrategyPriority, "", model.ErrInsufficientInventory},
+		{"a line is never split across locations", []*model.InventoryLevelRecord{level("a", 3, true), level("b", 3, true)}, 4, AllocationStrategyMostStock, "", model.ErrInsufficientInventory},
+		{"no levels", nil, 1, AllocationStrategyPriority, "", model.ErrInsufficientInventory},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := allocateStockLocation(tt.levels, tt.quantity, tt.strategy)
+
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got error %v, want %v", err, tt.wantErr)
+			}
+
+			if got != tt.want {
+				t.Errorf("got location %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
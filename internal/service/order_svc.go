@@ -0,0 +1,771 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"ecom-backend/internal/consts"
+	"ecom-backend/internal/model"
+	"ecom-backend/internal/money"
+	"ecom-backend/internal/validator"
+	"ecom-backend/pkg/sqldb"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+var (
+	ErrEmptyCart            = errors.New("cart is empty")
+	ErrVariantPriceNotFound = errors.New("variant has no price in the requested currency")
+	ErrShippingMethodNeeded = errors.New("a shipping method is required when the cart has items to ship")
+)
+
+type OrderService struct {
+	db                 *sql.DB
+	models             *model.Models
+	productSvc         *ProductService
+	promotionSvc       *PromotionService
+	shippingSvc        *ShippingService
+	taxCalculator      TaxCalculator
+	allocationStrategy string
+}
+
+func NewOrderService(db *sql.DB, models *model.Models, productSvc *ProductService, promotionSvc *PromotionService, shippingSvc *ShippingService, taxCalculator TaxCalculator, allocationStrategy string) *OrderService {
+	return &OrderService{db: db, models: models, productSvc: productSvc, promotionSvc: promotionSvc, shippingSvc: shippingSvc, taxCalculator: taxCalculator, allocationStrategy: allocationStrategy}
+}
+
+type OrderDTO struct {
+	Id               string                       `json:"id"`
+	UserId           *string                      `json:"user_id"`
+	Email            string                       `json:"email"`
+	CurrencyCode     string                       `json:"currency_code"`
+	RegionId         *string                      `json:"region_id"`
+	ShippingAddress  *model.AddressRecord         `json:"shipping_address"`
+	BillingAddress   *model.AddressRecord         `json:"billing_address"`
+	Items            []*model.OrderLineItemRecord `json:"items"`
+	Subtotal         money.Money                  `json:"subtotal"`
+	DiscountTotal    money.Money                  `json:"discount_total"`
+	TaxTotal         money.Money                  `json:"tax_total"`
+	IsTaxInclusive   bool                         `json:"is_tax_inclusive"`
+	ShippingMethod   *OrderShippingMethodDTO      `json:"shipping_method"` // nil when the order has no shipping
+	ShippingTotal    money.Money                  `json:"shipping_total"`
+	Total            money.Money                  `json:"total"`
+	GiftCardTotal    money.Money                  `json:"gift_card_total"`
+	StoreCreditTotal money.Money                  `json:"store_credit_total"`
+	AmountDue        money.Money                  `json:"amount_due"` // what the payment provider is charged
+	Status           string                       `json:"status"`
+	PaidAt           *time.Time                   `json:"paid_at"`
+	FulfilledAt      *time.Time                   `json:"fulfilled_at"`
+	ShippedAt        *time.Time                   `json:"shipped_at"`
+	DeliveredAt      *time.Time                   `json:"delivered_at"`
+	CancelledAt      *time.Time                   `json:"cancelled_at"`
+	RefundedAt       *time.Time                   `json:"refunded_at"`
+	CreatedAt        time.Time                    `json:"created_at"`
+	UpdatedAt        time.Time                    `json:"updated_at"`
+
+	userIdentifier string // used for ownership checks, never exposed
+}
+
+// OrderShippingMethodDTO is the snapshot of the method the order is shipped with
+type OrderShippingMethodDTO struct {
+	Id   *string `json:"id"` // nil when the method was removed after the order was placed
+	Name string  `json:"name"`
+}
+
+type AddressInput struct {
+	FirstName   string  `json:"first_name"`
+	LastName    string  `json:"last_name"`
+	Address1    string  `json:"address_1"`
+	Address2    *string `json:"address_2"`
+	City        string  `json:"city"`
+	PostalCode  string  `json:"postal_code"`
+	Province    *string `json:"province"`
+	CountryCode string  `json:"country_code"`
+	Phone       *string `json:"phone"`
+}
+
+func (input *AddressInput) Validate(v *validator.Validator, prefix string) {
+	v.Check(input.FirstName != "", prefix+".first_name", "must be provided")
+	v.Check(input.LastName != "", prefix+".last_name", "must be provided")
+	v.Check(input.Address1 != "", prefix+".address_1", "must be provided")
+	v.Check(input.City != "", prefix+".city", "must be provided")
+	v.Check(input.PostalCode != "", prefix+".postal_code", "must be provided")
+	v.Check(len(input.CountryCode) == 2, prefix+".country_code", "must be a 2 letter ISO code")
+}
+
+func (input *AddressInput) toRecord() *model.AddressRecord {
+	return &model.AddressRecord{
+		FirstName:   input.FirstName,
+		LastName:    input.LastName,
+		Address1:    input.Address1,
+		Address2:    input.Address2,
+		City:        input.City,
+		PostalCode:  input.PostalCode,
+		Province:    input.Province,
+		CountryCode: strings.ToLower(input.CountryCode),
+		Phone:       input.Phone,
+	}
+}
+
+type CheckoutInput struct {
+	Email           string        `json:"email"`
+	CurrencyCode    string        `json:"currency_code"` // the currency of the region is used when one is selected
+	ShippingAddress *AddressInput `json:"shipping_address"`
+	BillingAddress  *AddressInput `json:"billing_address"` // optional, the shipping address is used when missing
+	PromotionCode   *string       `json:"promotion_code"`  // optional, the code applied to the cart is used when missing
+	// one of the shipping options of the cart for the shipping address, only optional when the cart has nothing but gift
+	// cards since the orders without one aren't shipped
+	ShippingMethodId *string `json:"shipping_method_id"`
+	// the groups of the authenticated customer, they decide which price lists and promotions apply
+	CustomerGroupIds []string `json:"-"`
+	// optional, the cards pay for the order in this order until the total is covered, the payment provider is charged the rest
+	GiftCardCodes  []string `json:"gift_card_codes"`
+	UseStoreCredit bool     `json:"use_store_credit"` // pay with the store credit of the customer, after the gift cards
+}
+
+func (input *CheckoutInput) Validate(v *validator.Validator) {
+	v.Check(input.Email != "", "email", "must be provided")
+	v.Check(validator.Matches(input.Email, validator.EmailRX), "email", "must be valid")
+	v.Check(input.CurrencyCode != "", "currency_code", "must be provided")
+	v.Check(input.ShippingAddress != nil, "shipping_address", "must be provided")
+
+	if input.ShippingAddress != nil {
+		input.ShippingAddress.Validate(v, "shipping_address")
+	}
+
+	if input.BillingAddress != nil {
+		input.BillingAddress.Validate(v, "billing_address")
+	}
+
+	if input.ShippingMethodId != nil {
+		v.Check(validator.IsValidUUID(*input.ShippingMethodId), "shipping_method_id", "must be a valid UUID")
+	}
+
+	codes := []string{}
+
+	for _, code := range input.GiftCardCodes {
+		codes = append(codes, strings.ToLower(strings.TrimSpace(code)))
+	}
+
+	v.Check(!validator.In("", codes...), "gift_card_codes", "must not contain empty codes")
+	v.Check(validator.Unique(codes), "gift_card_codes", "must not contain duplicate codes")
+}
+
+// Checkout turns the cart of the client into an order. Everything happens inside a single transaction:
+// the variants are locked, the line items are snapshotted, the inventory is decremented and the cart is emptied.
+// When the client selected a region the order is placed in it, the shipping address must then be in one of its countries.
+func (svc *OrderService) Checkout(ctx context.Context, userIdentifier string, userId *string, region *RegionDTO, input *CheckoutInput) (*OrderDTO, error) {
+	currencyCode := strings.ToLower(input.CurrencyCode)
+
+	var regionId *string
+
+	if region != nil {
+		if !region.HasCountry(input.ShippingAddress.CountryCode) {
+			return nil, ErrCountryNotInRegion
+		}
+
+		currencyCode = region.CurrencyCode
+		regionId = &region.Id
+	}
+
+	tx, err := svc.db.BeginTx(ctx, nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer tx.Rollback()
+
+	cart, err := svc.models.CartModel.FindByUserIdentifier(ctx, tx, userIdentifier)
+
+	if err != nil {
+		if errors.Is(err, model.ErrRecordNotFound) {
+			return nil, ErrEmptyCart
+		}
+		return nil, err
+	}
+
+	cartItems, err := svc.models.CartItemModel.FindAllByCartId(ctx, tx, cart.Id)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(cartItems) == 0 {
+		return nil, ErrEmptyCart
+	}
+
+	variantIds := []string{}
+	quantities := map[string]int{}
+
+	for _, item := range cartItems {
+		variantIds = append(variantIds, item.VariantId)
+		quantities[item.VariantId] = item.Quantity
+	}
+
+	// lock the variants so no other checkout can touch their inventory until this transaction ends
+	variantsMap, err := svc.models.ProductVariantModel.FindAllByIdsForUpdate(ctx, tx, variantIds)
+
+	if err != nil {
+		return nil, err
+	}
+
+	productIds := []string{}
+
+	for _, variant := range variantsMap {
+		productIds = append(productIds, variant.ProductId)
+	}
+
+	productsMap, err := svc.models.ProductModel.FindAllByIds(ctx, tx, productIds)
+
+	if err != nil {
+		return nil, err
+	}
+
+	aggFieldsMap, err := svc.productSvc.GetAggregateFieldsForProductsList(ctx, tx, productIds, PriceContext{CurrencyCode: &currencyCode, At: time.Now(), Quantities: quantities, CustomerGroupIds: input.CustomerGroupIds})
+
+	if err != nil {
+		return nil, err
+	}
+
+	// stock held by other carts isn't available to this checkout, while the cart's own reservations are
+	reservedByOthers, err := svc.models.InventoryReservationModel.SumActiveByVariantIds(ctx, tx, variantIds, &cart.Id)
+
+	if err != nil {
+		return nil, err
+	}
+
+	inventoryLevelsMap, err := svc.models.InventoryLevelModel.FindAllByVariantIds(ctx, tx, variantIds)
+
+	if err != nil {
+		return nil, err
+	}
+
+	lineItems := []*model.OrderLineItemRecord{}
+	subtotal := money.Zero(currencyCode)
+
+	for _, item := range cartItems {
+		variant, ok := variantsMap[item.VariantId]
+
+		if !ok || variant.DeletedAt != nil {
+			return nil, model.ErrVariantNotFound
+		}
+
+		product, ok := productsMap[variant.ProductId]
+
+		// a product can be unpublished or deleted while it sits in the cart, it's no longer for sale
+		if !ok || product.DeletedAt != nil || product.Status != consts.StatusPublished {
+			return nil, model.ErrVariantNotFound
+		}
+
+		if variant.InventoryQuantity-reservedByOthers[variant.Id] < item.Quantity {
+			return nil, fmt.Errorf("%w for %s - %s", model.ErrInsufficientInventory, product.Title, variant.Title)
+		}
+
+		locationId, err := allocateStockLocation(inventoryLevelsMap[variant.Id], item.Quantity, svc.allocationStrategy)
+
+		if err != nil {
+			return nil, fmt.Errorf("%w at a single stock location for %s - %s", err, product.Title, variant.Title)
+		}
+
+		lineItem, err := buildOrderLineItem(product, aggFieldsMap[product.Id], variant.Id, currencyCode)
+
+		if err != nil {
+			return nil, err
+		}
+
+		lineItem.LocationId = &locationId
+
+		lineItem.Quantity = item.Quantity
+		lineItem.Subtotal = lineItem.UnitPrice.Mul(item.Quantity)
+		subtotal = subtotal.Add(lineItem.Subtotal)
+
+		lineItems = append(lineItems, lineItem)
+	}
+
+	promotionCode := cart.PromotionCode
+
+	if input.PromotionCode != nil {
+		promotionCode = input.PromotionCode
+	}
+
+	lines := []*promotionLine{}
+
+	for _, lineItem := range lineItems {
+		lines = append(lines, &promotionLine{VariantId: *lineItem.VariantId, ProductId: lineItem.ProductId, Quantity: lineItem.Quantity, Subtotal: lineItem.Subtotal})
+	}
+
+	evaluation, err := svc.promotionSvc.lockPromotions(ctx, tx, promotionCode, currencyCode, lines, promotionCustomer{UserIdentifier: userIdentifier, Email: &input.Email, CustomerGroupIds: input.CustomerGroupIds})
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, applied := range evaluation.Applied {
+		for _, lineItem := range lineItems {
+			for _, lineDiscount := range applied.Items {
+				if lineDiscount.VariantId == *lineItem.VariantId {
+					lineItem.DiscountTotal = lineItem.DiscountTotal.Add(lineDiscount.Amount)
+				}
+			}
+		}
+	}
+
+	discountTotal := evaluation.DiscountTotal
+
+	taxLines := []TaxLine{}
+
+	for _, lineItem := range lineItems {
+		taxLines = append(taxLines, TaxLine{VariantId: *lineItem.VariantId, ProductId: lineItem.ProductId, Quantity: lineItem.Quantity, Amount: lineItem.Subtotal.Sub(lineItem.DiscountTotal)})
+	}
+
+	taxes, err := svc.taxCalculator.Calculate(ctx, TaxCalculationRequest{
+		CurrencyCode: currencyCode,
+		Location:     TaxLocation{CountryCode: input.ShippingAddress.CountryCode, Province: input.ShippingAddress.Province},
+		Lines:        taxLines,
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, lineItem := range lineItems {
+		if lineTax := taxes.findLineTax(*lineItem.VariantId); lineTax != nil {
+			lineItem.TaxRate = lineTax.Rate
+			lineItem.TaxTotal = lineTax.Amount
+		}
+	}
+
+	// the order is only shipped when a method was chosen, its name is kept in case the method is removed later
+	var shippingMethodId, shippingMethodName *string
+	shippingTotal := money.Zero(currencyCode)
+
+	if input.ShippingMethodId == nil {
+		for _, lineItem := range lineItems {
+			if !lineItem.IsGiftCard {
+				return nil, ErrShippingMethodNeeded
+			}
+		}
+	}
+
+	if input.ShippingMethodId != nil {
+		var weight float32
+
+		for _, lineItem := range lineItems {
+			if variant := variantsMap[*lineItem.VariantId]; variant.Weight != nil {
+				weight += *variant.Weight * float32(lineItem.Quantity)
+			}
+		}
+
+		shippingOption, err := svc.shippingSvc.findShippingOption(ctx, tx, *input.ShippingMethodId, input.ShippingAddress.CountryCode, currencyCode, subtotal.Sub(discountTotal), weight)
+
+		if err != nil {
+			return nil, err
+		}
+
+		shippingMethodId = &shippingOption.MethodId
+		shippingMethodName = &shippingOption.Name
+		shippingTotal = shippingOption.Amount
+	}
+
+	total := subtotal.Sub(discountTotal).Add(shippingTotal)
+
+	if !taxes.IsTaxInclusive {
+		total = total.Add(taxes.TaxTotal)
+	}
+
+	// the gift cards and the store credit are locked until the order is placed so their balances can't be spent twice
+	giftCardRedemptions, err := lockGiftCards(ctx, tx, svc.models, input.GiftCardCodes, total)
+
+	if err != nil {
+		return nil, err
+	}
+
+	giftCardTotal := money.Zero(currencyCode)
+
+	for _, redemption := range giftCardRedemptions {
+		giftCardTotal = giftCardTotal.Add(redemption.amount)
+	}
+
+	storeCreditTotal := money.Zero(currencyCode)
+
+	if input.UseStoreCredit {
+		if userId == nil {
+			return nil, ErrStoreCreditUnavailable
+		}
+
+		storeCreditTotal, err = lockStoreCredit(ctx, tx, svc.models, *userId, total.Sub(giftCardTotal))
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	shippingAddress, err := svc.models.AddressModel.Insert(ctx, tx, input.ShippingAddress.toRecord())
+
+	if err != nil {
+		return nil, err
+	}
+
+	billingAddressInput := input.ShippingAddress
+
+	if input.BillingAddress != nil {
+		billingAddressInput = input.BillingAddress
+	}
+
+	billingAddress, err := svc.models.AddressModel.Insert(ctx, tx, billingAddressInput.toRecord())
+
+	if err != nil {
+		return nil, err
+	}
+
+	order, err := svc.models.OrderModel.Insert(ctx, tx, &model.OrderRecord{
+		UserIdentifier:     userIdentifier,
+		UserId:             userId,
+		Email:              input.Email,
+		CurrencyCode:       currencyCode,
+		RegionId:           regionId,
+		ShippingAddressId:  shippingAddress.Id,
+		BillingAddressId:   billingAddress.Id,
+		Subtotal:           subtotal,
+		DiscountTotal:      discountTotal,
+		TaxTotal:           taxes.TaxTotal,
+		IsTaxInclusive:     taxes.IsTaxInclusive,
+		ShippingMethodId:   shippingMethodId,
+		ShippingMethodName: shippingMethodName,
+		ShippingTotal:      shippingTotal,
+		Total:              total,
+		GiftCardTotal:      giftCardTotal,
+		StoreCreditTotal:   storeCreditTotal,
+	})
+
+	if err != nil {
+		return nil, fmt.Errorf("failed to create order record: %w", err)
+	}
+
+	err = svc.promotionSvc.recordRedemptions(ctx, tx, evaluation, order)
+
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = svc.models.OrderEventModel.Insert(ctx, tx, &model.OrderEventRecord{OrderId: order.Id, ToStatus: order.Status, ActorUserId: userId})
+
+	if err != nil {
+		return nil, err
+	}
+
+	err = redeemGiftCards(ctx, tx, svc.models, giftCardRedemptions, order.Id)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if userId != nil {
+		err = redeemStoreCredit(ctx, tx, svc.models, *userId, storeCreditTotal, order.Id)
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, lineItem := range lineItems {
+		lineItem.OrderId = order.Id
+
+		_, err := svc.models.OrderLineItemModel.Insert(ctx, tx, lineItem)
+
+		if err != nil {
+			return nil, fmt.Errorf("failed to create order_line_item record: %w", err)
+		}
+
+		_, err = recordStockMovement(ctx, tx, svc.models, &model.StockMovementRecord{
+			VariantId:   *lineItem.VariantId,
+			LocationId:  *lineItem.LocationId,
+			Type:        consts.StockMovementSale,
+			Quantity:    -lineItem.Quantity,
+			OrderId:     &order.Id,
+			ActorUserId: userId,
+		})
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = svc.models.InventoryReservationModel.UpdateActiveStatusByCartId(ctx, tx, cart.Id, consts.ReservationStatusConsumed)
+
+	if err != nil {
+		return nil, err
+	}
+
+	err = svc.models.CartItemModel.DeleteAllByCartId(ctx, tx, cart.Id)
+
+	if err != nil {
+		return nil, err
+	}
+
+	// the code was used up by this order
+	err = svc.models.CartModel.SetPromotionCode(ctx, tx, cart.Id, nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	// nothing is left for a payment provider to charge
+	if !order.AmountDue().IsPositive() {
+		note := "paid with gift cards and store credit"
+
+		order, err = svc.transitionOrder(ctx, tx, order, consts.OrderStatusPaid, userId, &note)
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return buildOrderDTO(order, shippingAddress, billingAddress, lineItems), nil
+}
+
+// buildOrderLineItem snapshots the variant details and its price in the order currency
+func buildOrderLineItem(product *model.ProductRecord, aggFields *AggregateProductListFields, variantId string, currencyCode string) (*model.OrderLineItemRecord, error) {
+	var variant *AggregateProductVariant
+
+	for i := range aggFields.Variants {
+		if aggFields.Variants[i].Id == variantId {
+			variant = &aggFields.Variants[i]
+			break
+		}
+	}
+
+	if variant == nil {
+		return nil, model.ErrVariantNotFound
+	}
+
+	var unitPrice *money.Money
+
+	for _, price := range variant.Prices {
+		if price.CurrencyCode == currencyCode {
+			unitPrice = &price.Amount
+			break
+		}
+	}
+
+	if unitPrice == nil {
+		return nil, fmt.Errorf("%w: %s - %s", ErrVariantPriceNotFound, product.Title, variant.Title)
+	}
+
+	optionTitles := map[string]string{}
+
+	for _, option := range aggFields.Options {
+		optionTitles[option.Id] = option.Title
+	}
+
+	options := []model.OrderLineItemOption{}
+
+	for _, optionValue := range variant.Options {
+		options = append(options, model.OrderLineItemOption{OptionId: optionValue.OptionId, OptionTitle: optionTitles[optionValue.OptionId], Value: optionValue.Value})
+	}
+
+	return &model.OrderLineItemRecord{
+		VariantId:     &variant.Id,
+		ProductId:     product.Id,
+		ProductTitle:  product.Title,
+		VariantTitle:  variant.Title,
+		Sku:           variant.Sku,
+		ThumbnailId:   product.ThumbnailId,
+		Options:       options,
+		UnitPrice:     *unitPrice,
+		DiscountTotal: money.Zero(currencyCode),
+		TaxTotal:      money.Zero(currencyCode),
+		IsGiftCard:    product.IsGiftCard,
+	}, nil
+}
+
+func buildOrderDTO(order *model.OrderRecord, shippingAddress *model.AddressRecord, billingAddress *model.AddressRecord, items []*model.OrderLineItemRecord) *OrderDTO {
+	if items == nil {
+		items = []*model.OrderLineItemRecord{}
+	}
+
+	var shippingMethod *OrderShippingMethodDTO
+
+	if order.ShippingMethodName != nil {
+		shippingMethod = &OrderShippingMethodDTO{Id: order.ShippingMethodId, Name: *order.ShippingMethodName}
+	}
+
+	return &OrderDTO{
+		Id:               order.Id,
+		UserId:           order.UserId,
+		Email:            order.Email,
+		CurrencyCode:     order.CurrencyCode,
+		RegionId:         order.RegionId,
+		ShippingAddress:  shippingAddress,
+		BillingAddress:   billingAddress,
+		Items:            items,
+		Subtotal:         order.Subtotal,
+		DiscountTotal:    order.DiscountTotal,
+		TaxTotal:         order.TaxTotal,
+		IsTaxInclusive:   order.IsTaxInclusive,
+		ShippingMethod:   shippingMethod,
+		ShippingTotal:    order.ShippingTotal,
+		Total:            order.Total,
+		GiftCardTotal:    order.GiftCardTotal,
+		StoreCreditTotal: order.StoreCreditTotal,
+		AmountDue:        order.AmountDue(),
+		Status:           order.Status,
+		PaidAt:           order.PaidAt,
+		FulfilledAt:      order.FulfilledAt,
+		ShippedAt:        order.ShippedAt,
+		DeliveredAt:      order.DeliveredAt,
+		CancelledAt:      order.CancelledAt,
+		RefundedAt:       order.RefundedAt,
+		CreatedAt:        order.CreatedAt,
+		UpdatedAt:        order.UpdatedAt,
+		userIdentifier:   order.UserIdentifier,
+	}
+}
+
+func (svc *OrderService) GetOrder(ctx context.Context, id string) (*OrderDTO, error) {
+	order, err := svc.models.OrderModel.FindById(ctx, svc.db, id)
+
+	if err != nil {
+		return nil, err
+	}
+
+	orders, err := svc.buildOrderDTOs(ctx, svc.db, []*model.OrderRecord{order})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return orders[0], nil
+}
+
+// GetClientOrder returns the order only if it was placed by the given client
+func (svc *OrderService) GetClientOrder(ctx context.Context, userIdentifier string, id string) (*OrderDTO, error) {
+	order, err := svc.GetOrder(ctx, id)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if order.userIdentifier != userIdentifier {
+		return nil, ErrUnauthorizedRequest
+	}
+
+	return order, nil
+}
+
+type OrderListingOptions struct {
+	Page     uint
+	PageSize uint
+	Cursor   *Cursor // the page next to the cursor is listed instead of the page number when set
+}
+
+// orderSort is the only order the orders are listed in, the newest first
+const orderSort = "-created_at"
+
+// ListOrders returns a page of the orders, the newest first, from the cursor when one is given and by page number
+// otherwise
+func (svc *OrderService) ListOrders(ctx context.Context, opt OrderListingOptions) ([]*OrderDTO, *PageInfo, error) {
+	var orders []*model.OrderRecord
+	var totalCount *int
+	var err error
+
+	// one more order than the page size tells whether there's another page
+	if opt.Cursor != nil {
+		if opt.Cursor.Sort != orderSort || opt.Cursor.Key == nil {
+			return nil, nil, ErrInvalidCursor
+		}
+
+		createdAt, err := time.Parse(cursorTimeLayout, *opt.Cursor.Key)
+
+		if err != nil {
+			return nil, nil, ErrInvalidCursor
+		}
+
+		orders, err = svc.models.OrderModel.FindAllFrom(ctx, svc.db, createdAt, opt.Cursor.Id, opt.Cursor.Before, opt.PageSize+1)
+
+		if err != nil {
+			return nil, nil, err
+		}
+	} else {
+		count, err := svc.models.OrderModel.Count(ctx, svc.db)
+
+		if err != nil {
+			return nil, nil, err
+		}
+
+		totalCount = &count
+
+		orders, err = svc.models.OrderModel.FindAll(ctx, svc.db, opt.PageSize+1, (opt.Page-1)*opt.PageSize)
+
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	hasMore := len(orders) > int(opt.PageSize)
+
+	if hasMore {
+		orders = orders[:opt.PageSize]
+	}
+
+	// the page before the cursor is listed backwards
+	if opt.Cursor != nil && opt.Cursor.Before {
+		slices.Reverse(orders)
+	}
+
+	keys := []any{}
+	ids := []string{}
+
+	for _, order := range orders {
+		keys = append(keys, order.CreatedAt)
+		ids = append(ids, order.Id)
+	}
+
+	info := newPageInfo(orderSort, keys, ids, opt.Cursor, hasMore, opt.Page)
+	info.TotalCount = totalCount
+
+	orderDtos, err := svc.buildOrderDTOs(ctx, svc.db, orders)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return orderDtos, info, nil
+}
+
+// buildOrderDTOs loads the addresses and line items of the orders
+func (svc *OrderService) buildOrderDTOs(ctx context.Context, conn sqldb.Connection, orders []*model.OrderRecord) ([]*OrderDTO, error) {
+	orderIds := []string{}
+	addressIds := []string{}
+
+	for _, order := range orders {
+		orderIds = append(orderIds, order.Id)
+		addressIds = append(addressIds, order.ShippingAddressId, order.BillingAddressId)
+	}
+
+	addressesMap, err := svc.models.AddressModel.FindAllByIds(ctx, conn, addressIds)
+
+	if err != nil {
+		return nil, err
+	}
+
+	lineItemsMap, err := svc.models.OrderLineItemModel.FindAllByOrderIds(ctx, conn, orderIds)
+
+	if err != nil {
+		return nil, err
+	}
+
+	orderDtos := []*OrderDTO{}
+
+	for _, order := range orders {
+		orderDtos = append(orderDtos, buildOrderDTO(order, addressesMap[order.ShippingAddressId], addressesMap[order.BillingAddressId], lineItemsMap[order.Id]))
+	}
+
+	return orderDtos, nil
+}
@@ -0,0 +1,242 @@
+package service
+
+import (
+	"context"
+	"ecom-backend/internal/consts"
+	"ecom-backend/internal/model"
+	"ecom-backend/internal/validator"
+	"ecom-backend/pkg/sqldb"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var ErrInvalidOrderTransition = errors.New("invalid order status transition")
+
+// orderStatusTransitions lists, for every status, the statuses an order can move to
+var orderStatusTransitions = map[string][]string{
+	consts.OrderStatusPending:   {consts.OrderStatusPaid, consts.OrderStatusCancelled},
+	consts.OrderStatusPaid:      {consts.OrderStatusFulfilled, consts.OrderStatusCancelled, consts.OrderStatusRefunded},
+	consts.OrderStatusFulfilled: {consts.OrderStatusShipped, consts.OrderStatusCancelled, consts.OrderStatusRefunded},
+	consts.OrderStatusShipped:   {consts.OrderStatusDelivered},
+	consts.OrderStatusDelivered: {consts.OrderStatusRefunded},
+	consts.OrderStatusCancelled: {consts.OrderStatusRefunded},
+	consts.OrderStatusRefunded:  {},
+}
+
+func CanTransitionOrder(from string, to string) bool {
+	return validator.In(to, orderStatusTransitions[from]...)
+}
+
+type TransitionOrderInput struct {
+	Status string  `json:"status"`
+	Note   *string `json:"note"`
+}
+
+func (input *TransitionOrderInput) Validate(v *validator.Validator) {
+	_, ok := orderStatusTransitions[input.Status]
+	v.Check(ok, "status", "invalid status")
+}
+
+func (svc *OrderService) TransitionOrder(ctx context.Context, orderId string, actorUserId *string, input *TransitionOrderInput) (*OrderDTO, error) {
+	tx, err := svc.db.BeginTx(ctx, nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer tx.Rollback()
+
+	order, err := svc.models.OrderModel.FindByIdForUpdate(ctx, tx, orderId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	order, err = svc.transitionOrder(ctx, tx, order, input.Status, actorUserId, input.Note)
+
+	if err != nil {
+		return nil, err
+	}
+
+	orders, err := svc.buildOrderDTOs(ctx, tx, []*model.OrderRecord{order})
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return orders[0], nil
+}
+
+// transitionOrder moves an already locked order to the next status, stamps the transition time and records the event.
+// Paying an order issues the gift cards it bought. Cancelling an order puts the purchased quantities back in stock,
+// and cancelling or refunding it gives back the gift card balances and the store credit it was paid with.
+func (svc *OrderService) transitionOrder(ctx context.Context, conn sqldb.Connection, order *model.OrderRecord, toStatus string, actorUserId *string, note *string) (*model.OrderRecord, error) {
+	fromStatus := order.Status
+
+	if !CanTransitionOrder(fromStatus, toStatus) {
+		return nil, fmt.Errorf("%w from %s to %s", ErrInvalidOrderTransition, fromStatus, toStatus)
+	}
+
+	now := time.Now()
+
+	switch toStatus {
+	case consts.OrderStatusPaid:
+		order.PaidAt = &now
+	case consts.OrderStatusFulfilled:
+		order.FulfilledAt = &now
+	case consts.OrderStatusShipped:
+		order.ShippedAt = &now
+	case consts.OrderStatusDelivered:
+		order.DeliveredAt = &now
+	case consts.OrderStatusCancelled:
+		order.CancelledAt = &now
+	case consts.OrderStatusRefunded:
+		order.RefundedAt = &now
+	}
+
+	order.Status = toStatus
+
+	order, err := svc.models.OrderModel.UpdateStatus(ctx, conn, order)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if toStatus == consts.OrderStatusPaid {
+		err := issueOrderGiftCards(ctx, conn, svc.models, order)
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if toStatus == consts.OrderStatusCancelled {
+		err := svc.restockOrder(ctx, conn, order.Id, actorUserId)
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if toStatus == consts.OrderStatusCancelled || toStatus == consts.OrderStatusRefunded {
+		err := svc.restoreOrderBalances(ctx, conn, order)
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	_, err = svc.models.OrderEventModel.Insert(ctx, conn, &model.OrderEventRecord{OrderId: order.Id, FromStatus: &fromStatus, ToStatus: toStatus, ActorUserId: actorUserId, Note: note})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return order, nil
+}
+
+// restoreOrderBalances gives back everything the order still takes from the store credit and the gift cards, and
+// disables the gift cards bought with it
+func (svc *OrderService) restoreOrderBalances(ctx context.Context, conn sqldb.Connection, order *model.OrderRecord) error {
+	_, err := restoreOrderStoreCredit(ctx, conn, svc.models, order, order.Total)
+
+	if err != nil {
+		return err
+	}
+
+	_, err = restoreOrderGiftCards(ctx, conn, svc.models, order, order.Total)
+
+	if err != nil {
+		return err
+	}
+
+	return disableOrderGiftCards(ctx, conn, svc.models, order.Id)
+}
+
+// restockOrder adds the quantities of the order line items back to the inventory of their variants
+func (svc *OrderService) restockOrder(ctx context.Context, conn sqldb.Connection, orderId string, actorUserId *string) error {
+	lineItemsMap, err := svc.models.OrderLineItemModel.FindAllByOrderIds(ctx, conn, []string{orderId})
+
+	if err != nil {
+		return err
+	}
+
+	variantIds := []string{}
+
+	for _, lineItem := range lineItemsMap[orderId] {
+		if lineItem.VariantId != nil {
+			variantIds = append(variantIds, *lineItem.VariantId)
+		}
+	}
+
+	// locked in the same order as the checkouts do
+	_, err = svc.models.ProductVariantModel.FindAllByIdsForUpdate(ctx, conn, variantIds)
+
+	if err != nil {
+		return err
+	}
+
+	for _, lineItem := range lineItemsMap[orderId] {
+		// the variant was deleted in the meantime, there's nothing to restock
+		if lineItem.VariantId == nil {
+			continue
+		}
+
+		locationId, err := restockLocationId(ctx, conn, svc.models, lineItem)
+
+		if err != nil {
+			return err
+		}
+
+		_, err = recordStockMovement(ctx, conn, svc.models, &model.StockMovementRecord{
+			VariantId:   *lineItem.VariantId,
+			LocationId:  locationId,
+			Type:        consts.StockMovementReturn,
+			Quantity:    lineItem.Quantity,
+			OrderId:     &orderId,
+			ActorUserId: actorUserId,
+		})
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// restockLocationId returns the location the line was allocated from, or an empty id for the default location if that
+// one was removed
+func restockLocationId(ctx context.Context, conn sqldb.Connection, models *model.Models, lineItem *model.OrderLineItemRecord) (string, error) {
+	if lineItem.LocationId == nil {
+		return "", nil
+	}
+
+	location, err := models.StockLocationModel.FindById(ctx, conn, *lineItem.LocationId)
+
+	if err != nil && !errors.Is(err, model.ErrRecordNotFound) {
+		return "", err
+	}
+
+	if location == nil {
+		return "", nil
+	}
+
+	return location.Id, nil
+}
+
+func (svc *OrderService) ListOrderEvents(ctx context.Context, orderId string) ([]*model.OrderEventRecord, error) {
+	// make sure the order exists so a missing order is reported as not found instead of an empty history
+	_, err := svc.models.OrderModel.FindById(ctx, svc.db, orderId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return svc.models.OrderEventModel.FindAllByOrderId(ctx, svc.db, orderId)
+}
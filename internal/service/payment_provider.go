@@ -0,0 +1,145 @@
+package service
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"ecom-backend/internal/consts"
+	"ecom-backend/internal/money"
+	"ecom-backend/internal/validator"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+)
+
+var (
+	ErrInvalidWebhookSignature = errors.New("invalid webhook signature")
+	ErrInvalidWebhookPayload   = errors.New("invalid webhook payload")
+)
+
+// PaymentProvider is implemented by every payment gateway the shop can take payments through ( fake, stripe, etc ).
+// The payment service talks to the gateways only through this interface, so adding a new one
+// doesn't require any change to the checkout or payment flows.
+type PaymentProvider interface {
+	// Id is the unique identifier of the provider, it's stored on the payment sessions and used in the webhook route
+	Id() string
+	// Authorize reserves the amount on the customer payment method
+	Authorize(ctx context.Context, req AuthorizePaymentRequest) (*PaymentProviderResult, error)
+	// Capture collects a previously authorized amount, a capture asked again with the same idempotency key must not be
+	// made twice
+	Capture(ctx context.Context, reference string, amount money.Money, idempotencyKey string) (*PaymentProviderResult, error)
+	// Refund gives back (part of) a captured amount, the status is refunded once the amount was given back whatever is left
+	// of the payment, it's up to the payment service to tell whether the payment is partially or fully refunded. A refund
+	// asked again with the same idempotency key must not be made twice.
+	Refund(ctx context.Context, reference string, amount money.Money, idempotencyKey string) (*PaymentProviderResult, error)
+	// Void cancels an authorization that wasn't captured yet
+	Void(ctx context.Context, reference string) (*PaymentProviderResult, error)
+	// VerifyWebhook checks that a webhook call really comes from the provider and parses its payload
+	VerifyWebhook(payload []byte, header http.Header) (*PaymentWebhookEvent, error)
+}
+
+type AuthorizePaymentRequest struct {
+	SessionId     string
+	OrderId       string
+	Email         string
+	Amount        money.Money
+	CurrencyCode  string
+	PaymentMethod string // provider specific token describing the customer payment method
+}
+
+type PaymentProviderResult struct {
+	Reference string // id of the payment on the provider side
+	Status    string // one of the payment session statuses for Authorize, of the payment statuses for the other operations
+}
+
+type PaymentWebhookEvent struct {
+	Reference string
+	Status    string // one of the payment session statuses
+}
+
+// Payment methods understood by the fake provider, any other value is authorized right away
+const (
+	FakePaymentMethodDeclined    = "fake_card_declined"
+	FakePaymentMethodRequires3DS = "fake_card_3ds"
+)
+
+// FakePaymentProvider is a deterministic provider used for development and tests, it never calls the network.
+// The outcome of an authorization depends only on the payment method token, and webhooks are signed
+// with an HMAC-SHA256 of the body using the configured secret, sent in the `Fake-Signature` header.
+type FakePaymentProvider struct {
+	webhookSecret []byte
+}
+
+func NewFakePaymentProvider(webhookSecret string) *FakePaymentProvider {
+	return &FakePaymentProvider{webhookSecret: []byte(webhookSecret)}
+}
+
+func (p *FakePaymentProvider) Id() string {
+	return "fake"
+}
+
+func (p *FakePaymentProvider) Authorize(ctx context.Context, req AuthorizePaymentRequest) (*PaymentProviderResult, error) {
+	result := &PaymentProviderResult{Reference: "fake_" + req.SessionId}
+
+	switch req.PaymentMethod {
+	case FakePaymentMethodDeclined:
+		result.Status = consts.PaymentSessionStatusDeclined
+	case FakePaymentMethodRequires3DS:
+		result.Status = consts.PaymentSessionStatusRequiresAction
+	default:
+		result.Status = consts.PaymentSessionStatusAuthorized
+	}
+
+	return result, nil
+}
+
+func (p *FakePaymentProvider) Capture(ctx context.Context, reference string, amount money.Money, idempotencyKey string) (*PaymentProviderResult, error) {
+	return &PaymentProviderResult{Reference: reference, Status: consts.PaymentStatusCaptured}, nil
+}
+
+func (p *FakePaymentProvider) Refund(ctx context.Context, reference string, amount money.Money, idempotencyKey string) (*PaymentProviderResult, error) {
+	return &PaymentProviderResult{Reference: reference, Status: consts.PaymentStatusRefunded}, nil
+}
+
+func (p *FakePaymentProvider) Void(ctx context.Context, reference string) (*PaymentProviderResult, error) {
+	return &PaymentProviderResult{Reference: reference, Status: consts.PaymentStatusVoided}, nil
+}
+
+func (p *FakePaymentProvider) VerifyWebhook(payload []byte, header http.Header) (*PaymentWebhookEvent, error) {
+	signature, err := hex.DecodeString(header.Get("Fake-Signature"))
+
+	if err != nil {
+		return nil, ErrInvalidWebhookSignature
+	}
+
+	if !hmac.Equal(signature, p.Sign(payload)) {
+		return nil, ErrInvalidWebhookSignature
+	}
+
+	var event struct {
+		Reference string `json:"reference"`
+		Status    string `json:"status"`
+	}
+
+	err = json.Unmarshal(payload, &event)
+
+	if err != nil {
+		return nil, ErrInvalidWebhookPayload
+	}
+
+	// the only asynchronous outcome of the fake provider is the result of the 3D Secure confirmation
+	if !validator.In(event.Status, consts.PaymentSessionStatusAuthorized, consts.PaymentSessionStatusDeclined) {
+		return nil, ErrInvalidWebhookPayload
+	}
+
+	return &PaymentWebhookEvent{Reference: event.Reference, Status: event.Status}, nil
+}
+
+// Sign returns the signature the fake provider expects for a webhook payload, handy to simulate webhooks locally
+func (p *FakePaymentProvider) Sign(payload []byte) []byte {
+	mac := hmac.New(sha256.New, p.webhookSecret)
+	mac.Write(payload)
+
+	return mac.Sum(nil)
+}
@@ -0,0 +1,744 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"ecom-backend/internal/consts"
+	"ecom-backend/internal/model"
+	"ecom-backend/internal/money"
+	"ecom-backend/internal/validator"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+)
+
+var (
+	ErrPaymentProviderNotFound    = errors.New("payment provider not found")
+	ErrPaymentProviderNotInRegion = errors.New("payment provider is not available in the region of the order")
+	ErrOrderNotPayable            = errors.New("order can't be paid in its current status")
+	ErrInvalidPaymentOperation    = errors.New("operation not allowed for the current payment status")
+	ErrInvalidRefundAmount        = errors.New("refund amount exceeds the refundable amount")
+	ErrRefundAmountDecimals       = errors.New("refund amount has more decimals than the currency of the order")
+	ErrPaymentSessionInProgress   = errors.New("order already has a payment session in progress")
+	ErrPaymentRefundInProgress    = errors.New("payment has another refund in progress")
+)
+
+type PaymentService struct {
+	db        *sql.DB
+	models    *model.Models
+	orderSvc  *OrderService
+	providers map[string]PaymentProvider
+}
+
+func NewPaymentService(db *sql.DB, models *model.Models, orderSvc *OrderService, providers []PaymentProvider) *PaymentService {
+	providersMap := map[string]PaymentProvider{}
+
+	for _, provider := range providers {
+		providersMap[provider.Id()] = provider
+	}
+
+	return &PaymentService{db: db, models: models, orderSvc: orderSvc, providers: providersMap}
+}
+
+func (svc *PaymentService) getProvider(providerId string) (PaymentProvider, error) {
+	provider, ok := svc.providers[providerId]
+
+	if !ok {
+		return nil, ErrPaymentProviderNotFound
+	}
+
+	return provider, nil
+}
+
+// ListProviderIds returns the ids of the registered payment providers, only the ones allowed in the region when one is given
+func (svc *PaymentService) ListProviderIds(region *RegionDTO) []string {
+	ids := []string{}
+
+	for id := range svc.providers {
+		if region == nil || region.HasPaymentProvider(id) {
+			ids = append(ids, id)
+		}
+	}
+
+	sort.Strings(ids)
+
+	return ids
+}
+
+type CreatePaymentSessionInput struct {
+	ProviderId    string `json:"provider_id"`
+	PaymentMethod string `json:"payment_method"`
+}
+
+func (input *CreatePaymentSessionInput) Validate(v *validator.Validator) {
+	v.Check(input.ProviderId != "", "provider_id", "must be provided")
+}
+
+// CreatePaymentSession asks the provider to authorize the amount due of the order, i.e. the part of the total not paid
+// with gift cards or store credit. The session is opened while the order is locked, so an order has only one session in
+// progress at a time, then the provider is called outside of any transaction so no lock is held while waiting for it.
+func (svc *PaymentService) CreatePaymentSession(ctx context.Context, userIdentifier string, orderId string, input *CreatePaymentSessionInput) (*model.PaymentSessionRecord, error) {
+	provider, err := svc.getProvider(input.ProviderId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	order, session, err := svc.openPaymentSession(ctx, userIdentifier, orderId, provider)
+
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := provider.Authorize(ctx, AuthorizePaymentRequest{
+		SessionId:     session.Id,
+		OrderId:       order.Id,
+		Email:         order.Email,
+		Amount:        session.Amount,
+		CurrencyCode:  session.CurrencyCode,
+		PaymentMethod: input.PaymentMethod,
+	})
+
+	if err != nil {
+		err = fmt.Errorf("payment provider %s failed to authorize: %w", provider.Id(), err)
+		session.Status = consts.PaymentSessionStatusError
+
+		if _, updateErr := svc.models.PaymentSessionModel.Update(ctx, svc.db, session); updateErr != nil {
+			return nil, errors.Join(err, updateErr)
+		}
+
+		return nil, err
+	}
+
+	tx, err := svc.db.BeginTx(ctx, nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer tx.Rollback()
+
+	session.ProviderReference = &result.Reference
+
+	session, err = svc.updateSessionStatus(ctx, tx, session, result.Status)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return session, nil
+}
+
+// openPaymentSession locks the order, checks it can be paid with the provider and creates a pending session for its amount due
+func (svc *PaymentService) openPaymentSession(ctx context.Context, userIdentifier string, orderId string, provider PaymentProvider) (*model.OrderRecord, *model.PaymentSessionRecord, error) {
+	tx, err := svc.db.BeginTx(ctx, nil)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	defer tx.Rollback()
+
+	order, err := svc.models.OrderModel.FindByIdForUpdate(ctx, tx, orderId)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if order.UserIdentifier != userIdentifier {
+		return nil, nil, ErrUnauthorizedRequest
+	}
+
+	if order.Status != consts.OrderStatusPending {
+		return nil, nil, ErrOrderNotPayable
+	}
+
+	if order.RegionId != nil {
+		providerIds, err := svc.models.RegionModel.FindPaymentProvidersByRegionIds(ctx, tx, []string{*order.RegionId})
+
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if !validator.In(provider.Id(), providerIds[*order.RegionId]...) {
+			return nil, nil, ErrPaymentProviderNotInRegion
+		}
+	}
+
+	payments, err := svc.models.PaymentModel.FindAllByOrderId(ctx, tx, orderId)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// an order can have only one payment that wasn't voided
+	for _, payment := range payments {
+		if payment.Status != consts.PaymentStatusVoided {
+			return nil, nil, ErrOrderNotPayable
+		}
+	}
+
+	sessions, err := svc.models.PaymentSessionModel.FindAllByOrderId(ctx, tx, orderId)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, session := range sessions {
+		if isOpenPaymentSession(session) {
+			return nil, nil, ErrPaymentSessionInProgress
+		}
+	}
+
+	session, err := svc.models.PaymentSessionModel.Insert(ctx, tx, &model.PaymentSessionRecord{OrderId: order.Id, ProviderId: provider.Id(), Amount: order.AmountDue(), CurrencyCode: order.CurrencyCode})
+
+	if err != nil {
+		if errors.Is(err, model.ErrOpenPaymentSessionExists) {
+			return nil, nil, ErrPaymentSessionInProgress
+		}
+		return nil, nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, nil, err
+	}
+
+	return order, session, nil
+}
+
+// isOpenPaymentSession tells whether the session is waiting for an outcome or was authorized
+func isOpenPaymentSession(session *model.PaymentSessionRecord) bool {
+	return validator.In(session.Status, consts.PaymentSessionStatusPending, consts.PaymentSessionStatusRequiresAction, consts.PaymentSessionStatusAuthorized)
+}
+
+// updateSessionStatus stores the new status of the session and creates the payment once the session gets authorized
+func (svc *PaymentService) updateSessionStatus(ctx context.Context, tx *sql.Tx, session *model.PaymentSessionRecord, status string) (*model.PaymentSessionRecord, error) {
+	session.Status = status
+
+	session, err := svc.models.PaymentSessionModel.Update(ctx, tx, session)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if status == consts.PaymentSessionStatusAuthorized {
+		_, err := svc.models.PaymentModel.Insert(ctx, tx, &model.PaymentRecord{
+			OrderId:           session.OrderId,
+			PaymentSessionId:  session.Id,
+			ProviderId:        session.ProviderId,
+			ProviderReference: *session.ProviderReference,
+			Amount:            session.Amount,
+			CurrencyCode:      session.CurrencyCode,
+		})
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return session, nil
+}
+
+// HandleWebhook applies the asynchronous updates sent by the providers, ex: the result of a 3D Secure confirmation
+func (svc *PaymentService) HandleWebhook(ctx context.Context, providerId string, payload []byte, header http.Header) error {
+	provider, err := svc.getProvider(providerId)
+
+	if err != nil {
+		return err
+	}
+
+	event, err := provider.VerifyWebhook(payload, header)
+
+	if err != nil {
+		return err
+	}
+
+	tx, err := svc.db.BeginTx(ctx, nil)
+
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	session, err := svc.models.PaymentSessionModel.FindByProviderReferenceForUpdate(ctx, tx, providerId, event.Reference)
+
+	if err != nil {
+		return err
+	}
+
+	// webhooks can be delivered more than once, only sessions still waiting for an outcome are updated
+	if session.Status != consts.PaymentSessionStatusPending && session.Status != consts.PaymentSessionStatusRequiresAction {
+		return nil
+	}
+
+	_, err = svc.updateSessionStatus(ctx, tx, session, event.Status)
+
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// CapturePayment collects the authorized payment of the order and marks the order as paid. The payment is claimed as
+// capturing while the order is locked, then the provider is called outside of any transaction so no lock is held while
+// waiting for it. A payment left capturing by a failed call is completed by capturing it again, the provider is asked
+// with the id of the payment as idempotency key so it collects it once.
+func (svc *PaymentService) CapturePayment(ctx context.Context, orderId string, actorUserId *string) (*model.PaymentRecord, error) {
+	payment, err := svc.claimPaymentCapture(ctx, orderId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	provider, err := svc.getProvider(payment.ProviderId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := provider.Capture(ctx, payment.ProviderReference, payment.Amount, payment.Id)
+
+	if err != nil {
+		return nil, fmt.Errorf("payment provider %s failed to capture: %w", provider.Id(), err)
+	}
+
+	if result.Status != consts.PaymentStatusCaptured {
+		return nil, fmt.Errorf("payment provider %s didn't capture the payment, status %s", provider.Id(), result.Status)
+	}
+
+	tx, err := svc.db.BeginTx(ctx, nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer tx.Rollback()
+
+	order, err := svc.models.OrderModel.FindByIdForUpdate(ctx, tx, orderId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	payment, err = svc.models.PaymentModel.FindByIdForUpdate(ctx, tx, payment.Id)
+
+	if err != nil {
+		return nil, err
+	}
+
+	// recorded by a concurrent call
+	if payment.Status != consts.PaymentStatusCapturing {
+		return payment, nil
+	}
+
+	now := time.Now()
+	payment.Status = consts.PaymentStatusCaptured
+	payment.AmountCaptured = payment.Amount
+	payment.CapturedAt = &now
+
+	payment, err = svc.models.PaymentModel.Update(ctx, tx, payment)
+
+	if err != nil {
+		return nil, err
+	}
+
+	// the capture is recorded even when the order moved on meanwhile, the money was collected
+	if CanTransitionOrder(order.Status, consts.OrderStatusPaid) {
+		note := "payment captured"
+
+		_, err = svc.orderSvc.transitionOrder(ctx, tx, order, consts.OrderStatusPaid, actorUserId, &note)
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return payment, nil
+}
+
+// claimPaymentCapture locks the order and moves its authorized payment to capturing, a payment already capturing is
+// returned as is so the capture can be retried
+func (svc *PaymentService) claimPaymentCapture(ctx context.Context, orderId string) (*model.PaymentRecord, error) {
+	tx, err := svc.db.BeginTx(ctx, nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer tx.Rollback()
+
+	order, err := svc.models.OrderModel.FindByIdForUpdate(ctx, tx, orderId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	payment, err := svc.models.PaymentModel.FindActiveByOrderIdForUpdate(ctx, tx, order.Id)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if payment.Status == consts.PaymentStatusCapturing {
+		return payment, nil
+	}
+
+	if payment.Status != consts.PaymentStatusAuthorized {
+		return nil, ErrInvalidPaymentOperation
+	}
+
+	if !CanTransitionOrder(order.Status, consts.OrderStatusPaid) {
+		return nil, fmt.Errorf("%w from %s to %s", ErrInvalidOrderTransition, order.Status, consts.OrderStatusPaid)
+	}
+
+	payment.Status = consts.PaymentStatusCapturing
+
+	payment, err = svc.models.PaymentModel.Update(ctx, tx, payment)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return payment, nil
+}
+
+type RefundPaymentInput struct {
+	Amount money.Decimal `json:"amount"` // in the currency of the order
+	Note   *string       `json:"note"`
+}
+
+func (input *RefundPaymentInput) Validate(v *validator.Validator) {
+	v.Check(input.Amount.Sign() > 0, "amount", "must be greater than zero")
+}
+
+// RefundPayment gives back part or all of the captured amount, once everything is refunded the order is marked as
+// refunded. The refund is claimed while the order is locked, then the provider is called outside of any transaction so
+// no lock is held while waiting for it. A refund left pending by a failed call is completed by refunding the same
+// amount again, the provider being asked with the same idempotency key.
+func (svc *PaymentService) RefundPayment(ctx context.Context, orderId string, actorUserId *string, input *RefundPaymentInput) (*model.PaymentRecord, error) {
+	payment, refund, err := svc.claimOrderPaymentRefund(ctx, orderId, input.Amount)
+
+	if err != nil {
+		return nil, err
+	}
+
+	err = svc.refundProviderPayment(ctx, payment, refund)
+
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := svc.db.BeginTx(ctx, nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer tx.Rollback()
+
+	order, err := svc.models.OrderModel.FindByIdForUpdate(ctx, tx, orderId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	payment, err = svc.models.PaymentModel.FindByIdForUpdate(ctx, tx, refund.PaymentId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	payment, err = svc.settlePaymentRefund(ctx, tx, order, payment, refund.Id, actorUserId, input.Note)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return payment, nil
+}
+
+// claimOrderPaymentRefund locks the order and claims the refund of the amount from its payment. A pending refund of the
+// payment itself, not of a return, is returned instead to be retried when it's of the same amount.
+func (svc *PaymentService) claimOrderPaymentRefund(ctx context.Context, orderId string, amount money.Decimal) (*model.PaymentRecord, *model.PaymentRefundRecord, error) {
+	tx, err := svc.db.BeginTx(ctx, nil)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	defer tx.Rollback()
+
+	order, err := svc.models.OrderModel.FindByIdForUpdate(ctx, tx, orderId)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if !amount.FitsCurrency(order.CurrencyCode) {
+		return nil, nil, ErrRefundAmountDecimals
+	}
+
+	refundAmount := amount.Money(order.CurrencyCode)
+
+	payment, err := svc.models.PaymentModel.FindActiveByOrderIdForUpdate(ctx, tx, order.Id)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	pendingRefunds, err := svc.models.PaymentRefundModel.FindAllPendingByPaymentId(ctx, tx, payment.Id)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	for _, refund := range pendingRefunds {
+		if refund.ReturnId != nil {
+			continue
+		}
+
+		if refund.Amount.Cmp(refundAmount) != 0 {
+			return nil, nil, ErrPaymentRefundInProgress
+		}
+
+		return payment, refund, nil
+	}
+
+	refund, err := svc.claimPaymentRefund(ctx, tx, payment, nil, refundAmount)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, nil, err
+	}
+
+	return payment, refund, nil
+}
+
+// checkPaymentRefund returns an error when the amount can't be refunded from the payment
+func checkPaymentRefund(payment *model.PaymentRecord, amount money.Money) error {
+	if payment.Status != consts.PaymentStatusCaptured && payment.Status != consts.PaymentStatusPartiallyRefunded {
+		return ErrInvalidPaymentOperation
+	}
+
+	if payment.AmountCaptured.Sub(payment.AmountRefunded).LessThan(amount) {
+		return ErrInvalidRefundAmount
+	}
+
+	return nil
+}
+
+// claimPaymentRefund records a pending refund of the amount on the locked payment, once checked that the payment covers
+// it next to the refunds the provider didn't make yet. The provider is asked for the refund with its id as idempotency key.
+func (svc *PaymentService) claimPaymentRefund(ctx context.Context, tx *sql.Tx, payment *model.PaymentRecord, returnId *string, amount money.Money) (*model.PaymentRefundRecord, error) {
+	pendingRefunds, err := svc.models.PaymentRefundModel.FindAllPendingByPaymentId(ctx, tx, payment.Id)
+
+	if err != nil {
+		return nil, err
+	}
+
+	pendingAmount := money.Zero(payment.CurrencyCode)
+
+	for _, refund := range pendingRefunds {
+		pendingAmount = pendingAmount.Add(refund.Amount)
+	}
+
+	err = checkPaymentRefund(payment, amount.Add(pendingAmount))
+
+	if err != nil {
+		return nil, err
+	}
+
+	return svc.models.PaymentRefundModel.Insert(ctx, tx, &model.PaymentRefundRecord{PaymentId: payment.Id, ReturnId: returnId, Amount: amount, CurrencyCode: payment.CurrencyCode})
+}
+
+// refundProviderPayment has the provider of the payment make a pending refund, nothing is stored. Asking again for a
+// refund the provider already made doesn't refund twice since the id of the refund is the idempotency key.
+func (svc *PaymentService) refundProviderPayment(ctx context.Context, payment *model.PaymentRecord, refund *model.PaymentRefundRecord) error {
+	provider, err := svc.getProvider(payment.ProviderId)
+
+	if err != nil {
+		return err
+	}
+
+	result, err := provider.Refund(ctx, payment.ProviderReference, refund.Amount, refund.Id)
+
+	if err != nil {
+		return fmt.Errorf("payment provider %s failed to refund: %w", provider.Id(), err)
+	}
+
+	if result.Status != consts.PaymentStatusRefunded {
+		return fmt.Errorf("payment provider %s didn't refund the payment, status %s", provider.Id(), result.Status)
+	}
+
+	return nil
+}
+
+// settlePaymentRefund marks a refund the provider made as succeeded and adds it to the locked payment of the locked
+// order, a refund already settled by a concurrent call is left as is
+func (svc *PaymentService) settlePaymentRefund(ctx context.Context, tx *sql.Tx, order *model.OrderRecord, payment *model.PaymentRecord, refundId string, actorUserId *string, note *string) (*model.PaymentRecord, error) {
+	refund, err := svc.models.PaymentRefundModel.FindByIdForUpdate(ctx, tx, refundId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if refund.Status != consts.PaymentRefundStatusPending {
+		return payment, nil
+	}
+
+	refund.Status = consts.PaymentRefundStatusSucceeded
+
+	_, err = svc.models.PaymentRefundModel.Update(ctx, tx, refund)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return svc.recordPaymentRefund(ctx, tx, order, payment, actorUserId, refund.Amount, note)
+}
+
+// recordPaymentRefund adds an amount the provider refunded to the locked payment of the locked order, the order is
+// marked as refunded once the whole payment is
+func (svc *PaymentService) recordPaymentRefund(ctx context.Context, tx *sql.Tx, order *model.OrderRecord, payment *model.PaymentRecord, actorUserId *string, amount money.Money, note *string) (*model.PaymentRecord, error) {
+	payment.AmountRefunded = payment.AmountRefunded.Add(amount)
+
+	if !payment.AmountRefunded.LessThan(payment.AmountCaptured) {
+		payment.Status = consts.PaymentStatusRefunded
+	} else {
+		payment.Status = consts.PaymentStatusPartiallyRefunded
+	}
+
+	payment, err := svc.models.PaymentModel.Update(ctx, tx, payment)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if payment.Status == consts.PaymentStatusRefunded && CanTransitionOrder(order.Status, consts.OrderStatusRefunded) {
+		_, err := svc.orderSvc.transitionOrder(ctx, tx, order, consts.OrderStatusRefunded, actorUserId, note)
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return payment, nil
+}
+
+// VoidPayment cancels the authorization of a payment that wasn't captured yet
+func (svc *PaymentService) VoidPayment(ctx context.Context, orderId string) (*model.PaymentRecord, error) {
+	tx, err := svc.db.BeginTx(ctx, nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer tx.Rollback()
+
+	payment, err := svc.models.PaymentModel.FindActiveByOrderIdForUpdate(ctx, tx, orderId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if payment.Status != consts.PaymentStatusAuthorized {
+		return nil, ErrInvalidPaymentOperation
+	}
+
+	provider, err := svc.getProvider(payment.ProviderId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := provider.Void(ctx, payment.ProviderReference)
+
+	if err != nil {
+		return nil, fmt.Errorf("payment provider %s failed to void: %w", provider.Id(), err)
+	}
+
+	if result.Status != consts.PaymentStatusVoided {
+		return nil, fmt.Errorf("payment provider %s didn't void the payment, status %s", provider.Id(), result.Status)
+	}
+
+	now := time.Now()
+	payment.Status = consts.PaymentStatusVoided
+	payment.VoidedAt = &now
+
+	payment, err = svc.models.PaymentModel.Update(ctx, tx, payment)
+
+	if err != nil {
+		return nil, err
+	}
+
+	// the session of the payment is closed so the order can be paid again
+	session, err := svc.models.PaymentSessionModel.FindByProviderReferenceForUpdate(ctx, tx, payment.ProviderId, payment.ProviderReference)
+
+	if err != nil {
+		return nil, err
+	}
+
+	session.Status = consts.PaymentSessionStatusVoided
+
+	_, err = svc.models.PaymentSessionModel.Update(ctx, tx, session)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return payment, nil
+}
+
+type OrderPaymentsDTO struct {
+	Sessions []*model.PaymentSessionRecord `json:"sessions"`
+	Payments []*model.PaymentRecord        `json:"payments"`
+}
+
+func (svc *PaymentService) ListOrderPayments(ctx context.Context, orderId string) (*OrderPaymentsDTO, error) {
+	_, err := svc.models.OrderModel.FindById(ctx, svc.db, orderId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	sessions, err := svc.models.PaymentSessionModel.FindAllByOrderId(ctx, svc.db, orderId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	payments, err := svc.models.PaymentModel.FindAllByOrderId(ctx, svc.db, orderId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &OrderPaymentsDTO{Sessions: sessions, Payments: payments}, nil
+}
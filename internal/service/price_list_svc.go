@@ -0,0 +1,359 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"ecom-backend/internal/consts"
+	"ecom-backend/internal/model"
+	"ecom-backend/internal/money"
+	"ecom-backend/internal/validator"
+	"ecom-backend/pkg/sqldb"
+	"errors"
+	"strings"
+	"time"
+)
+
+var ErrPriceListWithoutCustomerGroups = errors.New("a wholesale or customer group price list needs at least one customer group")
+
+type PriceListService struct {
+	db     *sql.DB
+	models *model.Models
+}
+
+func NewPriceListService(db *sql.DB, models *model.Models) *PriceListService {
+	return &PriceListService{db: db, models: models}
+}
+
+type PriceListDTO struct {
+	*model.PriceListRecord
+	Prices           []*model.PriceListPriceRecord `json:"prices"`
+	CustomerGroupIds []string                      `json:"customer_group_ids"` // the list only applies to their members, to everyone when empty
+}
+
+type PriceListPriceInput struct {
+	VariantId    string        `json:"variant_id"`
+	CurrencyCode string        `json:"currency_code"`
+	Amount       money.Decimal `json:"amount"`
+}
+
+func validatePriceListPrices(v *validator.Validator, prices []PriceListPriceInput) {
+	keys := []string{}
+
+	for _, price := range prices {
+		v.Check(validator.IsValidUUID(price.VariantId), "prices.variant_id", "must be a valid UUID")
+		v.Check(price.CurrencyCode != "", "prices.currency_code", "must be provided")
+		v.Check(price.Amount.Sign() >= 0, "prices.amount", "should not be negative")
+		validateDecimals(v, price.Amount, price.CurrencyCode, "prices.amount")
+		keys = append(keys, price.VariantId+"-"+strings.ToLower(price.CurrencyCode))
+	}
+
+	v.Check(validator.Unique(keys), "prices", "must not contain the same variant twice for a currency")
+}
+
+func validateCustomerGroupIds(v *validator.Validator, groupIds []string) {
+	for _, groupId := range groupIds {
+		v.Check(validator.IsValidUUID(groupId), "customer_group_ids", "must contain valid UUIDs")
+	}
+
+	v.Check(validator.Unique(groupIds), "customer_group_ids", "must not contain the same group twice")
+}
+
+// requiresCustomerGroups tells whether the lists of the type only apply to the members of their groups
+func requiresCustomerGroups(priceListType string) bool {
+	return priceListType == consts.PriceListTypeWholesale || priceListType == consts.PriceListTypeCustomerGroup
+}
+
+type CreatePriceListInput struct {
+	Name        string                `json:"name"`
+	Description *string               `json:"description"`
+	Type        string                `json:"type"`
+	Priority    int                   `json:"priority"`
+	StartsAt    *time.Time            `json:"starts_at"` // optional, the list applies right away when missing
+	EndsAt      *time.Time            `json:"ends_at"`   // optional, the list never ends when missing
+	IsActive    *bool                 `json:"is_active"` // optional, active by default
+	Prices      []PriceListPriceInput `json:"prices"`
+	// CustomerGroupIds restrict the list to the members of the groups, required for wholesale and customer_group lists
+	CustomerGroupIds []string `json:"customer_group_ids"`
+}
+
+func (input *CreatePriceListInput) Validate(v *validator.Validator) {
+	v.Check(input.Name != "", "name", "must be provided")
+	v.Check(validator.In(input.Type, consts.PriceListTypeSale, consts.PriceListTypeWholesale, consts.PriceListTypeCustomerGroup), "type", "invalid price list type")
+
+	if input.StartsAt != nil && input.EndsAt != nil {
+		v.Check(input.EndsAt.After(*input.StartsAt), "ends_at", "must be after starts_at")
+	}
+
+	validatePriceListPrices(v, input.Prices)
+	validateCustomerGroupIds(v, input.CustomerGroupIds)
+
+	if requiresCustomerGroups(input.Type) {
+		v.Check(len(input.CustomerGroupIds) > 0, "customer_group_ids", ErrPriceListWithoutCustomerGroups.Error())
+	}
+}
+
+// the type of a list can't change, a new list has to be created instead
+type UpdatePriceListInput struct {
+	Name             *string                `json:"name"`
+	Description      *string                `json:"description"`
+	Priority         *int                   `json:"priority"`
+	StartsAt         *time.Time             `json:"starts_at"`
+	EndsAt           *time.Time             `json:"ends_at"`
+	IsActive         *bool                  `json:"is_active"`
+	Prices           *[]PriceListPriceInput `json:"prices"`
+	CustomerGroupIds *[]string              `json:"customer_group_ids"`
+}
+
+func (input *UpdatePriceListInput) Validate(v *validator.Validator) {
+	if input.Name != nil {
+		v.Check(*input.Name != "", "name", "must not be empty")
+	}
+
+	if input.StartsAt != nil && input.EndsAt != nil {
+		v.Check(input.EndsAt.After(*input.StartsAt), "ends_at", "must be after starts_at")
+	}
+
+	if input.Prices != nil {
+		validatePriceListPrices(v, *input.Prices)
+	}
+
+	if input.CustomerGroupIds != nil {
+		validateCustomerGroupIds(v, *input.CustomerGroupIds)
+	}
+}
+
+func (svc *PriceListService) CreatePriceList(ctx context.Context, input *CreatePriceListInput) (*PriceListDTO, error) {
+	tx, err := svc.db.BeginTx(ctx, nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer tx.Rollback()
+
+	priceList := &model.PriceListRecord{
+		Name:        input.Name,
+		Description: input.Description,
+		Type:        input.Type,
+		Priority:    input.Priority,
+		StartsAt:    input.StartsAt,
+		EndsAt:      input.EndsAt,
+		IsActive:    true,
+	}
+
+	if input.IsActive != nil {
+		priceList.IsActive = *input.IsActive
+	}
+
+	priceList, err = svc.models.PriceListModel.Insert(ctx, tx, priceList)
+
+	if err != nil {
+		return nil, err
+	}
+
+	err = svc.replacePriceListPrices(ctx, tx, priceList.Id, input.Prices)
+
+	if err != nil {
+		return nil, err
+	}
+
+	err = svc.models.PriceListModel.ReplaceCustomerGroups(ctx, tx, priceList.Id, input.CustomerGroupIds)
+
+	if err != nil {
+		return nil, err
+	}
+
+	dto, err := svc.buildPriceListDTO(ctx, tx, priceList)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return dto, nil
+}
+
+// replacePriceListPrices swaps the prices of the list, nil leaves them untouched
+func (svc *PriceListService) replacePriceListPrices(ctx context.Context, conn sqldb.Connection, priceListId string, prices []PriceListPriceInput) error {
+	if prices == nil {
+		return nil
+	}
+
+	err := svc.models.PriceListPriceModel.DeleteAllByPriceListId(ctx, conn, priceListId)
+
+	if err != nil {
+		return err
+	}
+
+	for _, price := range prices {
+		_, err := svc.models.PriceListPriceModel.Insert(ctx, conn, &model.PriceListPriceRecord{
+			PriceListId:  priceListId,
+			VariantId:    price.VariantId,
+			CurrencyCode: strings.ToLower(price.CurrencyCode),
+			Amount:       price.Amount.Money(price.CurrencyCode),
+		})
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (svc *PriceListService) buildPriceListDTO(ctx context.Context, conn sqldb.Connection, priceList *model.PriceListRecord) (*PriceListDTO, error) {
+	dtos, err := svc.buildPriceListDTOs(ctx, conn, []*model.PriceListRecord{priceList})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return dtos[0], nil
+}
+
+func (svc *PriceListService) buildPriceListDTOs(ctx context.Context, conn sqldb.Connection, priceLists []*model.PriceListRecord) ([]*PriceListDTO, error) {
+	priceListIds := []string{}
+
+	for _, priceList := range priceLists {
+		priceListIds = append(priceListIds, priceList.Id)
+	}
+
+	pricesMap, err := svc.models.PriceListPriceModel.FindAllByPriceListIds(ctx, conn, priceListIds)
+
+	if err != nil {
+		return nil, err
+	}
+
+	groupIdsMap, err := svc.models.PriceListModel.FindCustomerGroupIds(ctx, conn, priceListIds)
+
+	if err != nil {
+		return nil, err
+	}
+
+	dtos := []*PriceListDTO{}
+
+	for _, priceList := range priceLists {
+		dto := &PriceListDTO{PriceListRecord: priceList, Prices: pricesMap[priceList.Id], CustomerGroupIds: groupIdsMap[priceList.Id]}
+
+		if dto.Prices == nil {
+			dto.Prices = []*model.PriceListPriceRecord{}
+		}
+
+		if dto.CustomerGroupIds == nil {
+			dto.CustomerGroupIds = []string{}
+		}
+
+		dtos = append(dtos, dto)
+	}
+
+	return dtos, nil
+}
+
+func (svc *PriceListService) ListPriceLists(ctx context.Context) ([]*PriceListDTO, error) {
+	priceLists, err := svc.models.PriceListModel.FindAll(ctx, svc.db)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return svc.buildPriceListDTOs(ctx, svc.db, priceLists)
+}
+
+func (svc *PriceListService) GetPriceList(ctx context.Context, id string) (*PriceListDTO, error) {
+	priceList, err := svc.models.PriceListModel.FindById(ctx, svc.db, id)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return svc.buildPriceListDTO(ctx, svc.db, priceList)
+}
+
+func (svc *PriceListService) UpdatePriceList(ctx context.Context, id string, input *UpdatePriceListInput) (*PriceListDTO, error) {
+	tx, err := svc.db.BeginTx(ctx, nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer tx.Rollback()
+
+	priceList, err := svc.models.PriceListModel.FindById(ctx, tx, id)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if input.Name != nil {
+		priceList.Name = *input.Name
+	}
+
+	if input.Description != nil {
+		priceList.Description = input.Description
+	}
+
+	if input.Priority != nil {
+		priceList.Priority = *input.Priority
+	}
+
+	if input.StartsAt != nil {
+		priceList.StartsAt = input.StartsAt
+	}
+
+	if input.EndsAt != nil {
+		priceList.EndsAt = input.EndsAt
+	}
+
+	if input.IsActive != nil {
+		priceList.IsActive = *input.IsActive
+	}
+
+	priceList, err = svc.models.PriceListModel.Update(ctx, tx, priceList)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var prices []PriceListPriceInput
+
+	if input.Prices != nil {
+		prices = *input.Prices
+	}
+
+	err = svc.replacePriceListPrices(ctx, tx, priceList.Id, prices)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if input.CustomerGroupIds != nil {
+		if requiresCustomerGroups(priceList.Type) && len(*input.CustomerGroupIds) == 0 {
+			return nil, ErrPriceListWithoutCustomerGroups
+		}
+
+		err = svc.models.PriceListModel.ReplaceCustomerGroups(ctx, tx, priceList.Id, *input.CustomerGroupIds)
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	dto, err := svc.buildPriceListDTO(ctx, tx, priceList)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return dto, nil
+}
+
+func (svc *PriceListService) DeletePriceList(ctx context.Context, id string) error {
+	return svc.models.PriceListModel.Delete(ctx, svc.db, id)
+}
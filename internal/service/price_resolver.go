@@ -0,0 +1,250 @@
+package service
+
+import (
+	"context"
+	"ecom-backend/internal/model"
+	"ecom-backend/internal/money"
+	"ecom-backend/pkg/sqldb"
+	"sort"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// PriceContext describes the purchase the prices are resolved for, it decides which price lists apply
+type PriceContext struct {
+	CurrencyCode *string        // every currency is resolved when nil
+	At           time.Time      // the price lists are applied as they are at this time
+	Quantities   map[string]int // quantity bought of the variants, picks the quantity tier of their price. 1 when missing.
+	// CustomerGroupIds are the groups of the customer, the price lists restricted to other groups don't apply
+	CustomerGroupIds []string
+}
+
+// ResolvedPrice is the price a variant is sold at in a currency
+type ResolvedPrice struct {
+	*model.MoneyAmountRecord                             // the base price of the variant, the tier of the quantity bought
+	ListPrice                *model.PriceListPriceRecord // the price list price overriding the base one, nil when no list applies
+	Tiers                    []*model.MoneyAmountRecord  // every quantity tier of the variant in the currency
+	IsConverted              bool                        // the base price is converted from the base currency, the variant has no price in the currency
+}
+
+// EffectiveAmount returns the amount the customer pays
+func (price *ResolvedPrice) EffectiveAmount() money.Money {
+	if price.ListPrice != nil {
+		return price.ListPrice.Amount
+	}
+
+	return price.Amount
+}
+
+// PriceResolver works out the price every variant is sold at. The catalog, the cart and the checkout all go
+// through it so a customer always sees the price they'll be charged.
+type PriceResolver struct {
+	models           *model.Models
+	baseCurrencyCode string // the prices missing in a currency are converted from the ones in this currency
+}
+
+func NewPriceResolver(models *model.Models, baseCurrencyCode string) *PriceResolver {
+	return &PriceResolver{models: models, baseCurrencyCode: baseCurrencyCode}
+}
+
+// Resolve returns the prices of the variants by variant id. The base price is the tier of the quantity bought, a price
+// list price overrides it when it's lower so a sale never makes a volume price more expensive. A variant without a base
+// price in a currency is sold at its base currency price converted at the exchange rate, it isn't sold in the currency
+// when there's no rate even when a list has a price for it.
+func (resolver *PriceResolver) Resolve(ctx context.Context, conn sqldb.Connection, priceCtx PriceContext, variantIds []string) (map[string][]*ResolvedPrice, error) {
+	var currencyCodes []string
+
+	if priceCtx.CurrencyCode != nil {
+		currencyCodes = []string{*priceCtx.CurrencyCode, resolver.baseCurrencyCode}
+	}
+
+	basePricesMap, err := resolver.models.MoneyAmountModel.FindAllByVariantIds(ctx, conn, variantIds, currencyCodes)
+
+	if err != nil {
+		return nil, err
+	}
+
+	rates, err := resolver.models.ExchangeRateModel.FindAllByBaseCurrency(ctx, conn, resolver.baseCurrencyCode)
+
+	if err != nil {
+		return nil, err
+	}
+
+	listPrices, err := resolver.models.PriceListPriceModel.FindActiveByVariantIds(ctx, conn, variantIds, priceCtx.At, priceCtx.CustomerGroupIds)
+
+	if err != nil {
+		return nil, err
+	}
+
+	// the list prices come sorted, the first one of every variant and currency wins
+	listPricesMap := map[string]map[string]*model.PriceListPriceRecord{}
+
+	for _, listPrice := range listPrices {
+		if listPricesMap[listPrice.VariantId] == nil {
+			listPricesMap[listPrice.VariantId] = map[string]*model.PriceListPriceRecord{}
+		}
+
+		if _, ok := listPricesMap[listPrice.VariantId][listPrice.CurrencyCode]; !ok {
+			listPricesMap[listPrice.VariantId][listPrice.CurrencyCode] = listPrice
+		}
+	}
+
+	resultMap := map[string][]*ResolvedPrice{}
+
+	for variantId, basePrices := range basePricesMap {
+		quantity := max(priceCtx.Quantities[variantId], 1)
+
+		// the base prices come sorted by currency, the tiers of a currency follow each other
+		prices := []*ResolvedPrice{}
+
+		for _, basePrice := range basePrices {
+			if len(prices) == 0 || prices[len(prices)-1].Tiers[0].CurrencyCode != basePrice.CurrencyCode {
+				prices = append(prices, &ResolvedPrice{})
+			}
+
+			prices[len(prices)-1].Tiers = append(prices[len(prices)-1].Tiers, basePrice)
+		}
+
+		resultMap[variantId] = []*ResolvedPrice{}
+
+		for _, price := range resolver.convertPrices(prices, rates, priceCtx.CurrencyCode) {
+			for _, tier := range price.Tiers {
+				if tier.CoversQuantity(quantity) {
+					price.MoneyAmountRecord = tier
+				}
+			}
+
+			// the variant isn't sold in this quantity in the currency
+			if price.MoneyAmountRecord == nil {
+				continue
+			}
+
+			if listPrice, ok := listPricesMap[variantId][price.CurrencyCode]; ok && listPrice.Amount.LessThan(price.Amount) {
+				price.ListPrice = listPrice
+			}
+
+			resultMap[variantId] = append(resultMap[variantId], price)
+		}
+	}
+
+	return resultMap, nil
+}
+
+// convertPrices adds the prices converted from the base currency in the currencies the variant has no price in and
+// that have an exchange rate, only in the currency of the context when there's one. The base currency prices fetched
+// for the conversion are dropped when they weren't asked for. The prices stay sorted by currency.
+func (resolver *PriceResolver) convertPrices(prices []*ResolvedPrice, rates map[string]*model.ExchangeRateRecord, currencyCode *string) []*ResolvedPrice {
+	var basePrice *ResolvedPrice
+
+	result := []*ResolvedPrice{}
+	explicitCurrencies := map[string]bool{}
+
+	for _, price := range prices {
+		priceCurrencyCode := price.Tiers[0].CurrencyCode
+		explicitCurrencies[priceCurrencyCode] = true
+
+		if priceCurrencyCode == resolver.baseCurrencyCode {
+			basePrice = price
+		}
+
+		if currencyCode == nil || priceCurrencyCode == *currencyCode {
+			result = append(result, price)
+		}
+	}
+
+	if basePrice == nil {
+		return result
+	}
+
+	for quoteCurrencyCode, rate := range rates {
+		if explicitCurrencies[quoteCurrencyCode] || (currencyCode != nil && quoteCurrencyCode != *currencyCode) {
+			continue
+		}
+
+		converted := &ResolvedPrice{IsConverted: true}
+
+		// the converted tiers keep the id of the base currency tier they come from
+		for _, tier := range basePrice.Tiers {
+			convertedTier := *tier
+			convertedTier.CurrencyCode = quoteCurrencyCode
+			convertedTier.Amount = rate.Convert(tier.Amount)
+
+			converted.Tiers = append(converted.Tiers, &convertedTier)
+		}
+
+		result = append(result, converted)
+	}
+
+	sort.Slice(result, func(i, j int) bool { return result[i].Tiers[0].CurrencyCode < result[j].Tiers[0].CurrencyCode })
+
+	return result
+}
+
+// resolveVariantPrices returns the price of every variant in the currency of the context, the variants without one are left out
+func (resolver *PriceResolver) resolveVariantPrices(ctx context.Context, conn sqldb.Connection, priceCtx PriceContext, variantIds []string) (map[string]*ResolvedPrice, error) {
+	pricesMap, err := resolver.Resolve(ctx, conn, priceCtx, variantIds)
+
+	if err != nil {
+		return nil, err
+	}
+
+	resultMap := map[string]*ResolvedPrice{}
+
+	for variantId, prices := range pricesMap {
+		if len(prices) > 0 {
+			resultMap[variantId] = prices[0]
+		}
+	}
+
+	return resultMap, nil
+}
+
+// unitPriceExpression returns the SQL of the price one unit of the variant aliased pv is sold at in the currency of the
+// context, in minor units, worked out like Resolve does: the base price or, when the variant has no price in the
+// currency, the base currency price converted at the exchange rate, lowered by the first price list that applies like
+// in PriceListPriceModel.FindActiveByVariantIds. It's NULL when the variant isn't sold in the currency.
+func (resolver *PriceResolver) unitPriceExpression(args *queryArgs, priceCtx PriceContext) string {
+	// the parameters are cast since they're compared to columns of different types
+	currencyCode := args.add(*priceCtx.CurrencyCode) + `::text`
+	baseCurrencyCode := args.add(resolver.baseCurrencyCode) + `::text`
+	exponentShift := args.add(money.Exponent(*priceCtx.CurrencyCode)-money.Exponent(resolver.baseCurrencyCode)) + `::int`
+	at := args.add(priceCtx.At) + `::timestamp`
+	customerGroupIds := args.add(pq.Array(priceCtx.CustomerGroupIds)) + `::uuid[]`
+
+	// the last tier covering one unit wins like in Resolve
+	tier := func(currencyCode string) string {
+		return `SELECT ma.amount FROM product_variant_money_amount AS pvma
+			INNER JOIN money_amount AS ma ON ma.id = pvma.money_amount_id
+			WHERE pvma.variant_id = pv.id AND ma.currency_code = ` + currencyCode + `
+			AND ma.min_quantity <= 1 AND (ma.max_quantity IS NULL OR ma.max_quantity >= 1)
+			ORDER BY ma.min_quantity DESC LIMIT 1`
+	}
+
+	// the conversion rounds like money.Money Convert then RoundUp
+	converted := `SELECT CASE WHEN er.rounding_increment <= 1 THEN c.amount
+				ELSE ceil((c.amount - er.rounding_ending)::numeric / er.rounding_increment)::bigint * er.rounding_increment + er.rounding_ending END
+			FROM (` + tier(baseCurrencyCode) + `) AS b
+			INNER JOIN exchange_rate AS er ON er.base_currency_code = ` + baseCurrencyCode + ` AND er.quote_currency_code = ` + currencyCode + `
+			CROSS JOIN LATERAL (SELECT round(b.amount * er.rate * power(10::numeric, ` + exponentShift + `))::bigint AS amount) AS c
+			WHERE NOT EXISTS (
+				SELECT 1 FROM product_variant_money_amount AS pvma
+				INNER JOIN money_amount AS ma ON ma.id = pvma.money_amount_id
+				WHERE pvma.variant_id = pv.id AND ma.currency_code = ` + currencyCode + `
+			)`
+
+	listPrice := `SELECT plp.amount FROM price_list_price AS plp
+			INNER JOIN price_list AS pl ON pl.id = plp.price_list_id
+			WHERE plp.variant_id = pv.id AND plp.currency_code = ` + currencyCode + ` AND pl.is_active
+			AND (pl.starts_at IS NULL OR pl.starts_at <= ` + at + `) AND (pl.ends_at IS NULL OR pl.ends_at > ` + at + `)
+			AND ((pl.type = 'sale' AND NOT EXISTS (SELECT 1 FROM price_list_customer_group AS plcg WHERE plcg.price_list_id = pl.id))
+				OR EXISTS (SELECT 1 FROM price_list_customer_group AS plcg WHERE plcg.price_list_id = pl.id AND plcg.customer_group_id = ANY(` + customerGroupIds + `)))
+			ORDER BY pl.priority DESC, plp.amount LIMIT 1`
+
+	// LEAST skips NULLs, the list price only lowers a base price that exists
+	return `(
+		SELECT LEAST(base.amount, (` + listPrice + `))
+		FROM (SELECT COALESCE((` + tier(currencyCode) + `), (` + converted + `)) AS amount) AS base
+		WHERE base.amount IS NOT NULL
+	)`
+}
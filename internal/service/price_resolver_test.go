@@ -0,0 +1,139 @@
+package service
+
+import (
+	"ecom-backend/internal/model"
+	"ecom-backend/internal/money"
+	"math/big"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+func TestUnitPriceExpressionArgs(t *testing.T) {
+	money.SetExponents(map[string]int{"usd": 2, "eur": 2, "jpy": 0, "bhd": 3})
+	t.Cleanup(func() { money.SetExponents(nil) })
+
+	at := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	groupIds := []string{"6f1c2a52-3a8e-4d8b-9d43-5b0f3f6f7e1a"}
+
+	tests := []struct {
+		currency string
+		shift    int
+	}{
+		{"eur", 0},
+		{"jpy", -2},
+		{"bhd", 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.currency, func(t *testing.T) {
+			// an arg already bound by the rest of the query
+			args := queryArgs{"search"}
+			currency := tt.currency
+
+			sql := NewPriceResolver(nil, "usd").unitPriceExpression(&args, PriceContext{CurrencyCode: &currency, At: at, CustomerGroupIds: groupIds})
+
+			want := queryArgs{"search", tt.currency, "usd", tt.shift, at, pq.Array(groupIds)}
+
+			if !reflect.DeepEqual(args, want) {
+				t.Errorf("got args %#v, want %#v", args, want)
+			}
+
+			// the conversion has to round like money.Money Convert then RoundUp, which sqlConvert below checks
+			for _, fragment := range []string{
+				`round(b.amount * er.rate * power(10::numeric, $4::int))::bigint`,
+				`WHEN er.rounding_increment <= 1 THEN c.amount`,
+				`ceil((c.amount - er.rounding_ending)::numeric / er.rounding_increment)::bigint * er.rounding_increment + er.rounding_ending`,
+			} {
+				if !strings.Contains(sql, fragment) {
+					t.Errorf("the expression is missing %s", fragment)
+				}
+			}
+		})
+	}
+}
+
+// sqlConvert works out the converted price the way the numeric arithmetic of unitPriceExpression does, rate is the
+// DECIMAL(18,8) as the database stores it
+func sqlConvert(t *testing.T, amount int64, rate string, shift int, increment, ending int64) int64 {
+	t.Helper()
+
+	product, ok := new(big.Rat).SetString(rate)
+
+	if !ok {
+		t.Fatalf("invalid rate %s", rate)
+	}
+
+	product.Mul(product, new(big.Rat).SetInt64(amount))
+	power := new(big.Rat).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(max(shift, -shift))), nil))
+
+	if shift >= 0 {
+		product.Mul(product, power)
+	} else {
+		product.Quo(product, power)
+	}
+
+	// round: halves away from zero
+	half := new(big.Rat).SetFrac64(1, 2)
+
+	if product.Sign() < 0 {
+		half.Neg(half)
+	}
+
+	product.Add(product, half)
+	converted := new(big.Int).Quo(product.Num(), product.Denom()).Int64()
+
+	if increment <= 1 {
+		return converted
+	}
+
+	// ceil
+	steps := new(big.Int).Sub(big.NewInt(converted), big.NewInt(ending))
+	steps.Add(steps, big.NewInt(increment-1))
+	steps.Div(steps, big.NewInt(increment))
+
+	return steps.Int64()*increment + ending
+}
+
+func TestUnitPriceExpressionConvertsLikeExchangeRateRecord(t *testing.T) {
+	money.SetExponents(map[string]int{"usd": 2, "eur": 2, "jpy": 0, "bhd": 3})
+	t.Cleanup(func() { money.SetExponents(nil) })
+
+	rates := []string{"0.58000000", "1.14000000", "2.05000000", "0.92000000", "0.00673125", "150.50000000", "1.23456789", "0.37600000"}
+	roundings := [][2]int64{{0, 0}, {1, 0}, {5, 0}, {50, 0}, {100, 99}}
+
+	for _, quote := range []string{"eur", "jpy", "bhd"} {
+		shift := money.Exponent(quote) - money.Exponent("usd")
+
+		for _, rate := range rates {
+			// the driver hands the DECIMAL over as text, parsed into the record's float
+			parsed, err := strconv.ParseFloat(rate, 64)
+
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for _, rounding := range roundings {
+				record := &model.ExchangeRateRecord{
+					BaseCurrencyCode:  "usd",
+					QuoteCurrencyCode: quote,
+					Rate:              parsed,
+					RoundingIncrement: money.New(rounding[0], quote),
+					RoundingEnding:    money.New(rounding[1], quote),
+				}
+
+				for amount := int64(1); amount <= 2000; amount++ {
+					want := sqlConvert(t, amount, rate, shift, rounding[0], rounding[1])
+
+					if got := record.Convert(money.New(amount, "usd")); got.Amount != want {
+						t.Fatalf("%d usd at %s to %s rounded %v: got %d, the database computes %d", amount, rate, quote, rounding, got.Amount, want)
+					}
+				}
+			}
+		}
+	}
+}
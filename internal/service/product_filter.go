@@ -0,0 +1,423 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"ecom-backend/internal/money"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// queryArgs collects the parameters of a query and numbers their placeholders as they're added
+type queryArgs []any
+
+func (args *queryArgs) add(value any) string {
+	*args = append(*args, value)
+
+	return "$" + strconv.Itoa(len(*args))
+}
+
+// the dimensions the product listing is filtered on
+const (
+	productDimensionSearch   = "search"
+	productDimensionCategory = "category"
+	productDimensionPrice    = "price"
+	productDimensionStock    = "stock"
+	productDimensionStatus   = "status"
+)
+
+// optionDimension returns the dimension of an option, the options are filtered and counted each on their own
+func optionDimension(title string) string {
+	return "option:" + title
+}
+
+type productCondition struct {
+	dimension string
+	build     func(args *queryArgs) string
+}
+
+// productFilter holds the conditions the listed products, aliased p, must meet. The conditions are grouped by
+// dimension so the facet of a dimension is counted without its own condition: picking a color doesn't hide the other
+// colors.
+type productFilter struct {
+	conditions []productCondition
+	at         time.Time // the reservations still active at that time hold stock
+	// unitPrice returns the price one unit of the variant aliased pv is sold at to the customer in the price currency
+	unitPrice func(args *queryArgs) string
+}
+
+func newProductFilter(opt ProductListingOptions, at time.Time, priceResolver *PriceResolver) *productFilter {
+	filter := &productFilter{at: at}
+
+	filter.unitPrice = func(args *queryArgs) string {
+		return priceResolver.unitPriceExpression(args, opt.unitPriceContext(at))
+	}
+
+	if opt.Query != nil {
+		filter.add(productDimensionSearch, func(args *queryArgs) string {
+			return `p.search_vector @@ websearch_to_tsquery('english', ` + args.add(*opt.Query) + `)`
+		})
+	}
+
+	if opt.CategoryId != nil {
+		filter.add(productDimensionCategory, func(args *queryArgs) string {
+			return `p.id IN (
+				SELECT pcp.product_id FROM product_category_product AS pcp WHERE pcp.category_id IN (
+					WITH RECURSIVE tree AS (
+						SELECT id FROM product_category WHERE id = ` + args.add(*opt.CategoryId) + ` AND deleted_at IS NULL
+						UNION
+						SELECT c.id FROM product_category AS c INNER JOIN tree ON c.parent_id = tree.id WHERE c.deleted_at IS NULL
+					)
+					SELECT id FROM tree
+				)
+			)`
+		})
+	}
+
+	for _, title := range sortedOptionTitles(opt.OptionValues) {
+		values := opt.OptionValues[title]
+
+		filter.add(optionDimension(title), func(args *queryArgs) string {
+			return `EXISTS (
+				SELECT 1 FROM product_variant AS pv
+				INNER JOIN product_option_value AS pov ON pov.variant_id = pv.id AND pov.deleted_at IS NULL
+				INNER JOIN product_option AS po ON po.id = pov.option_id AND po.deleted_at IS NULL
+				WHERE pv.product_id = p.id AND pv.deleted_at IS NULL
+				AND lower(po.title) = ` + args.add(title) + ` AND lower(pov.title) = ANY(` + args.add(pq.Array(values)) + `)
+			)`
+		})
+	}
+
+	if opt.MinPrice != nil || opt.MaxPrice != nil {
+		filter.add(productDimensionPrice, func(args *queryArgs) string {
+			bounds := ""
+
+			if opt.MinPrice != nil {
+				bounds += ` AND price.amount >= ` + args.add(opt.MinPrice.Amount)
+			}
+
+			if opt.MaxPrice != nil {
+				bounds += ` AND price.amount <= ` + args.add(opt.MaxPrice.Amount)
+			}
+
+			return `EXISTS (
+				SELECT 1 FROM product_variant AS pv
+				CROSS JOIN LATERAL (SELECT ` + filter.unitPrice(args) + ` AS amount) AS price
+				WHERE pv.product_id = p.id AND pv.deleted_at IS NULL` + bounds + `
+			)`
+		})
+	}
+
+	if opt.InStock {
+		filter.add(productDimensionStock, filter.inStockCondition)
+	}
+
+	if opt.Status != nil {
+		filter.add(productDimensionStatus, func(args *queryArgs) string {
+			return `p.status = ` + args.add(*opt.Status)
+		})
+	}
+
+	return filter
+}
+
+func (filter *productFilter) add(dimension string, build func(args *queryArgs) string) {
+	filter.conditions = append(filter.conditions, productCondition{dimension: dimension, build: build})
+}
+
+// inStockCondition matches the products with a variant whose stock isn't entirely held by reservations
+func (filter *productFilter) inStockCondition(args *queryArgs) string {
+	return `EXISTS (
+		SELECT 1 FROM product_variant AS pv
+		WHERE pv.product_id = p.id AND pv.deleted_at IS NULL AND pv.inventory_quantity > COALESCE((
+			SELECT SUM(r.quantity) FROM inventory_reservation AS r
+			WHERE r.variant_id = pv.id AND r.status = 'active' AND r.expires_at > ` + args.add(filter.at) + `
+		), 0)
+	)`
+}
+
+// where returns the conditions of every dimension but the excluded one, an empty dimension excludes none
+func (filter *productFilter) where(args *queryArgs, excludedDimension string) string {
+	clauses := []string{"p.deleted_at IS NULL"}
+
+	for _, condition := range filter.conditions {
+		if condition.dimension != excludedDimension {
+			clauses = append(clauses, condition.build(args))
+		}
+	}
+
+	return strings.Join(clauses, " AND ")
+}
+
+func sortedOptionTitles(optionValues map[string][]string) []string {
+	titles := []string{}
+
+	for title := range optionValues {
+		titles = append(titles, title)
+	}
+
+	sort.Strings(titles)
+
+	return titles
+}
+
+type CategoryFacet struct {
+	Id       string  `json:"id"`
+	Name     string  `json:"name"`
+	ParentId *string `json:"parent_id"`
+	Count    int     `json:"count"` // the matching products in the category or one of its descendants
+}
+
+type OptionValueFacet struct {
+	Value string `json:"value"`
+	Count int    `json:"count"`
+}
+
+type OptionFacet struct {
+	Title  string              `json:"title"`
+	Values []*OptionValueFacet `json:"values"`
+}
+
+type PriceFacet struct {
+	Min   money.Money `json:"min"`
+	Max   money.Money `json:"max"`
+	Count int         `json:"count"` // the matching products with a price in the currency
+}
+
+// ProductFacets counts the products matching the listing for every value of the filters. The counts of a dimension
+// ignore the filter of that dimension so the other values can still be picked.
+type ProductFacets struct {
+	Categories []*CategoryFacet `json:"categories"`
+	Options    []*OptionFacet   `json:"options"`
+	Price      *PriceFacet      `json:"price"`    // nil when no product has a price in the currency
+	InStock    int              `json:"in_stock"` // the matching products with a variant in stock
+	Statuses   map[string]int   `json:"statuses"`
+}
+
+// ListProductFacets returns the facets of the products matching the listing options
+func (svc *ProductService) ListProductFacets(ctx context.Context, opt ProductListingOptions) (*ProductFacets, error) {
+	filter := newProductFilter(opt, time.Now(), svc.priceResolver)
+	facets := &ProductFacets{}
+	var err error
+
+	facets.Categories, err = svc.countCategoryFacets(ctx, filter)
+
+	if err != nil {
+		return nil, err
+	}
+
+	facets.Options, err = svc.countOptionFacets(ctx, filter, opt)
+
+	if err != nil {
+		return nil, err
+	}
+
+	facets.Price, err = svc.countPriceFacet(ctx, filter, opt.PriceCurrencyCode)
+
+	if err != nil {
+		return nil, err
+	}
+
+	args := queryArgs{}
+	q := `SELECT COUNT(*) FROM product AS p WHERE ` + filter.where(&args, productDimensionStock) + ` AND ` + filter.inStockCondition(&args)
+
+	err = svc.db.QueryRowContext(ctx, q, args...).Scan(&facets.InStock)
+
+	if err != nil {
+		return nil, err
+	}
+
+	facets.Statuses, err = svc.countStatusFacets(ctx, filter)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return facets, nil
+}
+
+func (svc *ProductService) countCategoryFacets(ctx context.Context, filter *productFilter) ([]*CategoryFacet, error) {
+	args := queryArgs{}
+
+	// every category is paired with itself and its descendants so a product counts for all its ancestors
+	q := `WITH RECURSIVE tree AS (
+			  SELECT id AS root_id, id FROM product_category WHERE deleted_at IS NULL
+			  UNION
+			  SELECT tree.root_id, c.id FROM product_category AS c INNER JOIN tree ON c.parent_id = tree.id WHERE c.deleted_at IS NULL
+		  )
+		  SELECT c.id, c.name, c.parent_id, COUNT(DISTINCT p.id) FROM product_category AS c
+		  INNER JOIN tree ON tree.root_id = c.id
+		  INNER JOIN product_category_product AS pcp ON pcp.category_id = tree.id
+		  INNER JOIN product AS p ON p.id = pcp.product_id
+		  WHERE ` + filter.where(&args, productDimensionCategory) + `
+		  GROUP BY c.id ORDER BY c.name`
+
+	rows, err := svc.db.QueryContext(ctx, q, args...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	categories := []*CategoryFacet{}
+
+	for rows.Next() {
+		var category CategoryFacet
+
+		err := rows.Scan(&category.Id, &category.Name, &category.ParentId, &category.Count)
+
+		if err != nil {
+			return nil, err
+		}
+
+		categories = append(categories, &category)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return categories, nil
+}
+
+// countOptionFacets counts the values of every option, case-insensitively. The options nobody filtered on are counted
+// together, every filtered option is counted without its own filter.
+func (svc *ProductService) countOptionFacets(ctx context.Context, filter *productFilter, opt ProductListingOptions) ([]*OptionFacet, error) {
+	titles := sortedOptionTitles(opt.OptionValues)
+	optionsMap := map[string]*OptionFacet{}
+
+	args := queryArgs{}
+	where := filter.where(&args, "") + ` AND lower(po.title) <> ALL(` + args.add(pq.Array(titles)) + `)`
+
+	err := svc.countOptionValues(ctx, where, args, optionsMap)
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, title := range titles {
+		args := queryArgs{}
+		where := filter.where(&args, optionDimension(title)) + ` AND lower(po.title) = ` + args.add(title)
+
+		err := svc.countOptionValues(ctx, where, args, optionsMap)
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	options := []*OptionFacet{}
+
+	for _, option := range optionsMap {
+		options = append(options, option)
+	}
+
+	sort.Slice(options, func(i, j int) bool {
+		return strings.ToLower(options[i].Title) < strings.ToLower(options[j].Title)
+	})
+
+	return options, nil
+}
+
+func (svc *ProductService) countOptionValues(ctx context.Context, where string, args queryArgs, optionsMap map[string]*OptionFacet) error {
+	q := `SELECT min(po.title), min(pov.title), COUNT(DISTINCT p.id) FROM product AS p
+		  INNER JOIN product_variant AS pv ON pv.product_id = p.id AND pv.deleted_at IS NULL
+		  INNER JOIN product_option_value AS pov ON pov.variant_id = pv.id AND pov.deleted_at IS NULL
+		  INNER JOIN product_option AS po ON po.id = pov.option_id AND po.deleted_at IS NULL
+		  WHERE ` + where + `
+		  GROUP BY lower(po.title), lower(pov.title)
+		  ORDER BY lower(po.title), lower(pov.title)`
+
+	rows, err := svc.db.QueryContext(ctx, q, args...)
+
+	if err != nil {
+		return err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var title string
+		var value OptionValueFacet
+
+		err := rows.Scan(&title, &value.Value, &value.Count)
+
+		if err != nil {
+			return err
+		}
+
+		key := strings.ToLower(title)
+
+		if optionsMap[key] == nil {
+			optionsMap[key] = &OptionFacet{Title: title, Values: []*OptionValueFacet{}}
+		}
+
+		optionsMap[key].Values = append(optionsMap[key].Values, &value)
+	}
+
+	return rows.Err()
+}
+
+// countPriceFacet returns the range of the prices the variants of the matching products are sold at in the currency
+func (svc *ProductService) countPriceFacet(ctx context.Context, filter *productFilter, currencyCode string) (*PriceFacet, error) {
+	args := queryArgs{}
+
+	q := `SELECT MIN(price.amount), MAX(price.amount), COUNT(DISTINCT p.id) FROM product AS p
+		  INNER JOIN product_variant AS pv ON pv.product_id = p.id AND pv.deleted_at IS NULL
+		  CROSS JOIN LATERAL (SELECT ` + filter.unitPrice(&args) + ` AS amount) AS price
+		  WHERE ` + filter.where(&args, productDimensionPrice) + ` AND price.amount IS NOT NULL`
+
+	var minAmount, maxAmount sql.NullInt64
+	var count int
+
+	err := svc.db.QueryRowContext(ctx, q, args...).Scan(&minAmount, &maxAmount, &count)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if count == 0 {
+		return nil, nil
+	}
+
+	return &PriceFacet{Min: money.New(minAmount.Int64, currencyCode), Max: money.New(maxAmount.Int64, currencyCode), Count: count}, nil
+}
+
+func (svc *ProductService) countStatusFacets(ctx context.Context, filter *productFilter) (map[string]int, error) {
+	args := queryArgs{}
+	q := `SELECT p.status, COUNT(*) FROM product AS p WHERE ` + filter.where(&args, productDimensionStatus) + ` GROUP BY p.status`
+
+	rows, err := svc.db.QueryContext(ctx, q, args...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	statuses := map[string]int{}
+
+	for rows.Next() {
+		var status string
+		var count int
+
+		err := rows.Scan(&status, &count)
+
+		if err != nil {
+			return nil, err
+		}
+
+		statuses[status] = count
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return statuses, nil
+}
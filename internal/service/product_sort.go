@@ -0,0 +1,101 @@
+package service
+
+import "strings"
+
+// ProductSortSafelist lists what the products can be sorted by, a leading "-" sorts in descending order. The relevance
+// is always descending and only applies to a search.
+var ProductSortSafelist = []string{
+	"created_at", "-created_at",
+	"updated_at", "-updated_at",
+	"title", "-title",
+	"price", "-price",
+	"popularity", "-popularity",
+	"relevance",
+}
+
+const ProductSortRelevance = "relevance"
+
+// productSort is the order of the product listing. The products are finally sorted by id so the products with the
+// same value keep the same order from one page to the next.
+type productSort struct {
+	name       string // the value of the safelist, recorded in the cursors
+	column     string
+	descending bool
+}
+
+func newProductSort(opt ProductListingOptions) productSort {
+	sort := opt.Sort
+
+	if sort == "" {
+		sort = "-created_at"
+
+		if opt.Query != nil {
+			sort = ProductSortRelevance
+		}
+	}
+
+	if sort == ProductSortRelevance {
+		return productSort{name: sort, column: ProductSortRelevance, descending: true}
+	}
+
+	return productSort{name: sort, column: strings.TrimPrefix(sort, "-"), descending: strings.HasPrefix(sort, "-")}
+}
+
+// expression returns the value the products, aliased p, are sorted by. The price is the lowest price the variants are
+// sold at, the same the price range of the filter is matched against, and the popularity the number of wishlists the
+// variants are in.
+func (sort productSort) expression(args *queryArgs, filter *productFilter) string {
+	switch sort.column {
+	case "updated_at":
+		return `p.updated_at`
+	case "title":
+		return `lower(p.title)`
+	case "price":
+		return `(
+			SELECT MIN(` + filter.unitPrice(args) + `) FROM product_variant AS pv
+			WHERE pv.product_id = p.id AND pv.deleted_at IS NULL
+		)`
+	case "popularity":
+		return `(
+			SELECT COUNT(*) FROM wishlist AS w
+			INNER JOIN product_variant AS pv ON pv.id = w.variant_id
+			WHERE pv.product_id = p.id AND pv.deleted_at IS NULL
+		)`
+	case ProductSortRelevance:
+		// the search query is joined as query
+		return `ts_rank_cd(p.search_vector, query)`
+	default:
+		return `p.created_at`
+	}
+}
+
+// keyType returns the type the sort key is cast to when it's read back from a cursor
+func (sort productSort) keyType() string {
+	switch sort.column {
+	case "title":
+		return "text"
+	case "price", "popularity":
+		return "bigint"
+	case ProductSortRelevance:
+		return "real"
+	default:
+		return "timestamp"
+	}
+}
+
+// orderBy returns the ORDER BY clause of the sort, the products without a price in the currency come last. The
+// reversed order lists the page before a cursor backwards.
+func (sort productSort) orderBy(expression string, reversed bool) string {
+	direction := "ASC"
+	nulls := "LAST"
+
+	if sort.descending != reversed {
+		direction = "DESC"
+	}
+
+	if reversed {
+		nulls = "FIRST"
+	}
+
+	return expression + ` ` + direction + ` NULLS ` + nulls + `, p.id ` + direction
+}
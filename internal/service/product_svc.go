@@ -3,21 +3,26 @@ package service
 import (
 	"context"
 	"database/sql"
+	"ecom-backend/internal/consts"
 	"ecom-backend/internal/model"
+	"ecom-backend/internal/money"
 	"ecom-backend/pkg/sqldb"
 	"errors"
 	"fmt"
+	"slices"
+	"time"
 
 	"github.com/lib/pq"
 )
 
 type ProductService struct {
-	db     *sql.DB
-	models *model.Models
+	db            *sql.DB
+	models        *model.Models
+	priceResolver *PriceResolver
 }
 
-func NewProductService(db *sql.DB, models *model.Models) *ProductService {
-	return &ProductService{db: db, models: models}
+func NewProductService(db *sql.DB, models *model.Models, priceResolver *PriceResolver) *ProductService {
+	return &ProductService{db: db, models: models, priceResolver: priceResolver}
 }
 
 func (svc *ProductService) CreateProduct(ctx context.Context, input *CreateProductInput) (*AggregateProduct, error) {
@@ -25,7 +30,6 @@ func (svc *ProductService) CreateProduct(ctx context.Context, input *CreateProdu
 	productCategoryRecords := []*model.ProductCategoryRecord{}
 	productOptionRecords := []*model.ProductOptionRecord{}
 	variantRecords := []*model.ProductVariantRecord{}
-	moneyAmountRecords := map[string][]*model.MoneyAmountRecord{}
 	variantOptionValueRecords := map[string][]*model.ProductOptionValueRecord{}
 
 	tx, err := svc.db.BeginTx(ctx, nil)
@@ -39,7 +43,7 @@ func (svc *ProductService) CreateProduct(ctx context.Context, input *CreateProdu
 	// create product record
 	// the product record is the general description of the product,
 	// the actual product entity containing the price that is used for purchase, wishlist, cart is the product_variant
-	productRecord := &model.ProductRecord{Title: input.Title, Subtitle: input.Subtitle, Description: input.Description, ThumbnailId: input.ThumbnailId, Status: input.Status}
+	productRecord := &model.ProductRecord{Title: input.Title, Subtitle: input.Subtitle, Description: input.Description, ThumbnailId: input.ThumbnailId, Status: input.Status, IsGiftCard: input.IsGiftCard}
 
 	product, err := svc.models.ProductModel.Insert(ctx, tx, productRecord)
 
@@ -85,13 +89,24 @@ func (svc *ProductService) CreateProduct(ctx context.Context, input *CreateProdu
 	// create product_variant records
 	// the entity that contains the price, inventory quantity, sku, barcode, etc and it's used in the cart, wishlist, purchase
 	for _, variant := range input.Variants {
-		productVariantRecord := &model.ProductVariantRecord{ProductId: product.Id, Title: variant.Title, Sku: &variant.Sku, Barcode: &variant.Barcode, InventoryQuantity: variant.InventoryQuantity}
+		productVariantRecord := &model.ProductVariantRecord{ProductId: product.Id, Title: variant.Title, Sku: &variant.Sku, Barcode: &variant.Barcode}
 
 		variantRecord, err := svc.models.ProductVariantModel.Insert(ctx, tx, productVariantRecord)
 		if err != nil {
 			return nil, fmt.Errorf("failed to create product_variant record: %w", err)
 		}
 
+		// the initial stock enters the ledger as a receipt
+		if variant.InventoryQuantity > 0 {
+			_, err := recordStockMovement(ctx, tx, svc.models, &model.StockMovementRecord{VariantId: variantRecord.Id, Type: consts.StockMovementReceipt, Quantity: variant.InventoryQuantity})
+
+			if err != nil {
+				return nil, fmt.Errorf("failed to create stock_movement record: %w", err)
+			}
+
+			variantRecord.InventoryQuantity = variant.InventoryQuantity
+		}
+
 		variantRecords = append(variantRecords, variantRecord)
 
 		for i, option := range variant.Options {
@@ -110,7 +125,7 @@ func (svc *ProductService) CreateProduct(ctx context.Context, input *CreateProdu
 		for _, price := range variant.Prices {
 			// create money_amount record
 			// the price is stored in the money_amount table and will be linked to the variant using the product_variant_money_amount table
-			moneyAmountRecord, err := svc.models.MoneyAmountModel.Insert(ctx, tx, &model.MoneyAmountRecord{CurrencyCode: price.Code, Amount: price.Amount})
+			moneyAmountRecord, err := svc.models.MoneyAmountModel.Insert(ctx, tx, &model.MoneyAmountRecord{CurrencyCode: price.Code, Amount: price.Amount.Money(price.Code), MinQuantity: price.minQuantity(), MaxQuantity: price.MaxQuantity})
 
 			if err != nil {
 				return nil, fmt.Errorf("failed to create money_amount record: %w", err)
@@ -124,12 +139,16 @@ func (svc *ProductService) CreateProduct(ctx context.Context, input *CreateProdu
 			if err != nil {
 				return nil, fmt.Errorf("failed to create product_variant_money_amount record: %w", err)
 			}
-
-			moneyAmountRecords[variantRecord.Id] = append(moneyAmountRecords[variantRecord.Id], moneyAmountRecord)
 		}
 
 	}
 
+	err = svc.models.ProductModel.RefreshSearchVector(ctx, tx, product.Id)
+
+	if err != nil {
+		return nil, err
+	}
+
 	imageIds := []string{}
 
 	// link product to images
@@ -143,11 +162,29 @@ func (svc *ProductService) CreateProduct(ctx context.Context, input *CreateProdu
 		}
 	}
 
+	variantIds := []string{}
+
+	for _, variantRecord := range variantRecords {
+		variantIds = append(variantIds, variantRecord.Id)
+	}
+
+	inventoryLevels, err := svc.models.InventoryLevelModel.FindAllByVariantIds(ctx, tx, variantIds)
+
+	if err != nil {
+		return nil, err
+	}
+
+	prices, err := svc.priceResolver.Resolve(ctx, tx, PriceContext{At: time.Now()}, variantIds)
+
+	if err != nil {
+		return nil, err
+	}
+
 	if err := tx.Commit(); err != nil {
 		return nil, err
 	}
 
-	aggFields := BuildAggregateFieldsList(imageIds, productCategoryRecords, productOptionRecords, variantRecords, moneyAmountRecords, variantOptionValueRecords)
+	aggFields := BuildAggregateFieldsList(imageIds, productCategoryRecords, productOptionRecords, variantRecords, prices, variantOptionValueRecords, nil, inventoryLevels)
 
 	aggProduct := BuildAggregateProduct(product, aggFields)
 
@@ -183,6 +220,10 @@ func (svc *ProductService) UpdateProductDetails(ctx context.Context, productId s
 		productRecord.Status = *input.Status
 	}
 
+	if input.IsGiftCard != nil {
+		productRecord.IsGiftCard = *input.IsGiftCard
+	}
+
 	if input.ThumbnailId != nil {
 		productRecord.ThumbnailId = input.ThumbnailId
 	}
@@ -256,6 +297,13 @@ func (svc *ProductService) UpdateProductDetails(ctx context.Context, productId s
 		}
 	}
 
+	// removing the options removes the option values of the variants along with them
+	err = svc.models.ProductModel.RefreshSearchVector(ctx, tx, productId)
+
+	if err != nil {
+		return nil, err
+	}
+
 	if err := tx.Commit(); err != nil {
 		return nil, err
 	}
@@ -263,7 +311,7 @@ func (svc *ProductService) UpdateProductDetails(ctx context.Context, productId s
 	return productRecord, nil
 }
 
-func (svc *ProductService) UpdateVariantDetails(ctx context.Context, variantId string, input *UpdateVariantInput) (any, error) {
+func (svc *ProductService) UpdateVariantDetails(ctx context.Context, variantId string, actorUserId *string, input *UpdateVariantInput) (any, error) {
 	tx, err := svc.db.BeginTx(ctx, nil)
 
 	if err != nil {
@@ -272,7 +320,8 @@ func (svc *ProductService) UpdateVariantDetails(ctx context.Context, variantId s
 
 	defer tx.Rollback()
 
-	variantRecord, err := svc.models.ProductVariantModel.FindById(ctx, tx, variantId)
+	// locked so a correction of the quantity is computed against the stock checkouts see
+	variantRecord, err := svc.models.ProductVariantModel.FindByIdForUpdate(ctx, tx, variantId)
 
 	if err != nil {
 		return nil, err
@@ -290,8 +339,8 @@ func (svc *ProductService) UpdateVariantDetails(ctx context.Context, variantId s
 		variantRecord.Barcode = input.Barcode
 	}
 
-	if input.InventoryQuantity != nil {
-		variantRecord.InventoryQuantity = *input.InventoryQuantity
+	if input.LowStockThreshold != nil {
+		variantRecord.LowStockThreshold = input.LowStockThreshold
 	}
 
 	variantRecord, err = svc.models.ProductVariantModel.Update(ctx, tx, variantRecord)
@@ -300,6 +349,23 @@ func (svc *ProductService) UpdateVariantDetails(ctx context.Context, variantId s
 		return nil, err
 	}
 
+	// setting the quantity directly is kept for the admin form, it's recorded as a correction of the difference
+	if input.InventoryQuantity != nil && *input.InventoryQuantity != variantRecord.InventoryQuantity {
+		reason := "inventory quantity set from the variant details"
+
+		_, err := recordStockMovement(ctx, tx, svc.models, &model.StockMovementRecord{
+			VariantId:   variantId,
+			Type:        consts.StockMovementCorrection,
+			Quantity:    *input.InventoryQuantity - variantRecord.InventoryQuantity,
+			Reason:      &reason,
+			ActorUserId: actorUserId,
+		})
+
+		if err != nil {
+			return nil, fmt.Errorf("failed to create stock_movement record: %w", err)
+		}
+	}
+
 	if input.Options != nil {
 		// handle variant option values
 
@@ -334,7 +400,7 @@ func (svc *ProductService) UpdateVariantDetails(ctx context.Context, variantId s
 
 		// create new money amount records
 		for _, price := range *input.Prices {
-			moneyAmountRecord, err := svc.models.MoneyAmountModel.Insert(ctx, tx, &model.MoneyAmountRecord{CurrencyCode: price.Code, Amount: price.Amount})
+			moneyAmountRecord, err := svc.models.MoneyAmountModel.Insert(ctx, tx, &model.MoneyAmountRecord{CurrencyCode: price.Code, Amount: price.Amount.Money(price.Code), MinQuantity: price.minQuantity(), MaxQuantity: price.MaxQuantity})
 
 			if err != nil {
 				return nil, fmt.Errorf("error while creating money_amount records")
@@ -351,6 +417,14 @@ func (svc *ProductService) UpdateVariantDetails(ctx context.Context, variantId s
 
 	}
 
+	if input.Sku != nil || input.Options != nil {
+		err := svc.models.ProductModel.RefreshSearchVector(ctx, tx, variantRecord.ProductId)
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	if err := tx.Commit(); err != nil {
 		return nil, err
 	}
@@ -359,52 +433,186 @@ func (svc *ProductService) UpdateVariantDetails(ctx context.Context, variantId s
 
 }
 
-func (svc *ProductService) ListProducts(ctx context.Context, opt ProductListingOptions) ([]*model.ProductRecord, int, error) {
-	limit := opt.PageSize
-	offset := (opt.Page - 1) * opt.PageSize
+// ProductSearchHit tells how well a product matched the search and where
+type ProductSearchHit struct {
+	Rank    float64 `json:"rank"`
+	Title   string  `json:"title"`   // the title with the matched words highlighted
+	Snippet string  `json:"snippet"` // the best matching fragments of the description, highlighted
+}
 
-	totalCountQ := `SELECT COUNT(*) from product WHERE deleted_at IS NULL`
-	var totalCount int
-	err := svc.db.QueryRowContext(ctx, totalCountQ).Scan(&totalCount)
+// ListProducts returns a page of the products matching the filters in the order of the sort, from the cursor when one
+// is given and by page number otherwise. When a search query is given the search hits are returned keyed by product id.
+func (svc *ProductService) ListProducts(ctx context.Context, opt ProductListingOptions) ([]*model.ProductRecord, map[string]*ProductSearchHit, *PageInfo, error) {
+	sort := newProductSort(opt)
 
-	if err != nil {
-		return nil, 0, err
+	var cursorKey any
+
+	if opt.Cursor != nil {
+		if opt.Cursor.Sort != sort.name {
+			return nil, nil, nil, ErrInvalidCursor
+		}
+
+		key, err := parseCursorKey(opt.Cursor.Key, sort.keyType())
+
+		if err != nil {
+			return nil, nil, nil, err
+		}
+
+		cursorKey = key
+	}
+
+	filter := newProductFilter(opt, time.Now(), svc.priceResolver)
+	args := queryArgs{}
+	where := filter.where(&args, "")
+
+	var totalCount *int
+
+	// counting every product is what makes the pages slow, the cursors don't need it
+	if opt.Cursor == nil {
+		totalCountQ := `SELECT COUNT(*) FROM product AS p WHERE ` + where
+		var count int
+		err := svc.db.QueryRowContext(ctx, totalCountQ, args...).Scan(&count)
+
+		if err != nil {
+			return nil, nil, nil, err
+		}
+
+		totalCount = &count
+	}
+
+	sortKey := sort.expression(&args, filter)
+	columns := `p.id, p.title, p.subtitle, p.description, p.thumbnail_id, p.status, p.is_gift_card, p.created_at, p.updated_at, p.deleted_at, ` + sortKey
+	from := `product AS p`
+
+	// the query accepts the web search syntax: quoted phrases, "or" and "-" to exclude a word
+	if opt.Query != nil {
+		columns += `, ts_rank_cd(p.search_vector, query),
+			ts_headline('english', p.title, query, 'StartSel=<mark>, StopSel=</mark>, HighlightAll=true'),
+			ts_headline('english', p.description, query, 'StartSel=<mark>, StopSel=</mark>, MaxFragments=2, MaxWords=20, MinWords=5')`
+		from += `, websearch_to_tsquery('english', ` + args.add(*opt.Query) + `) AS query`
 	}
 
-	q := `SELECT id, title, subtitle, description, thumbnail_id, status, created_at, updated_at, deleted_at FROM product WHERE deleted_at IS NULL ORDER BY created_at DESC LIMIT $1 OFFSET $2`
+	reversed := false
+	page := ` LIMIT ` + args.add(opt.PageSize+1)
+
+	if opt.Cursor != nil {
+		where += ` AND ` + keysetCondition(sortKey, sort.keyType(), "p.id", sort.descending, opt.Cursor, cursorKey, &args)
+		reversed = opt.Cursor.Before
+	} else {
+		page += ` OFFSET ` + args.add((opt.Page-1)*opt.PageSize)
+	}
 
-	rows, err := svc.db.QueryContext(ctx, q, limit, offset)
+	q := `SELECT ` + columns + ` FROM ` + from + ` WHERE ` + where + ` ORDER BY ` + sort.orderBy(sortKey, reversed) + page
+
+	rows, err := svc.db.QueryContext(ctx, q, args...)
 	if err != nil {
-		return nil, 0, err
+		return nil, nil, nil, err
 	}
 
+	defer rows.Close()
+
 	productsList := []*model.ProductRecord{}
+	keys := []any{}
+	var hits map[string]*ProductSearchHit
+
+	if opt.Query != nil {
+		hits = map[string]*ProductSearchHit{}
+	}
 
 	for rows.Next() {
 		var product model.ProductRecord
+		var key any
+		var hit ProductSearchHit
 
-		err := rows.Scan(&product.Id, &product.Title, &product.Subtitle, &product.Description, &product.ThumbnailId, &product.Status, &product.CreatedAt, &product.UpdatedAt, &product.DeletedAt)
+		dest := []any{&product.Id, &product.Title, &product.Subtitle, &product.Description, &product.ThumbnailId, &product.Status, &product.IsGiftCard, &product.CreatedAt, &product.UpdatedAt, &product.DeletedAt, &key}
+
+		if hits != nil {
+			dest = append(dest, &hit.Rank, &hit.Title, &hit.Snippet)
+		}
+
+		err := rows.Scan(dest...)
 
 		if err != nil {
-			return nil, 0, err
+			return nil, nil, nil, err
 		}
 
 		productsList = append(productsList, &product)
+		keys = append(keys, key)
+
+		if hits != nil {
+			hits[product.Id] = &hit
+		}
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, nil, nil, err
+	}
+
+	// the extra row only tells there's another page
+	hasMore := len(productsList) > int(opt.PageSize)
+
+	if hasMore {
+		delete(hits, productsList[opt.PageSize].Id)
+		productsList = productsList[:opt.PageSize]
+		keys = keys[:opt.PageSize]
+	}
+
+	if reversed {
+		slices.Reverse(productsList)
+		slices.Reverse(keys)
 	}
 
-	return productsList, totalCount, nil
+	ids := []string{}
+
+	for _, product := range productsList {
+		ids = append(ids, product.Id)
+	}
+
+	info := newPageInfo(sort.name, keys, ids, opt.Cursor, hasMore, opt.Page)
+	info.TotalCount = totalCount
+
+	return productsList, hits, info, nil
 }
 
 type ProductListingOptions struct {
-	Page     uint
-	PageSize uint
+	Page         uint
+	PageSize     uint
+	Query        *string // full-text search over the products when set
+	CurrencyCode *string // only the prices in this currency are returned when set
+	CategoryId   *string // only the products of the category or one of its descendants when set
+	// the option values the products must have a variant with, keyed by lowercase option title. A product matches one of
+	// the values of every option.
+	OptionValues map[string][]string
+	// the range the price of a variant must be in, in the price currency. It's the price of one unit the customer pays,
+	// with the price lists of their groups and converted from the base currency when the variant has no price in the currency.
+	MinPrice          *money.Money
+	MaxPrice          *money.Money
+	PriceCurrencyCode string // the currency of the price range, the price facet and the price sort
+	InStock           bool   // only the products with a variant in stock when true
+	Status            *string
+	// one of ProductSortSafelist, by relevance when searching and the newest first otherwise when empty
+	Sort   string
+	Cursor *Cursor // the page next to the cursor is listed instead of the page number when set
+	// the groups of the customer browsing the catalog, the prices of their price lists are shown
+	CustomerGroupIds []string
+}
+
+// unitPriceContext returns the context the prices of the price range, the price facet and the price sort are worked out in
+func (opt ProductListingOptions) unitPriceContext(at time.Time) PriceContext {
+	return PriceContext{CurrencyCode: &opt.PriceCurrencyCode, At: at, CustomerGroupIds: opt.CustomerGroupIds}
+}
+
+// priceContext returns the context the prices of the listing are resolved in
+func (opt ProductListingOptions) priceContext() PriceContext {
+	return PriceContext{CurrencyCode: opt.CurrencyCode, At: time.Now(), CustomerGroupIds: opt.CustomerGroupIds}
 }
 
-func (svc *ProductService) ListAggregateProducts(ctx context.Context, opt ProductListingOptions) ([]*AggregateProduct, int, error) {
-	products, rowCount, err := svc.ListProducts(ctx, opt)
+// ListAggregateProducts returns a page of the products with their details, and the search hits when searching
+func (svc *ProductService) ListAggregateProducts(ctx context.Context, opt ProductListingOptions) ([]*AggregateProduct, map[string]*ProductSearchHit, *PageInfo, error) {
+	products, hits, info, err := svc.ListProducts(ctx, opt)
 
 	if err != nil {
-		return nil, 0, err
+		return nil, nil, nil, err
 	}
 
 	productIds := []string{}
@@ -413,10 +621,10 @@ func (svc *ProductService) ListAggregateProducts(ctx context.Context, opt Produc
 		productIds = append(productIds, p.Id)
 	}
 
-	aggFieldsMap, err := svc.GetAggregateFieldsForProductsList(ctx, productIds)
+	aggFieldsMap, err := svc.GetAggregateFieldsForProductsList(ctx, svc.db, productIds, opt.priceContext())
 
 	if err != nil {
-		return nil, 0, err
+		return nil, nil, nil, err
 	}
 
 	aggProductList := []*AggregateProduct{}
@@ -425,112 +633,92 @@ func (svc *ProductService) ListAggregateProducts(ctx context.Context, opt Produc
 		aggProductList = append(aggProductList, BuildAggregateProduct(p, aggFieldsMap[p.Id]))
 	}
 
-	return aggProductList, rowCount, nil
+	return aggProductList, hits, info, nil
 }
 
-func (svc *ProductService) GetAggregateFieldsForProductsList(ctx context.Context, productIds []string) (map[string]*AggregateProductListFields, error) {
-	variantsMap, err := svc.models.ProductVariantModel.FindAllByProductIds(ctx, svc.db, productIds)
+// GetAggregateFieldsForProductsList returns the details of the products, their variants are priced in the given context
+func (svc *ProductService) GetAggregateFieldsForProductsList(ctx context.Context, conn sqldb.Connection, productIds []string, priceCtx PriceContext) (map[string]*AggregateProductListFields, error) {
+	variantsMap, err := svc.models.ProductVariantModel.FindAllByProductIds(ctx, conn, productIds)
 
 	if err != nil {
 		return nil, err
 	}
 
-	categoriesMap, err := svc.models.ProductCategoryProductModel.FindCategoriesForProducts(ctx, svc.db, productIds)
+	categoriesMap, err := svc.models.ProductCategoryProductModel.FindCategoriesForProducts(ctx, conn, productIds)
 
 	if err != nil {
 		return nil, err
 	}
 
-	productOptionsMap, err := svc.models.ProductOptionModel.FindForProducts(ctx, svc.db, productIds)
+	productOptionsMap, err := svc.models.ProductOptionModel.FindForProducts(ctx, conn, productIds)
 
 	if err != nil {
 		return nil, err
 	}
 
-	variantPricesMap, err := svc.getVariantPricesMap(ctx, svc.db, productIds)
+	variantOptionValuesMap, err := svc.getVariantOptionValuesMap(ctx, conn, productIds)
 
 	if err != nil {
 		return nil, err
 	}
 
-	variantOptionValuesMap, err := svc.getVariantOptionValuesMap(ctx, svc.db, productIds)
+	imageIds, err := svc.models.EntityFileModel.FindAllFilesByEntityId(ctx, conn, productIds)
 
 	if err != nil {
 		return nil, err
 	}
 
-	imageIds, err := svc.models.EntityFileModel.FindAllFilesByEntityId(ctx, svc.db, productIds)
+	variantIds := []string{}
 
-	if err != nil {
-		return nil, err
+	for _, variants := range variantsMap {
+		for _, variant := range variants {
+			variantIds = append(variantIds, variant.Id)
+		}
 	}
 
-	resultMap := make(map[string]*AggregateProductListFields)
-
-	for _, id := range productIds {
-		resultMap[id] = BuildAggregateFieldsList(imageIds[id], categoriesMap[id], productOptionsMap[id], variantsMap[id], variantPricesMap[id], variantOptionValuesMap[id])
-	}
-
-	return resultMap, nil
-}
-
-func (svc *ProductService) GetAggregateProductById(ctx context.Context, id string) (*AggregateProduct, error) {
-	product, err := svc.models.ProductModel.FindById(ctx, svc.db, id)
+	variantPricesMap, err := svc.priceResolver.Resolve(ctx, conn, priceCtx, variantIds)
 
 	if err != nil {
 		return nil, err
 	}
 
-	aggFieldsMap, err := svc.GetAggregateFieldsForProductsList(ctx, []string{id})
+	reservedQuantities, err := svc.models.InventoryReservationModel.SumActiveByVariantIds(ctx, conn, variantIds, nil)
 
 	if err != nil {
 		return nil, err
 	}
 
-	return BuildAggregateProduct(product, aggFieldsMap[id]), nil
-}
-
-func (svc *ProductService) getVariantPricesMap(ctx context.Context, conn sqldb.Connection, productIds []string) (map[string]map[string][]*model.MoneyAmountRecord, error) {
-	q := `SELECT ma.id, ma.currency_code, ma.amount, ma.created_at, ma.updated_at, pvma.variant_id, pv.product_id
-		  FROM product_variant_money_amount AS pvma 
-		  INNER JOIN money_amount AS ma ON pvma.money_amount_id = ma.id
-		  INNER JOIN product_variant AS pv ON pvma.variant_id = pv.id
-		  WHERE pv.product_id = ANY($1)`
-
-	rows, err := conn.QueryContext(ctx, q, pq.Array(productIds))
+	inventoryLevels, err := svc.models.InventoryLevelModel.FindAllByVariantIds(ctx, conn, variantIds)
 
 	if err != nil {
 		return nil, err
 	}
 
-	defer rows.Close()
-
-	pricesMap := map[string]map[string][]*model.MoneyAmountRecord{}
-
-	for rows.Next() {
-		var record model.MoneyAmountRecord
-		var variantId string
-		var productId string
+	resultMap := make(map[string]*AggregateProductListFields)
 
-		err := rows.Scan(&record.Id, &record.CurrencyCode, &record.Amount, &record.CreatedAt, &record.UpdatedAt, &variantId, &productId)
+	for _, id := range productIds {
+		resultMap[id] = BuildAggregateFieldsList(imageIds[id], categoriesMap[id], productOptionsMap[id], variantsMap[id], variantPricesMap, variantOptionValuesMap[id], reservedQuantities, inventoryLevels)
+	}
 
-		if err != nil {
-			return nil, err
-		}
+	return resultMap, nil
+}
 
-		if pricesMap[productId] == nil {
-			pricesMap[productId] = make(map[string][]*model.MoneyAmountRecord)
-		}
+// GetAggregateProductById returns the product with all its prices, or only the ones in the currency when one is given,
+// priced for the groups of the customer
+func (svc *ProductService) GetAggregateProductById(ctx context.Context, id string, currencyCode *string, customerGroupIds []string) (*AggregateProduct, error) {
+	product, err := svc.models.ProductModel.FindById(ctx, svc.db, id)
 
-		if pricesMap[productId][variantId] == nil {
-			pricesMap[productId][variantId] = []*model.MoneyAmountRecord{}
-		}
+	if err != nil {
+		return nil, err
+	}
 
-		pricesMap[productId][variantId] = append(pricesMap[productId][variantId], &record)
+	aggFieldsMap, err := svc.GetAggregateFieldsForProductsList(ctx, svc.db, []string{id}, PriceContext{CurrencyCode: currencyCode, At: time.Now(), CustomerGroupIds: customerGroupIds})
 
+	if err != nil {
+		return nil, err
 	}
 
-	return pricesMap, nil
+	return BuildAggregateProduct(product, aggFieldsMap[id]), nil
 }
 
 func (svc *ProductService) getVariantOptionValuesMap(ctx context.Context, conn sqldb.Connection, productIds []string) (map[string]map[string][]*model.ProductOptionValueRecord, error) {
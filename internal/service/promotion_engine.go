@@ -0,0 +1,799 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"ecom-backend/internal/consts"
+	"ecom-backend/internal/model"
+	"ecom-backend/internal/money"
+	"ecom-backend/internal/validator"
+	"ecom-backend/pkg/sqldb"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// PromotionEvaluation is the outcome of running the promotions against a cart or an order being placed
+type PromotionEvaluation struct {
+	Applied       []*AppliedPromotionDTO     `json:"applied"`
+	Explanations  []*PromotionExplanationDTO `json:"explanations"` // one per promotion considered, in evaluation order
+	DiscountTotal money.Money                `json:"discount_total"`
+	// CodeError tells why the promotion code gives no discount, nil when there's no code or it applied
+	CodeError error `json:"-"`
+}
+
+// PromotionExplanationDTO tells whether a promotion matched and why
+type PromotionExplanationDTO struct {
+	PromotionId string      `json:"promotion_id"`
+	Code        *string     `json:"code"`
+	Description *string     `json:"description"`
+	Type        string      `json:"type"`
+	IsAutomatic bool        `json:"is_automatic"`
+	Priority    int         `json:"priority"`
+	Matched     bool        `json:"matched"`
+	Amount      money.Money `json:"amount"`
+	Reason      string      `json:"reason"`
+}
+
+// promotionData is what the evaluation needs to know about the promotions and the products, loaded once for all of them
+type promotionData struct {
+	rules             map[string][]*model.PromotionCurrencyRuleRecord
+	targets           map[string][]*model.PromotionTargetRecord
+	tiers             map[string][]*model.PromotionTierRecord
+	customerGroups    map[string][]string        // ids of the groups each promotion is restricted to
+	productCategories map[string]map[string]bool // ids of the categories of each product together with their ancestors
+}
+
+// EvaluateCart runs the automatic promotions and the optional code against the lines without redeeming anything,
+// it's used to preview the discounts of the cart
+func (svc *PromotionService) EvaluateCart(ctx context.Context, conn sqldb.Connection, code *string, currencyCode string, lines []*promotionLine, customer promotionCustomer) (*PromotionEvaluation, error) {
+	promotions, err := svc.models.PromotionModel.FindAllAutomatic(ctx, conn)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var codeErr error
+
+	if code != nil && *code != "" {
+		promotion, err := svc.models.PromotionModel.FindByCode(ctx, conn, *code)
+
+		switch {
+		case err == nil:
+			promotions = append(promotions, promotion)
+		case errors.Is(err, model.ErrRecordNotFound):
+			codeErr = ErrPromotionNotFound
+		default:
+			return nil, err
+		}
+	}
+
+	evaluation, err := svc.evaluatePromotions(ctx, conn, promotions, currencyCode, lines, customer)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if codeErr != nil {
+		evaluation.CodeError = codeErr
+	}
+
+	return evaluation, nil
+}
+
+// lockPromotions evaluates the promotions for an order being placed. The rows of the promotions with usage limits that
+// can apply to the lines stay locked until the transaction ends, so concurrent checkouts can't go over the limits while
+// the other promotions don't serialize the checkouts. A code that doesn't apply fails the checkout.
+func (svc *PromotionService) lockPromotions(ctx context.Context, tx *sql.Tx, code *string, currencyCode string, lines []*promotionLine, customer promotionCustomer) (*PromotionEvaluation, error) {
+	promotions, err := svc.models.PromotionModel.FindAllAutomatic(ctx, tx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if code != nil && *code != "" {
+		promotion, err := svc.models.PromotionModel.FindByCode(ctx, tx, *code)
+
+		if err != nil {
+			if errors.Is(err, model.ErrRecordNotFound) {
+				return nil, ErrPromotionNotFound
+			}
+			return nil, err
+		}
+
+		promotions = append(promotions, promotion)
+	}
+
+	lockIds, err := svc.applicableLimitedPromotionIds(ctx, tx, promotions, currencyCode, lines, customer)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(lockIds) > 0 {
+		locked, err := svc.models.PromotionModel.FindAllByIdsForUpdate(ctx, tx, lockIds)
+
+		if err != nil {
+			return nil, err
+		}
+
+		// the locked rows carry usage counts no other checkout can change anymore
+		lockedMap := map[string]*model.PromotionRecord{}
+
+		for _, promotion := range locked {
+			lockedMap[promotion.Id] = promotion
+		}
+
+		for i, promotion := range promotions {
+			if lockedPromotion, ok := lockedMap[promotion.Id]; ok {
+				promotions[i] = lockedPromotion
+			}
+		}
+	}
+
+	evaluation, err := svc.evaluatePromotions(ctx, tx, promotions, currencyCode, lines, customer)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if evaluation.CodeError != nil {
+		return nil, evaluation.CodeError
+	}
+
+	return evaluation, nil
+}
+
+// applicableLimitedPromotionIds returns the ids of the promotions with usage limits that apply to the lines on their own,
+// whatever the other promotions and the usage left
+func (svc *PromotionService) applicableLimitedPromotionIds(ctx context.Context, conn sqldb.Connection, promotions []*model.PromotionRecord, currencyCode string, lines []*promotionLine, customer promotionCustomer) ([]string, error) {
+	limited := []*model.PromotionRecord{}
+	now := time.Now()
+
+	for _, promotion := range promotions {
+		if (promotion.UsageLimit != nil || promotion.UsageLimitPerCustomer != nil) && checkPromotionRunning(promotion, now) == nil {
+			limited = append(limited, promotion)
+		}
+	}
+
+	if len(limited) == 0 {
+		return nil, nil
+	}
+
+	data, err := svc.loadPromotionData(ctx, conn, limited, lines)
+
+	if err != nil {
+		return nil, err
+	}
+
+	ids := []string{}
+
+	for _, promotion := range limited {
+		if checkPromotionCustomerGroups(promotion, data, customer) != nil {
+			continue
+		}
+
+		_, err := computePromotion(promotion, data, currencyCode, lines)
+
+		if err != nil {
+			if !isPromotionError(err) {
+				return nil, err
+			}
+			continue
+		}
+
+		ids = append(ids, promotion.Id)
+	}
+
+	return ids, nil
+}
+
+// recordRedemptions counts the use of the promotions applied to the order against their limits,
+// the promotions with limits must have been locked by lockPromotions
+func (svc *PromotionService) recordRedemptions(ctx context.Context, tx *sql.Tx, evaluation *PromotionEvaluation, order *model.OrderRecord) error {
+	for _, applied := range evaluation.Applied {
+		_, err := svc.models.PromotionRedemptionModel.Insert(ctx, tx, &model.PromotionRedemptionRecord{
+			PromotionId:    applied.PromotionId,
+			OrderId:        order.Id,
+			UserIdentifier: order.UserIdentifier,
+			Email:          order.Email,
+			CurrencyCode:   order.CurrencyCode,
+			Amount:         applied.Amount,
+		})
+
+		if err != nil {
+			return err
+		}
+
+		err = svc.models.PromotionModel.IncrementUsage(ctx, tx, applied.PromotionId)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+type PromotionDryRunItemInput struct {
+	VariantId string `json:"variant_id"`
+	Quantity  int    `json:"quantity"`
+}
+
+type PromotionDryRunInput struct {
+	CurrencyCode  string  `json:"currency_code"`
+	PromotionCode *string `json:"promotion_code"`
+	Email         *string `json:"email"` // optional, checks the per customer usage limits
+	// CustomerGroupIds are the groups of the customer to simulate, optional
+	CustomerGroupIds []string                   `json:"customer_group_ids"`
+	Items            []PromotionDryRunItemInput `json:"items"`
+}
+
+func (input *PromotionDryRunInput) Validate(v *validator.Validator) {
+	v.Check(input.CurrencyCode != "", "currency_code", "must be provided")
+	v.Check(len(input.Items) > 0, "items", "must contain at least one item")
+
+	variantIds := []string{}
+
+	for _, item := range input.Items {
+		v.Check(validator.IsValidUUID(item.VariantId), "items.variant_id", "must be a valid UUID")
+		v.Check(item.Quantity > 0, "items.quantity", "must be greater than zero")
+		variantIds = append(variantIds, item.VariantId)
+	}
+
+	v.Check(validator.Unique(variantIds), "items", "must not contain the same variant twice")
+	validateCustomerGroupIds(v, input.CustomerGroupIds)
+}
+
+// DryRun evaluates the promotions against the given items as if they were in a cart, explaining which
+// promotions matched and why the others didn't. Nothing is redeemed.
+func (svc *PromotionService) DryRun(ctx context.Context, input *PromotionDryRunInput) (*PromotionEvaluation, error) {
+	variantIds := []string{}
+	quantities := map[string]int{}
+
+	for _, item := range input.Items {
+		variantIds = append(variantIds, item.VariantId)
+		quantities[item.VariantId] = item.Quantity
+	}
+
+	q := `SELECT pv.id, pv.product_id FROM product_variant AS pv
+		  INNER JOIN product AS p ON p.id = pv.product_id
+		  WHERE pv.id = ANY($1) AND pv.deleted_at IS NULL AND p.deleted_at IS NULL`
+
+	rows, err := svc.db.QueryContext(ctx, q, pq.Array(variantIds))
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	linesMap := map[string]*promotionLine{}
+
+	for rows.Next() {
+		var line promotionLine
+
+		err := rows.Scan(&line.VariantId, &line.ProductId)
+
+		if err != nil {
+			return nil, err
+		}
+
+		linesMap[line.VariantId] = &line
+	}
+
+	pricesMap, err := svc.priceResolver.resolveVariantPrices(ctx, svc.db, PriceContext{CurrencyCode: &input.CurrencyCode, At: time.Now(), Quantities: quantities, CustomerGroupIds: input.CustomerGroupIds}, variantIds)
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, line := range linesMap {
+		price, ok := pricesMap[line.VariantId]
+
+		if !ok {
+			return nil, ErrVariantPriceNotFound
+		}
+
+		line.Subtotal = price.EffectiveAmount()
+	}
+
+	lines := []*promotionLine{}
+
+	for _, item := range input.Items {
+		line, ok := linesMap[item.VariantId]
+
+		if !ok {
+			return nil, model.ErrVariantNotFound
+		}
+
+		line.Quantity = item.Quantity
+		line.Subtotal = line.Subtotal.Mul(item.Quantity)
+		lines = append(lines, line)
+	}
+
+	evaluation, err := svc.EvaluateCart(ctx, svc.db, input.PromotionCode, input.CurrencyCode, lines, promotionCustomer{Email: input.Email, CustomerGroupIds: input.CustomerGroupIds})
+
+	if err != nil {
+		return nil, err
+	}
+
+	if errors.Is(evaluation.CodeError, ErrPromotionNotFound) {
+		return nil, ErrPromotionNotFound
+	}
+
+	return evaluation, nil
+}
+
+// evaluatePromotions applies the promotions one after the other from the lowest priority value, automatic promotions
+// before the code on a tie. The discounts stack, each promotion works on what the previous ones left of the lines.
+// A promotion that isn't combinable is skipped when another one was already applied, and once applied it ends the evaluation.
+func (svc *PromotionService) evaluatePromotions(ctx context.Context, conn sqldb.Connection, promotions []*model.PromotionRecord, currencyCode string, lines []*promotionLine, customer promotionCustomer) (*PromotionEvaluation, error) {
+	evaluation := &PromotionEvaluation{Applied: []*AppliedPromotionDTO{}, Explanations: []*PromotionExplanationDTO{}, DiscountTotal: money.Zero(currencyCode)}
+
+	if len(promotions) == 0 {
+		return evaluation, nil
+	}
+
+	sort.SliceStable(promotions, func(i, j int) bool {
+		if promotions[i].Priority != promotions[j].Priority {
+			return promotions[i].Priority < promotions[j].Priority
+		}
+		return promotions[i].IsAutomatic && !promotions[j].IsAutomatic
+	})
+
+	data, err := svc.loadPromotionData(ctx, conn, promotions, lines)
+
+	if err != nil {
+		return nil, err
+	}
+
+	// the lines of the caller are left untouched
+	remaining := []*promotionLine{}
+
+	for _, line := range lines {
+		lineCopy := *line
+		remaining = append(remaining, &lineCopy)
+	}
+
+	stopped := false
+
+	for _, promotion := range promotions {
+		explanation := &PromotionExplanationDTO{
+			PromotionId: promotion.Id,
+			Code:        promotion.Code,
+			Description: promotion.Description,
+			Type:        promotion.Type,
+			IsAutomatic: promotion.IsAutomatic,
+			Priority:    promotion.Priority,
+			Amount:      money.Zero(currencyCode),
+		}
+
+		evaluation.Explanations = append(evaluation.Explanations, explanation)
+
+		var applied *AppliedPromotionDTO
+		var err error
+
+		switch {
+		case stopped:
+			err = fmt.Errorf("%w: a promotion that can't be combined with others was applied first", ErrPromotionNotApplicable)
+		case !promotion.IsCombinable && len(evaluation.Applied) > 0:
+			err = fmt.Errorf("%w: the promotion can't be combined with the ones already applied", ErrPromotionNotApplicable)
+		default:
+			err = checkPromotionCustomerGroups(promotion, data, customer)
+
+			if err == nil {
+				err = svc.checkPromotionUsable(ctx, conn, promotion, customer)
+			}
+
+			if err == nil {
+				applied, err = computePromotion(promotion, data, currencyCode, remaining)
+			}
+		}
+
+		if err != nil {
+			if !isPromotionError(err) {
+				return nil, err
+			}
+
+			explanation.Reason = err.Error()
+
+			if !promotion.IsAutomatic {
+				evaluation.CodeError = err
+			}
+			continue
+		}
+
+		for _, lineDiscount := range applied.Items {
+			for _, line := range remaining {
+				if line.VariantId == lineDiscount.VariantId {
+					line.Discount = line.Discount.Add(lineDiscount.Amount)
+				}
+			}
+		}
+
+		explanation.Matched = true
+		explanation.Amount = applied.Amount
+		explanation.Reason = fmt.Sprintf("applied to %d line(s)", len(applied.Items))
+
+		evaluation.Applied = append(evaluation.Applied, applied)
+		evaluation.DiscountTotal = evaluation.DiscountTotal.Add(applied.Amount)
+
+		stopped = !promotion.IsCombinable
+	}
+
+	return evaluation, nil
+}
+
+// loadPromotionData fetches the rules, targets and tiers of the promotions and, when some promotion targets categories,
+// the categories of the products on the lines
+func (svc *PromotionService) loadPromotionData(ctx context.Context, conn sqldb.Connection, promotions []*model.PromotionRecord, lines []*promotionLine) (*promotionData, error) {
+	promotionIds := []string{}
+
+	for _, promotion := range promotions {
+		promotionIds = append(promotionIds, promotion.Id)
+	}
+
+	data := &promotionData{productCategories: map[string]map[string]bool{}}
+	var err error
+
+	data.rules, err = svc.models.PromotionCurrencyRuleModel.FindAllByPromotionIds(ctx, conn, promotionIds)
+
+	if err != nil {
+		return nil, err
+	}
+
+	data.targets, err = svc.models.PromotionTargetModel.FindAllByPromotionIds(ctx, conn, promotionIds)
+
+	if err != nil {
+		return nil, err
+	}
+
+	data.tiers, err = svc.models.PromotionTierModel.FindAllByPromotionIds(ctx, conn, promotionIds)
+
+	if err != nil {
+		return nil, err
+	}
+
+	data.customerGroups, err = svc.models.PromotionCustomerGroupModel.FindAllByPromotionIds(ctx, conn, promotionIds)
+
+	if err != nil {
+		return nil, err
+	}
+
+	targetsCategories := false
+
+	for _, targets := range data.targets {
+		for _, target := range targets {
+			targetsCategories = targetsCategories || target.TargetType == consts.PromotionTargetCategory
+		}
+	}
+
+	if !targetsCategories {
+		return data, nil
+	}
+
+	tree, err := svc.productCategorySvc.GetAll(ctx)
+
+	if err != nil {
+		return nil, err
+	}
+
+	ancestors := categoryAncestors(tree)
+	productIds := []string{}
+
+	for _, line := range lines {
+		productIds = append(productIds, line.ProductId)
+	}
+
+	categoriesMap, err := svc.models.ProductCategoryProductModel.FindCategoriesForProducts(ctx, conn, productIds)
+
+	if err != nil {
+		return nil, err
+	}
+
+	// a product belongs to the subtree of every ancestor of its categories
+	for productId, categories := range categoriesMap {
+		data.productCategories[productId] = map[string]bool{}
+
+		for _, category := range categories {
+			data.productCategories[productId][category.Id] = true
+
+			for _, ancestorId := range ancestors[category.Id] {
+				data.productCategories[productId][ancestorId] = true
+			}
+		}
+	}
+
+	return data, nil
+}
+
+// categoryAncestors maps each category of the tree to the ids of its ancestors, from the root down
+func categoryAncestors(tree []*ProductCategoryWithChildren) map[string][]string {
+	result := map[string][]string{}
+
+	var walk func(categories []*ProductCategoryWithChildren, path []string)
+
+	walk = func(categories []*ProductCategoryWithChildren, path []string) {
+		for _, category := range categories {
+			result[category.Id] = path
+
+			childPath := append(append([]string{}, path...), category.Id)
+			walk(category.Children, childPath)
+		}
+	}
+
+	walk(tree, []string{})
+
+	return result
+}
+
+// checkPromotionUsable verifies the promotion is running and its usage limits weren't reached
+func (svc *PromotionService) checkPromotionUsable(ctx context.Context, conn sqldb.Connection, promotion *model.PromotionRecord, customer promotionCustomer) error {
+	err := checkPromotionRunning(promotion, time.Now())
+
+	if err != nil {
+		return err
+	}
+
+	if promotion.UsageLimit != nil && promotion.UsageCount >= *promotion.UsageLimit {
+		return ErrPromotionUsageLimitReached
+	}
+
+	if promotion.UsageLimitPerCustomer != nil {
+		count, err := svc.models.PromotionRedemptionModel.CountByCustomer(ctx, conn, promotion.Id, customer.UserIdentifier, customer.Email)
+
+		if err != nil {
+			return err
+		}
+
+		if count >= *promotion.UsageLimitPerCustomer {
+			return ErrPromotionUsageLimitReached
+		}
+	}
+
+	return nil
+}
+
+// checkPromotionRunning verifies the promotion is active and within its dates
+func checkPromotionRunning(promotion *model.PromotionRecord, now time.Time) error {
+	if !promotion.IsActive {
+		return fmt.Errorf("%w: the promotion is not active", ErrPromotionNotApplicable)
+	}
+
+	if promotion.StartsAt != nil && now.Before(*promotion.StartsAt) {
+		return fmt.Errorf("%w: the promotion has not started yet", ErrPromotionNotApplicable)
+	}
+
+	if promotion.EndsAt != nil && !now.Before(*promotion.EndsAt) {
+		return fmt.Errorf("%w: the promotion has ended", ErrPromotionNotApplicable)
+	}
+
+	return nil
+}
+
+// checkPromotionCustomerGroups tells whether the customer belongs to one of the groups the promotion is restricted to
+func checkPromotionCustomerGroups(promotion *model.PromotionRecord, data *promotionData, customer promotionCustomer) error {
+	groupIds := data.customerGroups[promotion.Id]
+
+	if len(groupIds) == 0 {
+		return nil
+	}
+
+	for _, groupId := range customer.CustomerGroupIds {
+		if validator.In(groupId, groupIds...) {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%w: the promotion is reserved to some customer groups", ErrPromotionNotApplicable)
+}
+
+// computePromotion works out the discount of the promotion on what's left of the lines, in the given currency.
+// The discount is spread over the eligible lines so refunds and returns can give back the right share.
+func computePromotion(promotion *model.PromotionRecord, data *promotionData, currencyCode string, lines []*promotionLine) (*AppliedPromotionDTO, error) {
+	var rule *model.PromotionCurrencyRuleRecord
+
+	for _, r := range data.rules[promotion.Id] {
+		if r.CurrencyCode == currencyCode {
+			rule = r
+		}
+	}
+
+	if promotion.Type == consts.PromotionTypeFixed && (rule == nil || rule.Amount == nil) {
+		return nil, fmt.Errorf("%w: the promotion is not available in %s", ErrPromotionNotApplicable, currencyCode)
+	}
+
+	subtotal := money.Zero(currencyCode)
+
+	for _, line := range lines {
+		subtotal = subtotal.Add(line.Subtotal)
+	}
+
+	if rule != nil && rule.MinSubtotal != nil && subtotal.LessThan(*rule.MinSubtotal) {
+		return nil, fmt.Errorf("%w: the cart subtotal must be at least %s", ErrPromotionNotApplicable, rule.MinSubtotal)
+	}
+
+	eligibleLines := findEligibleLines(promotion, data, lines)
+
+	if len(eligibleLines) == 0 {
+		return nil, fmt.Errorf("%w: none of the cart items is eligible", ErrPromotionNotApplicable)
+	}
+
+	applied := &AppliedPromotionDTO{
+		PromotionId: promotion.Id,
+		Code:        promotion.Code,
+		Description: promotion.Description,
+		Type:        promotion.Type,
+		IsAutomatic: promotion.IsAutomatic,
+		Amount:      money.Zero(currencyCode),
+		Items:       []*LineDiscountDTO{},
+	}
+
+	var err error
+
+	switch promotion.Type {
+	case consts.PromotionTypePercentage:
+		applyPercentage(applied, eligibleLines, *promotion.Percentage)
+	case consts.PromotionTypeFixed:
+		applyFixedAmount(applied, eligibleLines, *rule.Amount)
+	case consts.PromotionTypeTiered:
+		err = applyTier(applied, eligibleLines, data.tiers[promotion.Id])
+	case consts.PromotionTypeBuyXGetY:
+		err = applyBuyXGetY(applied, eligibleLines, *promotion.BuyQuantity, *promotion.GetQuantity, *promotion.Percentage)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if !applied.Amount.IsPositive() {
+		return nil, fmt.Errorf("%w: the promotion gives no discount on the cart", ErrPromotionNotApplicable)
+	}
+
+	return applied, nil
+}
+
+// remainingSubtotal is what's left to pay on the line after the discounts already applied
+func (line *promotionLine) remainingSubtotal() money.Money {
+	return line.Subtotal.Sub(line.Discount)
+}
+
+func applyPercentage(applied *AppliedPromotionDTO, lines []*promotionLine, percentage float32) {
+	for _, line := range lines {
+		amount := line.remainingSubtotal().Percentage(percentage)
+
+		applied.Items = append(applied.Items, &LineDiscountDTO{VariantId: line.VariantId, Amount: amount})
+		applied.Amount = applied.Amount.Add(amount)
+	}
+}
+
+func applyFixedAmount(applied *AppliedPromotionDTO, lines []*promotionLine, amount money.Money) {
+	eligibleSubtotal := money.Zero(amount.CurrencyCode)
+	weights := []int64{}
+
+	for _, line := range lines {
+		eligibleSubtotal = eligibleSubtotal.Add(line.remainingSubtotal())
+		weights = append(weights, line.remainingSubtotal().Amount)
+	}
+
+	// the discount can't be larger than what it applies to, it's split in proportion to the lines without losing a cent
+	total := money.Min(amount, eligibleSubtotal)
+
+	for i, lineAmount := range total.Allocate(weights) {
+		applied.Items = append(applied.Items, &LineDiscountDTO{VariantId: lines[i].VariantId, Amount: lineAmount})
+	}
+
+	applied.Amount = total
+}
+
+// applyTier takes off the percentage of the highest tier reached by the number of eligible units
+func applyTier(applied *AppliedPromotionDTO, lines []*promotionLine, tiers []*model.PromotionTierRecord) error {
+	quantity := 0
+
+	for _, line := range lines {
+		quantity += line.Quantity
+	}
+
+	var reached *model.PromotionTierRecord
+
+	for _, tier := range tiers {
+		if quantity >= tier.MinQuantity {
+			reached = tier
+		}
+	}
+
+	if reached == nil {
+		if len(tiers) == 0 {
+			return fmt.Errorf("%w: the promotion has no tiers", ErrPromotionNotApplicable)
+		}
+		return fmt.Errorf("%w: %d more eligible item(s) needed to reach the first tier", ErrPromotionNotApplicable, tiers[0].MinQuantity-quantity)
+	}
+
+	applyPercentage(applied, lines, reached.Percentage)
+
+	return nil
+}
+
+// applyBuyXGetY groups the eligible units by buy + get from the most expensive down, and discounts the get cheapest
+// units of each complete group. Going from the most expensive keeps the customer from getting expensive items
+// discounted by buying cheap ones.
+func applyBuyXGetY(applied *AppliedPromotionDTO, lines []*promotionLine, buyQuantity int, getQuantity int, percentage float32) error {
+	type unit struct {
+		line  *promotionLine
+		price money.Money
+	}
+
+	units := []unit{}
+
+	for _, line := range lines {
+		// the units of a line share what's left of it, the odd cents go to the first ones
+		prices := line.remainingSubtotal().Allocate(make([]int64, line.Quantity))
+
+		for _, price := range prices {
+			units = append(units, unit{line: line, price: price})
+		}
+	}
+
+	groupSize := buyQuantity + getQuantity
+
+	if len(units) < groupSize {
+		return fmt.Errorf("%w: %d more eligible item(s) needed", ErrPromotionNotApplicable, groupSize-len(units))
+	}
+
+	sort.SliceStable(units, func(i, j int) bool {
+		return units[j].price.LessThan(units[i].price)
+	})
+
+	discounted := map[string]money.Money{}
+	grouped := len(units) / groupSize * groupSize
+
+	for i := 0; i < grouped; i++ {
+		if i%groupSize >= buyQuantity {
+			discounted[units[i].line.VariantId] = discounted[units[i].line.VariantId].Add(units[i].price)
+		}
+	}
+
+	for _, line := range lines {
+		if free, ok := discounted[line.VariantId]; ok {
+			amount := free.Percentage(percentage)
+
+			applied.Items = append(applied.Items, &LineDiscountDTO{VariantId: line.VariantId, Amount: amount})
+			applied.Amount = applied.Amount.Add(amount)
+		}
+	}
+
+	return nil
+}
+
+// findEligibleLines returns the lines the promotion applies to, all of them when the promotion has no targets.
+// A category target covers the whole subtree of the category.
+func findEligibleLines(promotion *model.PromotionRecord, data *promotionData, lines []*promotionLine) []*promotionLine {
+	targets := data.targets[promotion.Id]
+	result := []*promotionLine{}
+
+	for _, line := range lines {
+		if !line.remainingSubtotal().IsPositive() || line.Quantity <= 0 {
+			continue
+		}
+
+		isTarget := len(targets) == 0
+
+		for _, target := range targets {
+			if target.TargetType == consts.PromotionTargetProduct {
+				isTarget = isTarget || target.TargetId == line.ProductId
+			} else {
+				isTarget = isTarget || data.productCategories[line.ProductId][target.TargetId]
+			}
+		}
+
+		if isTarget {
+			result = append(result, line)
+		}
+	}
+
+	return result
+}
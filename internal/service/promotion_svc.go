@@ -0,0 +1,646 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"ecom-backend/internal/consts"
+	"ecom-backend/internal/model"
+	"ecom-backend/internal/money"
+	"ecom-backend/internal/validator"
+	"ecom-backend/pkg/sqldb"
+	"errors"
+	"time"
+)
+
+var (
+	ErrPromotionNotFound          = errors.New("promotion code not found")
+	ErrPromotionNotApplicable     = errors.New("promotion code can't be applied")
+	ErrPromotionUsageLimitReached = errors.New("promotion code usage limit reached")
+)
+
+type PromotionService struct {
+	db                 *sql.DB
+	models             *model.Models
+	productCategorySvc *ProductCategoryService
+	priceResolver      *PriceResolver
+}
+
+func NewPromotionService(db *sql.DB, models *model.Models, productCategorySvc *ProductCategoryService, priceResolver *PriceResolver) *PromotionService {
+	return &PromotionService{db: db, models: models, productCategorySvc: productCategorySvc, priceResolver: priceResolver}
+}
+
+type PromotionDTO struct {
+	*model.PromotionRecord
+	CurrencyRules []*model.PromotionCurrencyRuleRecord `json:"currency_rules"`
+	Targets       []*model.PromotionTargetRecord       `json:"targets"`
+	Tiers         []*model.PromotionTierRecord         `json:"tiers"`
+	// CustomerGroupIds restrict the promotion to the members of the groups, it applies to everyone when empty
+	CustomerGroupIds []string `json:"customer_group_ids"`
+}
+
+// AppliedPromotionDTO is the breakdown of the discount a promotion gives on a cart or an order
+type AppliedPromotionDTO struct {
+	PromotionId string             `json:"promotion_id"`
+	Code        *string            `json:"code"`
+	Description *string            `json:"description"`
+	Type        string             `json:"type"`
+	IsAutomatic bool               `json:"is_automatic"`
+	Amount      money.Money        `json:"amount"`
+	Items       []*LineDiscountDTO `json:"items"`
+}
+
+type LineDiscountDTO struct {
+	VariantId string      `json:"variant_id"`
+	Amount    money.Money `json:"amount"`
+}
+
+// promotionLine is a cart or order line the discounts are computed on
+type promotionLine struct {
+	VariantId string
+	ProductId string
+	Quantity  int
+	Subtotal  money.Money
+	Discount  money.Money // taken off by the promotions evaluated before
+}
+
+// promotionCustomer identifies who redeems a promotion, the email is known only at checkout
+type promotionCustomer struct {
+	UserIdentifier   string
+	Email            *string
+	CustomerGroupIds []string
+}
+
+type PromotionCurrencyRuleInput struct {
+	CurrencyCode string         `json:"currency_code"`
+	Amount       *money.Decimal `json:"amount"`
+	MinSubtotal  *money.Decimal `json:"min_subtotal"`
+}
+
+type PromotionTargetInput struct {
+	TargetType string `json:"target_type"`
+	TargetId   string `json:"target_id"`
+}
+
+type PromotionTierInput struct {
+	MinQuantity int     `json:"min_quantity"`
+	Percentage  float32 `json:"percentage"`
+}
+
+func validatePromotionRules(v *validator.Validator, rules []PromotionCurrencyRuleInput, targets []PromotionTargetInput, tiers []PromotionTierInput) {
+	currencies := []string{}
+
+	for _, rule := range rules {
+		v.Check(rule.CurrencyCode != "", "currency_rules.currency_code", "must be provided")
+		currencies = append(currencies, rule.CurrencyCode)
+
+		if rule.Amount != nil {
+			v.Check(rule.Amount.Sign() > 0, "currency_rules.amount", "must be greater than zero")
+			validateDecimals(v, *rule.Amount, rule.CurrencyCode, "currency_rules.amount")
+		}
+
+		if rule.MinSubtotal != nil {
+			v.Check(rule.MinSubtotal.Sign() >= 0, "currency_rules.min_subtotal", "should not be negative")
+			validateDecimals(v, *rule.MinSubtotal, rule.CurrencyCode, "currency_rules.min_subtotal")
+		}
+	}
+
+	v.Check(validator.Unique(currencies), "currency_rules", "must not contain the same currency twice")
+
+	for _, target := range targets {
+		v.Check(validator.In(target.TargetType, consts.PromotionTargetProduct, consts.PromotionTargetCategory), "targets.target_type", "invalid target type")
+		v.Check(validator.IsValidUUID(target.TargetId), "targets.target_id", "must be a valid UUID")
+	}
+
+	quantities := []int{}
+
+	for _, tier := range tiers {
+		v.Check(tier.MinQuantity > 0, "tiers.min_quantity", "must be greater than zero")
+		v.Check(tier.Percentage > 0 && tier.Percentage <= 100, "tiers.percentage", "must be between 0 and 100")
+		quantities = append(quantities, tier.MinQuantity)
+	}
+
+	v.Check(validator.Unique(quantities), "tiers", "must not contain the same min_quantity twice")
+}
+
+type CreatePromotionInput struct {
+	Code                  string                       `json:"code"` // must be empty for automatic promotions
+	Description           *string                      `json:"description"`
+	Type                  string                       `json:"type"`
+	Percentage            *float32                     `json:"percentage"`   // for buy_x_get_y promotions it's taken off the free units, 100 by default
+	BuyQuantity           *int                         `json:"buy_quantity"` // buy_x_get_y only
+	GetQuantity           *int                         `json:"get_quantity"` // buy_x_get_y only
+	IsAutomatic           bool                         `json:"is_automatic"`
+	Priority              *int                         `json:"priority"`
+	IsCombinable          *bool                        `json:"is_combinable"`
+	UsageLimit            *int                         `json:"usage_limit"`
+	UsageLimitPerCustomer *int                         `json:"usage_limit_per_customer"`
+	StartsAt              *time.Time                   `json:"starts_at"`
+	EndsAt                *time.Time                   `json:"ends_at"`
+	IsActive              *bool                        `json:"is_active"`
+	CurrencyRules         []PromotionCurrencyRuleInput `json:"currency_rules"`
+	Targets               []PromotionTargetInput       `json:"targets"`
+	Tiers                 []PromotionTierInput         `json:"tiers"` // tiered only
+	CustomerGroupIds      []string                     `json:"customer_group_ids"`
+}
+
+func (input *CreatePromotionInput) Validate(v *validator.Validator) {
+	if input.IsAutomatic {
+		v.Check(input.Code == "", "code", "automatic promotions have no code")
+	} else {
+		v.Check(input.Code != "", "code", "must be provided")
+	}
+
+	v.Check(validator.In(input.Type, consts.PromotionTypePercentage, consts.PromotionTypeFixed, consts.PromotionTypeBuyXGetY, consts.PromotionTypeTiered), "type", "invalid promotion type")
+
+	if input.Type == consts.PromotionTypePercentage {
+		v.Check(input.Percentage != nil && *input.Percentage > 0 && *input.Percentage <= 100, "percentage", "must be between 0 and 100")
+	}
+
+	if input.Type == consts.PromotionTypeBuyXGetY {
+		v.Check(input.BuyQuantity != nil && *input.BuyQuantity > 0, "buy_quantity", "must be greater than zero")
+		v.Check(input.GetQuantity != nil && *input.GetQuantity > 0, "get_quantity", "must be greater than zero")
+
+		if input.Percentage != nil {
+			v.Check(*input.Percentage > 0 && *input.Percentage <= 100, "percentage", "must be between 0 and 100")
+		}
+	}
+
+	if input.Type == consts.PromotionTypeTiered {
+		v.Check(len(input.Tiers) > 0, "tiers", "a tiered promotion needs at least one tier")
+	}
+
+	if input.Type == consts.PromotionTypeFixed {
+		hasAmount := false
+
+		for _, rule := range input.CurrencyRules {
+			hasAmount = hasAmount || rule.Amount != nil
+		}
+
+		v.Check(hasAmount, "currency_rules", "a fixed promotion needs an amount in at least one currency")
+	}
+
+	if input.UsageLimit != nil {
+		v.Check(*input.UsageLimit > 0, "usage_limit", "must be greater than zero")
+	}
+
+	if input.UsageLimitPerCustomer != nil {
+		v.Check(*input.UsageLimitPerCustomer > 0, "usage_limit_per_customer", "must be greater than zero")
+	}
+
+	if input.StartsAt != nil && input.EndsAt != nil {
+		v.Check(input.EndsAt.After(*input.StartsAt), "ends_at", "must be after starts_at")
+	}
+
+	validatePromotionRules(v, input.CurrencyRules, input.Targets, input.Tiers)
+	validateCustomerGroupIds(v, input.CustomerGroupIds)
+}
+
+type UpdatePromotionInput struct {
+	Description           *string                       `json:"description"`
+	Percentage            *float32                      `json:"percentage"`
+	BuyQuantity           *int                          `json:"buy_quantity"`
+	GetQuantity           *int                          `json:"get_quantity"`
+	Priority              *int                          `json:"priority"`
+	IsCombinable          *bool                         `json:"is_combinable"`
+	UsageLimit            *int                          `json:"usage_limit"`
+	UsageLimitPerCustomer *int                          `json:"usage_limit_per_customer"`
+	StartsAt              *time.Time                    `json:"starts_at"`
+	EndsAt                *time.Time                    `json:"ends_at"`
+	IsActive              *bool                         `json:"is_active"`
+	CurrencyRules         *[]PromotionCurrencyRuleInput `json:"currency_rules"`
+	Targets               *[]PromotionTargetInput       `json:"targets"`
+	Tiers                 *[]PromotionTierInput         `json:"tiers"`
+	CustomerGroupIds      *[]string                     `json:"customer_group_ids"`
+}
+
+func (input *UpdatePromotionInput) Validate(v *validator.Validator) {
+	if input.Percentage != nil {
+		v.Check(*input.Percentage > 0 && *input.Percentage <= 100, "percentage", "must be between 0 and 100")
+	}
+
+	if input.BuyQuantity != nil {
+		v.Check(*input.BuyQuantity > 0, "buy_quantity", "must be greater than zero")
+	}
+
+	if input.GetQuantity != nil {
+		v.Check(*input.GetQuantity > 0, "get_quantity", "must be greater than zero")
+	}
+
+	if input.UsageLimit != nil {
+		v.Check(*input.UsageLimit > 0, "usage_limit", "must be greater than zero")
+	}
+
+	if input.UsageLimitPerCustomer != nil {
+		v.Check(*input.UsageLimitPerCustomer > 0, "usage_limit_per_customer", "must be greater than zero")
+	}
+
+	rules := []PromotionCurrencyRuleInput{}
+	targets := []PromotionTargetInput{}
+	tiers := []PromotionTierInput{}
+
+	if input.CurrencyRules != nil {
+		rules = *input.CurrencyRules
+	}
+
+	if input.Targets != nil {
+		targets = *input.Targets
+	}
+
+	if input.Tiers != nil {
+		tiers = *input.Tiers
+		v.Check(len(tiers) > 0, "tiers", "a tiered promotion needs at least one tier")
+	}
+
+	validatePromotionRules(v, rules, targets, tiers)
+
+	if input.CustomerGroupIds != nil {
+		validateCustomerGroupIds(v, *input.CustomerGroupIds)
+	}
+}
+
+func (svc *PromotionService) CreatePromotion(ctx context.Context, input *CreatePromotionInput) (*PromotionDTO, error) {
+	tx, err := svc.db.BeginTx(ctx, nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer tx.Rollback()
+
+	promotion := &model.PromotionRecord{
+		Description:           input.Description,
+		Type:                  input.Type,
+		IsAutomatic:           input.IsAutomatic,
+		IsCombinable:          true,
+		UsageLimit:            input.UsageLimit,
+		UsageLimitPerCustomer: input.UsageLimitPerCustomer,
+		StartsAt:              input.StartsAt,
+		EndsAt:                input.EndsAt,
+		IsActive:              true,
+	}
+
+	if !input.IsAutomatic {
+		promotion.Code = &input.Code
+	}
+
+	switch input.Type {
+	case consts.PromotionTypePercentage:
+		promotion.Percentage = input.Percentage
+	case consts.PromotionTypeBuyXGetY:
+		promotion.BuyQuantity = input.BuyQuantity
+		promotion.GetQuantity = input.GetQuantity
+		promotion.Percentage = input.Percentage
+
+		// the extra units are free unless said otherwise
+		if promotion.Percentage == nil {
+			free := float32(100)
+			promotion.Percentage = &free
+		}
+	}
+
+	if input.Priority != nil {
+		promotion.Priority = *input.Priority
+	}
+
+	if input.IsCombinable != nil {
+		promotion.IsCombinable = *input.IsCombinable
+	}
+
+	if input.IsActive != nil {
+		promotion.IsActive = *input.IsActive
+	}
+
+	promotion, err = svc.models.PromotionModel.Insert(ctx, tx, promotion)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var tiers []PromotionTierInput
+
+	if input.Type == consts.PromotionTypeTiered {
+		tiers = input.Tiers
+	}
+
+	err = svc.replacePromotionRules(ctx, tx, promotion.Id, input.CurrencyRules, input.Targets, tiers)
+
+	if err != nil {
+		return nil, err
+	}
+
+	err = svc.replacePromotionCustomerGroups(ctx, tx, promotion.Id, input.CustomerGroupIds)
+
+	if err != nil {
+		return nil, err
+	}
+
+	dto, err := svc.buildPromotionDTO(ctx, tx, promotion)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return dto, nil
+}
+
+// replacePromotionRules swaps the currency rules, the targets and the tiers of the promotion, nil leaves them untouched
+func (svc *PromotionService) replacePromotionRules(ctx context.Context, conn sqldb.Connection, promotionId string, rules []PromotionCurrencyRuleInput, targets []PromotionTargetInput, tiers []PromotionTierInput) error {
+	if rules != nil {
+		err := svc.models.PromotionCurrencyRuleModel.DeleteAllByPromotionId(ctx, conn, promotionId)
+
+		if err != nil {
+			return err
+		}
+
+		for _, rule := range rules {
+			_, err := svc.models.PromotionCurrencyRuleModel.Insert(ctx, conn, &model.PromotionCurrencyRuleRecord{PromotionId: promotionId, CurrencyCode: rule.CurrencyCode, Amount: decimalMoney(rule.Amount, rule.CurrencyCode),
+				MinSubtotal: decimalMoney(rule.MinSubtotal, rule.CurrencyCode)})
+
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	if targets != nil {
+		err := svc.models.PromotionTargetModel.DeleteAllByPromotionId(ctx, conn, promotionId)
+
+		if err != nil {
+			return err
+		}
+
+		for _, target := range targets {
+			_, err := svc.models.PromotionTargetModel.Insert(ctx, conn, &model.PromotionTargetRecord{PromotionId: promotionId, TargetType: target.TargetType, TargetId: target.TargetId})
+
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	if tiers != nil {
+		err := svc.models.PromotionTierModel.DeleteAllByPromotionId(ctx, conn, promotionId)
+
+		if err != nil {
+			return err
+		}
+
+		for _, tier := range tiers {
+			_, err := svc.models.PromotionTierModel.Insert(ctx, conn, &model.PromotionTierRecord{PromotionId: promotionId, MinQuantity: tier.MinQuantity, Percentage: tier.Percentage})
+
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// replacePromotionCustomerGroups swaps the groups the promotion is restricted to
+func (svc *PromotionService) replacePromotionCustomerGroups(ctx context.Context, conn sqldb.Connection, promotionId string, groupIds []string) error {
+	err := svc.models.PromotionCustomerGroupModel.DeleteAllByPromotionId(ctx, conn, promotionId)
+
+	if err != nil {
+		return err
+	}
+
+	for _, groupId := range groupIds {
+		err := svc.models.PromotionCustomerGroupModel.Insert(ctx, conn, promotionId, groupId)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (svc *PromotionService) buildPromotionDTO(ctx context.Context, conn sqldb.Connection, promotion *model.PromotionRecord) (*PromotionDTO, error) {
+	dtos, err := svc.buildPromotionDTOs(ctx, conn, []*model.PromotionRecord{promotion})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return dtos[0], nil
+}
+
+func (svc *PromotionService) buildPromotionDTOs(ctx context.Context, conn sqldb.Connection, promotions []*model.PromotionRecord) ([]*PromotionDTO, error) {
+	promotionIds := []string{}
+
+	for _, promotion := range promotions {
+		promotionIds = append(promotionIds, promotion.Id)
+	}
+
+	rulesMap, err := svc.models.PromotionCurrencyRuleModel.FindAllByPromotionIds(ctx, conn, promotionIds)
+
+	if err != nil {
+		return nil, err
+	}
+
+	targetsMap, err := svc.models.PromotionTargetModel.FindAllByPromotionIds(ctx, conn, promotionIds)
+
+	if err != nil {
+		return nil, err
+	}
+
+	tiersMap, err := svc.models.PromotionTierModel.FindAllByPromotionIds(ctx, conn, promotionIds)
+
+	if err != nil {
+		return nil, err
+	}
+
+	groupIdsMap, err := svc.models.PromotionCustomerGroupModel.FindAllByPromotionIds(ctx, conn, promotionIds)
+
+	if err != nil {
+		return nil, err
+	}
+
+	dtos := []*PromotionDTO{}
+
+	for _, promotion := range promotions {
+		dto := &PromotionDTO{PromotionRecord: promotion, CurrencyRules: rulesMap[promotion.Id], Targets: targetsMap[promotion.Id], Tiers: tiersMap[promotion.Id],
+			CustomerGroupIds: groupIdsMap[promotion.Id]}
+
+		if dto.CurrencyRules == nil {
+			dto.CurrencyRules = []*model.PromotionCurrencyRuleRecord{}
+		}
+
+		if dto.Targets == nil {
+			dto.Targets = []*model.PromotionTargetRecord{}
+		}
+
+		if dto.Tiers == nil {
+			dto.Tiers = []*model.PromotionTierRecord{}
+		}
+
+		if dto.CustomerGroupIds == nil {
+			dto.CustomerGroupIds = []string{}
+		}
+
+		dtos = append(dtos, dto)
+	}
+
+	return dtos, nil
+}
+
+func (svc *PromotionService) GetPromotion(ctx context.Context, id string) (*PromotionDTO, error) {
+	promotion, err := svc.models.PromotionModel.FindById(ctx, svc.db, id)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return svc.buildPromotionDTO(ctx, svc.db, promotion)
+}
+
+type PromotionListingOptions struct {
+	Page     uint
+	PageSize uint
+}
+
+func (svc *PromotionService) ListPromotions(ctx context.Context, opt PromotionListingOptions) ([]*PromotionDTO, int, error) {
+	count, err := svc.models.PromotionModel.Count(ctx, svc.db)
+
+	if err != nil {
+		return nil, 0, err
+	}
+
+	promotions, err := svc.models.PromotionModel.FindAll(ctx, svc.db, opt.PageSize, (opt.Page-1)*opt.PageSize)
+
+	if err != nil {
+		return nil, 0, err
+	}
+
+	dtos, err := svc.buildPromotionDTOs(ctx, svc.db, promotions)
+
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return dtos, count, nil
+}
+
+func (svc *PromotionService) UpdatePromotion(ctx context.Context, id string, input *UpdatePromotionInput) (*PromotionDTO, error) {
+	tx, err := svc.db.BeginTx(ctx, nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer tx.Rollback()
+
+	promotion, err := svc.models.PromotionModel.FindById(ctx, tx, id)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if input.Description != nil {
+		promotion.Description = input.Description
+	}
+
+	if input.Percentage != nil && validator.In(promotion.Type, consts.PromotionTypePercentage, consts.PromotionTypeBuyXGetY) {
+		promotion.Percentage = input.Percentage
+	}
+
+	if promotion.Type == consts.PromotionTypeBuyXGetY {
+		if input.BuyQuantity != nil {
+			promotion.BuyQuantity = input.BuyQuantity
+		}
+
+		if input.GetQuantity != nil {
+			promotion.GetQuantity = input.GetQuantity
+		}
+	}
+
+	if input.Priority != nil {
+		promotion.Priority = *input.Priority
+	}
+
+	if input.IsCombinable != nil {
+		promotion.IsCombinable = *input.IsCombinable
+	}
+
+	if input.UsageLimit != nil {
+		promotion.UsageLimit = input.UsageLimit
+	}
+
+	if input.UsageLimitPerCustomer != nil {
+		promotion.UsageLimitPerCustomer = input.UsageLimitPerCustomer
+	}
+
+	if input.StartsAt != nil {
+		promotion.StartsAt = input.StartsAt
+	}
+
+	if input.EndsAt != nil {
+		promotion.EndsAt = input.EndsAt
+	}
+
+	if input.IsActive != nil {
+		promotion.IsActive = *input.IsActive
+	}
+
+	promotion, err = svc.models.PromotionModel.Update(ctx, tx, promotion)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var rules []PromotionCurrencyRuleInput
+	var targets []PromotionTargetInput
+	var tiers []PromotionTierInput
+
+	if input.CurrencyRules != nil {
+		rules = *input.CurrencyRules
+	}
+
+	if input.Targets != nil {
+		targets = *input.Targets
+	}
+
+	if input.Tiers != nil && promotion.Type == consts.PromotionTypeTiered {
+		tiers = *input.Tiers
+	}
+
+	err = svc.replacePromotionRules(ctx, tx, promotion.Id, rules, targets, tiers)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if input.CustomerGroupIds != nil {
+		err = svc.replacePromotionCustomerGroups(ctx, tx, promotion.Id, *input.CustomerGroupIds)
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	dto, err := svc.buildPromotionDTO(ctx, tx, promotion)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return dto, nil
+}
+
+func (svc *PromotionService) DeletePromotion(ctx context.Context, id string) error {
+	return svc.models.PromotionModel.SoftDelete(ctx, svc.db, id)
+}
+
+// isPromotionError tells whether the error explains why a promotion can't be used, as opposed to a failure
+func isPromotionError(err error) bool {
+	return errors.Is(err, ErrPromotionNotFound) || errors.Is(err, ErrPromotionNotApplicable) || errors.Is(err, ErrPromotionUsageLimitReached)
+}
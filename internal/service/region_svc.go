@@ -0,0 +1,304 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"ecom-backend/internal/model"
+	"ecom-backend/internal/validator"
+	"ecom-backend/pkg/sqldb"
+	"errors"
+	"strings"
+)
+
+var ErrCountryNotInRegion = errors.New("country is not part of the region")
+
+// RegionService manages the regions a customer can shop in. A region decides the currency the customer is charged in,
+// whether the cart estimates the taxes and which payment providers can be used.
+type RegionService struct {
+	db          *sql.DB
+	models      *model.Models
+	providerIds []string // ids of the registered payment providers
+}
+
+func NewRegionService(db *sql.DB, models *model.Models, providers []PaymentProvider) *RegionService {
+	providerIds := []string{}
+
+	for _, provider := range providers {
+		providerIds = append(providerIds, provider.Id())
+	}
+
+	return &RegionService{db: db, models: models, providerIds: providerIds}
+}
+
+type RegionDTO struct {
+	*model.RegionRecord
+	Countries        []string `json:"countries"`
+	PaymentProviders []string `json:"payment_providers"`
+}
+
+// HasCountry reports whether the country is part of the region
+func (region *RegionDTO) HasCountry(countryCode string) bool {
+	return validator.In(strings.ToLower(countryCode), region.Countries...)
+}
+
+// HasPaymentProvider reports whether the customers of the region can pay with the provider
+func (region *RegionDTO) HasPaymentProvider(providerId string) bool {
+	return validator.In(providerId, region.PaymentProviders...)
+}
+
+func validateRegionCountries(v *validator.Validator, countries []string) {
+	v.Check(len(countries) > 0, "countries", "must contain at least one country")
+
+	for _, countryCode := range countries {
+		v.Check(len(countryCode) == 2, "countries", "must contain 2 letter ISO codes")
+	}
+
+	v.Check(validator.Unique(countries), "countries", "must not contain the same country twice")
+}
+
+func validateRegionPaymentProviders(v *validator.Validator, providerIds []string) {
+	v.Check(len(providerIds) > 0, "payment_providers", "must contain at least one provider")
+	v.Check(validator.Unique(providerIds), "payment_providers", "must not contain the same provider twice")
+}
+
+type CreateRegionInput struct {
+	Name             string   `json:"name"`
+	CurrencyCode     string   `json:"currency_code"`
+	AutomaticTaxes   *bool    `json:"automatic_taxes"` // optional, on by default
+	Countries        []string `json:"countries"`
+	PaymentProviders []string `json:"payment_providers"`
+}
+
+func (input *CreateRegionInput) Validate(v *validator.Validator) {
+	v.Check(input.Name != "", "name", "must be provided")
+	v.Check(input.CurrencyCode != "", "currency_code", "must be provided")
+	validateRegionCountries(v, input.Countries)
+	validateRegionPaymentProviders(v, input.PaymentProviders)
+}
+
+type UpdateRegionInput struct {
+	Name             *string   `json:"name"`
+	CurrencyCode     *string   `json:"currency_code"`
+	AutomaticTaxes   *bool     `json:"automatic_taxes"`
+	Countries        *[]string `json:"countries"`
+	PaymentProviders *[]string `json:"payment_providers"`
+}
+
+func (input *UpdateRegionInput) Validate(v *validator.Validator) {
+	if input.Name != nil {
+		v.Check(*input.Name != "", "name", "must not be empty")
+	}
+
+	if input.CurrencyCode != nil {
+		v.Check(*input.CurrencyCode != "", "currency_code", "must not be empty")
+	}
+
+	if input.Countries != nil {
+		validateRegionCountries(v, *input.Countries)
+	}
+
+	if input.PaymentProviders != nil {
+		validateRegionPaymentProviders(v, *input.PaymentProviders)
+	}
+}
+
+func (svc *RegionService) CreateRegion(ctx context.Context, input *CreateRegionInput) (*RegionDTO, error) {
+	tx, err := svc.db.BeginTx(ctx, nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer tx.Rollback()
+
+	region := &model.RegionRecord{Name: input.Name, CurrencyCode: strings.ToLower(input.CurrencyCode), AutomaticTaxes: true}
+
+	if input.AutomaticTaxes != nil {
+		region.AutomaticTaxes = *input.AutomaticTaxes
+	}
+
+	region, err = svc.models.RegionModel.Insert(ctx, tx, region)
+
+	if err != nil {
+		return nil, err
+	}
+
+	err = svc.replaceRegionSettings(ctx, tx, region.Id, input.Countries, input.PaymentProviders)
+
+	if err != nil {
+		return nil, err
+	}
+
+	dto, err := svc.buildRegionDTO(ctx, tx, region)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return dto, nil
+}
+
+// replaceRegionSettings swaps the countries and the payment providers of the region, nil leaves them untouched
+func (svc *RegionService) replaceRegionSettings(ctx context.Context, conn sqldb.Connection, regionId string, countries []string, providerIds []string) error {
+	if countries != nil {
+		err := svc.models.RegionModel.ReplaceCountries(ctx, conn, regionId, normalizeCountryCodes(countries))
+
+		if err != nil {
+			return err
+		}
+	}
+
+	if providerIds != nil {
+		for _, providerId := range providerIds {
+			if !validator.In(providerId, svc.providerIds...) {
+				return ErrPaymentProviderNotFound
+			}
+		}
+
+		err := svc.models.RegionModel.ReplacePaymentProviders(ctx, conn, regionId, providerIds)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (svc *RegionService) buildRegionDTO(ctx context.Context, conn sqldb.Connection, region *model.RegionRecord) (*RegionDTO, error) {
+	dtos, err := svc.buildRegionDTOs(ctx, conn, []*model.RegionRecord{region})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return dtos[0], nil
+}
+
+func (svc *RegionService) buildRegionDTOs(ctx context.Context, conn sqldb.Connection, regions []*model.RegionRecord) ([]*RegionDTO, error) {
+	regionIds := []string{}
+
+	for _, region := range regions {
+		regionIds = append(regionIds, region.Id)
+	}
+
+	countriesMap, err := svc.models.RegionModel.FindCountriesByRegionIds(ctx, conn, regionIds)
+
+	if err != nil {
+		return nil, err
+	}
+
+	providersMap, err := svc.models.RegionModel.FindPaymentProvidersByRegionIds(ctx, conn, regionIds)
+
+	if err != nil {
+		return nil, err
+	}
+
+	dtos := []*RegionDTO{}
+
+	for _, region := range regions {
+		dto := &RegionDTO{RegionRecord: region, Countries: countriesMap[region.Id], PaymentProviders: providersMap[region.Id]}
+
+		if dto.Countries == nil {
+			dto.Countries = []string{}
+		}
+
+		if dto.PaymentProviders == nil {
+			dto.PaymentProviders = []string{}
+		}
+
+		dtos = append(dtos, dto)
+	}
+
+	return dtos, nil
+}
+
+func (svc *RegionService) ListRegions(ctx context.Context) ([]*RegionDTO, error) {
+	regions, err := svc.models.RegionModel.FindAll(ctx, svc.db)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return svc.buildRegionDTOs(ctx, svc.db, regions)
+}
+
+func (svc *RegionService) GetRegion(ctx context.Context, id string) (*RegionDTO, error) {
+	region, err := svc.models.RegionModel.FindById(ctx, svc.db, id)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return svc.buildRegionDTO(ctx, svc.db, region)
+}
+
+func (svc *RegionService) UpdateRegion(ctx context.Context, id string, input *UpdateRegionInput) (*RegionDTO, error) {
+	tx, err := svc.db.BeginTx(ctx, nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer tx.Rollback()
+
+	region, err := svc.models.RegionModel.FindById(ctx, tx, id)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if input.Name != nil {
+		region.Name = *input.Name
+	}
+
+	if input.CurrencyCode != nil {
+		region.CurrencyCode = strings.ToLower(*input.CurrencyCode)
+	}
+
+	if input.AutomaticTaxes != nil {
+		region.AutomaticTaxes = *input.AutomaticTaxes
+	}
+
+	region, err = svc.models.RegionModel.Update(ctx, tx, region)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var countries, providerIds []string
+
+	if input.Countries != nil {
+		countries = *input.Countries
+	}
+
+	if input.PaymentProviders != nil {
+		providerIds = *input.PaymentProviders
+	}
+
+	err = svc.replaceRegionSettings(ctx, tx, region.Id, countries, providerIds)
+
+	if err != nil {
+		return nil, err
+	}
+
+	dto, err := svc.buildRegionDTO(ctx, tx, region)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return dto, nil
+}
+
+func (svc *RegionService) DeleteRegion(ctx context.Context, id string) error {
+	return svc.models.RegionModel.Delete(ctx, svc.db, id)
+}
@@ -0,0 +1,941 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"ecom-backend/internal/consts"
+	"ecom-backend/internal/model"
+	"ecom-backend/internal/money"
+	"ecom-backend/internal/validator"
+	"ecom-backend/pkg/sqldb"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+var (
+	ErrOrderNotReturnable       = errors.New("only delivered orders can be returned")
+	ErrInvalidReturnQuantity    = errors.New("return quantity exceeds the quantity left to return")
+	ErrInvalidReturnTransition  = errors.New("invalid return status transition")
+	ErrReturnItemNotFound       = errors.New("return item not found")
+	ErrOrderLineItemNotReturned = errors.New("order line item not found in the order")
+	ErrGiftCardNotReturnable    = errors.New("gift cards can't be returned")
+)
+
+// returnStatusTransitions lists, for every status, the statuses a return can move to
+var returnStatusTransitions = map[string][]string{
+	consts.ReturnStatusRequested: {consts.ReturnStatusApproved, consts.ReturnStatusRejected, consts.ReturnStatusCancelled},
+	consts.ReturnStatusApproved:  {consts.ReturnStatusReceived},
+	consts.ReturnStatusReceived:  {consts.ReturnStatusRefunding, consts.ReturnStatusRefunded},
+	consts.ReturnStatusRefunding: {consts.ReturnStatusRefunded},
+	consts.ReturnStatusRejected:  {},
+	consts.ReturnStatusRefunded:  {},
+	consts.ReturnStatusCancelled: {},
+}
+
+var returnReasons = []string{
+	consts.ReturnReasonDamaged,
+	consts.ReturnReasonDefective,
+	consts.ReturnReasonWrongItem,
+	consts.ReturnReasonNotAsDescribed,
+	consts.ReturnReasonNoLongerNeeded,
+	consts.ReturnReasonOther,
+}
+
+// ReturnService handles the returns of delivered orders: the customer requests one, the admins approve it, receive the
+// items back in stock and refund them
+type ReturnService struct {
+	db         *sql.DB
+	models     *model.Models
+	paymentSvc *PaymentService
+}
+
+func NewReturnService(db *sql.DB, models *model.Models, paymentSvc *PaymentService) *ReturnService {
+	return &ReturnService{db: db, models: models, paymentSvc: paymentSvc}
+}
+
+type ReturnDTO struct {
+	Id             string                     `json:"id"`
+	OrderId        string                     `json:"order_id"`
+	Status         string                     `json:"status"`
+	Note           *string                    `json:"note"`
+	LocationId     *string                    `json:"location_id"`
+	Items          []*model.ReturnItemRecord  `json:"items"`
+	ShippingRefund money.Money                `json:"shipping_refund"`
+	RefundTotal    money.Money                `json:"refund_total"`
+	PaymentRefund  money.Money                `json:"payment_refund"`
+	Events         []*model.ReturnEventRecord `json:"events"`
+	ApprovedAt     *time.Time                 `json:"approved_at"`
+	RejectedAt     *time.Time                 `json:"rejected_at"`
+	ReceivedAt     *time.Time                 `json:"received_at"`
+	RefundedAt     *time.Time                 `json:"refunded_at"`
+	CancelledAt    *time.Time                 `json:"cancelled_at"`
+	CreatedAt      time.Time                  `json:"created_at"`
+	UpdatedAt      time.Time                  `json:"updated_at"`
+}
+
+type RequestReturnItemInput struct {
+	OrderLineItemId string  `json:"order_line_item_id"`
+	Quantity        int     `json:"quantity"`
+	Reason          string  `json:"reason"`
+	Note            *string `json:"note"`
+}
+
+type RequestReturnInput struct {
+	Items []RequestReturnItemInput `json:"items"`
+	Note  *string                  `json:"note"`
+}
+
+func (input *RequestReturnInput) Validate(v *validator.Validator) {
+	v.Check(len(input.Items) > 0, "items", "must contain at least one item")
+
+	lineItemIds := []string{}
+
+	for i, item := range input.Items {
+		key := "items." + strconv.Itoa(i)
+
+		v.Check(validator.IsValidUUID(item.OrderLineItemId), key+".order_line_item_id", "must be a valid UUID")
+		v.Check(item.Quantity > 0, key+".quantity", "must be greater than zero")
+		v.Check(validator.In(item.Reason, returnReasons...), key+".reason", "invalid reason")
+
+		lineItemIds = append(lineItemIds, item.OrderLineItemId)
+	}
+
+	v.Check(validator.Unique(lineItemIds), "items", "must not contain the same line twice")
+}
+
+// ReturnTransitionInput is the note left when approving, rejecting or cancelling a return
+type ReturnTransitionInput struct {
+	Note *string `json:"note"`
+}
+
+type ReceiveReturnItemInput struct {
+	ReturnItemId string `json:"return_item_id"`
+	Quantity     int    `json:"quantity"`
+	Restock      *bool  `json:"restock"` // put the received units back in stock, true when missing
+}
+
+type ReceiveReturnInput struct {
+	LocationId *string                  `json:"location_id"` // optional, the units go back where they were sold from when missing
+	Items      []ReceiveReturnItemInput `json:"items"`       // optional, every unit is received and restocked when missing
+	Note       *string                  `json:"note"`
+}
+
+func (input *ReceiveReturnInput) Validate(v *validator.Validator) {
+	if input.LocationId != nil {
+		v.Check(validator.IsValidUUID(*input.LocationId), "location_id", "must be a valid UUID")
+	}
+
+	returnItemIds := []string{}
+
+	for i, item := range input.Items {
+		key := "items." + strconv.Itoa(i)
+
+		v.Check(validator.IsValidUUID(item.ReturnItemId), key+".return_item_id", "must be a valid UUID")
+		v.Check(item.Quantity >= 0, key+".quantity", "should not be negative")
+
+		returnItemIds = append(returnItemIds, item.ReturnItemId)
+	}
+
+	v.Check(validator.Unique(returnItemIds), "items", "must not contain the same item twice")
+}
+
+type RefundReturnItemInput struct {
+	ReturnItemId string        `json:"return_item_id"`
+	Amount       money.Decimal `json:"amount"`
+}
+
+type RefundReturnInput struct {
+	Items          []RefundReturnItemInput `json:"items"`           // optional, the received units are refunded at the price paid when missing
+	ShippingAmount *money.Decimal          `json:"shipping_amount"` // optional, the part of the shipping given back
+	Note           *string                 `json:"note"`
+}
+
+func (input *RefundReturnInput) Validate(v *validator.Validator) {
+	returnItemIds := []string{}
+
+	for i, item := range input.Items {
+		key := "items." + strconv.Itoa(i)
+
+		v.Check(validator.IsValidUUID(item.ReturnItemId), key+".return_item_id", "must be a valid UUID")
+		v.Check(item.Amount.Sign() >= 0, key+".amount", "should not be negative")
+
+		returnItemIds = append(returnItemIds, item.ReturnItemId)
+	}
+
+	v.Check(validator.Unique(returnItemIds), "items", "must not contain the same item twice")
+
+	if input.ShippingAmount != nil {
+		v.Check(input.ShippingAmount.Sign() >= 0, "shipping_amount", "should not be negative")
+	}
+}
+
+type ReturnListingOptions struct {
+	Page     uint
+	PageSize uint
+	Status   *string // only the returns in this status when set
+}
+
+// RequestReturn creates a return for lines of a delivered order of the client. The quantity of a line can't exceed
+// what is left once the returns that weren't rejected or cancelled are counted.
+func (svc *ReturnService) RequestReturn(ctx context.Context, userIdentifier string, userId *string, orderId string, input *RequestReturnInput) (*ReturnDTO, error) {
+	tx, err := svc.db.BeginTx(ctx, nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer tx.Rollback()
+
+	// the order is locked so two requests can't return the same units
+	order, err := svc.models.OrderModel.FindByIdForUpdate(ctx, tx, orderId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if order.UserIdentifier != userIdentifier {
+		return nil, ErrUnauthorizedRequest
+	}
+
+	if order.Status != consts.OrderStatusDelivered {
+		return nil, ErrOrderNotReturnable
+	}
+
+	lineItemsMap, err := svc.models.OrderLineItemModel.FindAllByOrderIds(ctx, tx, []string{order.Id})
+
+	if err != nil {
+		return nil, err
+	}
+
+	returnedQuantities, err := svc.models.ReturnItemModel.SumReturnedQuantitiesByOrderId(ctx, tx, order.Id)
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, item := range input.Items {
+		lineItem := findOrderLineItem(lineItemsMap[order.Id], item.OrderLineItemId)
+
+		if lineItem == nil {
+			return nil, fmt.Errorf("%w: %s", ErrOrderLineItemNotReturned, item.OrderLineItemId)
+		}
+
+		// the gift cards issued for the line were sent to the customer and can already be spent
+		if lineItem.IsGiftCard {
+			return nil, fmt.Errorf("%w: %s", ErrGiftCardNotReturnable, item.OrderLineItemId)
+		}
+
+		quantity := lineItem.Quantity - returnedQuantities[lineItem.Id]
+
+		if item.Quantity > quantity {
+			return nil, fmt.Errorf("%w: %d left for %s", ErrInvalidReturnQuantity, quantity, item.OrderLineItemId)
+		}
+	}
+
+	record, err := svc.models.ReturnModel.Insert(ctx, tx, &model.ReturnRecord{OrderId: order.Id, CurrencyCode: order.CurrencyCode, Note: input.Note})
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, item := range input.Items {
+		_, err := svc.models.ReturnItemModel.Insert(ctx, tx, &model.ReturnItemRecord{
+			ReturnId:        record.Id,
+			OrderLineItemId: item.OrderLineItemId,
+			Quantity:        item.Quantity,
+			Reason:          item.Reason,
+			Note:            item.Note,
+		})
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	_, err = svc.models.ReturnEventModel.Insert(ctx, tx, &model.ReturnEventRecord{ReturnId: record.Id, ToStatus: record.Status, ActorUserId: userId, Note: input.Note})
+
+	if err != nil {
+		return nil, err
+	}
+
+	returns, err := svc.buildReturnDTOs(ctx, tx, []*model.ReturnRecord{record})
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return returns[0], nil
+}
+
+// ListClientOrderReturns returns the returns of the order only if it was placed by the client
+func (svc *ReturnService) ListClientOrderReturns(ctx context.Context, userIdentifier string, orderId string) ([]*ReturnDTO, error) {
+	order, err := svc.models.OrderModel.FindById(ctx, svc.db, orderId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if order.UserIdentifier != userIdentifier {
+		return nil, ErrUnauthorizedRequest
+	}
+
+	records, err := svc.models.ReturnModel.FindAllByOrderId(ctx, svc.db, orderId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return svc.buildReturnDTOs(ctx, svc.db, records)
+}
+
+// CancelClientReturn withdraws a return of the client that wasn't approved or rejected yet
+func (svc *ReturnService) CancelClientReturn(ctx context.Context, userIdentifier string, userId *string, orderId string, id string, input *ReturnTransitionInput) (*ReturnDTO, error) {
+	tx, err := svc.db.BeginTx(ctx, nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer tx.Rollback()
+
+	record, err := svc.models.ReturnModel.FindByIdForUpdate(ctx, tx, id)
+
+	if err != nil {
+		return nil, err
+	}
+
+	order, err := svc.models.OrderModel.FindById(ctx, tx, record.OrderId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if order.Id != orderId {
+		return nil, model.ErrRecordNotFound
+	}
+
+	if order.UserIdentifier != userIdentifier {
+		return nil, ErrUnauthorizedRequest
+	}
+
+	return svc.commitTransition(ctx, tx, record, consts.ReturnStatusCancelled, userId, input.Note)
+}
+
+func (svc *ReturnService) ListReturns(ctx context.Context, opt ReturnListingOptions) ([]*ReturnDTO, int, error) {
+	totalCount, err := svc.models.ReturnModel.Count(ctx, svc.db, opt.Status)
+
+	if err != nil {
+		return nil, 0, err
+	}
+
+	records, err := svc.models.ReturnModel.FindAll(ctx, svc.db, opt.Status, opt.PageSize, (opt.Page-1)*opt.PageSize)
+
+	if err != nil {
+		return nil, 0, err
+	}
+
+	returns, err := svc.buildReturnDTOs(ctx, svc.db, records)
+
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return returns, totalCount, nil
+}
+
+func (svc *ReturnService) GetReturn(ctx context.Context, id string) (*ReturnDTO, error) {
+	record, err := svc.models.ReturnModel.FindById(ctx, svc.db, id)
+
+	if err != nil {
+		return nil, err
+	}
+
+	returns, err := svc.buildReturnDTOs(ctx, svc.db, []*model.ReturnRecord{record})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return returns[0], nil
+}
+
+// ApproveReturn accepts the return, the customer can then send the items back
+func (svc *ReturnService) ApproveReturn(ctx context.Context, id string, actorUserId *string, input *ReturnTransitionInput) (*ReturnDTO, error) {
+	return svc.transitionReturn(ctx, id, consts.ReturnStatusApproved, actorUserId, input.Note)
+}
+
+func (svc *ReturnService) RejectReturn(ctx context.Context, id string, actorUserId *string, input *ReturnTransitionInput) (*ReturnDTO, error) {
+	return svc.transitionReturn(ctx, id, consts.ReturnStatusRejected, actorUserId, input.Note)
+}
+
+func (svc *ReturnService) transitionReturn(ctx context.Context, id string, toStatus string, actorUserId *string, note *string) (*ReturnDTO, error) {
+	tx, err := svc.db.BeginTx(ctx, nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer tx.Rollback()
+
+	record, err := svc.models.ReturnModel.FindByIdForUpdate(ctx, tx, id)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return svc.commitTransition(ctx, tx, record, toStatus, actorUserId, note)
+}
+
+// commitTransition moves the locked return to the status, commits the transaction and returns the updated return
+func (svc *ReturnService) commitTransition(ctx context.Context, tx *sql.Tx, record *model.ReturnRecord, toStatus string, actorUserId *string, note *string) (*ReturnDTO, error) {
+	record, err := svc.updateReturnStatus(ctx, tx, record, toStatus, actorUserId, note)
+
+	if err != nil {
+		return nil, err
+	}
+
+	returns, err := svc.buildReturnDTOs(ctx, tx, []*model.ReturnRecord{record})
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return returns[0], nil
+}
+
+// ReceiveReturn records the units that came back and puts the ones in a sellable state back in stock
+func (svc *ReturnService) ReceiveReturn(ctx context.Context, id string, actorUserId *string, input *ReceiveReturnInput) (*ReturnDTO, error) {
+	tx, err := svc.db.BeginTx(ctx, nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer tx.Rollback()
+
+	record, err := svc.models.ReturnModel.FindByIdForUpdate(ctx, tx, id)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if !validator.In(consts.ReturnStatusReceived, returnStatusTransitions[record.Status]...) {
+		return nil, fmt.Errorf("%w from %s to %s", ErrInvalidReturnTransition, record.Status, consts.ReturnStatusReceived)
+	}
+
+	if input.LocationId != nil {
+		_, err := svc.models.StockLocationModel.FindById(ctx, tx, *input.LocationId)
+
+		if err != nil {
+			if errors.Is(err, model.ErrRecordNotFound) {
+				return nil, model.ErrStockLocationNotFound
+			}
+			return nil, err
+		}
+	}
+
+	itemsMap, err := svc.models.ReturnItemModel.FindAllByReturnIds(ctx, tx, []string{record.Id})
+
+	if err != nil {
+		return nil, err
+	}
+
+	items := itemsMap[record.Id]
+
+	if input.Items == nil {
+		for _, item := range items {
+			item.ReceivedQuantity = item.Quantity
+			item.RestockedQuantity = item.Quantity
+		}
+	} else {
+		for _, itemInput := range input.Items {
+			item := findReturnItem(items, itemInput.ReturnItemId)
+
+			if item == nil {
+				return nil, fmt.Errorf("%w: %s", ErrReturnItemNotFound, itemInput.ReturnItemId)
+			}
+
+			if itemInput.Quantity > item.Quantity {
+				return nil, fmt.Errorf("%w: %d were returned for %s", ErrInvalidReturnQuantity, item.Quantity, item.Id)
+			}
+
+			item.ReceivedQuantity = itemInput.Quantity
+			item.RestockedQuantity = itemInput.Quantity
+
+			if itemInput.Restock != nil && !*itemInput.Restock {
+				item.RestockedQuantity = 0
+			}
+		}
+	}
+
+	lineItemsMap, err := svc.models.OrderLineItemModel.FindAllByOrderIds(ctx, tx, []string{record.OrderId})
+
+	if err != nil {
+		return nil, err
+	}
+
+	variantIds := []string{}
+
+	for _, item := range items {
+		lineItem := findOrderLineItem(lineItemsMap[record.OrderId], item.OrderLineItemId)
+
+		if lineItem.VariantId != nil && item.RestockedQuantity > 0 {
+			variantIds = append(variantIds, *lineItem.VariantId)
+		}
+	}
+
+	// locked in the same order as the checkouts do
+	_, err = svc.models.ProductVariantModel.FindAllByIdsForUpdate(ctx, tx, variantIds)
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, item := range items {
+		lineItem := findOrderLineItem(lineItemsMap[record.OrderId], item.OrderLineItemId)
+
+		// the variant was deleted in the meantime, there's nothing to restock
+		if lineItem.VariantId == nil {
+			item.RestockedQuantity = 0
+		}
+
+		if item.RestockedQuantity > 0 {
+			var locationId string
+
+			if input.LocationId != nil {
+				locationId = *input.LocationId
+			} else {
+				locationId, err = restockLocationId(ctx, tx, svc.models, lineItem)
+
+				if err != nil {
+					return nil, err
+				}
+			}
+
+			_, err = recordStockMovement(ctx, tx, svc.models, &model.StockMovementRecord{
+				VariantId:   *lineItem.VariantId,
+				LocationId:  locationId,
+				Type:        consts.StockMovementReturn,
+				Quantity:    item.RestockedQuantity,
+				OrderId:     &record.OrderId,
+				ActorUserId: actorUserId,
+			})
+
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		_, err := svc.models.ReturnItemModel.Update(ctx, tx, item)
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	record.LocationId = input.LocationId
+
+	return svc.commitTransition(ctx, tx, record, consts.ReturnStatusReceived, actorUserId, input.Note)
+}
+
+// RefundReturn refunds the received units and optionally part of the shipping. What the order took from the store
+// credit and the gift cards is given back first and the return stays refunding until the payment provider refunds the
+// rest, the provider being called once that is committed so no lock is held while waiting for it. A return left
+// refunding by a failed provider call is completed by refunding it again, with the amounts and the provider refund
+// claimed the first time.
+func (svc *ReturnService) RefundReturn(ctx context.Context, id string, actorUserId *string, input *RefundReturnInput) (*ReturnDTO, error) {
+	orderReturn, err := svc.settleReturnRefund(ctx, id, actorUserId, input)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if orderReturn.Status != consts.ReturnStatusRefunding {
+		return orderReturn, nil
+	}
+
+	return svc.completeReturnRefund(ctx, id, actorUserId, input.Note)
+}
+
+// settleReturnRefund sets the refunded amounts of a received return and restores the store credit and the gift cards,
+// the return is refunded when they cover everything or moves to refunding along with a pending refund of the payment
+// for the rest. A refunding return is returned as is.
+func (svc *ReturnService) settleReturnRefund(ctx context.Context, id string, actorUserId *string, input *RefundReturnInput) (*ReturnDTO, error) {
+	tx, err := svc.db.BeginTx(ctx, nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer tx.Rollback()
+
+	record, err := svc.models.ReturnModel.FindByIdForUpdate(ctx, tx, id)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if record.Status == consts.ReturnStatusRefunding {
+		returns, err := svc.buildReturnDTOs(ctx, tx, []*model.ReturnRecord{record})
+
+		if err != nil {
+			return nil, err
+		}
+
+		return returns[0], nil
+	}
+
+	if !validator.In(consts.ReturnStatusRefunded, returnStatusTransitions[record.Status]...) {
+		return nil, fmt.Errorf("%w from %s to %s", ErrInvalidReturnTransition, record.Status, consts.ReturnStatusRefunded)
+	}
+
+	order, err := svc.models.OrderModel.FindByIdForUpdate(ctx, tx, record.OrderId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	itemsMap, err := svc.models.ReturnItemModel.FindAllByReturnIds(ctx, tx, []string{record.Id})
+
+	if err != nil {
+		return nil, err
+	}
+
+	items := itemsMap[record.Id]
+
+	lineItemsMap, err := svc.models.OrderLineItemModel.FindAllByOrderIds(ctx, tx, []string{order.Id})
+
+	if err != nil {
+		return nil, err
+	}
+
+	// every received unit is refunded at the price paid unless the amounts are given
+	for _, item := range items {
+		item.RefundAmount = refundableReturnItemAmount(order, findOrderLineItem(lineItemsMap[order.Id], item.OrderLineItemId), item)
+	}
+
+	if input.Items != nil {
+		for _, item := range items {
+			item.RefundAmount = money.Zero(order.CurrencyCode)
+		}
+
+		for _, itemInput := range input.Items {
+			item := findReturnItem(items, itemInput.ReturnItemId)
+
+			if item == nil {
+				return nil, fmt.Errorf("%w: %s", ErrReturnItemNotFound, itemInput.ReturnItemId)
+			}
+
+			if !itemInput.Amount.FitsCurrency(order.CurrencyCode) {
+				return nil, ErrRefundAmountDecimals
+			}
+
+			amount := itemInput.Amount.Money(order.CurrencyCode)
+
+			if refundableReturnItemAmount(order, findOrderLineItem(lineItemsMap[order.Id], item.OrderLineItemId), item).LessThan(amount) {
+				return nil, ErrInvalidRefundAmount
+			}
+
+			item.RefundAmount = amount
+		}
+	}
+
+	shippingRefund := money.Zero(order.CurrencyCode)
+
+	if input.ShippingAmount != nil {
+		if !input.ShippingAmount.FitsCurrency(order.CurrencyCode) {
+			return nil, ErrRefundAmountDecimals
+		}
+
+		shippingRefund = input.ShippingAmount.Money(order.CurrencyCode)
+
+		refundedShipping, err := svc.models.ReturnModel.SumShippingRefundByOrderId(ctx, tx, order.Id)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if order.ShippingTotal.Sub(money.New(refundedShipping, order.CurrencyCode)).LessThan(shippingRefund) {
+			return nil, ErrInvalidRefundAmount
+		}
+	}
+
+	refundTotal := shippingRefund
+
+	for _, item := range items {
+		refundTotal = refundTotal.Add(item.RefundAmount)
+
+		_, err := svc.models.ReturnItemModel.Update(ctx, tx, item)
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	paymentRefund, err := svc.refundOrderBalances(ctx, tx, order, refundTotal)
+
+	if err != nil {
+		return nil, err
+	}
+
+	toStatus := consts.ReturnStatusRefunded
+
+	if paymentRefund.IsPositive() {
+		payment, err := svc.models.PaymentModel.FindActiveByOrderIdForUpdate(ctx, tx, order.Id)
+
+		if err != nil {
+			// nothing was captured for the order, the amount can't be refunded
+			if errors.Is(err, model.ErrRecordNotFound) {
+				return nil, ErrInvalidRefundAmount
+			}
+			return nil, err
+		}
+
+		// claimed while the return is locked so however many times the refund is completed the provider refunds it once
+		_, err = svc.paymentSvc.claimPaymentRefund(ctx, tx, payment, &record.Id, paymentRefund)
+
+		if err != nil {
+			return nil, err
+		}
+
+		toStatus = consts.ReturnStatusRefunding
+	}
+
+	record.ShippingRefund = shippingRefund
+	record.RefundTotal = refundTotal
+	record.PaymentRefund = paymentRefund
+
+	return svc.commitTransition(ctx, tx, record, toStatus, actorUserId, input.Note)
+}
+
+// completeReturnRefund has the payment provider make the refund claimed for a refunding return, outside of any
+// transaction, then settles it on the payment and marks the return as refunded. The provider is asked with the same
+// idempotency key every time so concurrent or retried calls refund the payment once.
+func (svc *ReturnService) completeReturnRefund(ctx context.Context, id string, actorUserId *string, note *string) (*ReturnDTO, error) {
+	refund, err := svc.models.PaymentRefundModel.FindByReturnId(ctx, svc.db, id)
+
+	if err != nil {
+		return nil, err
+	}
+
+	payment, err := svc.models.PaymentModel.FindById(ctx, svc.db, refund.PaymentId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if refund.Status == consts.PaymentRefundStatusPending {
+		err = svc.paymentSvc.refundProviderPayment(ctx, payment, refund)
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	tx, err := svc.db.BeginTx(ctx, nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer tx.Rollback()
+
+	record, err := svc.models.ReturnModel.FindByIdForUpdate(ctx, tx, id)
+
+	if err != nil {
+		return nil, err
+	}
+
+	// completed by a concurrent call
+	if record.Status != consts.ReturnStatusRefunding {
+		returns, err := svc.buildReturnDTOs(ctx, tx, []*model.ReturnRecord{record})
+
+		if err != nil {
+			return nil, err
+		}
+
+		return returns[0], nil
+	}
+
+	order, err := svc.models.OrderModel.FindByIdForUpdate(ctx, tx, record.OrderId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	payment, err = svc.models.PaymentModel.FindByIdForUpdate(ctx, tx, refund.PaymentId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = svc.paymentSvc.settlePaymentRefund(ctx, tx, order, payment, refund.Id, actorUserId, note)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return svc.commitTransition(ctx, tx, record, consts.ReturnStatusRefunded, actorUserId, note)
+}
+
+// refundOrderBalances gives the amount back to the store credit and the gift cards the locked order was paid with and
+// returns what is left for the payment provider to refund. The balances are restored first so a fully refunded payment
+// never leaves balances to restore twice.
+func (svc *ReturnService) refundOrderBalances(ctx context.Context, tx *sql.Tx, order *model.OrderRecord, amount money.Money) (money.Money, error) {
+	restored, err := restoreOrderStoreCredit(ctx, tx, svc.models, order, amount)
+
+	if err != nil {
+		return money.Money{}, err
+	}
+
+	amount = amount.Sub(restored)
+
+	restored, err = restoreOrderGiftCards(ctx, tx, svc.models, order, amount)
+
+	if err != nil {
+		return money.Money{}, err
+	}
+
+	return amount.Sub(restored), nil
+}
+
+// updateReturnStatus moves an already locked return to the status, stamps the transition time and records the event
+func (svc *ReturnService) updateReturnStatus(ctx context.Context, conn sqldb.Connection, record *model.ReturnRecord, toStatus string, actorUserId *string, note *string) (*model.ReturnRecord, error) {
+	fromStatus := record.Status
+
+	if !validator.In(toStatus, returnStatusTransitions[fromStatus]...) {
+		return nil, fmt.Errorf("%w from %s to %s", ErrInvalidReturnTransition, fromStatus, toStatus)
+	}
+
+	now := time.Now()
+
+	switch toStatus {
+	case consts.ReturnStatusApproved:
+		record.ApprovedAt = &now
+	case consts.ReturnStatusRejected:
+		record.RejectedAt = &now
+	case consts.ReturnStatusReceived:
+		record.ReceivedAt = &now
+	case consts.ReturnStatusRefunded:
+		record.RefundedAt = &now
+	case consts.ReturnStatusCancelled:
+		record.CancelledAt = &now
+	}
+
+	record.Status = toStatus
+
+	record, err := svc.models.ReturnModel.Update(ctx, conn, record)
+
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = svc.models.ReturnEventModel.Insert(ctx, conn, &model.ReturnEventRecord{ReturnId: record.Id, FromStatus: &fromStatus, ToStatus: toStatus, ActorUserId: actorUserId, Note: note})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return record, nil
+}
+
+// refundableReturnItemAmount returns what the customer paid for the received units of the line, rounded down so the
+// returns of a line never refund more than the line
+func refundableReturnItemAmount(order *model.OrderRecord, lineItem *model.OrderLineItemRecord, item *model.ReturnItemRecord) money.Money {
+	paid := lineItem.Subtotal.Sub(lineItem.DiscountTotal)
+
+	if !order.IsTaxInclusive {
+		paid = paid.Add(lineItem.TaxTotal)
+	}
+
+	return money.New(paid.Amount*int64(item.ReceivedQuantity)/int64(lineItem.Quantity), order.CurrencyCode)
+}
+
+func findReturnItem(items []*model.ReturnItemRecord, id string) *model.ReturnItemRecord {
+	for _, item := range items {
+		if item.Id == id {
+			return item
+		}
+	}
+
+	return nil
+}
+
+func findOrderLineItem(lineItems []*model.OrderLineItemRecord, id string) *model.OrderLineItemRecord {
+	for _, lineItem := range lineItems {
+		if lineItem.Id == id {
+			return lineItem
+		}
+	}
+
+	return nil
+}
+
+// buildReturnDTOs loads the items and the history of the returns
+func (svc *ReturnService) buildReturnDTOs(ctx context.Context, conn sqldb.Connection, records []*model.ReturnRecord) ([]*ReturnDTO, error) {
+	returnIds := []string{}
+
+	for _, record := range records {
+		returnIds = append(returnIds, record.Id)
+	}
+
+	itemsMap, err := svc.models.ReturnItemModel.FindAllByReturnIds(ctx, conn, returnIds)
+
+	if err != nil {
+		return nil, err
+	}
+
+	eventsMap, err := svc.models.ReturnEventModel.FindAllByReturnIds(ctx, conn, returnIds)
+
+	if err != nil {
+		return nil, err
+	}
+
+	returns := []*ReturnDTO{}
+
+	for _, record := range records {
+		items := itemsMap[record.Id]
+
+		if items == nil {
+			items = []*model.ReturnItemRecord{}
+		}
+
+		events := eventsMap[record.Id]
+
+		if events == nil {
+			events = []*model.ReturnEventRecord{}
+		}
+
+		returns = append(returns, &ReturnDTO{
+			Id:             record.Id,
+			OrderId:        record.OrderId,
+			Status:         record.Status,
+			Note:           record.Note,
+			LocationId:     record.LocationId,
+			Items:          items,
+			ShippingRefund: record.ShippingRefund,
+			RefundTotal:    record.RefundTotal,
+			PaymentRefund:  record.PaymentRefund,
+			Events:         events,
+			ApprovedAt:     record.ApprovedAt,
+			RejectedAt:     record.RejectedAt,
+			ReceivedAt:     record.ReceivedAt,
+			RefundedAt:     record.RefundedAt,
+			CancelledAt:    record.CancelledAt,
+			CreatedAt:      record.CreatedAt,
+			UpdatedAt:      record.UpdatedAt,
+		})
+	}
+
+	return returns, nil
+}
@@ -0,0 +1,107 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"ecom-backend/internal/model"
+	"ecom-backend/internal/validator"
+	"unicode/utf8"
+)
+
+const (
+	defaultSuggestionLimit = 5
+	maxSuggestionLimit     = 10
+	// how close a word of a title, a name or a sku must be to what the user typed, lower than the pg_trgm default so
+	// a typo in a short prefix still matches
+	suggestionWordSimilarityThreshold = 0.3
+	// shorter queries match nearly everything, nothing is suggested until the user typed that many characters
+	minSuggestionQueryLength = 2
+)
+
+// SearchService suggests what to search for while the user types
+type SearchService struct {
+	db     *sql.DB
+	models *model.Models
+}
+
+func NewSearchService(db *sql.DB, models *model.Models) *SearchService {
+	return &SearchService{db: db, models: models}
+}
+
+type SearchSuggestionsDTO struct {
+	Products   []*model.SearchSuggestionRecord `json:"products"`
+	Categories []*model.SearchSuggestionRecord `json:"categories"`
+	Skus       []*model.SearchSuggestionRecord `json:"skus"`
+}
+
+type SuggestInput struct {
+	Query string
+	Limit *int // the number of suggestions of every kind, the default when nil
+}
+
+func (input *SuggestInput) Validate(v *validator.Validator) {
+	v.Check(input.Query != "", "q", "must be provided")
+	v.Check(len(input.Query) <= 100, "q", "must not be more than 100 bytes long")
+
+	if input.Limit != nil {
+		v.Check(*input.Limit >= 1 && *input.Limit <= maxSuggestionLimit, "limit", "must be between 1 and 10")
+	}
+}
+
+// Suggest returns the titles of the published products, the categories and the skus matching what the user typed
+func (svc *SearchService) Suggest(ctx context.Context, input *SuggestInput) (*SearchSuggestionsDTO, error) {
+	suggestions := &SearchSuggestionsDTO{
+		Products:   []*model.SearchSuggestionRecord{},
+		Categories: []*model.SearchSuggestionRecord{},
+		Skus:       []*model.SearchSuggestionRecord{},
+	}
+
+	if utf8.RuneCountInString(input.Query) < minSuggestionQueryLength {
+		return suggestions, nil
+	}
+
+	limit := defaultSuggestionLimit
+
+	if input.Limit != nil {
+		limit = *input.Limit
+	}
+
+	// the threshold is only set for the transaction so the other queries keep the default one
+	tx, err := svc.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer tx.Rollback()
+
+	err = svc.models.SearchSuggestionModel.SetWordSimilarityThreshold(ctx, tx, suggestionWordSimilarityThreshold)
+
+	if err != nil {
+		return nil, err
+	}
+
+	suggestions.Products, err = svc.models.SearchSuggestionModel.FindProductTitles(ctx, tx, input.Query, limit)
+
+	if err != nil {
+		return nil, err
+	}
+
+	suggestions.Categories, err = svc.models.SearchSuggestionModel.FindCategories(ctx, tx, input.Query, limit)
+
+	if err != nil {
+		return nil, err
+	}
+
+	suggestions.Skus, err = svc.models.SearchSuggestionModel.FindSkus(ctx, tx, input.Query, limit)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return suggestions, nil
+}
@@ -4,10 +4,20 @@ import (
 	"database/sql"
 	"ecom-backend/internal/model"
 	"errors"
+	"time"
 )
 
 var ErrUnauthorizedRequest = errors.New("unauthorized request")
 
+type Config struct {
+	PaymentProviders []PaymentProvider
+	ReservationTTL   time.Duration // how long the stock of a cart stays reserved during checkout
+	// AllocationStrategy decides which stock location fulfills an order line item, one of the AllocationStrategy* constants
+	AllocationStrategy string
+	TaxCalculator      TaxCalculator
+	BaseCurrency       string // the prices missing in a currency are converted from the ones in this currency
+}
+
 type Services struct {
 	Product         *ProductService
 	ProductCategory *ProductCategoryService
@@ -15,17 +25,54 @@ type Services struct {
 	Auth            *AuthService
 	Token           *TokenService
 	Wishlist        *WishlistService
+	Cart            *CartService
+	Order           *OrderService
+	Payment         *PaymentService
+	Inventory       *InventoryService
+	Promotion       *PromotionService
+	Tax             *TaxService
+	Shipping        *ShippingService
+	Region          *RegionService
+	PriceList       *PriceListService
+	ExchangeRate    *ExchangeRateService
+	CustomerGroup   *CustomerGroupService
+	GiftCard        *GiftCardService
+	StoreCredit     *StoreCreditService
+	Return          *ReturnService
+	Search          *SearchService
 }
 
-func NewServices(db *sql.DB, models *model.Models) *Services {
+func NewServices(db *sql.DB, models *model.Models, cfg Config) *Services {
 	tokenSvc := NewTokenService(db, models.TokenModel, models.UserModel)
+	priceResolver := NewPriceResolver(models, cfg.BaseCurrency)
+	productSvc := NewProductService(db, models, priceResolver)
+	productCategorySvc := NewProductCategoryService(db, models)
+	promotionSvc := NewPromotionService(db, models, productCategorySvc, priceResolver)
+	shippingSvc := NewShippingService(db, models)
+	orderSvc := NewOrderService(db, models, productSvc, promotionSvc, shippingSvc, cfg.TaxCalculator, cfg.AllocationStrategy)
+	paymentSvc := NewPaymentService(db, models, orderSvc, cfg.PaymentProviders)
 
 	return &Services{
-		Product:         NewProductService(db, models),
-		ProductCategory: NewProductCategoryService(db, models),
+		Product:         productSvc,
+		ProductCategory: productCategorySvc,
 		Upload:          NewUploadService(db, models.FileModel),
 		Token:           tokenSvc,
 		Auth:            NewAuthService(db, models.UserModel, models.TokenModel, tokenSvc),
 		Wishlist:        NewWishlistService(db, models.WishlistModel),
+		Cart:            NewCartService(db, models, promotionSvc, shippingSvc, cfg.TaxCalculator, priceResolver),
+		Order:           orderSvc,
+		Payment:         paymentSvc,
+		Inventory:       NewInventoryService(db, models, cfg.ReservationTTL),
+		Promotion:       promotionSvc,
+		Tax:             NewTaxService(db, models),
+		Shipping:        shippingSvc,
+		Region:          NewRegionService(db, models, cfg.PaymentProviders),
+		PriceList:       NewPriceListService(db, models),
+		ExchangeRate:    NewExchangeRateService(db, models),
+		CustomerGroup:   NewCustomerGroupService(db, models),
+		GiftCard:        NewGiftCardService(db, models),
+		StoreCredit:     NewStoreCreditService(db, models),
+		Return:          NewReturnService(db, models, paymentSvc),
+		Search:          NewSearchService(db, models),
 	}
 }
@@ -0,0 +1,651 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"ecom-backend/internal/consts"
+	"ecom-backend/internal/model"
+	"ecom-backend/internal/money"
+	"ecom-backend/internal/validator"
+	"ecom-backend/pkg/sqldb"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var ErrShippingMethodNotAvailable = errors.New("shipping method is not available for this cart")
+
+type ShippingService struct {
+	db     *sql.DB
+	models *model.Models
+}
+
+func NewShippingService(db *sql.DB, models *model.Models) *ShippingService {
+	return &ShippingService{db: db, models: models}
+}
+
+type ShippingZoneDTO struct {
+	*model.ShippingZoneRecord
+	Countries []string             `json:"countries"`
+	Methods   []*ShippingMethodDTO `json:"methods"`
+}
+
+type ShippingMethodDTO struct {
+	*model.ShippingMethodRecord
+	Rates       []*model.ShippingRateRecord       `json:"rates"`
+	WeightRates []*model.ShippingWeightRateRecord `json:"weight_rates"`
+}
+
+// ShippingOptionDTO is a shipping method offered for a cart, with its price for that cart
+type ShippingOptionDTO struct {
+	MethodId    string       `json:"method_id"`
+	Name        string       `json:"name"`
+	Description *string      `json:"description"`
+	Amount      money.Money  `json:"amount"`
+	FreeAbove   *money.Money `json:"free_above"` // the subtotal from which the method is free, if any
+}
+
+type CreateShippingZoneInput struct {
+	Name      string   `json:"name"`
+	Countries []string `json:"countries"`
+}
+
+func validateShippingCountries(v *validator.Validator, countries []string) {
+	for _, countryCode := range countries {
+		v.Check(len(countryCode) == 2, "countries", "must contain 2 letter ISO codes")
+	}
+
+	v.Check(validator.Unique(countries), "countries", "must not contain the same country twice")
+}
+
+func (input *CreateShippingZoneInput) Validate(v *validator.Validator) {
+	v.Check(input.Name != "", "name", "must be provided")
+	v.Check(len(input.Countries) > 0, "countries", "must contain at least one country")
+	validateShippingCountries(v, input.Countries)
+}
+
+type UpdateShippingZoneInput struct {
+	Name      *string   `json:"name"`
+	Countries *[]string `json:"countries"`
+}
+
+func (input *UpdateShippingZoneInput) Validate(v *validator.Validator) {
+	if input.Name != nil {
+		v.Check(*input.Name != "", "name", "must not be empty")
+	}
+
+	if input.Countries != nil {
+		v.Check(len(*input.Countries) > 0, "countries", "must contain at least one country")
+		validateShippingCountries(v, *input.Countries)
+	}
+}
+
+type ShippingRateInput struct {
+	CurrencyCode string         `json:"currency_code"`
+	Amount       *money.Decimal `json:"amount"`
+	FreeAbove    *money.Decimal `json:"free_above"`
+}
+
+type ShippingWeightRateInput struct {
+	CurrencyCode string        `json:"currency_code"`
+	MaxWeight    float32       `json:"max_weight"`
+	Amount       money.Decimal `json:"amount"`
+}
+
+func validateShippingRates(v *validator.Validator, rates []ShippingRateInput, weightRates []ShippingWeightRateInput) {
+	currencies := []string{}
+
+	for _, rate := range rates {
+		v.Check(rate.CurrencyCode != "", "rates.currency_code", "must be provided")
+		currencies = append(currencies, rate.CurrencyCode)
+
+		if rate.Amount != nil {
+			v.Check(rate.Amount.Sign() >= 0, "rates.amount", "should not be negative")
+			validateDecimals(v, *rate.Amount, rate.CurrencyCode, "rates.amount")
+		}
+
+		if rate.FreeAbove != nil {
+			v.Check(rate.FreeAbove.Sign() >= 0, "rates.free_above", "should not be negative")
+			validateDecimals(v, *rate.FreeAbove, rate.CurrencyCode, "rates.free_above")
+		}
+	}
+
+	v.Check(validator.Unique(currencies), "rates", "must not contain the same currency twice")
+
+	brackets := []string{}
+
+	for _, rate := range weightRates {
+		v.Check(rate.CurrencyCode != "", "weight_rates.currency_code", "must be provided")
+		v.Check(rate.MaxWeight > 0, "weight_rates.max_weight", "must be greater than zero")
+		v.Check(rate.Amount.Sign() >= 0, "weight_rates.amount", "should not be negative")
+		validateDecimals(v, rate.Amount, rate.CurrencyCode, "weight_rates.amount")
+		brackets = append(brackets, fmt.Sprintf("%s-%f", rate.CurrencyCode, rate.MaxWeight))
+	}
+
+	v.Check(validator.Unique(brackets), "weight_rates", "must not contain the same max_weight twice for a currency")
+}
+
+type CreateShippingMethodInput struct {
+	Name        string                    `json:"name"`
+	Description *string                   `json:"description"`
+	RateType    string                    `json:"rate_type"`
+	IsActive    *bool                     `json:"is_active"`
+	Rates       []ShippingRateInput       `json:"rates"`
+	WeightRates []ShippingWeightRateInput `json:"weight_rates"` // weight_based only
+}
+
+func (input *CreateShippingMethodInput) Validate(v *validator.Validator) {
+	v.Check(input.Name != "", "name", "must be provided")
+	v.Check(validator.In(input.RateType, consts.ShippingRateTypeFlat, consts.ShippingRateTypeWeightBased), "rate_type", "invalid rate type")
+
+	if input.RateType == consts.ShippingRateTypeFlat {
+		hasAmount := false
+
+		for _, rate := range input.Rates {
+			hasAmount = hasAmount || rate.Amount != nil
+		}
+
+		v.Check(hasAmount, "rates", "a flat method needs an amount in at least one currency")
+	}
+
+	if input.RateType == consts.ShippingRateTypeWeightBased {
+		v.Check(len(input.WeightRates) > 0, "weight_rates", "a weight based method needs at least one weight rate")
+	}
+
+	validateShippingRates(v, input.Rates, input.WeightRates)
+}
+
+// the rate type of a method can't change, a new method has to be created instead
+type UpdateShippingMethodInput struct {
+	Name        *string                    `json:"name"`
+	Description *string                    `json:"description"`
+	IsActive    *bool                      `json:"is_active"`
+	Rates       *[]ShippingRateInput       `json:"rates"`
+	WeightRates *[]ShippingWeightRateInput `json:"weight_rates"`
+}
+
+func (input *UpdateShippingMethodInput) Validate(v *validator.Validator) {
+	if input.Name != nil {
+		v.Check(*input.Name != "", "name", "must not be empty")
+	}
+
+	rates := []ShippingRateInput{}
+	weightRates := []ShippingWeightRateInput{}
+
+	if input.Rates != nil {
+		rates = *input.Rates
+	}
+
+	if input.WeightRates != nil {
+		weightRates = *input.WeightRates
+	}
+
+	validateShippingRates(v, rates, weightRates)
+}
+
+func normalizeCountryCodes(countries []string) []string {
+	result := []string{}
+
+	for _, countryCode := range countries {
+		result = append(result, strings.ToLower(countryCode))
+	}
+
+	return result
+}
+
+func (svc *ShippingService) CreateShippingZone(ctx context.Context, input *CreateShippingZoneInput) (*ShippingZoneDTO, error) {
+	tx, err := svc.db.BeginTx(ctx, nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer tx.Rollback()
+
+	zone, err := svc.models.ShippingZoneModel.Insert(ctx, tx, &model.ShippingZoneRecord{Name: input.Name})
+
+	if err != nil {
+		return nil, err
+	}
+
+	err = svc.models.ShippingZoneModel.ReplaceCountries(ctx, tx, zone.Id, normalizeCountryCodes(input.Countries))
+
+	if err != nil {
+		return nil, err
+	}
+
+	dto, err := svc.buildShippingZoneDTO(ctx, tx, zone)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return dto, nil
+}
+
+func (svc *ShippingService) buildShippingZoneDTO(ctx context.Context, conn sqldb.Connection, zone *model.ShippingZoneRecord) (*ShippingZoneDTO, error) {
+	dtos, err := svc.buildShippingZoneDTOs(ctx, conn, []*model.ShippingZoneRecord{zone})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return dtos[0], nil
+}
+
+func (svc *ShippingService) buildShippingZoneDTOs(ctx context.Context, conn sqldb.Connection, zones []*model.ShippingZoneRecord) ([]*ShippingZoneDTO, error) {
+	zoneIds := []string{}
+
+	for _, zone := range zones {
+		zoneIds = append(zoneIds, zone.Id)
+	}
+
+	countriesMap, err := svc.models.ShippingZoneModel.FindCountriesByZoneIds(ctx, conn, zoneIds)
+
+	if err != nil {
+		return nil, err
+	}
+
+	methodsMap, err := svc.models.ShippingMethodModel.FindAllByZoneIds(ctx, conn, zoneIds)
+
+	if err != nil {
+		return nil, err
+	}
+
+	methods := []*model.ShippingMethodRecord{}
+
+	for _, zoneMethods := range methodsMap {
+		methods = append(methods, zoneMethods...)
+	}
+
+	methodDtos, err := svc.buildShippingMethodDTOs(ctx, conn, methods)
+
+	if err != nil {
+		return nil, err
+	}
+
+	dtos := []*ShippingZoneDTO{}
+
+	for _, zone := range zones {
+		dto := &ShippingZoneDTO{ShippingZoneRecord: zone, Countries: countriesMap[zone.Id], Methods: []*ShippingMethodDTO{}}
+
+		if dto.Countries == nil {
+			dto.Countries = []string{}
+		}
+
+		for _, method := range methodsMap[zone.Id] {
+			dto.Methods = append(dto.Methods, methodDtos[method.Id])
+		}
+
+		dtos = append(dtos, dto)
+	}
+
+	return dtos, nil
+}
+
+// buildShippingMethodDTOs returns the methods with their rates, by method id
+func (svc *ShippingService) buildShippingMethodDTOs(ctx context.Context, conn sqldb.Connection, methods []*model.ShippingMethodRecord) (map[string]*ShippingMethodDTO, error) {
+	methodIds := []string{}
+
+	for _, method := range methods {
+		methodIds = append(methodIds, method.Id)
+	}
+
+	ratesMap, err := svc.models.ShippingRateModel.FindAllByMethodIds(ctx, conn, methodIds)
+
+	if err != nil {
+		return nil, err
+	}
+
+	weightRatesMap, err := svc.models.ShippingWeightRateModel.FindAllByMethodIds(ctx, conn, methodIds)
+
+	if err != nil {
+		return nil, err
+	}
+
+	dtos := map[string]*ShippingMethodDTO{}
+
+	for _, method := range methods {
+		dto := &ShippingMethodDTO{ShippingMethodRecord: method, Rates: ratesMap[method.Id], WeightRates: weightRatesMap[method.Id]}
+
+		if dto.Rates == nil {
+			dto.Rates = []*model.ShippingRateRecord{}
+		}
+
+		if dto.WeightRates == nil {
+			dto.WeightRates = []*model.ShippingWeightRateRecord{}
+		}
+
+		dtos[method.Id] = dto
+	}
+
+	return dtos, nil
+}
+
+func (svc *ShippingService) ListShippingZones(ctx context.Context) ([]*ShippingZoneDTO, error) {
+	zones, err := svc.models.ShippingZoneModel.FindAll(ctx, svc.db)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return svc.buildShippingZoneDTOs(ctx, svc.db, zones)
+}
+
+func (svc *ShippingService) GetShippingZone(ctx context.Context, id string) (*ShippingZoneDTO, error) {
+	zone, err := svc.models.ShippingZoneModel.FindById(ctx, svc.db, id)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return svc.buildShippingZoneDTO(ctx, svc.db, zone)
+}
+
+func (svc *ShippingService) UpdateShippingZone(ctx context.Context, id string, input *UpdateShippingZoneInput) (*ShippingZoneDTO, error) {
+	tx, err := svc.db.BeginTx(ctx, nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer tx.Rollback()
+
+	zone, err := svc.models.ShippingZoneModel.FindById(ctx, tx, id)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if input.Name != nil {
+		zone.Name = *input.Name
+	}
+
+	zone, err = svc.models.ShippingZoneModel.Update(ctx, tx, zone)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if input.Countries != nil {
+		err = svc.models.ShippingZoneModel.ReplaceCountries(ctx, tx, zone.Id, normalizeCountryCodes(*input.Countries))
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	dto, err := svc.buildShippingZoneDTO(ctx, tx, zone)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return dto, nil
+}
+
+func (svc *ShippingService) DeleteShippingZone(ctx context.Context, id string) error {
+	return svc.models.ShippingZoneModel.Delete(ctx, svc.db, id)
+}
+
+func (svc *ShippingService) CreateShippingMethod(ctx context.Context, zoneId string, input *CreateShippingMethodInput) (*ShippingMethodDTO, error) {
+	tx, err := svc.db.BeginTx(ctx, nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer tx.Rollback()
+
+	method := &model.ShippingMethodRecord{ZoneId: zoneId, Name: input.Name, Description: input.Description, RateType: input.RateType, IsActive: true}
+
+	if input.IsActive != nil {
+		method.IsActive = *input.IsActive
+	}
+
+	method, err = svc.models.ShippingMethodModel.Insert(ctx, tx, method)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var weightRates []ShippingWeightRateInput
+
+	if method.RateType == consts.ShippingRateTypeWeightBased {
+		weightRates = input.WeightRates
+	}
+
+	err = svc.replaceShippingRates(ctx, tx, method.Id, input.Rates, weightRates)
+
+	if err != nil {
+		return nil, err
+	}
+
+	dtos, err := svc.buildShippingMethodDTOs(ctx, tx, []*model.ShippingMethodRecord{method})
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return dtos[method.Id], nil
+}
+
+// replaceShippingRates swaps the rates and the weight rates of the method, nil leaves them untouched
+func (svc *ShippingService) replaceShippingRates(ctx context.Context, conn sqldb.Connection, methodId string, rates []ShippingRateInput, weightRates []ShippingWeightRateInput) error {
+	if rates != nil {
+		err := svc.models.ShippingRateModel.DeleteAllByMethodId(ctx, conn, methodId)
+
+		if err != nil {
+			return err
+		}
+
+		for _, rate := range rates {
+			_, err := svc.models.ShippingRateModel.Insert(ctx, conn, &model.ShippingRateRecord{MethodId: methodId, CurrencyCode: rate.CurrencyCode, Amount: decimalMoney(rate.Amount, rate.CurrencyCode),
+				FreeAbove: decimalMoney(rate.FreeAbove, rate.CurrencyCode)})
+
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	if weightRates != nil {
+		err := svc.models.ShippingWeightRateModel.DeleteAllByMethodId(ctx, conn, methodId)
+
+		if err != nil {
+			return err
+		}
+
+		for _, rate := range weightRates {
+			_, err := svc.models.ShippingWeightRateModel.Insert(ctx, conn, &model.ShippingWeightRateRecord{MethodId: methodId, CurrencyCode: rate.CurrencyCode, MaxWeight: rate.MaxWeight, Amount: rate.Amount.Money(rate.CurrencyCode)})
+
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func (svc *ShippingService) UpdateShippingMethod(ctx context.Context, id string, input *UpdateShippingMethodInput) (*ShippingMethodDTO, error) {
+	tx, err := svc.db.BeginTx(ctx, nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer tx.Rollback()
+
+	method, err := svc.models.ShippingMethodModel.FindById(ctx, tx, id)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if input.Name != nil {
+		method.Name = *input.Name
+	}
+
+	if input.Description != nil {
+		method.Description = input.Description
+	}
+
+	if input.IsActive != nil {
+		method.IsActive = *input.IsActive
+	}
+
+	method, err = svc.models.ShippingMethodModel.Update(ctx, tx, method)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var rates []ShippingRateInput
+	var weightRates []ShippingWeightRateInput
+
+	if input.Rates != nil {
+		rates = *input.Rates
+	}
+
+	if input.WeightRates != nil && method.RateType == consts.ShippingRateTypeWeightBased {
+		weightRates = *input.WeightRates
+	}
+
+	err = svc.replaceShippingRates(ctx, tx, method.Id, rates, weightRates)
+
+	if err != nil {
+		return nil, err
+	}
+
+	dtos, err := svc.buildShippingMethodDTOs(ctx, tx, []*model.ShippingMethodRecord{method})
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return dtos[method.Id], nil
+}
+
+func (svc *ShippingService) DeleteShippingMethod(ctx context.Context, id string) error {
+	return svc.models.ShippingMethodModel.Delete(ctx, svc.db, id)
+}
+
+// findShippingOptions returns the active methods of the zone of the country that can ship the cart, priced for it.
+// The subtotal is taken once the discounts are off, the weight is the total weight of the cart.
+func (svc *ShippingService) findShippingOptions(ctx context.Context, conn sqldb.Connection, countryCode string, currencyCode string, subtotal money.Money, weight float32) ([]*ShippingOptionDTO, error) {
+	options := []*ShippingOptionDTO{}
+
+	zone, err := svc.models.ShippingZoneModel.FindByCountryCode(ctx, conn, strings.ToLower(countryCode))
+
+	if err != nil {
+		if errors.Is(err, model.ErrRecordNotFound) {
+			return options, nil
+		}
+		return nil, err
+	}
+
+	methodsMap, err := svc.models.ShippingMethodModel.FindAllByZoneIds(ctx, conn, []string{zone.Id})
+
+	if err != nil {
+		return nil, err
+	}
+
+	methodDtos, err := svc.buildShippingMethodDTOs(ctx, conn, methodsMap[zone.Id])
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, method := range methodsMap[zone.Id] {
+		if !method.IsActive {
+			continue
+		}
+
+		if option := computeShippingOption(methodDtos[method.Id], currencyCode, subtotal, weight); option != nil {
+			options = append(options, option)
+		}
+	}
+
+	return options, nil
+}
+
+// findShippingOption prices the chosen method for the cart, the method must be one of its shipping options
+func (svc *ShippingService) findShippingOption(ctx context.Context, conn sqldb.Connection, methodId string, countryCode string, currencyCode string, subtotal money.Money, weight float32) (*ShippingOptionDTO, error) {
+	options, err := svc.findShippingOptions(ctx, conn, countryCode, currencyCode, subtotal, weight)
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, option := range options {
+		if option.MethodId == methodId {
+			return option, nil
+		}
+	}
+
+	return nil, ErrShippingMethodNotAvailable
+}
+
+// computeShippingOption prices the method for the cart, nil when the method can't ship it in the currency
+func computeShippingOption(method *ShippingMethodDTO, currencyCode string, subtotal money.Money, weight float32) *ShippingOptionDTO {
+	var rate *model.ShippingRateRecord
+
+	for _, r := range method.Rates {
+		if r.CurrencyCode == currencyCode {
+			rate = r
+		}
+	}
+
+	option := &ShippingOptionDTO{MethodId: method.Id, Name: method.Name, Description: method.Description, Amount: money.Zero(currencyCode)}
+
+	switch method.RateType {
+	case consts.ShippingRateTypeFlat:
+		if rate == nil || rate.Amount == nil {
+			return nil
+		}
+
+		option.Amount = *rate.Amount
+	case consts.ShippingRateTypeWeightBased:
+		found := false
+
+		// the brackets are sorted from the lightest, the first one the cart fits in is the cheapest
+		for _, weightRate := range method.WeightRates {
+			if weightRate.CurrencyCode == currencyCode && weight <= weightRate.MaxWeight {
+				option.Amount = weightRate.Amount
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			return nil
+		}
+	}
+
+	if rate != nil && rate.FreeAbove != nil {
+		option.FreeAbove = rate.FreeAbove
+
+		if !subtotal.LessThan(*rate.FreeAbove) {
+			option.Amount = money.Zero(currencyCode)
+		}
+	}
+
+	return option
+}
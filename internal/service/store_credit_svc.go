@@ -0,0 +1,201 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"ecom-backend/internal/consts"
+	"ecom-backend/internal/model"
+	"ecom-backend/internal/money"
+	"ecom-backend/internal/validator"
+	"ecom-backend/pkg/sqldb"
+	"errors"
+	"strings"
+)
+
+var (
+	ErrInsufficientStoreCredit = errors.New("insufficient store credit")
+	ErrStoreCreditUnavailable  = errors.New("store credit is only available to registered customers")
+)
+
+// StoreCreditService manages the credit ledger of the users, the credit pays for their future orders
+type StoreCreditService struct {
+	db     *sql.DB
+	models *model.Models
+}
+
+func NewStoreCreditService(db *sql.DB, models *model.Models) *StoreCreditService {
+	return &StoreCreditService{db: db, models: models}
+}
+
+type StoreCreditDTO struct {
+	Balances     []money.Money                         `json:"balances"` // one per currency the user has credit in
+	Transactions []*model.StoreCreditTransactionRecord `json:"transactions"`
+}
+
+type AdjustStoreCreditInput struct {
+	Amount       money.Decimal `json:"amount"` // added to the credit, negative to take some away
+	CurrencyCode string        `json:"currency_code"`
+	Note         *string       `json:"note"`
+}
+
+func (input *AdjustStoreCreditInput) Validate(v *validator.Validator) {
+	v.Check(input.Amount.Sign() != 0, "amount", "must not be zero")
+	v.Check(input.CurrencyCode != "", "currency_code", "must be provided")
+	validateDecimals(v, input.Amount, input.CurrencyCode, "amount")
+}
+
+// GetStoreCredit returns the balances and the ledger of the user
+func (svc *StoreCreditService) GetStoreCredit(ctx context.Context, userId string) (*StoreCreditDTO, error) {
+	_, err := svc.models.UserModel.FindById(ctx, svc.db, userId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return svc.buildStoreCreditDTO(ctx, svc.db, userId)
+}
+
+// AdjustStoreCredit adds credit to the user or takes some away, the credit can't go below zero
+func (svc *StoreCreditService) AdjustStoreCredit(ctx context.Context, userId string, actorUserId *string, input *AdjustStoreCreditInput) (*StoreCreditDTO, error) {
+	tx, err := svc.db.BeginTx(ctx, nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer tx.Rollback()
+
+	err = svc.models.StoreCreditModel.LockUser(ctx, tx, userId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	currencyCode := strings.ToLower(input.CurrencyCode)
+	amount := input.Amount.Money(currencyCode)
+
+	balance, err := svc.models.StoreCreditModel.FindBalance(ctx, tx, userId, currencyCode)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if balance.Add(amount).IsNegative() {
+		return nil, ErrInsufficientStoreCredit
+	}
+
+	_, err = svc.models.StoreCreditModel.Insert(ctx, tx, &model.StoreCreditTransactionRecord{
+		UserId:       userId,
+		CurrencyCode: currencyCode,
+		Type:         consts.BalanceTransactionAdjustment,
+		Amount:       amount,
+		ActorUserId:  actorUserId,
+		Note:         input.Note,
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	storeCredit, err := svc.buildStoreCreditDTO(ctx, tx, userId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return storeCredit, nil
+}
+
+func (svc *StoreCreditService) buildStoreCreditDTO(ctx context.Context, conn sqldb.Connection, userId string) (*StoreCreditDTO, error) {
+	balances, err := svc.models.StoreCreditModel.FindBalancesByUserId(ctx, conn, userId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	transactions, err := svc.models.StoreCreditModel.FindAllByUserId(ctx, conn, userId)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &StoreCreditDTO{Balances: balances, Transactions: transactions}, nil
+}
+
+// lockStoreCredit locks the credit of the user until the end of the transaction and returns how much of the amount
+// it covers
+func lockStoreCredit(ctx context.Context, conn sqldb.Connection, models *model.Models, userId string, amount money.Money) (money.Money, error) {
+	err := models.StoreCreditModel.LockUser(ctx, conn, userId)
+
+	if err != nil {
+		return money.Money{}, err
+	}
+
+	balance, err := models.StoreCreditModel.FindBalance(ctx, conn, userId, amount.CurrencyCode)
+
+	if err != nil {
+		return money.Money{}, err
+	}
+
+	if !balance.IsPositive() {
+		return money.Zero(amount.CurrencyCode), nil
+	}
+
+	return money.Min(balance, amount), nil
+}
+
+// redeemStoreCredit takes the amount from the credit of the user on behalf of the order
+func redeemStoreCredit(ctx context.Context, conn sqldb.Connection, models *model.Models, userId string, amount money.Money, orderId string) error {
+	if !amount.IsPositive() {
+		return nil
+	}
+
+	_, err := models.StoreCreditModel.Insert(ctx, conn, &model.StoreCreditTransactionRecord{
+		UserId:       userId,
+		CurrencyCode: amount.CurrencyCode,
+		Type:         consts.BalanceTransactionRedeem,
+		Amount:       amount.Mul(-1),
+		OrderId:      &orderId,
+	})
+
+	return err
+}
+
+// restoreOrderStoreCredit gives back to the user at most the amount out of the credit the order still takes and returns
+// what was given back. Restoring twice never gives back more than the order took.
+func restoreOrderStoreCredit(ctx context.Context, conn sqldb.Connection, models *model.Models, order *model.OrderRecord, amount money.Money) (money.Money, error) {
+	// the credit of a removed user is gone with them
+	if order.UserId == nil {
+		return money.Zero(order.CurrencyCode), nil
+	}
+
+	redeemed, err := models.StoreCreditModel.SumRedeemedByOrderId(ctx, conn, order.Id)
+
+	if err != nil {
+		return money.Money{}, err
+	}
+
+	restored := money.Min(money.New(redeemed, order.CurrencyCode), amount)
+
+	if !restored.IsPositive() {
+		return money.Zero(order.CurrencyCode), nil
+	}
+
+	_, err = models.StoreCreditModel.Insert(ctx, conn, &model.StoreCreditTransactionRecord{
+		UserId:       *order.UserId,
+		CurrencyCode: order.CurrencyCode,
+		Type:         consts.BalanceTransactionRestore,
+		Amount:       restored,
+		OrderId:      &order.Id,
+	})
+
+	if err != nil {
+		return money.Money{}, err
+	}
+
+	return restored, nil
+}
@@ -0,0 +1,150 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"ecom-backend/internal/model"
+	"ecom-backend/internal/money"
+	"errors"
+	"strings"
+)
+
+// TaxCalculator works out the taxes of a cart or an order being placed. The cart and order services talk to it
+// only through this interface, so an external tax engine can replace the tax region tables without any change to them.
+type TaxCalculator interface {
+	// Id is the unique identifier of the calculator
+	Id() string
+	// Calculate returns the taxes of every line of the request, lines without taxes can be left out
+	Calculate(ctx context.Context, req TaxCalculationRequest) (*TaxCalculationResult, error)
+}
+
+// TaxLocation is where the goods are shipped to, it decides which taxes apply
+type TaxLocation struct {
+	CountryCode string
+	Province    *string
+}
+
+type TaxCalculationRequest struct {
+	CurrencyCode string
+	Location     TaxLocation
+	Lines        []TaxLine
+}
+
+type TaxLine struct {
+	VariantId string
+	ProductId string
+	Quantity  int
+	Amount    money.Money // subtotal of the line once the discounts are taken off
+}
+
+type TaxCalculationResult struct {
+	IsTaxInclusive bool // the amounts of the lines already include the taxes, they're not added to the total
+	Lines          []*LineTaxDTO
+	TaxTotal       money.Money
+}
+
+type LineTaxDTO struct {
+	VariantId string      `json:"variant_id"`
+	Rate      float32     `json:"rate"` // percentage
+	Amount    money.Money `json:"amount"`
+}
+
+// findLineTax returns the tax of the variant in the result, nil when it's not taxed
+func (result *TaxCalculationResult) findLineTax(variantId string) *LineTaxDTO {
+	for _, line := range result.Lines {
+		if line.VariantId == variantId {
+			return line
+		}
+	}
+
+	return nil
+}
+
+// TableTaxCalculator is the default calculator, it takes the rates from the tax regions. The region of the province is
+// used when there's one, the region of the country otherwise, and nothing is taxed outside of the regions.
+// A product in a category with an override is taxed at the override rate, the lowest one when several categories have one.
+type TableTaxCalculator struct {
+	db     *sql.DB
+	models *model.Models
+}
+
+func NewTableTaxCalculator(db *sql.DB, models *model.Models) *TableTaxCalculator {
+	return &TableTaxCalculator{db: db, models: models}
+}
+
+func (c *TableTaxCalculator) Id() string {
+	return "table"
+}
+
+func (c *TableTaxCalculator) Calculate(ctx context.Context, req TaxCalculationRequest) (*TaxCalculationResult, error) {
+	result := &TaxCalculationResult{Lines: []*LineTaxDTO{}, TaxTotal: money.Zero(req.CurrencyCode)}
+
+	region, err := c.models.TaxRegionModel.FindByLocation(ctx, c.db, strings.ToLower(req.Location.CountryCode), req.Location.Province)
+
+	if err != nil {
+		if errors.Is(err, model.ErrRecordNotFound) {
+			return result, nil
+		}
+		return nil, err
+	}
+
+	result.IsTaxInclusive = region.IsTaxInclusive
+
+	overridesMap, err := c.models.TaxRateOverrideModel.FindAllByTaxRegionIds(ctx, c.db, []string{region.Id})
+
+	if err != nil {
+		return nil, err
+	}
+
+	categoryRates := map[string]float32{}
+
+	for _, override := range overridesMap[region.Id] {
+		categoryRates[override.CategoryId] = override.Rate
+	}
+
+	productCategories := map[string][]*model.ProductCategoryRecord{}
+
+	if len(categoryRates) > 0 {
+		productIds := []string{}
+
+		for _, line := range req.Lines {
+			productIds = append(productIds, line.ProductId)
+		}
+
+		productCategories, err = c.models.ProductCategoryProductModel.FindCategoriesForProducts(ctx, c.db, productIds)
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, line := range req.Lines {
+		rate := region.Rate
+		overridden := false
+
+		for _, category := range productCategories[line.ProductId] {
+			if categoryRate, ok := categoryRates[category.Id]; ok && (!overridden || categoryRate < rate) {
+				rate = categoryRate
+				overridden = true
+			}
+		}
+
+		if rate == 0 || !line.Amount.IsPositive() {
+			continue
+		}
+
+		var amount money.Money
+
+		if region.IsTaxInclusive {
+			// the tax is the part of the amount above its price before taxes
+			amount = line.Amount.Sub(line.Amount.Scale(100 / (100 + float64(rate))))
+		} else {
+			amount = line.Amount.Percentage(rate)
+		}
+
+		result.Lines = append(result.Lines, &LineTaxDTO{VariantId: line.VariantId, Rate: rate, Amount: amount})
+		result.TaxTotal = result.TaxTotal.Add(amount)
+	}
+
+	return result, nil
+}
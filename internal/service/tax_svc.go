@@ -0,0 +1,276 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"ecom-backend/internal/model"
+	"ecom-backend/internal/validator"
+	"ecom-backend/pkg/sqldb"
+	"strings"
+)
+
+type TaxService struct {
+	db     *sql.DB
+	models *model.Models
+}
+
+func NewTaxService(db *sql.DB, models *model.Models) *TaxService {
+	return &TaxService{db: db, models: models}
+}
+
+type TaxRegionDTO struct {
+	*model.TaxRegionRecord
+	Overrides []*model.TaxRateOverrideRecord `json:"overrides"`
+}
+
+type TaxRateOverrideInput struct {
+	CategoryId string  `json:"category_id"`
+	Rate       float32 `json:"rate"`
+}
+
+func validateTaxRate(v *validator.Validator, rate float32, key string) {
+	v.Check(rate >= 0 && rate <= 100, key, "must be between 0 and 100")
+}
+
+func validateTaxRateOverrides(v *validator.Validator, overrides []TaxRateOverrideInput) {
+	categoryIds := []string{}
+
+	for _, override := range overrides {
+		v.Check(validator.IsValidUUID(override.CategoryId), "overrides.category_id", "must be a valid UUID")
+		validateTaxRate(v, override.Rate, "overrides.rate")
+		categoryIds = append(categoryIds, override.CategoryId)
+	}
+
+	v.Check(validator.Unique(categoryIds), "overrides", "must not contain the same category twice")
+}
+
+type CreateTaxRegionInput struct {
+	Name           string                 `json:"name"`
+	CountryCode    string                 `json:"country_code"`
+	Province       *string                `json:"province"` // optional, the region covers the whole country when missing
+	Rate           *float32               `json:"rate"`
+	IsTaxInclusive bool                   `json:"is_tax_inclusive"`
+	Overrides      []TaxRateOverrideInput `json:"overrides"`
+}
+
+func (input *CreateTaxRegionInput) Validate(v *validator.Validator) {
+	v.Check(input.Name != "", "name", "must be provided")
+	v.Check(len(input.CountryCode) == 2, "country_code", "must be a 2 letter ISO code")
+
+	if input.Province != nil {
+		v.Check(*input.Province != "", "province", "must not be empty")
+	}
+
+	v.Check(input.Rate != nil, "rate", "must be provided")
+
+	if input.Rate != nil {
+		validateTaxRate(v, *input.Rate, "rate")
+	}
+
+	validateTaxRateOverrides(v, input.Overrides)
+}
+
+// the location of a region can't change, a new region has to be created instead
+type UpdateTaxRegionInput struct {
+	Name           *string                 `json:"name"`
+	Rate           *float32                `json:"rate"`
+	IsTaxInclusive *bool                   `json:"is_tax_inclusive"`
+	Overrides      *[]TaxRateOverrideInput `json:"overrides"`
+}
+
+func (input *UpdateTaxRegionInput) Validate(v *validator.Validator) {
+	if input.Name != nil {
+		v.Check(*input.Name != "", "name", "must not be empty")
+	}
+
+	if input.Rate != nil {
+		validateTaxRate(v, *input.Rate, "rate")
+	}
+
+	if input.Overrides != nil {
+		validateTaxRateOverrides(v, *input.Overrides)
+	}
+}
+
+func (svc *TaxService) CreateTaxRegion(ctx context.Context, input *CreateTaxRegionInput) (*TaxRegionDTO, error) {
+	tx, err := svc.db.BeginTx(ctx, nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer tx.Rollback()
+
+	region, err := svc.models.TaxRegionModel.Insert(ctx, tx, &model.TaxRegionRecord{
+		Name:           input.Name,
+		CountryCode:    strings.ToLower(input.CountryCode),
+		Province:       input.Province,
+		Rate:           *input.Rate,
+		IsTaxInclusive: input.IsTaxInclusive,
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	err = svc.replaceTaxRateOverrides(ctx, tx, region.Id, input.Overrides)
+
+	if err != nil {
+		return nil, err
+	}
+
+	dto, err := svc.buildTaxRegionDTO(ctx, tx, region)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return dto, nil
+}
+
+// replaceTaxRateOverrides swaps the category overrides of the region, nil leaves them untouched
+func (svc *TaxService) replaceTaxRateOverrides(ctx context.Context, conn sqldb.Connection, taxRegionId string, overrides []TaxRateOverrideInput) error {
+	if overrides == nil {
+		return nil
+	}
+
+	err := svc.models.TaxRateOverrideModel.DeleteAllByTaxRegionId(ctx, conn, taxRegionId)
+
+	if err != nil {
+		return err
+	}
+
+	for _, override := range overrides {
+		_, err := svc.models.TaxRateOverrideModel.Insert(ctx, conn, &model.TaxRateOverrideRecord{TaxRegionId: taxRegionId, CategoryId: override.CategoryId, Rate: override.Rate})
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (svc *TaxService) buildTaxRegionDTO(ctx context.Context, conn sqldb.Connection, region *model.TaxRegionRecord) (*TaxRegionDTO, error) {
+	dtos, err := svc.buildTaxRegionDTOs(ctx, conn, []*model.TaxRegionRecord{region})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return dtos[0], nil
+}
+
+func (svc *TaxService) buildTaxRegionDTOs(ctx context.Context, conn sqldb.Connection, regions []*model.TaxRegionRecord) ([]*TaxRegionDTO, error) {
+	regionIds := []string{}
+
+	for _, region := range regions {
+		regionIds = append(regionIds, region.Id)
+	}
+
+	overridesMap, err := svc.models.TaxRateOverrideModel.FindAllByTaxRegionIds(ctx, conn, regionIds)
+
+	if err != nil {
+		return nil, err
+	}
+
+	dtos := []*TaxRegionDTO{}
+
+	for _, region := range regions {
+		dto := &TaxRegionDTO{TaxRegionRecord: region, Overrides: overridesMap[region.Id]}
+
+		if dto.Overrides == nil {
+			dto.Overrides = []*model.TaxRateOverrideRecord{}
+		}
+
+		dtos = append(dtos, dto)
+	}
+
+	return dtos, nil
+}
+
+func (svc *TaxService) ListTaxRegions(ctx context.Context) ([]*TaxRegionDTO, error) {
+	regions, err := svc.models.TaxRegionModel.FindAll(ctx, svc.db)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return svc.buildTaxRegionDTOs(ctx, svc.db, regions)
+}
+
+func (svc *TaxService) GetTaxRegion(ctx context.Context, id string) (*TaxRegionDTO, error) {
+	region, err := svc.models.TaxRegionModel.FindById(ctx, svc.db, id)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return svc.buildTaxRegionDTO(ctx, svc.db, region)
+}
+
+func (svc *TaxService) UpdateTaxRegion(ctx context.Context, id string, input *UpdateTaxRegionInput) (*TaxRegionDTO, error) {
+	tx, err := svc.db.BeginTx(ctx, nil)
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer tx.Rollback()
+
+	region, err := svc.models.TaxRegionModel.FindById(ctx, tx, id)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if input.Name != nil {
+		region.Name = *input.Name
+	}
+
+	if input.Rate != nil {
+		region.Rate = *input.Rate
+	}
+
+	if input.IsTaxInclusive != nil {
+		region.IsTaxInclusive = *input.IsTaxInclusive
+	}
+
+	region, err = svc.models.TaxRegionModel.Update(ctx, tx, region)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var overrides []TaxRateOverrideInput
+
+	if input.Overrides != nil {
+		overrides = *input.Overrides
+	}
+
+	err = svc.replaceTaxRateOverrides(ctx, tx, region.Id, overrides)
+
+	if err != nil {
+		return nil, err
+	}
+
+	dto, err := svc.buildTaxRegionDTO(ctx, tx, region)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return dto, nil
+}
+
+func (svc *TaxService) DeleteTaxRegion(ctx context.Context, id string) error {
+	return svc.models.TaxRegionModel.Delete(ctx, svc.db, id)
+}
@@ -3,7 +3,11 @@ package service
 import (
 	"ecom-backend/internal/consts"
 	"ecom-backend/internal/model"
+	"ecom-backend/internal/money"
 	"ecom-backend/internal/validator"
+	"fmt"
+	"sort"
+	"strings"
 	"time"
 )
 
@@ -21,6 +25,7 @@ type AggregateProduct struct {
 	Description string                    `json:"description"`
 	Thumbnail   *ProductImage             `json:"thumbnail"`
 	Status      string                    `json:"status"`
+	IsGiftCard  bool                      `json:"is_gift_card"`
 	CreatedAt   time.Time                 `json:"created_at"`
 	UpdatedAt   time.Time                 `json:"updated_at"`
 	DeletedAt   *time.Time                `json:"deleted_at"`
@@ -42,11 +47,23 @@ type AggregateProductVariant struct {
 	Width             *float32             `json:"width"`
 	Height            *float32             `json:"height"`
 	InventoryQuantity int                  `json:"inventory_quantity"`
+	ReservedQuantity  int                  `json:"reserved_quantity"`
+	AvailableQuantity int                  `json:"available_quantity"` // on hand minus the quantity held by active reservations
+	LowStockThreshold *int                 `json:"low_stock_threshold"`
 	CreatedAt         time.Time            `json:"created_at"`
 	UpdatedAt         time.Time            `json:"updated_at"`
 	DeletedAt         *time.Time           `json:"deleted_at"`
 	Prices            []VariantPriceDTO    `json:"prices"`
 	Options           []VariantOptionValue `json:"options"`
+	Locations         []VariantLocationDTO `json:"locations"`
+}
+
+// VariantLocationDTO is the stock of a variant at one of the stock locations
+type VariantLocationDTO struct {
+	LocationId   string `json:"location_id"`
+	LocationName string `json:"location_name"`
+	IsActive     bool   `json:"is_active"`
+	Quantity     int    `json:"quantity"`
 }
 
 type ProductOptionDTO struct {
@@ -63,12 +80,22 @@ type ProductCategoryInfo struct {
 }
 
 type VariantPriceDTO struct {
-	Id           string     `json:"id"`
-	CurrencyCode string     `json:"currency_code"`
-	Amount       float32    `json:"amount"`
-	CreateAt     time.Time  `json:"created_at"`
-	UpdatedAt    time.Time  `json:"updated_at"`
-	DeletedAt    *time.Time `json:"deleted_at"`
+	Id             string         `json:"id"`
+	CurrencyCode   string         `json:"currency_code"`
+	Amount         money.Money    `json:"amount"`          // the effective price
+	OriginalAmount *money.Money   `json:"original_amount"` // the "compare at" base price, set only when a price list applies
+	PriceListId    *string        `json:"price_list_id"`   // the list the effective price comes from
+	IsConverted    bool           `json:"is_converted"`    // the price is converted from the base currency, the variant has no explicit price in the currency
+	Tiers          []PriceTierDTO `json:"tiers"`           // the volume prices, a single tier when the price doesn't depend on the quantity
+	CreateAt       time.Time      `json:"created_at"`
+	UpdatedAt      time.Time      `json:"updated_at"`
+	DeletedAt      *time.Time     `json:"deleted_at"`
+}
+
+type PriceTierDTO struct {
+	MinQuantity int         `json:"min_quantity"`
+	MaxQuantity *int        `json:"max_quantity"` // nil when there's no upper bound
+	Amount      money.Money `json:"amount"`
 }
 
 type VariantOptionValue struct {
@@ -85,6 +112,7 @@ func BuildAggregateProduct(productRecord *model.ProductRecord, aggListFields *Ag
 	p.Subtitle = productRecord.Subtitle
 	p.Description = productRecord.Description
 	p.Status = productRecord.Status
+	p.IsGiftCard = productRecord.IsGiftCard
 	p.CreatedAt = productRecord.CreatedAt
 	p.UpdatedAt = productRecord.UpdatedAt
 	p.DeletedAt = productRecord.DeletedAt
@@ -107,8 +135,10 @@ func BuildAggregateFieldsList(
 	categoryRecords []*model.ProductCategoryRecord,
 	optionRecords []*model.ProductOptionRecord,
 	variantRecords []*model.ProductVariantRecord,
-	variantMoneyAmountRecords map[string][]*model.MoneyAmountRecord,
-	variantOptionValueRecords map[string][]*model.ProductOptionValueRecord) *AggregateProductListFields {
+	variantPrices map[string][]*ResolvedPrice,
+	variantOptionValueRecords map[string][]*model.ProductOptionValueRecord,
+	reservedQuantities map[string]int,
+	inventoryLevels map[string][]*model.InventoryLevelRecord) *AggregateProductListFields {
 
 	agg := AggregateProductListFields{}
 
@@ -145,19 +175,34 @@ func BuildAggregateFieldsList(
 		dpv.Width = variantRecord.Width
 		dpv.Height = variantRecord.Height
 		dpv.InventoryQuantity = variantRecord.InventoryQuantity
+		dpv.LowStockThreshold = variantRecord.LowStockThreshold
+		dpv.ReservedQuantity = reservedQuantities[variantRecord.Id]
+		dpv.AvailableQuantity = max(dpv.InventoryQuantity-dpv.ReservedQuantity, 0)
 		dpv.CreatedAt = variantRecord.CreatedAt
 		dpv.UpdatedAt = variantRecord.UpdatedAt
 		dpv.DeletedAt = variantRecord.DeletedAt
 
 		dpv.Prices = []VariantPriceDTO{}
-		for _, moneyAmountRecord := range variantMoneyAmountRecords[variantRecord.Id] {
+		for _, price := range variantPrices[variantRecord.Id] {
 			vp := VariantPriceDTO{}
-			vp.Id = moneyAmountRecord.Id
-			vp.CurrencyCode = moneyAmountRecord.CurrencyCode
-			vp.Amount = moneyAmountRecord.Amount
-			vp.CreateAt = moneyAmountRecord.CreatedAt
-			vp.UpdatedAt = moneyAmountRecord.UpdatedAt
-			vp.DeletedAt = moneyAmountRecord.DeletedAt
+			vp.Id = price.Id
+			vp.CurrencyCode = price.CurrencyCode
+			vp.Amount = price.EffectiveAmount()
+			vp.IsConverted = price.IsConverted
+			vp.CreateAt = price.CreatedAt
+			vp.UpdatedAt = price.UpdatedAt
+			vp.DeletedAt = price.DeletedAt
+
+			if price.ListPrice != nil {
+				vp.OriginalAmount = &price.Amount
+				vp.PriceListId = &price.ListPrice.PriceListId
+			}
+
+			vp.Tiers = []PriceTierDTO{}
+
+			for _, tier := range price.Tiers {
+				vp.Tiers = append(vp.Tiers, PriceTierDTO{MinQuantity: tier.MinQuantity, MaxQuantity: tier.MaxQuantity, Amount: tier.Amount})
+			}
 
 			dpv.Prices = append(dpv.Prices, vp)
 		}
@@ -173,6 +218,12 @@ func BuildAggregateFieldsList(
 			dpv.Options = append(dpv.Options, vov)
 		}
 
+		dpv.Locations = []VariantLocationDTO{}
+
+		for _, level := range inventoryLevels[variantRecord.Id] {
+			dpv.Locations = append(dpv.Locations, VariantLocationDTO{LocationId: level.LocationId, LocationName: level.LocationName, IsActive: level.LocationIsActive, Quantity: level.Quantity})
+		}
+
 		agg.Variants = append(agg.Variants, dpv)
 	}
 
@@ -188,6 +239,7 @@ type CreateProductInput struct {
 	Images      []ProductImageInput `json:"images"`
 	Brand       *string             `json:"brand"`
 	Status      string              `json:"status"`
+	IsGiftCard  bool                `json:"is_gift_card"` // the variant prices are the values of the issued gift cards
 	Categories  []struct {
 		Id string `json:"id"`
 	} `json:"categories"`
@@ -221,8 +273,67 @@ type CreateProductVariantInput struct {
 }
 
 type PriceInput struct {
-	Code   string  `json:"code"`
-	Amount float32 `json:"amount"`
+	Code        string        `json:"code"`
+	Amount      money.Decimal `json:"amount"`
+	MinQuantity *int          `json:"min_quantity"` // optional, 1 when missing
+	MaxQuantity *int          `json:"max_quantity"` // optional, no upper bound when missing
+}
+
+func (price PriceInput) minQuantity() int {
+	if price.MinQuantity == nil {
+		return 1
+	}
+
+	return *price.MinQuantity
+}
+
+// validateDecimals checks that an amount sent in major units can be paid in its currency, 0.5 jpy can't
+func validateDecimals(v *validator.Validator, amount money.Decimal, currencyCode string, key string) {
+	v.Check(amount.FitsCurrency(currencyCode), key, fmt.Sprintf("must not have more than %d decimals in %s", money.Exponent(currencyCode), currencyCode))
+}
+
+// decimalMoney converts an optional amount sent in major units, nil stays nil
+func decimalMoney(amount *money.Decimal, currencyCode string) *money.Money {
+	if amount == nil {
+		return nil
+	}
+
+	result := amount.Money(currencyCode)
+
+	return &result
+}
+
+// validatePriceTiers checks that the quantity tiers of every currency start at 1 and follow each other without overlapping,
+// so a variant has exactly one price for any quantity
+func validatePriceTiers(v *validator.Validator, prices []PriceInput, key string) {
+	tiersMap := map[string][]PriceInput{}
+
+	for _, price := range prices {
+		v.Check(price.minQuantity() >= 1, key+".min_quantity", "must be at least 1")
+
+		if price.MaxQuantity != nil {
+			v.Check(*price.MaxQuantity >= price.minQuantity(), key+".max_quantity", "must not be lower than min_quantity")
+		}
+
+		code := strings.ToLower(price.Code)
+		tiersMap[code] = append(tiersMap[code], price)
+	}
+
+	for _, tiers := range tiersMap {
+		sort.Slice(tiers, func(i, j int) bool { return tiers[i].minQuantity() < tiers[j].minQuantity() })
+
+		v.Check(tiers[0].minQuantity() == 1, key, "the quantity tiers of a currency must start at 1")
+
+		for i := 1; i < len(tiers); i++ {
+			previous := tiers[i-1]
+
+			if previous.MaxQuantity == nil || *previous.MaxQuantity >= tiers[i].minQuantity() {
+				v.AddError(key, "the quantity tiers of a currency must not overlap")
+			} else if *previous.MaxQuantity+1 != tiers[i].minQuantity() {
+				v.AddError(key, "the quantity tiers of a currency must not leave gaps")
+			}
+		}
+	}
 }
 
 func (input *CreateProductVariantInput) Validate(v *validator.Validator) {
@@ -233,8 +344,11 @@ func (input *CreateProductVariantInput) Validate(v *validator.Validator) {
 	if len(input.Prices) > 0 {
 		for _, price := range input.Prices {
 			v.Check(price.Code != "", "variant.price_code", "must be provided")
-			v.Check(price.Amount > 0, "variant.price_amount", "must be greater than zero")
+			v.Check(price.Amount.Sign() > 0, "variant.price_amount", "must be greater than zero")
+			validateDecimals(v, price.Amount, price.Code, "variant.price_amount")
 		}
+
+		validatePriceTiers(v, input.Prices, "variant.prices")
 	}
 
 	if len(input.Options) > 0 {
@@ -266,6 +380,7 @@ type UpdateProductInput struct {
 	Images      *[]ProductImageInput    `json:"images"`
 	Brand       *string                 `json:"brand"`
 	Status      *string                 `json:"status"`
+	IsGiftCard  *bool                   `json:"is_gift_card"`
 	Categories  *[]ProductCategoryInput `json:"categories"`
 	Options     *[]ProductOptionInput   `json:"options"`
 }
@@ -289,6 +404,7 @@ type UpdateVariantInput struct {
 	Sku               *string `json:"sku"`
 	Barcode           *int    `json:"barcode"`
 	InventoryQuantity *int    `json:"inventory_quantity"`
+	LowStockThreshold *int    `json:"low_stock_threshold"`
 	Options           *[]struct {
 		Value string `json:"value"`
 		Id    string `json:"id"`
@@ -305,11 +421,18 @@ func (input *UpdateVariantInput) Validate(v *validator.Validator) {
 		v.Check(*input.InventoryQuantity >= 0, "inventory_quantity", "should not be negative")
 	}
 
+	if input.LowStockThreshold != nil {
+		v.Check(*input.LowStockThreshold >= 0, "low_stock_threshold", "should not be negative")
+	}
+
 	if input.Prices != nil {
 		for _, price := range *input.Prices {
 			v.Check(price.Code != "", "price.code", "must not be empty")
-			v.Check(price.Amount > 0, "price.amount", "must be greater than zero")
+			v.Check(price.Amount.Sign() > 0, "price.amount", "must be greater than zero")
+			validateDecimals(v, price.Amount, price.Code, "price.amount")
 		}
+
+		validatePriceTiers(v, *input.Prices, "prices")
 	}
 
 	if input.Options != nil {
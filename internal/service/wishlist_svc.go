@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"ecom-backend/internal/model"
+	"ecom-backend/internal/money"
 
 	"github.com/lib/pq"
 )
@@ -80,7 +81,7 @@ func (svc *WishlistService) ListProducts(ctx context.Context, userIdentifier str
 	// get variant prices
 	q = `SELECT ma.id, ma.currency_code, ma.amount, pvma.variant_id FROM money_amount AS ma
 		INNER JOIN product_variant_money_amount AS pvma ON pvma.money_amount_id = ma.id
-		WHERE variant_id = ANY($1)`
+		WHERE variant_id = ANY($1) AND ma.min_quantity = 1`
 
 	rows, err = svc.db.QueryContext(ctx, q, pq.Array(variantIds))
 
@@ -100,6 +101,8 @@ func (svc *WishlistService) ListProducts(ctx context.Context, userIdentifier str
 			return nil, err
 		}
 
+		money.SetCurrency(priceItem.CurrencyCode, &priceItem.Amount)
+
 		pricesMap[variantId] = append(pricesMap[variantId], priceItem)
 	}
 
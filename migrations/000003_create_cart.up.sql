@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS cart (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid() NOT NULL,
+    user_identifier text UNIQUE NOT NULL,
+    created_at timestamp NOT NULL DEFAULT now(),
+    updated_at timestamp NOT NULL DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS cart_item (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid() NOT NULL,
+    cart_id uuid NOT NULL REFERENCES cart ON DELETE CASCADE,
+    variant_id uuid NOT NULL REFERENCES product_variant ON DELETE CASCADE,
+    quantity int NOT NULL CHECK (quantity > 0),
+    created_at timestamp NOT NULL DEFAULT now(),
+    updated_at timestamp NOT NULL DEFAULT now(),
+    CONSTRAINT duplicate_variant_in_cart_not_allowed UNIQUE(cart_id, variant_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_cart_item_cart_id ON cart_item(cart_id);
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS order_line_item;
+
+DROP TABLE IF EXISTS orders;
+
+DROP TABLE IF EXISTS address;
@@ -0,0 +1,51 @@
+CREATE TABLE IF NOT EXISTS address (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid() NOT NULL,
+    first_name text NOT NULL,
+    last_name text NOT NULL,
+    address_1 text NOT NULL,
+    address_2 text,
+    city text NOT NULL,
+    postal_code text NOT NULL,
+    province text,
+    country_code VARCHAR(2) NOT NULL,
+    phone text,
+    created_at timestamp NOT NULL DEFAULT now()
+);
+
+CREATE TABLE IF NOT EXISTS orders (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid() NOT NULL,
+    user_identifier text NOT NULL,
+    user_id uuid REFERENCES users ON DELETE SET NULL,
+    email citext NOT NULL,
+    currency_code VARCHAR(10) NOT NULL REFERENCES currency(code),
+    shipping_address_id uuid NOT NULL REFERENCES address,
+    billing_address_id uuid NOT NULL REFERENCES address,
+    subtotal DECIMAL(10, 2) NOT NULL,
+    total DECIMAL(10, 2) NOT NULL,
+    created_at timestamp NOT NULL DEFAULT now(),
+    updated_at timestamp NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_orders_user_identifier ON orders(user_identifier);
+
+CREATE INDEX IF NOT EXISTS idx_orders_created_at ON orders(created_at);
+
+-- line items are a snapshot of the variant at the moment of the purchase,
+-- later changes of the product must not alter placed orders
+CREATE TABLE IF NOT EXISTS order_line_item (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid() NOT NULL,
+    order_id uuid NOT NULL REFERENCES orders ON DELETE CASCADE,
+    variant_id uuid REFERENCES product_variant ON DELETE SET NULL,
+    product_id uuid NOT NULL,
+    product_title text NOT NULL,
+    variant_title text NOT NULL,
+    sku text,
+    thumbnail_id text,
+    options jsonb NOT NULL DEFAULT '[]',
+    unit_price DECIMAL(10, 2) NOT NULL,
+    quantity int NOT NULL CHECK (quantity > 0),
+    subtotal DECIMAL(10, 2) NOT NULL,
+    created_at timestamp NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_order_line_item_order_id ON order_line_item(order_id);
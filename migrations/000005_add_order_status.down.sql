@@ -0,0 +1,12 @@
+DROP TABLE IF EXISTS order_event;
+
+ALTER TABLE orders
+    DROP COLUMN IF EXISTS status,
+    DROP COLUMN IF EXISTS paid_at,
+    DROP COLUMN IF EXISTS fulfilled_at,
+    DROP COLUMN IF EXISTS shipped_at,
+    DROP COLUMN IF EXISTS delivered_at,
+    DROP COLUMN IF EXISTS cancelled_at,
+    DROP COLUMN IF EXISTS refunded_at;
+
+DROP TYPE IF EXISTS order_status;
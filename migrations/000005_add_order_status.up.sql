@@ -0,0 +1,25 @@
+CREATE TYPE order_status AS ENUM ('pending', 'paid', 'fulfilled', 'shipped', 'delivered', 'cancelled', 'refunded');
+
+ALTER TABLE orders
+    ADD COLUMN IF NOT EXISTS status order_status NOT NULL DEFAULT 'pending',
+    ADD COLUMN IF NOT EXISTS paid_at timestamp,
+    ADD COLUMN IF NOT EXISTS fulfilled_at timestamp,
+    ADD COLUMN IF NOT EXISTS shipped_at timestamp,
+    ADD COLUMN IF NOT EXISTS delivered_at timestamp,
+    ADD COLUMN IF NOT EXISTS cancelled_at timestamp,
+    ADD COLUMN IF NOT EXISTS refunded_at timestamp;
+
+CREATE INDEX IF NOT EXISTS idx_orders_status ON orders(status);
+
+-- history of every status change of an order
+CREATE TABLE IF NOT EXISTS order_event (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid() NOT NULL,
+    order_id uuid NOT NULL REFERENCES orders ON DELETE CASCADE,
+    from_status order_status,
+    to_status order_status NOT NULL,
+    actor_user_id uuid REFERENCES users ON DELETE SET NULL,
+    note text,
+    created_at timestamp NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_order_event_order_id ON order_event(order_id);
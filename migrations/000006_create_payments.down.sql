@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS payment;
+
+DROP TABLE IF EXISTS payment_session;
+
+DROP TYPE IF EXISTS payment_status;
+
+DROP TYPE IF EXISTS payment_session_status;
@@ -0,0 +1,40 @@
+CREATE TYPE payment_session_status AS ENUM ('pending', 'authorized', 'requires_action', 'declined', 'voided', 'error');
+
+CREATE TYPE payment_status AS ENUM ('authorized', 'captured', 'partially_refunded', 'refunded', 'voided');
+
+-- a payment session is an attempt of the customer to pay an order through a payment provider
+CREATE TABLE IF NOT EXISTS payment_session (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid() NOT NULL,
+    order_id uuid NOT NULL REFERENCES orders ON DELETE CASCADE,
+    provider_id text NOT NULL,
+    provider_reference text,
+    status payment_session_status NOT NULL DEFAULT 'pending',
+    amount DECIMAL(10, 2) NOT NULL,
+    currency_code VARCHAR(10) NOT NULL REFERENCES currency(code),
+    created_at timestamp NOT NULL DEFAULT now(),
+    updated_at timestamp NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_payment_session_order_id ON payment_session(order_id);
+
+CREATE INDEX IF NOT EXISTS idx_payment_session_provider_reference ON payment_session(provider_id, provider_reference);
+
+-- a payment is created once a payment session gets authorized by the provider
+CREATE TABLE IF NOT EXISTS payment (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid() NOT NULL,
+    order_id uuid NOT NULL REFERENCES orders ON DELETE CASCADE,
+    payment_session_id uuid UNIQUE NOT NULL REFERENCES payment_session ON DELETE CASCADE,
+    provider_id text NOT NULL,
+    provider_reference text NOT NULL,
+    status payment_status NOT NULL DEFAULT 'authorized',
+    amount DECIMAL(10, 2) NOT NULL,
+    amount_captured DECIMAL(10, 2) NOT NULL DEFAULT 0,
+    amount_refunded DECIMAL(10, 2) NOT NULL DEFAULT 0,
+    currency_code VARCHAR(10) NOT NULL REFERENCES currency(code),
+    captured_at timestamp,
+    voided_at timestamp,
+    created_at timestamp NOT NULL DEFAULT now(),
+    updated_at timestamp NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_payment_order_id ON payment(order_id);
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS inventory_reservation;
+
+DROP TYPE IF EXISTS inventory_reservation_status;
@@ -0,0 +1,20 @@
+CREATE TYPE inventory_reservation_status AS ENUM ('active', 'consumed', 'released', 'expired');
+
+-- stock held for a cart during checkout, a reservation counts against the available quantity
+-- of the variant only while it's active and not past its expiry time
+CREATE TABLE IF NOT EXISTS inventory_reservation (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid() NOT NULL,
+    variant_id uuid NOT NULL REFERENCES product_variant ON DELETE CASCADE,
+    cart_id uuid NOT NULL REFERENCES cart ON DELETE CASCADE,
+    quantity int NOT NULL CHECK (quantity > 0),
+    status inventory_reservation_status NOT NULL DEFAULT 'active',
+    expires_at timestamp NOT NULL,
+    created_at timestamp NOT NULL DEFAULT now(),
+    updated_at timestamp NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_inventory_reservation_active_variant_id ON inventory_reservation(variant_id) WHERE status = 'active';
+
+CREATE INDEX IF NOT EXISTS idx_inventory_reservation_active_expires_at ON inventory_reservation(expires_at) WHERE status = 'active';
+
+CREATE INDEX IF NOT EXISTS idx_inventory_reservation_cart_id ON inventory_reservation(cart_id);
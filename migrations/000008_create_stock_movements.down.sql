@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS stock_movement;
+
+DROP TYPE IF EXISTS stock_movement_type;
@@ -0,0 +1,20 @@
+CREATE TYPE stock_movement_type AS ENUM ('receipt', 'sale', 'return', 'adjustment', 'correction');
+
+-- append-only ledger of every change to the stock of a variant, product_variant.inventory_quantity
+-- is kept only as the cached sum of the movements of the variant
+CREATE TABLE IF NOT EXISTS stock_movement (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid() NOT NULL,
+    variant_id uuid NOT NULL REFERENCES product_variant ON DELETE CASCADE,
+    type stock_movement_type NOT NULL,
+    quantity int NOT NULL CHECK (quantity != 0), -- positive when stock comes in, negative when it goes out
+    reason text,
+    order_id uuid REFERENCES orders ON DELETE SET NULL,
+    actor_user_id uuid REFERENCES users ON DELETE SET NULL,
+    created_at timestamp NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_stock_movement_variant_id ON stock_movement(variant_id, created_at);
+
+-- open the ledger of the existing variants with their current quantity
+INSERT INTO stock_movement (variant_id, type, quantity, reason)
+SELECT id, 'correction', inventory_quantity, 'opening balance' FROM product_variant WHERE inventory_quantity != 0;
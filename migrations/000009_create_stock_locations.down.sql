@@ -0,0 +1,7 @@
+ALTER TABLE order_line_item DROP COLUMN IF EXISTS location_id;
+
+ALTER TABLE stock_movement DROP COLUMN IF EXISTS location_id;
+
+DROP TABLE IF EXISTS inventory_level;
+
+DROP TABLE IF EXISTS stock_location;
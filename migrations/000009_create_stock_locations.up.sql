@@ -0,0 +1,35 @@
+CREATE TABLE IF NOT EXISTS stock_location (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid() NOT NULL,
+    name text NOT NULL,
+    priority int NOT NULL DEFAULT 0, -- locations with a lower priority are allocated first
+    is_active boolean NOT NULL DEFAULT true,
+    created_at timestamp NOT NULL DEFAULT now(),
+    updated_at timestamp NOT NULL DEFAULT now(),
+    deleted_at timestamp
+);
+
+-- cached sum of the stock movements of a variant at a location
+CREATE TABLE IF NOT EXISTS inventory_level (
+    variant_id uuid NOT NULL REFERENCES product_variant ON DELETE CASCADE,
+    location_id uuid NOT NULL REFERENCES stock_location ON DELETE CASCADE,
+    quantity int NOT NULL DEFAULT 0,
+    updated_at timestamp NOT NULL DEFAULT now(),
+    PRIMARY KEY (variant_id, location_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_inventory_level_location_id ON inventory_level(location_id);
+
+-- the stock that existed until now is moved to a default location
+INSERT INTO stock_location (name) VALUES ('Default warehouse');
+
+ALTER TABLE stock_movement ADD COLUMN IF NOT EXISTS location_id uuid REFERENCES stock_location;
+
+UPDATE stock_movement SET location_id = (SELECT id FROM stock_location ORDER BY created_at LIMIT 1);
+
+ALTER TABLE stock_movement ALTER COLUMN location_id SET NOT NULL;
+
+INSERT INTO inventory_level (variant_id, location_id, quantity)
+SELECT variant_id, location_id, SUM(quantity) FROM stock_movement GROUP BY variant_id, location_id;
+
+-- location the line item was allocated from
+ALTER TABLE order_line_item ADD COLUMN IF NOT EXISTS location_id uuid REFERENCES stock_location ON DELETE SET NULL;
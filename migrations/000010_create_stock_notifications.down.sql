@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS notification;
+
+DROP TYPE IF EXISTS notification_status;
+
+DROP TABLE IF EXISTS back_in_stock_subscription;
+
+ALTER TABLE product_variant DROP COLUMN IF EXISTS low_stock_threshold;
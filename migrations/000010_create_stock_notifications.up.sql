@@ -0,0 +1,30 @@
+-- NULL means no alert is wanted for the variant
+ALTER TABLE product_variant ADD COLUMN IF NOT EXISTS low_stock_threshold int CHECK (low_stock_threshold >= 0);
+
+CREATE TABLE IF NOT EXISTS back_in_stock_subscription (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid() NOT NULL,
+    variant_id uuid NOT NULL REFERENCES product_variant ON DELETE CASCADE,
+    user_identifier text NOT NULL,
+    email citext NOT NULL,
+    notified_at timestamp, -- set once the notification was enqueued, the subscription is then done
+    created_at timestamp NOT NULL DEFAULT now(),
+    CONSTRAINT duplicate_back_in_stock_subscription_not_allowed UNIQUE(user_identifier, variant_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_back_in_stock_subscription_pending_variant_id ON back_in_stock_subscription(variant_id) WHERE notified_at IS NULL;
+
+CREATE TYPE notification_status AS ENUM ('pending', 'sent', 'failed');
+
+-- outbox of the notifications to be delivered to customers
+CREATE TABLE IF NOT EXISTS notification (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid() NOT NULL,
+    type text NOT NULL,
+    email citext NOT NULL,
+    user_identifier text,
+    payload jsonb NOT NULL DEFAULT '{}',
+    status notification_status NOT NULL DEFAULT 'pending',
+    sent_at timestamp,
+    created_at timestamp NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_notification_pending ON notification(created_at) WHERE status = 'pending';
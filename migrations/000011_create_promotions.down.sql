@@ -0,0 +1,17 @@
+ALTER TABLE order_line_item DROP COLUMN IF EXISTS discount_total;
+
+ALTER TABLE orders DROP COLUMN IF EXISTS discount_total;
+
+ALTER TABLE cart DROP COLUMN IF EXISTS promotion_code;
+
+DROP TABLE IF EXISTS promotion_redemption;
+
+DROP TABLE IF EXISTS promotion_target;
+
+DROP TYPE IF EXISTS promotion_target_type;
+
+DROP TABLE IF EXISTS promotion_currency_rule;
+
+DROP TABLE IF EXISTS promotion;
+
+DROP TYPE IF EXISTS promotion_type;
@@ -0,0 +1,62 @@
+CREATE TYPE promotion_type AS ENUM ('percentage', 'fixed');
+
+CREATE TABLE IF NOT EXISTS promotion (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid() NOT NULL,
+    code citext NOT NULL,
+    description text,
+    type promotion_type NOT NULL,
+    percentage DECIMAL(5, 2) CHECK (percentage > 0 AND percentage <= 100), -- set only for percentage promotions
+    usage_limit int CHECK (usage_limit > 0), -- NULL means unlimited
+    usage_limit_per_customer int CHECK (usage_limit_per_customer > 0),
+    usage_count int NOT NULL DEFAULT 0,
+    starts_at timestamp,
+    ends_at timestamp,
+    is_active boolean NOT NULL DEFAULT true,
+    created_at timestamp NOT NULL DEFAULT now(),
+    updated_at timestamp NOT NULL DEFAULT now(),
+    deleted_at timestamp
+);
+
+-- codes must be unique only among the promotions that weren't deleted
+CREATE UNIQUE INDEX IF NOT EXISTS promotion_code_key ON promotion(code) WHERE deleted_at IS NULL;
+
+-- per currency values of a promotion: the amount taken off by fixed promotions and the minimum cart subtotal
+CREATE TABLE IF NOT EXISTS promotion_currency_rule (
+    promotion_id uuid NOT NULL REFERENCES promotion ON DELETE CASCADE,
+    currency_code text NOT NULL REFERENCES currency ON DELETE CASCADE,
+    amount DECIMAL(10, 2) CHECK (amount > 0),
+    min_subtotal DECIMAL(10, 2) CHECK (min_subtotal >= 0),
+    PRIMARY KEY (promotion_id, currency_code)
+);
+
+CREATE TYPE promotion_target_type AS ENUM ('product', 'category');
+
+-- restricts the promotion to some products or categories, a promotion without targets applies to the whole cart
+CREATE TABLE IF NOT EXISTS promotion_target (
+    promotion_id uuid NOT NULL REFERENCES promotion ON DELETE CASCADE,
+    target_type promotion_target_type NOT NULL,
+    target_id uuid NOT NULL,
+    PRIMARY KEY (promotion_id, target_type, target_id)
+);
+
+CREATE TABLE IF NOT EXISTS promotion_redemption (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid() NOT NULL,
+    promotion_id uuid NOT NULL REFERENCES promotion ON DELETE CASCADE,
+    order_id uuid NOT NULL REFERENCES orders ON DELETE CASCADE,
+    user_identifier text NOT NULL,
+    email citext NOT NULL,
+    currency_code text NOT NULL REFERENCES currency,
+    amount DECIMAL(10, 2) NOT NULL,
+    created_at timestamp NOT NULL DEFAULT now(),
+    CONSTRAINT duplicate_promotion_redemption_not_allowed UNIQUE(promotion_id, order_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_promotion_redemption_customer ON promotion_redemption(promotion_id, user_identifier);
+
+CREATE INDEX IF NOT EXISTS idx_promotion_redemption_email ON promotion_redemption(promotion_id, email);
+
+ALTER TABLE cart ADD COLUMN IF NOT EXISTS promotion_code citext;
+
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS discount_total DECIMAL(10, 2) NOT NULL DEFAULT 0;
+
+ALTER TABLE order_line_item ADD COLUMN IF NOT EXISTS discount_total DECIMAL(10, 2) NOT NULL DEFAULT 0;
@@ -0,0 +1,28 @@
+DROP TABLE IF EXISTS promotion_tier;
+
+DROP INDEX IF EXISTS idx_promotion_automatic;
+
+DELETE FROM promotion WHERE is_automatic OR type IN ('buy_x_get_y', 'tiered');
+
+ALTER TABLE promotion DROP CONSTRAINT IF EXISTS promotion_code_check;
+
+ALTER TABLE promotion DROP COLUMN IF EXISTS get_quantity;
+
+ALTER TABLE promotion DROP COLUMN IF EXISTS buy_quantity;
+
+ALTER TABLE promotion DROP COLUMN IF EXISTS is_combinable;
+
+ALTER TABLE promotion DROP COLUMN IF EXISTS priority;
+
+ALTER TABLE promotion DROP COLUMN IF EXISTS is_automatic;
+
+ALTER TABLE promotion ALTER COLUMN code SET NOT NULL;
+
+-- enum values can't be dropped, the type is recreated without them
+ALTER TYPE promotion_type RENAME TO promotion_type_old;
+
+CREATE TYPE promotion_type AS ENUM ('percentage', 'fixed');
+
+ALTER TABLE promotion ALTER COLUMN type TYPE promotion_type USING type::text::promotion_type;
+
+DROP TYPE IF EXISTS promotion_type_old;
@@ -0,0 +1,31 @@
+ALTER TYPE promotion_type ADD VALUE IF NOT EXISTS 'buy_x_get_y';
+
+ALTER TYPE promotion_type ADD VALUE IF NOT EXISTS 'tiered';
+
+-- automatic promotions are applied to every cart they match, they have no code
+ALTER TABLE promotion ALTER COLUMN code DROP NOT NULL;
+
+ALTER TABLE promotion ADD COLUMN IF NOT EXISTS is_automatic boolean NOT NULL DEFAULT false;
+
+-- promotions are evaluated from the lowest priority value to the highest
+ALTER TABLE promotion ADD COLUMN IF NOT EXISTS priority int NOT NULL DEFAULT 0;
+
+-- a promotion that isn't combinable is applied only when no other promotion was, and stops the evaluation
+ALTER TABLE promotion ADD COLUMN IF NOT EXISTS is_combinable boolean NOT NULL DEFAULT true;
+
+-- buy_x_get_y promotions: for every buy_quantity eligible units, get_quantity more are discounted by percentage
+ALTER TABLE promotion ADD COLUMN IF NOT EXISTS buy_quantity int CHECK (buy_quantity > 0);
+
+ALTER TABLE promotion ADD COLUMN IF NOT EXISTS get_quantity int CHECK (get_quantity > 0);
+
+ALTER TABLE promotion ADD CONSTRAINT promotion_code_check CHECK (is_automatic = (code IS NULL));
+
+CREATE INDEX IF NOT EXISTS idx_promotion_automatic ON promotion(priority) WHERE is_automatic AND deleted_at IS NULL;
+
+-- tiered promotions: the percentage of the highest tier reached by the quantity of eligible units applies
+CREATE TABLE IF NOT EXISTS promotion_tier (
+    promotion_id uuid NOT NULL REFERENCES promotion ON DELETE CASCADE,
+    min_quantity int NOT NULL CHECK (min_quantity > 0),
+    percentage DECIMAL(5, 2) NOT NULL CHECK (percentage > 0 AND percentage <= 100),
+    PRIMARY KEY (promotion_id, min_quantity)
+);
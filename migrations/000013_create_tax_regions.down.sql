@@ -0,0 +1,11 @@
+ALTER TABLE order_line_item DROP COLUMN IF EXISTS tax_total;
+
+ALTER TABLE order_line_item DROP COLUMN IF EXISTS tax_rate;
+
+ALTER TABLE orders DROP COLUMN IF EXISTS is_tax_inclusive;
+
+ALTER TABLE orders DROP COLUMN IF EXISTS tax_total;
+
+DROP TABLE IF EXISTS tax_rate_override;
+
+DROP TABLE IF EXISTS tax_region;
@@ -0,0 +1,30 @@
+-- a tax region covers a whole country, or a single province of it when province is set
+CREATE TABLE IF NOT EXISTS tax_region (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid() NOT NULL,
+    name text NOT NULL,
+    country_code char(2) NOT NULL,
+    province citext,
+    rate DECIMAL(6, 3) NOT NULL CHECK (rate >= 0 AND rate <= 100),
+    -- prices of the region already include the taxes, they're not added on top of the total
+    is_tax_inclusive boolean NOT NULL DEFAULT false,
+    created_at timestamp NOT NULL DEFAULT now(),
+    updated_at timestamp NOT NULL DEFAULT now()
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS tax_region_location_key ON tax_region(country_code, COALESCE(province, ''));
+
+-- products of the category are taxed at a different rate in the region ( ex: reduced rate on books )
+CREATE TABLE IF NOT EXISTS tax_rate_override (
+    tax_region_id uuid NOT NULL REFERENCES tax_region ON DELETE CASCADE,
+    category_id uuid NOT NULL REFERENCES product_category ON DELETE CASCADE,
+    rate DECIMAL(6, 3) NOT NULL CHECK (rate >= 0 AND rate <= 100),
+    PRIMARY KEY (tax_region_id, category_id)
+);
+
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS tax_total DECIMAL(10, 2) NOT NULL DEFAULT 0;
+
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS is_tax_inclusive boolean NOT NULL DEFAULT false;
+
+ALTER TABLE order_line_item ADD COLUMN IF NOT EXISTS tax_rate DECIMAL(6, 3) NOT NULL DEFAULT 0;
+
+ALTER TABLE order_line_item ADD COLUMN IF NOT EXISTS tax_total DECIMAL(10, 2) NOT NULL DEFAULT 0;
@@ -0,0 +1,17 @@
+ALTER TABLE orders DROP COLUMN IF EXISTS shipping_total;
+
+ALTER TABLE orders DROP COLUMN IF EXISTS shipping_method_name;
+
+ALTER TABLE orders DROP COLUMN IF EXISTS shipping_method_id;
+
+DROP TABLE IF EXISTS shipping_weight_rate;
+
+DROP TABLE IF EXISTS shipping_rate;
+
+DROP TABLE IF EXISTS shipping_method;
+
+DROP TYPE IF EXISTS shipping_rate_type;
+
+DROP TABLE IF EXISTS shipping_zone_country;
+
+DROP TABLE IF EXISTS shipping_zone;
@@ -0,0 +1,55 @@
+CREATE TABLE IF NOT EXISTS shipping_zone (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid() NOT NULL,
+    name text NOT NULL,
+    created_at timestamp NOT NULL DEFAULT now(),
+    updated_at timestamp NOT NULL DEFAULT now()
+);
+
+-- a country belongs to a single zone, so the shipping options of an address are never ambiguous
+CREATE TABLE IF NOT EXISTS shipping_zone_country (
+    zone_id uuid NOT NULL REFERENCES shipping_zone ON DELETE CASCADE,
+    country_code char(2) NOT NULL,
+    PRIMARY KEY (zone_id, country_code),
+    CONSTRAINT shipping_zone_country_code_key UNIQUE (country_code)
+);
+
+CREATE TYPE shipping_rate_type AS ENUM ('flat', 'weight_based');
+
+CREATE TABLE IF NOT EXISTS shipping_method (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid() NOT NULL,
+    zone_id uuid NOT NULL REFERENCES shipping_zone ON DELETE CASCADE,
+    name text NOT NULL,
+    description text,
+    rate_type shipping_rate_type NOT NULL,
+    is_active boolean NOT NULL DEFAULT true,
+    created_at timestamp NOT NULL DEFAULT now(),
+    updated_at timestamp NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_shipping_method_zone_id ON shipping_method(zone_id);
+
+-- per currency prices of a method: the price of flat methods and the subtotal from which shipping is free.
+-- A method is offered only in the currencies it has a rate for.
+CREATE TABLE IF NOT EXISTS shipping_rate (
+    method_id uuid NOT NULL REFERENCES shipping_method ON DELETE CASCADE,
+    currency_code text NOT NULL REFERENCES currency ON DELETE CASCADE,
+    amount DECIMAL(10, 2) CHECK (amount >= 0),
+    free_above DECIMAL(10, 2) CHECK (free_above >= 0),
+    PRIMARY KEY (method_id, currency_code)
+);
+
+-- prices of weight based methods, the cart is charged the price of the lightest bracket it fits in
+CREATE TABLE IF NOT EXISTS shipping_weight_rate (
+    method_id uuid NOT NULL REFERENCES shipping_method ON DELETE CASCADE,
+    currency_code text NOT NULL REFERENCES currency ON DELETE CASCADE,
+    max_weight float NOT NULL CHECK (max_weight > 0),
+    amount DECIMAL(10, 2) NOT NULL CHECK (amount >= 0),
+    PRIMARY KEY (method_id, currency_code, max_weight)
+);
+
+-- the method is kept as a snapshot since it can be changed or removed after the order is placed
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS shipping_method_id uuid REFERENCES shipping_method ON DELETE SET NULL;
+
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS shipping_method_name text;
+
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS shipping_total DECIMAL(10, 2) NOT NULL DEFAULT 0;
@@ -0,0 +1,7 @@
+ALTER TABLE orders DROP COLUMN IF EXISTS region_id;
+
+DROP TABLE IF EXISTS region_payment_provider;
+
+DROP TABLE IF EXISTS region_country;
+
+DROP TABLE IF EXISTS region;
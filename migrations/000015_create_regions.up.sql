@@ -0,0 +1,26 @@
+CREATE TABLE IF NOT EXISTS region (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid() NOT NULL,
+    name text NOT NULL,
+    currency_code text NOT NULL REFERENCES currency,
+    -- when off the cart doesn't estimate the taxes, they're only worked out at checkout
+    automatic_taxes boolean NOT NULL DEFAULT true,
+    created_at timestamp NOT NULL DEFAULT now(),
+    updated_at timestamp NOT NULL DEFAULT now()
+);
+
+-- a country belongs to a single region
+CREATE TABLE IF NOT EXISTS region_country (
+    region_id uuid NOT NULL REFERENCES region ON DELETE CASCADE,
+    country_code char(2) NOT NULL,
+    PRIMARY KEY (region_id, country_code),
+    CONSTRAINT region_country_code_key UNIQUE (country_code)
+);
+
+-- the payment providers the customers of the region can pay with
+CREATE TABLE IF NOT EXISTS region_payment_provider (
+    region_id uuid NOT NULL REFERENCES region ON DELETE CASCADE,
+    provider_id text NOT NULL,
+    PRIMARY KEY (region_id, provider_id)
+);
+
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS region_id uuid REFERENCES region ON DELETE SET NULL;
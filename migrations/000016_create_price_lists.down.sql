@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS price_list_price;
+
+DROP TABLE IF EXISTS price_list;
+
+DROP TYPE IF EXISTS price_list_type;
@@ -0,0 +1,26 @@
+CREATE TYPE price_list_type AS ENUM ('sale', 'wholesale', 'customer_group');
+
+CREATE TABLE IF NOT EXISTS price_list (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid() NOT NULL,
+    name text NOT NULL,
+    description text,
+    type price_list_type NOT NULL,
+    priority int NOT NULL DEFAULT 0, -- the list with the highest priority wins when several apply to a variant
+    starts_at timestamp,
+    ends_at timestamp,
+    is_active boolean NOT NULL DEFAULT true,
+    created_at timestamp NOT NULL DEFAULT now(),
+    updated_at timestamp NOT NULL DEFAULT now(),
+    CONSTRAINT price_list_window_check CHECK (ends_at IS NULL OR starts_at IS NULL OR ends_at > starts_at)
+);
+
+-- the prices of a list override the base price of the variant in the same currency
+CREATE TABLE IF NOT EXISTS price_list_price (
+    price_list_id uuid NOT NULL REFERENCES price_list ON DELETE CASCADE,
+    variant_id uuid NOT NULL REFERENCES product_variant ON DELETE CASCADE,
+    currency_code text NOT NULL REFERENCES currency ON DELETE CASCADE,
+    amount DECIMAL(10, 2) NOT NULL CHECK (amount >= 0),
+    PRIMARY KEY (price_list_id, variant_id, currency_code)
+);
+
+CREATE INDEX IF NOT EXISTS idx_price_list_price_variant_id ON price_list_price(variant_id);
@@ -0,0 +1,5 @@
+ALTER TABLE money_amount DROP CONSTRAINT IF EXISTS money_amount_quantity_check;
+
+ALTER TABLE money_amount DROP COLUMN IF EXISTS max_quantity;
+
+ALTER TABLE money_amount DROP COLUMN IF EXISTS min_quantity;
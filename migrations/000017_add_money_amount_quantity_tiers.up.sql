@@ -0,0 +1,6 @@
+-- volume pricing: a variant can have several prices in a currency, each one for a range of quantities
+ALTER TABLE money_amount ADD COLUMN IF NOT EXISTS min_quantity int NOT NULL DEFAULT 1;
+
+ALTER TABLE money_amount ADD COLUMN IF NOT EXISTS max_quantity int; -- NULL means no upper bound
+
+ALTER TABLE money_amount ADD CONSTRAINT money_amount_quantity_check CHECK (min_quantity >= 1 AND (max_quantity IS NULL OR max_quantity >= min_quantity));
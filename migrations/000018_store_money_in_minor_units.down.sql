@@ -0,0 +1,93 @@
+UPDATE shipping_weight_rate AS t SET
+    amount = round(t.amount * 100 / power(10::numeric, c.exponent))
+FROM currency AS c WHERE c.code = t.currency_code AND c.exponent <> 2;
+
+ALTER TABLE shipping_weight_rate
+    ALTER COLUMN amount TYPE DECIMAL(10, 2) USING amount / 100.0;
+
+UPDATE shipping_rate AS t SET
+    amount = round(t.amount * 100 / power(10::numeric, c.exponent)),
+    free_above = round(t.free_above * 100 / power(10::numeric, c.exponent))
+FROM currency AS c WHERE c.code = t.currency_code AND c.exponent <> 2;
+
+ALTER TABLE shipping_rate
+    ALTER COLUMN amount TYPE DECIMAL(10, 2) USING amount / 100.0,
+    ALTER COLUMN free_above TYPE DECIMAL(10, 2) USING free_above / 100.0;
+
+UPDATE promotion_redemption AS t SET
+    amount = round(t.amount * 100 / power(10::numeric, c.exponent))
+FROM currency AS c WHERE c.code = t.currency_code AND c.exponent <> 2;
+
+ALTER TABLE promotion_redemption
+    ALTER COLUMN amount TYPE DECIMAL(10, 2) USING amount / 100.0;
+
+UPDATE promotion_currency_rule AS t SET
+    amount = round(t.amount * 100 / power(10::numeric, c.exponent)),
+    min_subtotal = round(t.min_subtotal * 100 / power(10::numeric, c.exponent))
+FROM currency AS c WHERE c.code = t.currency_code AND c.exponent <> 2;
+
+ALTER TABLE promotion_currency_rule
+    ALTER COLUMN amount TYPE DECIMAL(10, 2) USING amount / 100.0,
+    ALTER COLUMN min_subtotal TYPE DECIMAL(10, 2) USING min_subtotal / 100.0;
+
+UPDATE payment AS t SET
+    amount = round(t.amount * 100 / power(10::numeric, c.exponent)),
+    amount_captured = round(t.amount_captured * 100 / power(10::numeric, c.exponent)),
+    amount_refunded = round(t.amount_refunded * 100 / power(10::numeric, c.exponent))
+FROM currency AS c WHERE c.code = t.currency_code AND c.exponent <> 2;
+
+ALTER TABLE payment
+    ALTER COLUMN amount TYPE DECIMAL(10, 2) USING amount / 100.0,
+    ALTER COLUMN amount_captured TYPE DECIMAL(10, 2) USING amount_captured / 100.0,
+    ALTER COLUMN amount_refunded TYPE DECIMAL(10, 2) USING amount_refunded / 100.0;
+
+UPDATE payment_session AS t SET
+    amount = round(t.amount * 100 / power(10::numeric, c.exponent))
+FROM currency AS c WHERE c.code = t.currency_code AND c.exponent <> 2;
+
+ALTER TABLE payment_session
+    ALTER COLUMN amount TYPE DECIMAL(10, 2) USING amount / 100.0;
+
+UPDATE order_line_item AS t SET
+    unit_price = round(t.unit_price * 100 / power(10::numeric, c.exponent)),
+    subtotal = round(t.subtotal * 100 / power(10::numeric, c.exponent)),
+    discount_total = round(t.discount_total * 100 / power(10::numeric, c.exponent)),
+    tax_total = round(t.tax_total * 100 / power(10::numeric, c.exponent))
+FROM orders AS o, currency AS c WHERE o.id = t.order_id AND c.code = o.currency_code AND c.exponent <> 2;
+
+ALTER TABLE order_line_item
+    ALTER COLUMN unit_price TYPE DECIMAL(10, 2) USING unit_price / 100.0,
+    ALTER COLUMN subtotal TYPE DECIMAL(10, 2) USING subtotal / 100.0,
+    ALTER COLUMN discount_total TYPE DECIMAL(10, 2) USING discount_total / 100.0,
+    ALTER COLUMN tax_total TYPE DECIMAL(10, 2) USING tax_total / 100.0;
+
+UPDATE orders AS t SET
+    subtotal = round(t.subtotal * 100 / power(10::numeric, c.exponent)),
+    discount_total = round(t.discount_total * 100 / power(10::numeric, c.exponent)),
+    tax_total = round(t.tax_total * 100 / power(10::numeric, c.exponent)),
+    shipping_total = round(t.shipping_total * 100 / power(10::numeric, c.exponent)),
+    total = round(t.total * 100 / power(10::numeric, c.exponent))
+FROM currency AS c WHERE c.code = t.currency_code AND c.exponent <> 2;
+
+ALTER TABLE orders
+    ALTER COLUMN subtotal TYPE DECIMAL(10, 2) USING subtotal / 100.0,
+    ALTER COLUMN discount_total TYPE DECIMAL(10, 2) USING discount_total / 100.0,
+    ALTER COLUMN tax_total TYPE DECIMAL(10, 2) USING tax_total / 100.0,
+    ALTER COLUMN shipping_total TYPE DECIMAL(10, 2) USING shipping_total / 100.0,
+    ALTER COLUMN total TYPE DECIMAL(10, 2) USING total / 100.0;
+
+UPDATE price_list_price AS t SET
+    amount = round(t.amount * 100 / power(10::numeric, c.exponent))
+FROM currency AS c WHERE c.code = t.currency_code AND c.exponent <> 2;
+
+ALTER TABLE price_list_price
+    ALTER COLUMN amount TYPE DECIMAL(10, 2) USING amount / 100.0;
+
+UPDATE money_amount AS t SET
+    amount = round(t.amount * 100 / power(10::numeric, c.exponent))
+FROM currency AS c WHERE c.code = t.currency_code AND c.exponent <> 2;
+
+ALTER TABLE money_amount
+    ALTER COLUMN amount TYPE DECIMAL(10, 2) USING amount / 100.0;
+
+ALTER TABLE currency DROP COLUMN IF EXISTS exponent;
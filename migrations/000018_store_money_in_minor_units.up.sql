@@ -0,0 +1,101 @@
+-- amounts of money are stored as integers in the minor units of their currency, the exponent of the currency
+-- is the number of decimals between the minor and the major unit (2 for usd, 0 for jpy, 3 for kwd)
+ALTER TABLE currency ADD COLUMN IF NOT EXISTS exponent smallint NOT NULL DEFAULT 2 CHECK (exponent >= 0 AND exponent <= 4);
+
+UPDATE currency SET exponent = 0 WHERE code IN ('bif', 'clp', 'djf', 'gnf', 'isk', 'jpy', 'kmf', 'krw', 'pyg', 'rwf', 'ugx', 'vnd', 'xaf', 'xof');
+
+UPDATE currency SET exponent = 3 WHERE code IN ('bhd', 'iqd', 'jod', 'kwd', 'lyd', 'omr', 'tnd');
+
+-- the DECIMAL(10, 2) amounts are moved to minor units assuming 2 decimals, then the currencies with another exponent
+-- are fixed up
+ALTER TABLE money_amount
+    ALTER COLUMN amount TYPE bigint USING round(amount * 100);
+
+UPDATE money_amount AS t SET
+    amount = round(t.amount * power(10::numeric, c.exponent) / 100)
+FROM currency AS c WHERE c.code = t.currency_code AND c.exponent <> 2;
+
+ALTER TABLE price_list_price
+    ALTER COLUMN amount TYPE bigint USING round(amount * 100);
+
+UPDATE price_list_price AS t SET
+    amount = round(t.amount * power(10::numeric, c.exponent) / 100)
+FROM currency AS c WHERE c.code = t.currency_code AND c.exponent <> 2;
+
+ALTER TABLE orders
+    ALTER COLUMN subtotal TYPE bigint USING round(subtotal * 100),
+    ALTER COLUMN discount_total TYPE bigint USING round(discount_total * 100),
+    ALTER COLUMN tax_total TYPE bigint USING round(tax_total * 100),
+    ALTER COLUMN shipping_total TYPE bigint USING round(shipping_total * 100),
+    ALTER COLUMN total TYPE bigint USING round(total * 100);
+
+UPDATE orders AS t SET
+    subtotal = round(t.subtotal * power(10::numeric, c.exponent) / 100),
+    discount_total = round(t.discount_total * power(10::numeric, c.exponent) / 100),
+    tax_total = round(t.tax_total * power(10::numeric, c.exponent) / 100),
+    shipping_total = round(t.shipping_total * power(10::numeric, c.exponent) / 100),
+    total = round(t.total * power(10::numeric, c.exponent) / 100)
+FROM currency AS c WHERE c.code = t.currency_code AND c.exponent <> 2;
+
+ALTER TABLE order_line_item
+    ALTER COLUMN unit_price TYPE bigint USING round(unit_price * 100),
+    ALTER COLUMN subtotal TYPE bigint USING round(subtotal * 100),
+    ALTER COLUMN discount_total TYPE bigint USING round(discount_total * 100),
+    ALTER COLUMN tax_total TYPE bigint USING round(tax_total * 100);
+
+UPDATE order_line_item AS t SET
+    unit_price = round(t.unit_price * power(10::numeric, c.exponent) / 100),
+    subtotal = round(t.subtotal * power(10::numeric, c.exponent) / 100),
+    discount_total = round(t.discount_total * power(10::numeric, c.exponent) / 100),
+    tax_total = round(t.tax_total * power(10::numeric, c.exponent) / 100)
+FROM orders AS o, currency AS c WHERE o.id = t.order_id AND c.code = o.currency_code AND c.exponent <> 2;
+
+ALTER TABLE payment_session
+    ALTER COLUMN amount TYPE bigint USING round(amount * 100);
+
+UPDATE payment_session AS t SET
+    amount = round(t.amount * power(10::numeric, c.exponent) / 100)
+FROM currency AS c WHERE c.code = t.currency_code AND c.exponent <> 2;
+
+ALTER TABLE payment
+    ALTER COLUMN amount TYPE bigint USING round(amount * 100),
+    ALTER COLUMN amount_captured TYPE bigint USING round(amount_captured * 100),
+    ALTER COLUMN amount_refunded TYPE bigint USING round(amount_refunded * 100);
+
+UPDATE payment AS t SET
+    amount = round(t.amount * power(10::numeric, c.exponent) / 100),
+    amount_captured = round(t.amount_captured * power(10::numeric, c.exponent) / 100),
+    amount_refunded = round(t.amount_refunded * power(10::numeric, c.exponent) / 100)
+FROM currency AS c WHERE c.code = t.currency_code AND c.exponent <> 2;
+
+ALTER TABLE promotion_currency_rule
+    ALTER COLUMN amount TYPE bigint USING round(amount * 100),
+    ALTER COLUMN min_subtotal TYPE bigint USING round(min_subtotal * 100);
+
+UPDATE promotion_currency_rule AS t SET
+    amount = round(t.amount * power(10::numeric, c.exponent) / 100),
+    min_subtotal = round(t.min_subtotal * power(10::numeric, c.exponent) / 100)
+FROM currency AS c WHERE c.code = t.currency_code AND c.exponent <> 2;
+
+ALTER TABLE promotion_redemption
+    ALTER COLUMN amount TYPE bigint USING round(amount * 100);
+
+UPDATE promotion_redemption AS t SET
+    amount = round(t.amount * power(10::numeric, c.exponent) / 100)
+FROM currency AS c WHERE c.code = t.currency_code AND c.exponent <> 2;
+
+ALTER TABLE shipping_rate
+    ALTER COLUMN amount TYPE bigint USING round(amount * 100),
+    ALTER COLUMN free_above TYPE bigint USING round(free_above * 100);
+
+UPDATE shipping_rate AS t SET
+    amount = round(t.amount * power(10::numeric, c.exponent) / 100),
+    free_above = round(t.free_above * power(10::numeric, c.exponent) / 100)
+FROM currency AS c WHERE c.code = t.currency_code AND c.exponent <> 2;
+
+ALTER TABLE shipping_weight_rate
+    ALTER COLUMN amount TYPE bigint USING round(amount * 100);
+
+UPDATE shipping_weight_rate AS t SET
+    amount = round(t.amount * power(10::numeric, c.exponent) / 100)
+FROM currency AS c WHERE c.code = t.currency_code AND c.exponent <> 2;
@@ -0,0 +1,15 @@
+-- a variant without a price in the quote currency is sold at its base currency price converted at the rate
+CREATE TABLE IF NOT EXISTS exchange_rate (
+    base_currency_code text NOT NULL REFERENCES currency ON DELETE CASCADE,
+    quote_currency_code text NOT NULL REFERENCES currency ON DELETE CASCADE,
+    rate DECIMAL(18, 8) NOT NULL CHECK (rate > 0), -- units of the quote currency for one unit of the base currency
+    -- the converted prices are rounded up to the next multiple of the increment plus the ending, in minor units of
+    -- the quote currency: an increment of 100 and an ending of 99 gives prices ending in .99
+    rounding_increment bigint NOT NULL DEFAULT 1 CHECK (rounding_increment > 0),
+    rounding_ending bigint NOT NULL DEFAULT 0,
+    created_at timestamp NOT NULL DEFAULT now(),
+    updated_at timestamp NOT NULL DEFAULT now(),
+    PRIMARY KEY (base_currency_code, quote_currency_code),
+    CONSTRAINT exchange_rate_currencies_check CHECK (base_currency_code <> quote_currency_code),
+    CONSTRAINT exchange_rate_rounding_check CHECK (rounding_ending >= 0 AND rounding_ending < rounding_increment)
+);
@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS promotion_customer_group;
+
+DROP TABLE IF EXISTS price_list_customer_group;
+
+DROP TABLE IF EXISTS customer_group_member;
+
+DROP TABLE IF EXISTS customer_group;
@@ -0,0 +1,40 @@
+CREATE TABLE IF NOT EXISTS customer_group (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid() NOT NULL,
+    name citext NOT NULL,
+    description text,
+    created_at timestamp NOT NULL DEFAULT now(),
+    updated_at timestamp NOT NULL DEFAULT now(),
+    CONSTRAINT customer_group_name_key UNIQUE (name)
+);
+
+-- a user can belong to several groups, e.g. staff and wholesale
+CREATE TABLE IF NOT EXISTS customer_group_member (
+    customer_group_id uuid NOT NULL REFERENCES customer_group ON DELETE CASCADE,
+    user_id uuid NOT NULL REFERENCES users ON DELETE CASCADE,
+    created_at timestamp NOT NULL DEFAULT now(),
+    PRIMARY KEY (customer_group_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_customer_group_member_user_id ON customer_group_member(user_id);
+
+-- a price list with groups only applies to their members, one without groups applies to everyone
+CREATE TABLE IF NOT EXISTS price_list_customer_group (
+    price_list_id uuid NOT NULL REFERENCES price_list ON DELETE CASCADE,
+    customer_group_id uuid NOT NULL REFERENCES customer_group ON DELETE CASCADE,
+    PRIMARY KEY (price_list_id, customer_group_id)
+);
+
+-- same for the promotions
+CREATE TABLE IF NOT EXISTS promotion_customer_group (
+    promotion_id uuid NOT NULL REFERENCES promotion ON DELETE CASCADE,
+    customer_group_id uuid NOT NULL REFERENCES customer_group ON DELETE CASCADE,
+    PRIMARY KEY (promotion_id, customer_group_id)
+);
+
+INSERT INTO
+    customer_group (name, description)
+VALUES
+    ('vip', 'Customers with exclusive prices and promotions'),
+    ('wholesale', 'Business customers buying in bulk'),
+    ('staff', 'Employees of the store')
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,15 @@
+ALTER TABLE orders DROP COLUMN IF EXISTS store_credit_total;
+
+ALTER TABLE orders DROP COLUMN IF EXISTS gift_card_total;
+
+DROP TABLE IF EXISTS store_credit_transaction;
+
+DROP TABLE IF EXISTS gift_card_transaction;
+
+DROP TYPE IF EXISTS balance_transaction_type;
+
+DROP TABLE IF EXISTS gift_card;
+
+ALTER TABLE order_line_item DROP COLUMN IF EXISTS is_gift_card;
+
+ALTER TABLE product DROP COLUMN IF EXISTS is_gift_card;
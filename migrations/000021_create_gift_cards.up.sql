@@ -0,0 +1,61 @@
+-- buying a variant of a gift card product issues a gift card worth the variant price once the order is paid
+ALTER TABLE product ADD COLUMN IF NOT EXISTS is_gift_card boolean NOT NULL DEFAULT false;
+
+ALTER TABLE order_line_item ADD COLUMN IF NOT EXISTS is_gift_card boolean NOT NULL DEFAULT false;
+
+CREATE TABLE IF NOT EXISTS gift_card (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid() NOT NULL,
+    code citext NOT NULL,
+    currency_code VARCHAR(10) NOT NULL REFERENCES currency(code),
+    initial_balance bigint NOT NULL CHECK (initial_balance > 0),
+    balance bigint NOT NULL,
+    email citext, -- the recipient of the card
+    order_id uuid REFERENCES orders ON DELETE SET NULL, -- the order the card was bought with, nil for the ones issued by an admin
+    note text,
+    is_active boolean NOT NULL DEFAULT true,
+    expires_at timestamp,
+    created_at timestamp NOT NULL DEFAULT now(),
+    updated_at timestamp NOT NULL DEFAULT now(),
+    CONSTRAINT gift_card_code_key UNIQUE (code),
+    CONSTRAINT gift_card_balance_check CHECK (balance >= 0 AND balance <= initial_balance)
+);
+
+CREATE INDEX IF NOT EXISTS idx_gift_card_order_id ON gift_card(order_id);
+
+CREATE TYPE balance_transaction_type AS ENUM ('issue', 'adjustment', 'redeem', 'restore');
+
+-- every change of the balance of a gift card, the redeemed amounts are negative
+CREATE TABLE IF NOT EXISTS gift_card_transaction (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid() NOT NULL,
+    gift_card_id uuid NOT NULL REFERENCES gift_card ON DELETE CASCADE,
+    type balance_transaction_type NOT NULL,
+    amount bigint NOT NULL CHECK (amount <> 0),
+    order_id uuid REFERENCES orders ON DELETE SET NULL,
+    created_at timestamp NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_gift_card_transaction_gift_card_id ON gift_card_transaction(gift_card_id);
+
+CREATE INDEX IF NOT EXISTS idx_gift_card_transaction_order_id ON gift_card_transaction(order_id);
+
+-- the store credit of a user is the sum of their transactions by currency, it never goes below zero
+CREATE TABLE IF NOT EXISTS store_credit_transaction (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid() NOT NULL,
+    user_id uuid NOT NULL REFERENCES users ON DELETE CASCADE,
+    currency_code VARCHAR(10) NOT NULL REFERENCES currency(code),
+    type balance_transaction_type NOT NULL,
+    amount bigint NOT NULL CHECK (amount <> 0),
+    order_id uuid REFERENCES orders ON DELETE SET NULL,
+    actor_user_id uuid REFERENCES users ON DELETE SET NULL,
+    note text,
+    created_at timestamp NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_store_credit_transaction_user_id ON store_credit_transaction(user_id, currency_code);
+
+CREATE INDEX IF NOT EXISTS idx_store_credit_transaction_order_id ON store_credit_transaction(order_id);
+
+-- the parts of the total paid with gift cards and store credit, the payment provider is charged the rest
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS gift_card_total bigint NOT NULL DEFAULT 0;
+
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS store_credit_total bigint NOT NULL DEFAULT 0;
@@ -0,0 +1,9 @@
+DROP TABLE IF EXISTS order_return_event;
+
+DROP TABLE IF EXISTS order_return_item;
+
+DROP TABLE IF EXISTS order_return;
+
+DROP TYPE IF EXISTS return_reason;
+
+DROP TYPE IF EXISTS return_status;
@@ -0,0 +1,54 @@
+CREATE TYPE return_status AS ENUM ('requested', 'approved', 'rejected', 'received', 'refunded', 'cancelled');
+
+CREATE TYPE return_reason AS ENUM ('damaged', 'defective', 'wrong_item', 'not_as_described', 'no_longer_needed', 'other');
+
+-- a return of some lines of a delivered order, requested by the customer and processed by the admins
+CREATE TABLE IF NOT EXISTS order_return (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid() NOT NULL,
+    order_id uuid NOT NULL REFERENCES orders ON DELETE CASCADE,
+    status return_status NOT NULL DEFAULT 'requested',
+    note text, -- from the customer
+    location_id uuid REFERENCES stock_location ON DELETE SET NULL, -- where the returned items were received
+    shipping_refund bigint NOT NULL DEFAULT 0 CHECK (shipping_refund >= 0),
+    refund_total bigint NOT NULL DEFAULT 0 CHECK (refund_total >= 0), -- the refunded lines plus the shipping refund
+    approved_at timestamp,
+    rejected_at timestamp,
+    received_at timestamp,
+    refunded_at timestamp,
+    cancelled_at timestamp,
+    created_at timestamp NOT NULL DEFAULT now(),
+    updated_at timestamp NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_order_return_order_id ON order_return(order_id);
+
+CREATE INDEX IF NOT EXISTS idx_order_return_status ON order_return(status, created_at);
+
+CREATE TABLE IF NOT EXISTS order_return_item (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid() NOT NULL,
+    return_id uuid NOT NULL REFERENCES order_return ON DELETE CASCADE,
+    order_line_item_id uuid NOT NULL REFERENCES order_line_item ON DELETE CASCADE,
+    quantity int NOT NULL CHECK (quantity > 0),
+    reason return_reason NOT NULL,
+    note text,
+    received_quantity int NOT NULL DEFAULT 0,
+    restocked_quantity int NOT NULL DEFAULT 0, -- the damaged units aren't put back in stock
+    refund_amount bigint NOT NULL DEFAULT 0 CHECK (refund_amount >= 0),
+    CONSTRAINT order_return_item_line_key UNIQUE (return_id, order_line_item_id),
+    CONSTRAINT order_return_item_quantities_check CHECK (received_quantity >= 0 AND received_quantity <= quantity AND restocked_quantity >= 0 AND restocked_quantity <= received_quantity)
+);
+
+CREATE INDEX IF NOT EXISTS idx_order_return_item_order_line_item_id ON order_return_item(order_line_item_id);
+
+-- the history of the return statuses
+CREATE TABLE IF NOT EXISTS order_return_event (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid() NOT NULL,
+    return_id uuid NOT NULL REFERENCES order_return ON DELETE CASCADE,
+    from_status return_status,
+    to_status return_status NOT NULL,
+    actor_user_id uuid REFERENCES users ON DELETE SET NULL,
+    note text,
+    created_at timestamp NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_order_return_event_return_id ON order_return_event(return_id);
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_product_search_vector;
+
+DROP FUNCTION IF EXISTS product_search_vector(uuid);
+
+ALTER TABLE product DROP COLUMN IF EXISTS search_vector;
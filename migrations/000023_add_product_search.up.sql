@@ -0,0 +1,23 @@
+ALTER TABLE product ADD COLUMN IF NOT EXISTS search_vector tsvector NOT NULL DEFAULT ''::tsvector;
+
+-- the title weighs the most, then the subtitle and the sku of the variants, then the option values, then the description
+CREATE OR REPLACE FUNCTION product_search_vector(p_product_id uuid) RETURNS tsvector AS $$
+    SELECT setweight(to_tsvector('english', p.title), 'A') ||
+           setweight(to_tsvector('english', coalesce(p.subtitle, '')), 'B') ||
+           setweight(to_tsvector('english', coalesce((
+               SELECT string_agg(pv.sku, ' ') FROM product_variant AS pv
+               WHERE pv.product_id = p.id AND pv.deleted_at IS NULL
+           ), '')), 'B') ||
+           setweight(to_tsvector('english', coalesce((
+               SELECT string_agg(DISTINCT pov.title, ' ') FROM product_option_value AS pov
+               INNER JOIN product_variant AS pv ON pv.id = pov.variant_id
+               WHERE pv.product_id = p.id AND pv.deleted_at IS NULL AND pov.deleted_at IS NULL
+           ), '')), 'C') ||
+           setweight(to_tsvector('english', p.description), 'D')
+    FROM product AS p
+    WHERE p.id = p_product_id
+$$ LANGUAGE sql STABLE;
+
+UPDATE product SET search_vector = product_search_vector(id);
+
+CREATE INDEX IF NOT EXISTS idx_product_search_vector ON product USING GIN (search_vector);
@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_product_category_name_trgm;
+
+DROP INDEX IF EXISTS idx_product_variant_sku_trgm;
+
+DROP INDEX IF EXISTS idx_product_title_trgm;
+
+DROP EXTENSION IF EXISTS pg_trgm;
@@ -0,0 +1,8 @@
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+-- the suggestions only look at what the storefront shows, the partial indexes stay small
+CREATE INDEX IF NOT EXISTS idx_product_title_trgm ON product USING GIN (title gin_trgm_ops) WHERE deleted_at IS NULL AND status = 'published';
+
+CREATE INDEX IF NOT EXISTS idx_product_variant_sku_trgm ON product_variant USING GIN (sku gin_trgm_ops) WHERE deleted_at IS NULL;
+
+CREATE INDEX IF NOT EXISTS idx_product_category_name_trgm ON product_category USING GIN (name gin_trgm_ops) WHERE deleted_at IS NULL;
@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_product_lower_title;
+
+DROP INDEX IF EXISTS idx_product_created_at;
+
+DROP INDEX IF EXISTS idx_product_updated_at;
+
+DROP INDEX IF EXISTS idx_wishlist_variant_id;
@@ -0,0 +1,8 @@
+-- the popularity of a product is the number of wishlists its variants are in
+CREATE INDEX IF NOT EXISTS idx_wishlist_variant_id ON wishlist(variant_id);
+
+CREATE INDEX IF NOT EXISTS idx_product_updated_at ON product(updated_at, id) WHERE deleted_at IS NULL;
+
+CREATE INDEX IF NOT EXISTS idx_product_created_at ON product(created_at, id) WHERE deleted_at IS NULL;
+
+CREATE INDEX IF NOT EXISTS idx_product_lower_title ON product(lower(title), id) WHERE deleted_at IS NULL;
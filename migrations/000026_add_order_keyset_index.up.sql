@@ -0,0 +1,2 @@
+-- the orders are paged on (created_at, id) with cursors
+CREATE INDEX IF NOT EXISTS idx_orders_created_at_id ON orders(created_at, id);
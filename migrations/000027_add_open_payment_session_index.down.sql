@@ -0,0 +1 @@
+DROP INDEX IF EXISTS idx_payment_session_open_order_id;
@@ -0,0 +1,17 @@
+-- the sessions of voided payments stay authorized, close them before enforcing a single open session per order
+UPDATE payment_session AS s SET status = 'voided', updated_at = now()
+FROM payment AS p
+WHERE p.payment_session_id = s.id AND p.status = 'voided' AND s.status = 'authorized';
+
+-- only the latest of the sessions still waiting for an outcome is kept open
+UPDATE payment_session AS s SET status = 'error', updated_at = now()
+WHERE s.status IN ('pending', 'requires_action')
+  AND EXISTS (
+    SELECT 1 FROM payment_session AS o
+    WHERE o.order_id = s.order_id AND o.status IN ('pending', 'requires_action', 'authorized')
+      AND (o.created_at, o.id) > (s.created_at, s.id)
+  );
+
+-- an order can have only one payment session in progress or authorized at a time
+CREATE UNIQUE INDEX IF NOT EXISTS idx_payment_session_open_order_id ON payment_session(order_id)
+WHERE status IN ('pending', 'requires_action', 'authorized');
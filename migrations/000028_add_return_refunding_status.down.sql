@@ -0,0 +1,4 @@
+ALTER TABLE order_return DROP COLUMN IF EXISTS payment_refund;
+
+-- the values of an enum can't be dropped, the refunding returns go back to received
+UPDATE order_return SET status = 'received' WHERE status = 'refunding';
@@ -0,0 +1,4 @@
+-- a return stays refunding while the payment provider refunds what the store credit and the gift cards didn't cover
+ALTER TYPE return_status ADD VALUE IF NOT EXISTS 'refunding' AFTER 'received';
+
+ALTER TABLE order_return ADD COLUMN IF NOT EXISTS payment_refund bigint NOT NULL DEFAULT 0 CHECK (payment_refund >= 0); -- the part of the refund total refunded by the payment provider
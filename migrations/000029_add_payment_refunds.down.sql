@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS payment_refund;
+
+DROP TYPE IF EXISTS payment_refund_status;
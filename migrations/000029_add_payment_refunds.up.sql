@@ -0,0 +1,28 @@
+CREATE TYPE payment_refund_status AS ENUM ('pending', 'succeeded');
+
+-- every refund asked to the payment provider, it's pending until the provider refunded it. Its id is the idempotency
+-- key of the request so retrying a pending refund never refunds twice.
+CREATE TABLE IF NOT EXISTS payment_refund (
+    id uuid PRIMARY KEY DEFAULT gen_random_uuid() NOT NULL,
+    payment_id uuid NOT NULL REFERENCES payment ON DELETE CASCADE,
+    return_id uuid REFERENCES order_return ON DELETE SET NULL, -- the return refunded, nil for the refunds of the payment itself
+    status payment_refund_status NOT NULL DEFAULT 'pending',
+    amount bigint NOT NULL CHECK (amount > 0),
+    currency_code VARCHAR(10) NOT NULL REFERENCES currency(code),
+    created_at timestamp NOT NULL DEFAULT now(),
+    updated_at timestamp NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_payment_refund_payment_id ON payment_refund(payment_id);
+
+-- a return is refunded by the provider at most once
+CREATE UNIQUE INDEX IF NOT EXISTS idx_payment_refund_return_id ON payment_refund(return_id);
+
+-- the returns already refunding get the refund of the payment of their order they're waiting for
+INSERT INTO payment_refund (payment_id, return_id, amount, currency_code)
+SELECT p.id, r.id, r.payment_refund, p.currency_code
+FROM order_return AS r
+CROSS JOIN LATERAL (
+    SELECT id, currency_code FROM payment WHERE order_id = r.order_id AND status != 'voided' ORDER BY created_at DESC LIMIT 1
+) AS p
+WHERE r.status = 'refunding' AND r.payment_refund > 0;
@@ -0,0 +1,2 @@
+-- the values of an enum can't be dropped, the capturing payments go back to authorized
+UPDATE payment SET status = 'authorized' WHERE status = 'capturing';
@@ -0,0 +1,2 @@
+-- a payment is capturing while the payment provider collects it, so it's neither captured nor voided twice meanwhile
+ALTER TYPE payment_status ADD VALUE IF NOT EXISTS 'capturing' AFTER 'authorized';